@@ -2,17 +2,31 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charly-vibes/fabbro/internal/config"
+	"github.com/charly-vibes/fabbro/internal/diff"
 	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/gitsync"
+	"github.com/charly-vibes/fabbro/internal/ingest"
+	"github.com/charly-vibes/fabbro/internal/output"
+	"github.com/charly-vibes/fabbro/internal/prompt"
+	"github.com/charly-vibes/fabbro/internal/report"
+	"github.com/charly-vibes/fabbro/internal/sarif"
+	"github.com/charly-vibes/fabbro/internal/scan"
+	"github.com/charly-vibes/fabbro/internal/search"
 	"github.com/charly-vibes/fabbro/internal/session"
+	"github.com/charly-vibes/fabbro/internal/snippet"
+	"github.com/charly-vibes/fabbro/internal/stats"
 	"github.com/charly-vibes/fabbro/internal/tui"
 	"github.com/charly-vibes/fabbro/internal/tutor"
 	"github.com/spf13/cobra"
@@ -43,12 +57,64 @@ func realMain(args []string, stdin io.Reader, stdout, stderr io.Writer, tui TUIR
 	rootCmd.SetOut(stdout)
 	rootCmd.SetErr(stderr)
 
+	structured := wantsJSON(rootCmd, args)
+	if structured {
+		// The error document replaces cobra's "Error:" line and usage text.
+		rootCmd.SilenceErrors = true
+		rootCmd.SilenceUsage = true
+	}
+
 	if err := rootCmd.Execute(); err != nil {
+		code := output.CodeOf(err)
+		if structured {
+			output.WriteError(stderr, err)
+			return code.ExitCode()
+		}
 		return 1
 	}
 	return 0
 }
 
+// wantsJSON reports whether errors take the structured form: with --json or
+// --format json, and always for wait, whose only output is the apply JSON
+// document. It's checked before parsing so flag errors are covered.
+func wantsJSON(root *cobra.Command, args []string) bool {
+	if cmd, _, err := root.Find(args); err == nil && cmd.Name() == "wait" && cmd.Parent() == root {
+		return true
+	}
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return false
+		case arg == "--json" || arg == "--json=true" || arg == "--format=json":
+			return true
+		case arg == "--format" && i+1 < len(args) && args[i+1] == "json":
+			return true
+		}
+	}
+	return false
+}
+
+var errNotInitialized = output.Errorf(output.CodeNotFound, "fabbro not initialized. Run 'fabbro init' first")
+
+// markUsageErrors classifies flag and argument-count errors of cmd and its
+// subcommands as usage errors. Subcommands inherit the flag error func.
+func markUsageErrors(cmd *cobra.Command) {
+	if !cmd.HasParent() {
+		cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+			return output.Wrap(output.CodeUsage, err)
+		})
+	}
+	if validate := cmd.Args; validate != nil {
+		cmd.Args = func(c *cobra.Command, args []string) error {
+			return output.Wrap(output.CodeUsage, validate(c, args))
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		markUsageErrors(sub)
+	}
+}
+
 func buildRootCmd(stdin io.Reader, stdout io.Writer, tuiRun TUIRunner) *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   "fabbro",
@@ -67,7 +133,18 @@ A code review annotation tool with a terminal UI.`,
 	rootCmd.AddCommand(buildCompletionCmd())
 	rootCmd.AddCommand(buildTutorCmd(stdout, tuiRun))
 	rootCmd.AddCommand(buildPrimeCmd(stdout))
-
+	rootCmd.AddCommand(buildSyncCmd(stdout))
+	rootCmd.AddCommand(buildWaitCmd(stdout))
+	rootCmd.AddCommand(buildReportCmd(stdout))
+	rootCmd.AddCommand(buildSchemaCmd(stdout))
+	rootCmd.AddCommand(buildCheckCmd(stdout))
+	rootCmd.AddCommand(buildSearchCmd(stdout))
+	rootCmd.AddCommand(buildStatsCmd(stdout))
+	rootCmd.AddCommand(buildScanCmd(stdout))
+	rootCmd.AddCommand(buildHooksCmd(stdout))
+	rootCmd.AddCommand(buildSnippetsCmd(stdout))
+
+	markUsageErrors(rootCmd)
 	return rootCmd
 }
 
@@ -104,7 +181,7 @@ PowerShell:
 			case "powershell":
 				return cmd.Root().GenPowerShellCompletionWithDesc(cmd.OutOrStdout())
 			default:
-				return fmt.Errorf("unsupported shell: %s. Supported shells: bash, zsh, fish, powershell", args[0])
+				return output.Errorf(output.CodeUsage, "unsupported shell: %s. Supported shells: bash, zsh, fish, powershell", args[0])
 			}
 		},
 	}
@@ -187,7 +264,7 @@ Post-conditions:
 			parentRoot, _ := config.FindProjectRoot()
 
 			if err := config.Init(); err != nil {
-				return fmt.Errorf("failed to initialize: %w", err)
+				return output.Errorf(output.CodeIO, "failed to initialize: %w", err)
 			}
 			if !quietFlag {
 				if parentRoot != "" {
@@ -211,11 +288,11 @@ Post-conditions:
 
 				for _, dir := range agentDirs {
 					if err := os.MkdirAll(dir, 0755); err != nil {
-						return fmt.Errorf("failed to create %s: %w", dir, err)
+						return output.Errorf(output.CodeIO, "failed to create %s: %w", dir, err)
 					}
 					dest := filepath.Join(dir, "fabbro-review.md")
 					if err := os.WriteFile(dest, []byte(agentCommandTemplate), 0644); err != nil {
-						return fmt.Errorf("failed to write %s: %w", dest, err)
+						return output.Errorf(output.CodeIO, "failed to write %s: %w", dest, err)
 					}
 				}
 
@@ -223,7 +300,7 @@ Post-conditions:
 				agentsMDPath := "AGENTS.md"
 				existing, err := os.ReadFile(agentsMDPath)
 				if err != nil && !os.IsNotExist(err) {
-					return fmt.Errorf("failed to read %s: %w", agentsMDPath, err)
+					return output.Errorf(output.CodeIO, "failed to read %s: %w", agentsMDPath, err)
 				}
 				content := string(existing)
 				if !strings.Contains(content, "## fabbro workflow") {
@@ -235,7 +312,7 @@ Post-conditions:
 					}
 					content += agentsWorkflowSection
 					if err := os.WriteFile(agentsMDPath, []byte(content), 0644); err != nil {
-						return fmt.Errorf("failed to write %s: %w", agentsMDPath, err)
+						return output.Errorf(output.CodeIO, "failed to write %s: %w", agentsMDPath, err)
 					}
 				}
 
@@ -262,6 +339,7 @@ func buildReviewCmd(stdin io.Reader, stdout io.Writer, tuiRun TUIRunner) *cobra.
 	var idFlag string
 	var editorFlag bool
 	var noInteractiveFlag bool
+	var inputFormatFlag string
 	cmd := &cobra.Command{
 		Use:   "review [file]",
 		Short: "Start a review session",
@@ -273,11 +351,23 @@ Pre-conditions:
 
 Post-conditions:
   - A new session is created and stored in .fabbro/sessions/.
+  - HTML input (.html/.htm files, or --input-format html) is converted to
+    Markdown; the session records the conversion and which line of the
+    original each Markdown line came from.
+  - Word documents (.docx files, or --input-format docx) are converted to
+    Markdown too, and their comments imported as comment annotations.
+    Legacy .doc files are rejected.
   - The TUI opens for interactive annotation.
   - Session ID is printed for later reference.`,
 		Example: `  # Review a specific file
   fabbro review main.go
 
+  # Review a Word document, importing its comments
+  fabbro review spec.docx
+
+  # Review a web page as Markdown
+  curl -s https://example.com/post | fabbro review --stdin --input-format html
+
   # Review content piped from another command
   git show HEAD:main.go | fabbro review --stdin
 
@@ -286,7 +376,7 @@ Post-conditions:
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !config.IsInitialized() {
-				return fmt.Errorf("fabbro not initialized. Run 'fabbro init' first")
+				return errNotInitialized
 			}
 
 			var content string
@@ -294,17 +384,17 @@ Post-conditions:
 			var err error
 
 			if stdinFlag && len(args) == 1 {
-				return fmt.Errorf("cannot use both --stdin and a file path")
+				return output.Errorf(output.CodeUsage, "cannot use both --stdin and a file path")
 			}
 
 			if stdinFlag {
 				limitedReader := io.LimitReader(stdin, maxInputBytes+1)
 				data, err := io.ReadAll(limitedReader)
 				if err != nil {
-					return fmt.Errorf("failed to read stdin: %w", err)
+					return output.Errorf(output.CodeIO, "failed to read stdin: %w", err)
 				}
 				if len(data) > maxInputBytes {
-					return fmt.Errorf("input too large: exceeds %d bytes", maxInputBytes)
+					return output.Errorf(output.CodeData, "input too large: exceeds %d bytes", maxInputBytes)
 				}
 				content = string(data)
 			} else if len(args) == 1 {
@@ -312,26 +402,48 @@ Post-conditions:
 				info, err := os.Stat(sourceFile)
 				if err != nil {
 					if os.IsNotExist(err) {
-						return fmt.Errorf("file not found: %s. Check the path and try again", sourceFile)
+						return output.Errorf(output.CodeNotFound, "file not found: %s. Check the path and try again", sourceFile)
 					}
-					return fmt.Errorf("failed to stat file: %w", err)
+					return output.Errorf(output.CodeIO, "failed to stat file: %w", err)
 				}
 				if info.Size() > maxInputBytes {
-					return fmt.Errorf("file too large: %s exceeds %d bytes", sourceFile, maxInputBytes)
+					return output.Errorf(output.CodeData, "file too large: %s exceeds %d bytes", sourceFile, maxInputBytes)
 				}
 				data, err := os.ReadFile(sourceFile)
 				if err != nil {
-					return fmt.Errorf("failed to read file: %w", err)
+					return output.Errorf(output.CodeIO, "failed to read file: %w", err)
 				}
 				content = string(data)
 			} else {
-				return fmt.Errorf("no input file specified. Provide a file path as an argument or pipe content via --stdin")
+				return output.Errorf(output.CodeUsage, "no input file specified. Provide a file path as an argument or pipe content via --stdin")
+			}
+
+			format, err := ingest.ParseFormat(inputFormatFlag)
+			if err != nil {
+				return output.Wrap(output.CodeUsage, err)
+			}
+			if format == "auto" {
+				format = ingest.Detect(sourceFile)
+			}
+			doc, err := ingest.Convert(format, []byte(content))
+			if err != nil {
+				return output.Wrap(output.CodeData, err)
 			}
 
 			var sess *session.Session
-			if idFlag != "" {
+			switch {
+			case doc != nil:
+				if doc.Markdown == "" {
+					return output.Errorf(output.CodeData, "no text found in %s input", doc.Format)
+				}
+				conv := &session.Conversion{From: doc.Format, LineMap: doc.LineMap}
+				// Render escapes any FEM markers in the converted text,
+				// so it goes through Render even without imported comments.
+				markdown := fem.Render(doc.Markdown, doc.Annotations)
+				sess, err = session.CreateConverted(idFlag, markdown, sourceFile, content, conv)
+			case idFlag != "":
 				sess, err = session.CreateWithID(idFlag, content, sourceFile)
-			} else {
+			default:
 				sess, err = session.Create(content, sourceFile)
 			}
 			if err != nil {
@@ -344,7 +456,11 @@ Post-conditions:
 			}
 
 			if jsonFlag {
-				json.NewEncoder(stdout).Encode(map[string]string{"sessionId": sess.ID})
+				output.Write(stdout, output.Review{SchemaVersion: output.SchemaVersion, SessionID: sess.ID}, true)
+			} else if sess.Conversion != nil && len(doc.Annotations) > 0 {
+				fmt.Fprintf(stdout, "Created session: %s (converted from %s to Markdown, %d comment(s) imported)\n", sess.ID, sess.Conversion.From, len(doc.Annotations))
+			} else if sess.Conversion != nil {
+				fmt.Fprintf(stdout, "Created session: %s (converted from %s to Markdown)\n", sess.ID, sess.Conversion.From)
 			} else {
 				fmt.Fprintf(stdout, "Created session: %s\n", sess.ID)
 			}
@@ -384,6 +500,7 @@ Post-conditions:
 	cmd.Flags().StringVar(&idFlag, "id", "", "Custom session ID (alphanumeric, dash, underscore; max 64 chars)")
 	cmd.Flags().BoolVar(&editorFlag, "editor", false, "Open in $EDITOR instead of TUI")
 	cmd.Flags().BoolVar(&noInteractiveFlag, "no-interactive", false, "Create session without opening TUI or editor")
+	cmd.Flags().StringVar(&inputFormatFlag, "input-format", "auto", "Input format: auto (by file extension), text, html, or docx")
 	return cmd
 }
 
@@ -391,6 +508,10 @@ func buildApplyCmd(stdout io.Writer) *cobra.Command {
 	var jsonFlag bool
 	var compactFlag bool
 	var fileFlag string
+	var formatFlag string
+	var markAppliedFlag bool
+	var contextFlag int
+	var maxTokensFlag int
 	cmd := &cobra.Command{
 		Use:   "apply [session-id]",
 		Short: "Apply annotations from a session",
@@ -402,7 +523,13 @@ Pre-conditions:
 
 Post-conditions:
   - Annotations are parsed from the session content.
-  - Output is printed to stdout (human-readable or JSON with --json).`,
+  - Output is printed to stdout: human-readable, JSON (--json or --format json),
+    a SARIF 2.1.0 log (--format sarif), or agent instructions with quoted
+    source (--format prompt).
+  - With --format prompt, each annotation is shown with --context lines of the
+    reviewed content around it. --max-tokens trims that context (and, if
+    needed, the quoted lines) to fit an approximate token budget.
+  - With --mark-applied, the session's status becomes applied.`,
 		Example: `  # Apply annotations from a specific session
   fabbro apply abc123
 
@@ -410,19 +537,46 @@ Post-conditions:
   fabbro apply --file main.go
 
   # Get annotations as JSON for programmatic use
-  fabbro apply abc123 --json`,
+  fabbro apply abc123 --json
+
+  # Record that the annotations have been acted on
+  fabbro apply abc123 --json --mark-applied
+
+  # Export for code-scanning viewers
+  fabbro apply abc123 --format sarif > review.sarif
+
+  # Hand the review to a coding agent, with 5 lines of context per annotation
+  fabbro apply abc123 --format prompt --context 5 --max-tokens 4000`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !config.IsInitialized() {
-				return fmt.Errorf("fabbro not initialized. Run 'fabbro init' first")
+				return errNotInitialized
+			}
+
+			switch formatFlag {
+			case "", "text", "json", "sarif", "prompt":
+			default:
+				return output.Errorf(output.CodeUsage, "invalid format %q: must be text, json, sarif, or prompt", formatFlag)
+			}
+			if formatFlag != "prompt" && (cmd.Flags().Changed("context") || cmd.Flags().Changed("max-tokens")) {
+				return output.Errorf(output.CodeUsage, "--context and --max-tokens require --format prompt")
+			}
+			if contextFlag < 0 || maxTokensFlag < 0 {
+				return output.Errorf(output.CodeUsage, "--context and --max-tokens must not be negative")
+			}
+			if jsonFlag {
+				if formatFlag != "" && formatFlag != "json" {
+					return output.Errorf(output.CodeUsage, "cannot use --json with --format %s", formatFlag)
+				}
+				formatFlag = "json"
 			}
 
 			// Validate mutual exclusivity
 			if fileFlag != "" && len(args) == 1 {
-				return fmt.Errorf("cannot use both session-id and --file")
+				return output.Errorf(output.CodeUsage, "cannot use both session-id and --file")
 			}
 			if fileFlag == "" && len(args) == 0 {
-				return fmt.Errorf("no session specified. Provide a session ID as an argument or use --file to find by source file. Run 'fabbro session list' to see available sessions")
+				return output.Errorf(output.CodeUsage, "no session specified. Provide a session ID as an argument or use --file to find by source file. Run 'fabbro session list' to see available sessions")
 			}
 
 			var sess *session.Session
@@ -443,7 +597,7 @@ Post-conditions:
 
 			annotations, _, err := fem.Parse(sess.Content)
 			if err != nil {
-				return fmt.Errorf("failed to parse FEM in session %q: %w", sess.ID, err)
+				return output.Errorf(output.CodeData, "failed to parse FEM in session %q: %w", sess.ID, err)
 			}
 
 			// Verify source file hash
@@ -453,47 +607,91 @@ Post-conditions:
 				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: source file has changed since session was created. Line numbers may have drifted.\n")
 			}
 
-			if jsonFlag {
-				output := struct {
-					SessionID   string           `json:"sessionId"`
-					SourceFile  string           `json:"sourceFile"`
-					CreatedAt   string           `json:"createdAt"`
-					Annotations []fem.Annotation `json:"annotations"`
-				}{
-					SessionID:   sess.ID,
-					SourceFile:  sess.SourceFile,
-					CreatedAt:   sess.CreatedAt.Format(time.RFC3339),
-					Annotations: annotations,
+			if markAppliedFlag {
+				if err := session.SetStatus(sess, session.StatusApplied); err != nil {
+					return err
 				}
+			}
 
+			switch formatFlag {
+			case "json":
+				return writeApplyJSON(stdout, sess, annotations, compactFlag)
+			case "sarif":
 				enc := json.NewEncoder(stdout)
 				if !compactFlag {
 					enc.SetIndent("", "  ")
 				}
-				return enc.Encode(output)
+				return enc.Encode(sarif.FromSession(sess, annotations, version))
+			case "prompt":
+				res, err := prompt.Render(sess, annotations, prompt.Options{Context: contextFlag, Budget: maxTokensFlag})
+				if err != nil {
+					return err
+				}
+				if res.OverLimit {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: prompt exceeds %d tokens even without source excerpts\n", maxTokensFlag)
+				}
+				_, err = io.WriteString(stdout, res.Text)
+				return err
 			}
 
 			fmt.Fprintf(stdout, "Session: %s\n", sess.ID)
 			if sess.SourceFile != "" {
 				fmt.Fprintf(stdout, "Source: %s\n", sess.SourceFile)
 			}
+			if c := sess.Conversion; c != nil && len(c.LineMap) > 0 {
+				fmt.Fprintf(stdout, "Converted from: %s (lines refer to the Markdown; source lines in parentheses)\n", c.From)
+			} else if c != nil {
+				fmt.Fprintf(stdout, "Converted from: %s (lines refer to the Markdown)\n", c.From)
+			}
 			fmt.Fprintf(stdout, "Annotations: %d\n", len(annotations))
 			for _, a := range annotations {
-				if a.StartLine == a.EndLine {
-					fmt.Fprintf(stdout, "  Line %d: [%s] %s\n", a.StartLine, a.Type, a.Text)
-				} else {
-					fmt.Fprintf(stdout, "  Lines %d-%d: [%s] %s\n", a.StartLine, a.EndLine, a.Type, a.Text)
+				desc := describeAnnotation(a)
+				if sess.Conversion != nil {
+					if from, to, ok := sess.SourceLines(a.StartLine, a.EndLine); ok {
+						desc += fmt.Sprintf(" (%s %s)", sess.Conversion.From, lineRange(from, to))
+					}
 				}
+				fmt.Fprintf(stdout, "  %s\n", desc)
 			}
 			return nil
 		},
 	}
 	cmd.Flags().BoolVar(&jsonFlag, "json", false, "Output as JSON")
-	cmd.Flags().BoolVar(&compactFlag, "compact", false, "Output minified JSON (use with --json)")
+	cmd.Flags().BoolVar(&compactFlag, "compact", false, "Output minified JSON (use with --json or --format sarif)")
 	cmd.Flags().StringVar(&fileFlag, "file", "", "Find session by source file path")
+	cmd.Flags().StringVar(&formatFlag, "format", "", "Output format: text, json, sarif, or prompt")
+	cmd.Flags().BoolVar(&markAppliedFlag, "mark-applied", false, "Set the session's status to applied")
+	cmd.Flags().IntVar(&contextFlag, "context", prompt.DefaultContext, "Lines of source around each annotation (with --format prompt)")
+	cmd.Flags().IntVar(&maxTokensFlag, "max-tokens", 0, "Approximate token budget for --format prompt; 0 for no limit")
 	return cmd
 }
 
+// writeApplyJSON writes the `apply --json` payload for sess.
+func writeApplyJSON(stdout io.Writer, sess *session.Session, annotations []fem.Annotation, compact bool) error {
+	return output.Write(stdout, output.NewApply(sess, annotations), compact)
+}
+
+// lineRange formats a line range as "line N" or "lines N-M".
+func lineRange(start, end int) string {
+	if start == end {
+		return fmt.Sprintf("line %d", start)
+	}
+	return fmt.Sprintf("lines %d-%d", start, end)
+}
+
+// describeAnnotation formats an annotation as "Line N: [type] text",
+// followed by "(by name)" when the annotation is attributed.
+func describeAnnotation(a fem.Annotation) string {
+	text := a.Text
+	if a.Author != "" {
+		text += " (by " + a.Author + ")"
+	}
+	if a.StartLine == a.EndLine {
+		return fmt.Sprintf("Line %d: [%s] %s", a.StartLine, a.Type, text)
+	}
+	return fmt.Sprintf("Lines %d-%d: [%s] %s", a.StartLine, a.EndLine, a.Type, text)
+}
+
 func buildSessionCmd(stdin io.Reader, stdout io.Writer, tuiRun TUIRunner) *cobra.Command {
 	sessionCmd := &cobra.Command{
 		Use:   "session",
@@ -506,31 +704,114 @@ Each session is identified by a unique ID and stored in .fabbro/sessions/.`,
 
 	sessionCmd.AddCommand(buildSessionListCmd(stdout))
 	sessionCmd.AddCommand(buildSessionShowCmd(stdout))
+	sessionCmd.AddCommand(buildSessionAnnotateCmd(stdout))
 	sessionCmd.AddCommand(buildSessionResumeCmd(stdout, tuiRun))
 	sessionCmd.AddCommand(buildSessionDeleteCmd(stdin, stdout))
 	sessionCmd.AddCommand(buildSessionCleanCmd(stdin, stdout))
 	sessionCmd.AddCommand(buildSessionExportCmd(stdout))
+	sessionCmd.AddCommand(buildSessionHistoryCmd(stdout))
+	sessionCmd.AddCommand(buildSessionDiffCmd(stdout))
+	sessionCmd.AddCommand(buildSessionRestoreCmd(stdout))
+	sessionCmd.AddCommand(buildSessionMergeCmd(stdout))
+	sessionCmd.AddCommand(buildSessionStatusCmd(stdout, "finish", session.StatusReady,
+		"Mark a session as ready for the agent",
+		`Mark a review as finished so the agent knows it can apply it.
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+  - The session must be in progress.
+
+Post-conditions:
+  - The session's status is ready.`))
+	sessionCmd.AddCommand(buildSessionStatusCmd(stdout, "reopen", session.StatusInProgress,
+		"Return a session to in progress",
+		`Reopen a finished, applied or closed session to continue reviewing it.
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+  - The session must not already be in progress.
+
+Post-conditions:
+  - The session's status is in_progress.`))
+	sessionCmd.AddCommand(buildSessionStatusCmd(stdout, "close", session.StatusClosed,
+		"Abandon a session without applying it",
+		`Close a session that will not be applied.
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+  - The session must be in progress or ready.
+
+Post-conditions:
+  - The session's status is closed. Its file is kept; use 'fabbro session clean --status closed' to remove it.`))
 	return sessionCmd
 }
 
+// buildSessionStatusCmd builds a command that moves a session to status to.
+func buildSessionStatusCmd(stdout io.Writer, name string, to session.Status, short, long string) *cobra.Command {
+	return &cobra.Command{
+		Use:     name + " <session-id>",
+		Short:   short,
+		Long:    long,
+		Example: fmt.Sprintf("  fabbro session %s abc123", name),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+
+			sess, err := session.LoadPartial(args[0])
+			if err != nil {
+				return err
+			}
+			if sess.Status == to {
+				return fmt.Errorf("session %s is already %s", sess.ID, to.Label())
+			}
+			if err := session.SetStatus(sess, to); err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "Session %s is now %s\n", sess.ID, to.Label())
+			return nil
+		},
+	}
+}
+
 // parseDaysDuration parses a duration string like "7d", "14d", "30d".
 func parseDaysDuration(s string) (time.Duration, error) {
 	if !strings.HasSuffix(s, "d") {
-		return 0, fmt.Errorf("invalid duration format: %s (use Nd, e.g. 7d)", s)
+		return 0, output.Errorf(output.CodeUsage, "invalid duration format: %s (use Nd, e.g. 7d)", s)
 	}
 	numStr := strings.TrimSuffix(s, "d")
 	var days int
 	if _, err := fmt.Sscanf(numStr, "%d", &days); err != nil {
-		return 0, fmt.Errorf("invalid duration: %s", s)
+		return 0, output.Errorf(output.CodeUsage, "invalid duration: %s", s)
 	}
 	if days < 0 {
-		return 0, fmt.Errorf("duration must be positive: %s", s)
+		return 0, output.Errorf(output.CodeUsage, "duration must be positive: %s", s)
 	}
 	return time.Duration(days) * 24 * time.Hour, nil
 }
 
+// parseStatusFilter parses --status values into a set. An empty filter
+// matches every session.
+func parseStatusFilter(values []string) (map[session.Status]bool, error) {
+	filter := make(map[session.Status]bool)
+	for _, v := range values {
+		st, err := session.ParseStatus(v)
+		if err != nil {
+			return nil, output.Wrap(output.CodeUsage, err)
+		}
+		filter[st] = true
+	}
+	return filter, nil
+}
+
+func matchesStatus(filter map[session.Status]bool, s *session.Session) bool {
+	return len(filter) == 0 || filter[s.Status]
+}
+
 func buildSessionListCmd(stdout io.Writer) *cobra.Command {
 	var jsonFlag bool
+	var statusFlag []string
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all editing sessions",
@@ -540,22 +821,37 @@ Pre-conditions:
   - fabbro must be initialized (run 'fabbro init' first).
 
 Post-conditions:
-  - All sessions are listed with their ID, creation date, and source file.
+  - All sessions are listed with their ID, creation date, status, and source file.
+  - Use --status to show only sessions in the given statuses.
   - Use --json for machine-readable output.`,
 		Example: `  # List all sessions
   fabbro session list
 
+  # List sessions ready for the agent
+  fabbro session list --status ready
+
   # List sessions as JSON for scripting
   fabbro session list --json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !config.IsInitialized() {
-				return fmt.Errorf("fabbro not initialized. Run 'fabbro init' first")
+				return errNotInitialized
 			}
 
-			sessions, err := session.List()
+			filter, err := parseStatusFilter(statusFlag)
+			if err != nil {
+				return err
+			}
+
+			all, err := session.List()
 			if err != nil {
 				return fmt.Errorf("failed to list sessions: %w", err)
 			}
+			var sessions []*session.Session
+			for _, s := range all {
+				if matchesStatus(filter, s) {
+					sessions = append(sessions, s)
+				}
+			}
 
 			// Count annotations for each session
 			type sessionInfo struct {
@@ -569,27 +865,24 @@ Post-conditions:
 			}
 
 			if jsonFlag {
-				type sessionOutput struct {
-					ID          string `json:"id"`
-					CreatedAt   string `json:"createdAt"`
-					SourceFile  string `json:"sourceFile,omitempty"`
-					Annotations int    `json:"annotations"`
-				}
-				output := make([]sessionOutput, len(infos))
-				for i, info := range infos {
-					output[i] = sessionOutput{
+				list := output.SessionList{SchemaVersion: output.SchemaVersion, Sessions: []output.SessionSummary{}}
+				for _, info := range infos {
+					list.Sessions = append(list.Sessions, output.SessionSummary{
 						ID:          info.session.ID,
-						CreatedAt:   info.session.CreatedAt.Format("2006-01-02 15:04:05"),
+						CreatedAt:   output.Timestamp(info.session.CreatedAt),
 						SourceFile:  info.session.SourceFile,
+						Status:      string(info.session.Status),
 						Annotations: info.annotations,
-					}
+					})
 				}
-				enc := json.NewEncoder(stdout)
-				enc.SetIndent("", "  ")
-				return enc.Encode(output)
+				return output.Write(stdout, list, false)
 			}
 
 			if len(sessions) == 0 {
+				if len(filter) > 0 {
+					fmt.Fprintln(stdout, "No sessions with that status.")
+					return nil
+				}
 				fmt.Fprintln(stdout, "No sessions found.")
 				fmt.Fprintln(stdout, "Start a review with: fabbro review <file>")
 				return nil
@@ -602,12 +895,13 @@ Post-conditions:
 				if s.SourceFile != "" {
 					source = s.SourceFile
 				}
-				fmt.Fprintf(stdout, "%s  %s  %-20s  %d annotations\n", s.ID, date, source, info.annotations)
+				fmt.Fprintf(stdout, "%s  %s  %-11s  %-20s  %d annotations\n", s.ID, date, s.Status.Label(), source, info.annotations)
 			}
 			return nil
 		},
 	}
 	cmd.Flags().BoolVar(&jsonFlag, "json", false, "Output as JSON")
+	cmd.Flags().StringSliceVar(&statusFlag, "status", nil, "Only list sessions with these statuses (in_progress, ready, applied, closed)")
 	return cmd
 }
 
@@ -622,13 +916,13 @@ Pre-conditions:
   - The session ID must exist (use 'fabbro session list' to find IDs).
 
 Post-conditions:
-  - Session metadata and annotation breakdown are printed to stdout.`,
+  - Session metadata, review coverage and annotation breakdown are printed to stdout.`,
 		Example: `  # Show details for a session
   fabbro session show abc123`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !config.IsInitialized() {
-				return fmt.Errorf("fabbro not initialized. Run 'fabbro init' first")
+				return errNotInitialized
 			}
 
 			sessionID := args[0]
@@ -639,7 +933,7 @@ Post-conditions:
 
 			annotations, _, err := fem.Parse(sess.Content)
 			if err != nil {
-				return fmt.Errorf("failed to parse session content: %w", err)
+				return output.Errorf(output.CodeData, "failed to parse session content: %w", err)
 			}
 
 			source := "(stdin)"
@@ -647,12 +941,20 @@ Post-conditions:
 				source = sess.SourceFile
 			}
 
-			contentLines := len(strings.Split(sess.Content, "\n"))
+			coverage := sess.Coverage()
 
 			fmt.Fprintf(stdout, "Session ID:     %s\n", sess.ID)
 			fmt.Fprintf(stdout, "Created:        %s\n", sess.CreatedAt.Format("2006-01-02 15:04:05"))
 			fmt.Fprintf(stdout, "Source:         %s\n", source)
-			fmt.Fprintf(stdout, "Content lines:  %d\n", contentLines)
+			if sess.Conversion != nil {
+				fmt.Fprintf(stdout, "Converted from: %s\n", sess.Conversion.From)
+			}
+			fmt.Fprintf(stdout, "Status:         %s\n", sess.Status.Label())
+			fmt.Fprintf(stdout, "Content lines:  %d\n", coverage.Lines)
+			fmt.Fprintf(stdout, "Reviewed:       %d%% (%d of %d lines)\n", coverage.Percent, coverage.Reviewed, coverage.Lines)
+			if coverage.Reviewed > 0 && len(coverage.Unreviewed) > 0 {
+				fmt.Fprintf(stdout, "Not reviewed:   lines %s\n", session.FormatRanges(coverage.Unreviewed))
+			}
 			fmt.Fprintln(stdout)
 
 			if len(annotations) == 0 {
@@ -675,6 +977,166 @@ Post-conditions:
 	}
 }
 
+func buildSessionAnnotateCmd(stdout io.Writer) *cobra.Command {
+	var typeFlag string
+	var snippetFlag string
+	cmd := &cobra.Command{
+		Use:   "annotate <session-id> <lines> [text]",
+		Short: "Add an annotation to a session",
+		Long: `Add an annotation to a saved session without opening the TUI.
+
+Lines are given as N or N-M. The text is either given as an argument or
+taken from a snippet configured in .fabbro/config.json or the user config,
+with its placeholders ({file}, {lines}, {start}, {end}, {text}) filled in
+from the annotated lines. See 'fabbro snippets'.
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+  - The session ID must exist (use 'fabbro session list' to find IDs).
+  - Either text or --snippet is given, not both.
+
+Post-conditions:
+  - The annotation is added to the session and a new revision is recorded.
+  - The type is --type, else the snippet's type, else comment.`,
+		Example: `  # Comment on lines 10-14
+  fabbro session annotate abc123 10-14 "Needs error wrapping"
+
+  # Use a snippet
+  fabbro session annotate abc123 42 --snippet test
+
+  # Ask a question with a snippet's text
+  fabbro session annotate abc123 7 --snippet why --type question`,
+		Args: cobra.RangeArgs(2, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+
+			text := ""
+			if len(args) == 3 {
+				text = strings.TrimSpace(args[2])
+			}
+			switch {
+			case text != "" && snippetFlag != "":
+				return output.Errorf(output.CodeUsage, "give either text or --snippet, not both")
+			case text == "" && snippetFlag == "":
+				return output.Errorf(output.CodeUsage, "no annotation text: give text or --snippet")
+			case typeFlag != "" && !fem.ValidAnnotationType(typeFlag):
+				return output.Errorf(output.CodeUsage, "unknown annotation type %q", typeFlag)
+			}
+
+			sess, err := session.LoadPartial(args[0])
+			if err != nil {
+				return err
+			}
+			annotations, cleanContent, err := fem.Parse(sess.Content)
+			if err != nil {
+				return output.Errorf(output.CodeData, "failed to parse session content: %w", err)
+			}
+			lines := strings.Split(cleanContent, "\n")
+			start, end, err := parseLineSpec(args[1], len(lines))
+			if err != nil {
+				return err
+			}
+
+			annType := typeFlag
+			if snippetFlag != "" {
+				snippets, err := snippet.Load()
+				if err != nil {
+					return output.Wrap(output.CodeData, err)
+				}
+				s, ok := snippet.Find(snippets, snippetFlag)
+				if !ok {
+					return output.Errorf(output.CodeNotFound, "unknown snippet %q. Run 'fabbro snippets' to list them", snippetFlag)
+				}
+				text = snippet.Expand(s.Text, snippet.Context{File: sess.SourceFile, Start: start, End: end, Lines: lines[start-1 : end]})
+				if annType == "" {
+					annType = snippet.Type(s)
+				}
+			}
+			if annType == "" {
+				annType = "comment"
+			}
+
+			ann := fem.Annotation{Type: annType, Text: strings.ReplaceAll(text, "\n", `\n`), StartLine: start, EndLine: end}
+			sess.Content = fem.Render(cleanContent, append(annotations, ann))
+			if err := session.Save(sess); err != nil {
+				return fmt.Errorf("failed to save session: %w", err)
+			}
+			fmt.Fprintf(stdout, "Annotated %s: %s\n", sess.ID, describeAnnotation(ann))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&typeFlag, "type", "", "Annotation type (comment, delete, question, expand, keep, unclear, change)")
+	cmd.Flags().StringVar(&snippetFlag, "snippet", "", "Use the text of a configured snippet")
+	return cmd
+}
+
+// parseLineSpec parses N or N-M as a range of lines from 1 to total.
+func parseLineSpec(spec string, total int) (start, end int, err error) {
+	first, last, isRange := strings.Cut(spec, "-")
+	if !isRange {
+		last = first
+	}
+	start, err1 := strconv.Atoi(first)
+	end, err2 := strconv.Atoi(last)
+	if err1 != nil || err2 != nil || start < 1 || end < start || end > total {
+		return 0, 0, output.Errorf(output.CodeUsage, "invalid lines %q: use N or N-M within the session's %d lines", spec, total)
+	}
+	return start, end, nil
+}
+
+func buildSnippetsCmd(stdout io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "snippets",
+		Short: "List the configured annotation snippets",
+		Long: `List the annotation snippets: canned texts such as "Add a test for this"
+that the TUI inserts with Ctrl+S while typing an annotation, with Space n or
+:snippet, and that 'fabbro session annotate --snippet' uses.
+
+Snippets are read from the "snippets" list of the user config
+(fabbro/config.json in the user config directory, e.g. ~/.config) and of
+the project's .fabbro/config.json; a project snippet replaces a user snippet
+of the same name:
+
+  {"snippets": [
+    {"name": "test", "text": "Add a test for {lines}"},
+    {"name": "why", "type": "question", "text": "Why does {file} do this?"}
+  ]}
+
+Placeholders are filled in from the annotated lines when a snippet is used.
+
+Pre-conditions:
+  - None; outside a fabbro project only the user's snippets are listed.
+
+Post-conditions:
+  - Each snippet is printed with its annotation type and text.`,
+		Example: `  # List snippets
+  fabbro snippets`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			snippets, err := snippet.Load()
+			if err != nil {
+				return output.Wrap(output.CodeData, err)
+			}
+			if len(snippets) == 0 {
+				path, _ := config.UserSettingsPath()
+				fmt.Fprintf(stdout, "No snippets. Add a \"snippets\" list to %s or %s.\n", config.SettingsFile, path)
+				return nil
+			}
+			for _, s := range snippets {
+				fmt.Fprintf(stdout, "%-12s %-9s %s\n", s.Name, snippet.Type(s), s.Text)
+			}
+			fmt.Fprintln(stdout)
+			fmt.Fprintln(stdout, "Placeholders:")
+			for _, p := range snippet.Placeholders {
+				fmt.Fprintf(stdout, "  %-8s %s\n", p[0], p[1])
+			}
+			return nil
+		},
+	}
+}
+
 func buildSessionDeleteCmd(stdin io.Reader, stdout io.Writer) *cobra.Command {
 	var forceFlag bool
 	cmd := &cobra.Command{
@@ -696,7 +1158,7 @@ Post-conditions:
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !config.IsInitialized() {
-				return fmt.Errorf("fabbro not initialized. Run 'fabbro init' first")
+				return errNotInitialized
 			}
 
 			sessionID := args[0]
@@ -751,7 +1213,7 @@ Post-conditions:
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !config.IsInitialized() {
-				return fmt.Errorf("fabbro not initialized. Run 'fabbro init' first")
+				return errNotInitialized
 			}
 
 			sessionID := args[0]
@@ -775,7 +1237,7 @@ Post-conditions:
 
 			if outputFlag != "" {
 				if err := os.WriteFile(outputFlag, data, 0644); err != nil {
-					return fmt.Errorf("failed to write output file: %w", err)
+					return output.Errorf(output.CodeIO, "failed to write output file: %w", err)
 				}
 				fmt.Fprintf(stdout, "Exported session %s to %s\n", sessionID, outputFlag)
 				return nil
@@ -791,18 +1253,20 @@ Post-conditions:
 
 func buildSessionCleanCmd(stdin io.Reader, stdout io.Writer) *cobra.Command {
 	var olderThan string
+	var statusFlag []string
 	var dryRun bool
 	var forceFlag bool
 	cmd := &cobra.Command{
 		Use:   "clean",
 		Short: "Remove old sessions",
-		Long: `Remove sessions older than a specified duration.
+		Long: `Remove sessions older than a specified duration or in given statuses.
 
 Pre-conditions:
   - fabbro must be initialized (run 'fabbro init' first).
+  - At least one of --older-than or --status must be given.
 
 Post-conditions:
-  - Sessions older than the threshold are deleted.
+  - Sessions matching every given filter are deleted.
   - With --dry-run, only lists what would be deleted.`,
 		Example: `  # Delete sessions older than 7 days (with confirmation)
   fabbro session clean --older-than 7d
@@ -811,18 +1275,33 @@ Post-conditions:
   fabbro session clean --older-than 7d --dry-run
 
   # Delete without confirmation
-  fabbro session clean --older-than 7d --force`,
+  fabbro session clean --older-than 7d --force
+
+  # Delete applied and abandoned sessions
+  fabbro session clean --status applied,closed`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !config.IsInitialized() {
-				return fmt.Errorf("fabbro not initialized. Run 'fabbro init' first")
+				return errNotInitialized
 			}
 
-			duration, err := parseDaysDuration(olderThan)
+			if olderThan == "" && len(statusFlag) == 0 {
+				return output.Errorf(output.CodeUsage, "specify --older-than, --status, or both")
+			}
+			filter, err := parseStatusFilter(statusFlag)
 			if err != nil {
 				return err
 			}
-			if duration < 24*time.Hour && !forceFlag {
-				return fmt.Errorf("minimum --older-than is 1d (safety limit). Use --force to override")
+
+			var cutoff time.Time
+			if olderThan != "" {
+				duration, err := parseDaysDuration(olderThan)
+				if err != nil {
+					return err
+				}
+				if duration < 24*time.Hour && !forceFlag {
+					return output.Errorf(output.CodeUsage, "minimum --older-than is 1d (safety limit). Use --force to override")
+				}
+				cutoff = time.Now().UTC().Add(-duration)
 			}
 
 			sessions, err := session.List()
@@ -830,29 +1309,40 @@ Post-conditions:
 				return fmt.Errorf("failed to list sessions: %w", err)
 			}
 
-			cutoff := time.Now().UTC().Add(-duration)
 			var matches []*session.Session
 			for _, s := range sessions {
-				if s.CreatedAt.Before(cutoff) {
+				if olderThan != "" && !s.CreatedAt.Before(cutoff) {
+					continue
+				}
+				if matchesStatus(filter, s) {
 					matches = append(matches, s)
 				}
 			}
 
+			var criteria []string
+			if olderThan != "" {
+				criteria = append(criteria, "older than "+olderThan)
+			}
+			if len(statusFlag) > 0 {
+				criteria = append(criteria, "with status "+strings.Join(statusFlag, ", "))
+			}
+			description := strings.Join(criteria, " and ")
+
 			if len(matches) == 0 {
-				fmt.Fprintln(stdout, "No sessions older than "+olderThan+".")
+				fmt.Fprintln(stdout, "No sessions "+description+".")
 				return nil
 			}
 
 			if dryRun {
 				fmt.Fprintf(stdout, "Would delete %d session(s):\n", len(matches))
 				for _, s := range matches {
-					fmt.Fprintf(stdout, "  %s  %s\n", s.ID, s.CreatedAt.Format("2006-01-02 15:04"))
+					fmt.Fprintf(stdout, "  %s  %s  %s\n", s.ID, s.CreatedAt.Format("2006-01-02 15:04"), s.Status.Label())
 				}
 				return nil
 			}
 
 			if !forceFlag {
-				fmt.Fprintf(stdout, "Delete %d session(s) older than %s? [y/N] ", len(matches), olderThan)
+				fmt.Fprintf(stdout, "Delete %d session(s) %s? [y/N] ", len(matches), description)
 				var answer string
 				fmt.Fscanln(stdin, &answer)
 				if answer != "y" && answer != "Y" {
@@ -873,108 +1363,1352 @@ Post-conditions:
 		},
 	}
 	cmd.Flags().StringVar(&olderThan, "older-than", "", "Delete sessions older than this duration (e.g. 7d, 30d)")
-	cmd.MarkFlagRequired("older-than")
+	cmd.Flags().StringSliceVar(&statusFlag, "status", nil, "Delete sessions with these statuses (in_progress, ready, applied, closed)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List sessions that would be deleted without deleting")
 	cmd.Flags().BoolVar(&forceFlag, "force", false, "Skip confirmation and safety limit")
 	return cmd
 }
 
-func buildSessionResumeCmd(stdout io.Writer, tuiRun TUIRunner) *cobra.Command {
-	var editorFlag bool
+// parseRevision parses a snapshot revision number argument.
+func parseRevision(s string) (int, error) {
+	rev, err := strconv.Atoi(strings.TrimPrefix(s, "r"))
+	if err != nil || rev < 1 {
+		return 0, output.Errorf(output.CodeUsage, "invalid revision %q: must be a positive number (see 'fabbro session history')", s)
+	}
+	return rev, nil
+}
+
+func buildSessionHistoryCmd(stdout io.Writer) *cobra.Command {
+	var jsonFlag bool
 	cmd := &cobra.Command{
-		Use:   "resume <session-id>",
-		Short: "Resume a previous editing session",
-		Long: `Resume a previous fabbro editing session by its ID.
+		Use:   "history <session-id>",
+		Short: "List saved revisions of a session",
+		Long: `List the snapshots recorded each time a session was saved.
 
 Pre-conditions:
   - fabbro must be initialized (run 'fabbro init' first).
   - The session ID must exist (use 'fabbro session list' to find IDs).
 
 Post-conditions:
-  - The session is loaded with its existing annotations.
-  - The TUI opens for continued annotation work (or $EDITOR with --editor).`,
-		Example: `  # Resume a session by ID
-  fabbro session resume abc123
+  - Each retained revision is listed with its time, author and annotation count.
+  - Older revisions beyond history.retention in .fabbro/config.json are pruned.`,
+		Example: `  # Show revisions of a session
+  fabbro session history abc123
 
-  # Resume in $EDITOR instead of TUI
-  fabbro session resume abc123 --editor
-
-  # Find a session ID first, then resume
-  fabbro session list
-  fabbro session resume <id-from-list>`,
+  # As JSON
+  fabbro session history abc123 --json`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if !config.IsInitialized() {
-				return fmt.Errorf("fabbro not initialized. Run 'fabbro init' first")
+				return errNotInitialized
 			}
 
-			sessionID := args[0]
-			sess, err := session.LoadPartial(sessionID)
+			sess, err := session.LoadPartial(args[0])
 			if err != nil {
-				return fmt.Errorf("failed to load session %q: %w", sessionID, err)
+				return err
+			}
+			snaps, err := session.History(sess.ID)
+			if err != nil {
+				return fmt.Errorf("failed to read history: %w", err)
 			}
 
-			if editorFlag {
-				editor := os.Getenv("EDITOR")
-				if editor == "" {
-					editor = os.Getenv("VISUAL")
-				}
-				if editor == "" {
-					return fmt.Errorf("no editor configured. Set $EDITOR or $VISUAL")
-				}
-
-				sessionsDir, err := config.GetSessionsDir()
-				if err != nil {
-					return fmt.Errorf("failed to find sessions directory: %w", err)
+			if jsonFlag {
+				history := output.SessionHistory{SchemaVersion: output.SchemaVersion, SessionID: sess.ID, Revisions: []output.Revision{}}
+				for _, snap := range snaps {
+					history.Revisions = append(history.Revisions, output.Revision{
+						Revision:    snap.Revision,
+						SavedAt:     output.Timestamp(snap.SavedAt),
+						Author:      snap.Author,
+						Annotations: len(snap.Annotations),
+					})
 				}
-				sessionPath := filepath.Join(sessionsDir, sess.ID+".fem")
-
-				fmt.Fprintf(stdout, "Opening session %s in %s\n", sess.ID, editor)
-
-				editorCmd := exec.Command(editor, sessionPath)
-				editorCmd.Stdin = os.Stdin
-				editorCmd.Stdout = os.Stdout
-				editorCmd.Stderr = os.Stderr
-				return editorCmd.Run()
+				return output.Write(stdout, history, false)
 			}
 
-			annotations, cleanContent, err := fem.Parse(sess.Content)
-			if err != nil {
-				return fmt.Errorf("failed to parse session content: %w", err)
+			if len(snaps) == 0 {
+				fmt.Fprintf(stdout, "No history for session %s (revisions are recorded on save).\n", sess.ID)
+				return nil
 			}
 
-			sess.Content = cleanContent
-
-			fmt.Fprintf(stdout, "Resuming session: %s\n", sess.ID)
-
-			model := tui.NewWithAnnotations(sess, sess.SourceFile, annotations)
-			if err := tuiRun(model); err != nil {
-				return fmt.Errorf("TUI error: %w", err)
+			for _, snap := range snaps {
+				author := snap.Author
+				if author == "" {
+					author = "(unknown)"
+				}
+				fmt.Fprintf(stdout, "r%-4d  %s  %-16s  %d annotations\n",
+					snap.Revision, snap.SavedAt.Local().Format("2006-01-02 15:04:05"), author, len(snap.Annotations))
 			}
-
 			return nil
 		},
 	}
-	cmd.Flags().BoolVar(&editorFlag, "editor", false, "Open session in $EDITOR instead of TUI")
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "Output as JSON")
 	return cmd
 }
 
-func buildTutorCmd(stdout io.Writer, tuiRun TUIRunner) *cobra.Command {
+func buildSessionDiffCmd(stdout io.Writer) *cobra.Command {
 	return &cobra.Command{
-		Use:   "tutor",
-		Short: "Start the interactive tutorial",
-		Long: `Launch an interactive tutorial that teaches fabbro basics.
+		Use:   "diff <session-id> <rev1> <rev2>",
+		Short: "Compare two revisions of a session",
+		Long: `Show how a session changed between two saved revisions.
 
-The tutor opens a guided lesson file in the TUI where you can
-practice navigation, selection, and annotation. Like vimtutor,
-this is hands-on learning.
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+  - Both revisions must exist (use 'fabbro session history' to find them).
 
-Your practice session is temporary and won't be saved.`,
+Post-conditions:
+  - Added (+) and removed (-) annotations are listed.
+  - Changed content lines are listed, if the reviewed text itself changed.`,
+		Example: `  # Compare the first and third revision
+  fabbro session diff abc123 1 3`,
+		Args: cobra.ExactArgs(3),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			sess := &session.Session{
-				ID:        tutor.SessionID,
-				Content:   tutor.Content,
-				CreatedAt: time.Now(),
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+
+			sess, err := session.LoadPartial(args[0])
+			if err != nil {
+				return err
+			}
+			rev1, err := parseRevision(args[1])
+			if err != nil {
+				return err
+			}
+			rev2, err := parseRevision(args[2])
+			if err != nil {
+				return err
+			}
+			oldSnap, err := session.LoadSnapshot(sess.ID, rev1)
+			if err != nil {
+				return err
+			}
+			newSnap, err := session.LoadSnapshot(sess.ID, rev2)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(stdout, "Session %s: r%d → r%d\n", sess.ID, rev1, rev2)
+
+			added, removed := fem.DiffAnnotations(oldSnap.Annotations, newSnap.Annotations)
+			if len(added) == 0 && len(removed) == 0 {
+				fmt.Fprintln(stdout, "Annotations: unchanged")
+			} else {
+				fmt.Fprintf(stdout, "Annotations: %d added, %d removed\n", len(added), len(removed))
+				for _, a := range removed {
+					fmt.Fprintf(stdout, "- %s\n", describeAnnotation(a))
+				}
+				for _, a := range added {
+					fmt.Fprintf(stdout, "+ %s\n", describeAnnotation(a))
+				}
+			}
+
+			_, oldClean, _ := fem.Parse(oldSnap.Content)
+			_, newClean, _ := fem.Parse(newSnap.Content)
+			edits := diff.Compute(strings.Split(oldClean, "\n"), strings.Split(newClean, "\n"))
+			if !diff.Changed(edits) {
+				fmt.Fprintln(stdout, "Content: unchanged")
+				return nil
+			}
+			fmt.Fprintln(stdout, "Content:")
+			for _, e := range edits {
+				switch e.Op {
+				case diff.Delete:
+					fmt.Fprintf(stdout, "- %4d │ %s\n", e.A+1, e.Text)
+				case diff.Insert:
+					fmt.Fprintf(stdout, "+ %4d │ %s\n", e.B+1, e.Text)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func buildSessionRestoreCmd(stdout io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <session-id> <rev>",
+		Short: "Restore a session to a previous revision",
+		Long: `Replace a session's content and annotations with a saved revision.
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+  - The revision must exist (use 'fabbro session history' to find it).
+
+Post-conditions:
+  - The session file holds the content of the chosen revision.
+  - The restore is recorded as a new revision, so it can itself be undone.`,
+		Example: `  # Roll back to revision 2
+  fabbro session restore abc123 2`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+
+			sess, err := session.LoadPartial(args[0])
+			if err != nil {
+				return err
+			}
+			rev, err := parseRevision(args[1])
+			if err != nil {
+				return err
+			}
+			if _, err := session.Restore(sess.ID, rev); err != nil {
+				return fmt.Errorf("failed to restore session: %w", err)
+			}
+			fmt.Fprintf(stdout, "Restored session %s to r%d\n", sess.ID, rev)
+			return nil
+		},
+	}
+}
+
+func buildSessionMergeCmd(stdout io.Writer) *cobra.Command {
+	var jsonFlag bool
+	cmd := &cobra.Command{
+		Use:   "merge <session-id> <session-id>...",
+		Short: "Merge reviews of the same source into one session",
+		Long: `Combine the annotations of several sessions that reviewed the same content
+into a new session.
+
+Each annotation is attributed to the reviewer of the session it came from
+(the session's author, or its ID when no author was recorded). Identical
+annotations are kept once and credited to every reviewer who made them.
+Change annotations on overlapping lines that propose different replacements
+are kept side by side and flagged with an unclear annotation.
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+  - All sessions must have the same source file and reviewed content.
+
+Post-conditions:
+  - A new session holds the merged annotations; the originals are unchanged.
+  - The new session ID is printed (or returned as JSON with --json).`,
+		Example: `  # Merge two reviews of the same plan
+  fabbro session merge abc123 def456
+
+  # Merge and hand the result to an agent
+  fabbro apply $(fabbro session merge abc123 def456 --json | jq -r .sessionId) --json`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+
+			var sessions []*session.Session
+			for _, id := range args {
+				sess, err := session.LoadPartial(id)
+				if err != nil {
+					return fmt.Errorf("failed to load session %q: %w", id, err)
+				}
+				sessions = append(sessions, sess)
+			}
+
+			res, err := session.Merge(sessions)
+			if err != nil {
+				return err
+			}
+
+			if jsonFlag {
+				merged := output.SessionMerge{
+					SchemaVersion: output.SchemaVersion,
+					SessionID:     res.Session.ID,
+					Sources:       res.Sources,
+					Duplicates:    res.Duplicates,
+					Conflicts:     []output.Conflict{},
+				}
+				for _, c := range res.Conflicts {
+					merged.Conflicts = append(merged.Conflicts, output.Conflict{A: c.A, B: c.B})
+				}
+				return output.Write(stdout, merged, false)
+			}
+
+			fmt.Fprintf(stdout, "Merged %d sessions into %s\n", len(res.Sources), res.Session.ID)
+			if res.Duplicates > 0 {
+				fmt.Fprintf(stdout, "Folded %d duplicate annotation(s)\n", res.Duplicates)
+			}
+			if len(res.Conflicts) > 0 {
+				fmt.Fprintf(stdout, "Conflicting changes (%d):\n", len(res.Conflicts))
+				for _, c := range res.Conflicts {
+					fmt.Fprintf(stdout, "  %s\n", describeAnnotation(c.A))
+					fmt.Fprintf(stdout, "  %s\n", describeAnnotation(c.B))
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "Output as JSON")
+	return cmd
+}
+
+func buildSessionResumeCmd(stdout io.Writer, tuiRun TUIRunner) *cobra.Command {
+	var editorFlag bool
+	cmd := &cobra.Command{
+		Use:   "resume <session-id>",
+		Short: "Resume a previous editing session",
+		Long: `Resume a previous fabbro editing session by its ID.
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+  - The session ID must exist (use 'fabbro session list' to find IDs).
+
+Post-conditions:
+  - The session is loaded with its existing annotations.
+  - The TUI opens for continued annotation work (or $EDITOR with --editor).`,
+		Example: `  # Resume a session by ID
+  fabbro session resume abc123
+
+  # Resume in $EDITOR instead of TUI
+  fabbro session resume abc123 --editor
+
+  # Find a session ID first, then resume
+  fabbro session list
+  fabbro session resume <id-from-list>`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+
+			sessionID := args[0]
+			sess, err := session.LoadPartial(sessionID)
+			if err != nil {
+				return fmt.Errorf("failed to load session %q: %w", sessionID, err)
+			}
+
+			if editorFlag {
+				editor := os.Getenv("EDITOR")
+				if editor == "" {
+					editor = os.Getenv("VISUAL")
+				}
+				if editor == "" {
+					return fmt.Errorf("no editor configured. Set $EDITOR or $VISUAL")
+				}
+
+				sessionsDir, err := config.GetSessionsDir()
+				if err != nil {
+					return fmt.Errorf("failed to find sessions directory: %w", err)
+				}
+				sessionPath := filepath.Join(sessionsDir, sess.ID+".fem")
+
+				fmt.Fprintf(stdout, "Opening session %s in %s\n", sess.ID, editor)
+
+				editorCmd := exec.Command(editor, sessionPath)
+				editorCmd.Stdin = os.Stdin
+				editorCmd.Stdout = os.Stdout
+				editorCmd.Stderr = os.Stderr
+				return editorCmd.Run()
+			}
+
+			annotations, cleanContent, err := fem.Parse(sess.Content)
+			if err != nil {
+				return output.Errorf(output.CodeData, "failed to parse session content: %w", err)
+			}
+
+			sess.Content = cleanContent
+
+			fmt.Fprintf(stdout, "Resuming session: %s\n", sess.ID)
+
+			model := tui.NewWithAnnotations(sess, sess.SourceFile, annotations)
+			if state, err := session.LoadState(sess.ID); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v; starting at the top\n", err)
+			} else {
+				model = model.WithState(state)
+			}
+			if err := tuiRun(model); err != nil {
+				return fmt.Errorf("TUI error: %w", err)
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&editorFlag, "editor", false, "Open session in $EDITOR instead of TUI")
+	return cmd
+}
+
+func buildSyncCmd(stdout io.Writer) *cobra.Command {
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Share sessions with your team through git",
+		Long: `Share fabbro sessions through a git remote.
+
+Sessions are stored on a dedicated ref (` + gitsync.Ref + `), separate from
+your branches and working tree. Annotations from different reviewers of the
+same session are merged; identical annotations are kept once.`,
+	}
+	syncCmd.AddCommand(buildSyncPushCmd(stdout))
+	syncCmd.AddCommand(buildSyncPullCmd(stdout))
+	return syncCmd
+}
+
+// newSyncer returns a Syncer rooted at the fabbro project directory.
+func newSyncer() (*gitsync.Syncer, error) {
+	root, err := config.FindProjectRoot()
+	if err != nil {
+		return nil, fmt.Errorf("fabbro not initialized. Run 'fabbro init' first")
+	}
+	sessionsDir, err := config.GetSessionsDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions directory: %w", err)
+	}
+	return gitsync.New(root, sessionsDir), nil
+}
+
+// printSyncResult writes the per-session outcome of a push or pull.
+func printSyncResult(stdout io.Writer, res *gitsync.Result) {
+	for _, id := range res.Added {
+		fmt.Fprintf(stdout, "  Added %s\n", id)
+	}
+	for _, id := range res.Merged {
+		fmt.Fprintf(stdout, "  Merged %s\n", id)
+	}
+	for _, id := range res.Conflicts {
+		fmt.Fprintf(stdout, "  Conflict %s: content differs, local and remote copies left as they are\n", id)
+	}
+}
+
+func buildSyncPushCmd(stdout io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "push [remote]",
+		Short: "Publish local sessions to a git remote",
+		Long: `Publish local sessions to a git remote (default: origin).
+
+Pre-conditions:
+  - fabbro must be initialized inside a git repository.
+  - The remote must be reachable with your git credentials.
+
+Post-conditions:
+  - Sessions already on the remote are merged into your local sessions first.
+  - The combined set is pushed to ` + gitsync.Ref + ` on the remote.
+  - Sessions whose content differs keep the remote's copy on the remote.`,
+		Example: `  # Push sessions to origin
+  fabbro sync push
+
+  # Push to another remote
+  fabbro sync push upstream`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			remote := "origin"
+			if len(args) == 1 {
+				remote = args[0]
+			}
+			syncer, err := newSyncer()
+			if err != nil {
+				return err
+			}
+			res, err := syncer.Push(remote)
+			if err != nil {
+				return fmt.Errorf("sync push failed: %w", err)
+			}
+			printSyncResult(stdout, res)
+			if res.UpToDate {
+				fmt.Fprintf(stdout, "Everything up to date on %s\n", remote)
+				return nil
+			}
+			fmt.Fprintf(stdout, "Pushed %d session(s) to %s\n", res.Pushed, remote)
+			return nil
+		},
+	}
+}
+
+func buildSyncPullCmd(stdout io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pull [remote]",
+		Short: "Fetch sessions from a git remote",
+		Long: `Fetch sessions from a git remote (default: origin) and merge them locally.
+
+Pre-conditions:
+  - fabbro must be initialized inside a git repository.
+  - Someone must have run 'fabbro sync push' to the remote.
+
+Post-conditions:
+  - Sessions missing locally are added.
+  - Sessions present on both sides get the union of their annotations.
+  - Sessions whose content differs are reported as conflicts and left as-is.`,
+		Example: `  # Pull teammates' sessions from origin
+  fabbro sync pull`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			remote := "origin"
+			if len(args) == 1 {
+				remote = args[0]
+			}
+			syncer, err := newSyncer()
+			if err != nil {
+				return err
+			}
+			res, err := syncer.Pull(remote)
+			if err != nil {
+				return fmt.Errorf("sync pull failed: %w", err)
+			}
+			printSyncResult(stdout, res)
+			fmt.Fprintf(stdout, "Pulled from %s: %d added, %d merged, %d conflict(s)\n",
+				remote, len(res.Added), len(res.Merged), len(res.Conflicts))
+			return nil
+		},
+	}
+}
+
+// defaultCheckTypes are the annotation types fabbro check fails on unless
+// --types is given: everything except keep, which needs no action.
+func defaultCheckTypes() []string {
+	var types []string
+	for _, at := range fem.AnnotationTypes {
+		if at.Name != "keep" {
+			types = append(types, at.Name)
+		}
+	}
+	return types
+}
+
+func buildCheckCmd(stdout io.Writer) *cobra.Command {
+	var typesFlag []string
+	var fileFlag []string
+	var jsonFlag bool
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Fail while review annotations remain open (for CI)",
+		Long: `Check open review sessions for unaddressed annotations and exit non-zero
+if any remain, so CI can block a merge until the review is acted on.
+
+A session is open while its status is in progress or ready; applying it
+('fabbro apply --mark-applied') or closing it ('fabbro session close')
+resolves its annotations.
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+
+Post-conditions:
+  - Open annotations of the checked types are listed by session, followed by
+    a summary line (or a JSON report on stdout with --json).
+  - Exits 1 when any remain, 0 otherwise.
+  - Sessions whose source file changed since they were created are reported
+    as drifted (a warning; drift alone does not fail the check).`,
+		Example: `  # Fail on any open annotation except keep
+  fabbro check
+
+  # Only block on requested edits and unanswered questions
+  fabbro check --types delete,change,question
+
+  # Check the reviews of specific files, as JSON for CI logs
+  fabbro check --file docs/plan.md --file main.go --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+
+			types := defaultCheckTypes()
+			if len(typesFlag) > 0 {
+				var err error
+				if types, err = parseTypesFlag(typesFlag); err != nil {
+					return err
+				}
+			}
+			checked := make(map[string]bool, len(types))
+			for _, t := range types {
+				checked[t] = true
+			}
+
+			all, err := session.List()
+			if err != nil {
+				return fmt.Errorf("failed to list sessions: %w", err)
+			}
+
+			report := output.Check{SchemaVersion: output.SchemaVersion, Types: types, Sessions: []output.CheckSession{}}
+			for _, sess := range all {
+				if !sess.Status.Open() || !sessionFromAny(sess, fileFlag) {
+					continue
+				}
+				annotations, _, err := fem.Parse(sess.Content)
+				if err != nil {
+					return output.Errorf(output.CodeData, "failed to parse FEM in session %q: %w", sess.ID, err)
+				}
+				cs := output.CheckSession{
+					SessionID:  sess.ID,
+					SourceFile: sess.SourceFile,
+					Status:     string(sess.Status),
+					Findings:   []fem.Annotation{},
+				}
+				for _, a := range fem.MergeAnnotations(nil, annotations) {
+					if checked[a.Type] {
+						cs.Findings = append(cs.Findings, a)
+					}
+				}
+				if valid, hashErr := sess.VerifySourceHash(); hashErr == nil && !valid {
+					cs.Drifted = true
+					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s changed since session %s was created. Line numbers may have drifted.\n", sess.SourceFile, sess.ID)
+				}
+				if len(cs.Findings) == 0 && !cs.Drifted {
+					continue
+				}
+				report.Findings += len(cs.Findings)
+				report.Sessions = append(report.Sessions, cs)
+			}
+			report.Passed = report.Findings == 0
+
+			if jsonFlag {
+				if err := output.Write(stdout, report, false); err != nil {
+					return err
+				}
+			} else {
+				for _, cs := range report.Sessions {
+					if len(cs.Findings) == 0 {
+						continue
+					}
+					source := cs.SourceFile
+					if source == "" {
+						source = "(stdin)"
+					}
+					fmt.Fprintf(stdout, "%s  %s (%s): %d open\n", cs.SessionID, source, session.Status(cs.Status).Label(), len(cs.Findings))
+					for _, a := range cs.Findings {
+						fmt.Fprintf(stdout, "  %s\n", describeAnnotation(a))
+					}
+				}
+				if report.Passed {
+					fmt.Fprintf(stdout, "No open annotations (%s).\n", strings.Join(types, ", "))
+				}
+			}
+
+			if !report.Passed {
+				// The report above is the explanation; usage text would bury it.
+				cmd.SilenceUsage = true
+				return output.Errorf(output.CodeFindings, "%d open annotation(s) in %d session(s) (%s)",
+					report.Findings, countWithFindings(report.Sessions), strings.Join(types, ", "))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&typesFlag, "types", nil, "Annotation types that fail the check (default: all except keep)")
+	cmd.Flags().StringSliceVar(&fileFlag, "file", nil, "Only check sessions of these source files")
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "Output the report as JSON")
+	return cmd
+}
+
+// parseTypesFlag normalizes and validates annotation type flag values.
+func parseTypesFlag(values []string) ([]string, error) {
+	var types []string
+	for _, t := range values {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if !fem.ValidAnnotationType(t) {
+			var names []string
+			for _, at := range fem.AnnotationTypes {
+				names = append(names, at.Name)
+			}
+			return nil, output.Errorf(output.CodeUsage, "invalid annotation type %q: must be one of %s", t, strings.Join(names, ", "))
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// sessionFromAny reports whether sess was created from one of files, or
+// true when no files are given.
+func sessionFromAny(sess *session.Session, files []string) bool {
+	if len(files) == 0 {
+		return true
+	}
+	for _, f := range files {
+		if sess.IsFrom(f) {
+			return true
+		}
+	}
+	return false
+}
+
+func countWithFindings(sessions []output.CheckSession) int {
+	n := 0
+	for _, cs := range sessions {
+		if len(cs.Findings) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func buildSearchCmd(stdout io.Writer) *cobra.Command {
+	var modeFlag string
+	var typesFlag []string
+	var sourceFlag string
+	var sinceFlag, untilFlag string
+	var statusFlag []string
+	var inFlag string
+	var jsonFlag bool
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search annotations and reviewed content across sessions",
+		Long: `Search every session's annotations and reviewed content, e.g. to find
+where a topic was already discussed.
+
+Matching is case-insensitive substring by default. --mode fuzzy matches the
+query's characters in order, like / in the TUI; --mode regex takes a Go
+regular expression (prefix it with (?i) to ignore case).
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+
+Post-conditions:
+  - Matches are listed as session, source:line and text, newest session first.
+  - Use --json for machine-readable output.`,
+		Example: `  # Where did we discuss retry logic?
+  fabbro search retry
+
+  # Open questions on Go files in the last two weeks
+  fabbro search timeout --type question --source '**/*.go' --since 14d --status in_progress,ready
+
+  # Regex over annotation text only, as JSON
+  fabbro search 'back ?off' --mode regex --in annotations --json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+
+			mode, err := search.ParseMode(modeFlag)
+			if err != nil {
+				return output.Wrap(output.CodeUsage, err)
+			}
+			filter, err := parseSessionFilter(sourceFlag, sinceFlag, untilFlag, statusFlag)
+			if err != nil {
+				return err
+			}
+			types, err := parseTypesFlag(typesFlag)
+			if err != nil {
+				return err
+			}
+			q := search.Query{Pattern: args[0], Mode: mode, Filter: filter, Types: types}
+			switch inFlag {
+			case "all":
+			case "annotations":
+				q.Annotations = true
+			case "content":
+				if len(q.Types) > 0 {
+					return output.Errorf(output.CodeUsage, "--type only applies to annotations; it cannot be combined with --in content")
+				}
+				q.Content = true
+			default:
+				return output.Errorf(output.CodeUsage, "invalid --in %q: must be all, annotations, or content", inFlag)
+			}
+
+			sessions, err := session.List()
+			if err != nil {
+				return fmt.Errorf("failed to list sessions: %w", err)
+			}
+			hits, err := search.Run(sessions, q)
+			if err != nil {
+				return output.Wrap(output.CodeUsage, err)
+			}
+
+			if jsonFlag {
+				return output.Write(stdout, output.Search{SchemaVersion: output.SchemaVersion, Query: q.Pattern, Mode: mode, Hits: hits}, false)
+			}
+			if len(hits) == 0 {
+				fmt.Fprintln(stdout, "No matches.")
+				return nil
+			}
+			for _, h := range hits {
+				source := h.SourceFile
+				if source == "" {
+					source = "(stdin)"
+				}
+				lines := strconv.Itoa(h.StartLine)
+				if h.EndLine != h.StartLine {
+					lines += "-" + strconv.Itoa(h.EndLine)
+				}
+				text := h.Text
+				if h.Kind == search.KindAnnotation {
+					text = "[" + h.Type + "] " + text
+				}
+				fmt.Fprintf(stdout, "%s  %s:%s  %s\n", h.SessionID, source, lines, text)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&modeFlag, "mode", string(search.ModeLiteral), "Matching mode: literal, fuzzy, or regex")
+	cmd.Flags().StringSliceVar(&typesFlag, "type", nil, "Only match annotations of these types")
+	cmd.Flags().StringVar(&sourceFlag, "source", "", "Only search sessions whose source file matches this glob (e.g. 'internal/**/*.go')")
+	cmd.Flags().StringVar(&sinceFlag, "since", "", "Only sessions created on or after this date (YYYY-MM-DD, or Nd for N days ago)")
+	cmd.Flags().StringVar(&untilFlag, "until", "", "Only sessions created on or before this date (YYYY-MM-DD, or Nd for N days ago)")
+	cmd.Flags().StringSliceVar(&statusFlag, "status", nil, "Only search sessions with these statuses (in_progress, ready, applied, closed)")
+	cmd.Flags().StringVar(&inFlag, "in", "all", "What to search: all, annotations, or content")
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "Output matches as JSON")
+	return cmd
+}
+
+// parseSessionFilter parses the --source, --since, --until and --status
+// flags shared by search and stats.
+func parseSessionFilter(source, since, until string, statuses []string) (session.Filter, error) {
+	f := session.Filter{Source: source}
+	var err error
+	if f.Since, err = parseDateFlag("since", since, false); err != nil {
+		return f, err
+	}
+	if f.Until, err = parseDateFlag("until", until, true); err != nil {
+		return f, err
+	}
+	if f.Statuses, err = parseStatusFilter(statuses); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// parseDateFlag parses a --since/--until value: a date (YYYY-MM-DD, local
+// time), an RFC 3339 timestamp, or Nd for N days ago. A bare date used as
+// an upper bound covers the whole day. Empty means unbounded.
+func parseDateFlag(name, value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if strings.HasSuffix(value, "d") {
+		if d, err := parseDaysDuration(value); err == nil {
+			return time.Now().Add(-d), nil
+		}
+	}
+	return time.Time{}, output.Errorf(output.CodeUsage, "invalid --%s %q: use YYYY-MM-DD, an RFC 3339 time, or Nd", name, value)
+}
+
+func buildStatsCmd(stdout io.Writer) *cobra.Command {
+	var typesFlag []string
+	var sourceFlag string
+	var sinceFlag, untilFlag string
+	var statusFlag []string
+	var topFlag int
+	var jsonFlag bool
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show where reviews concentrate and how they trend",
+		Long: `Aggregate annotations over all sessions, or those matching the filters:
+counts by type, by source file and by week, the median number of
+annotations per session, and hotspots - line ranges of a file where
+annotations from one or more reviews overlap or touch.
+
+Line numbers refer to each session's content, so hotspots across sessions
+of a file that has since changed are approximate.
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+
+Post-conditions:
+  - A report is printed as tables (or as JSON with --json).
+  - Sessions of stdin count towards the totals but not per file or hotspots.`,
+		Example: `  # Overview of every review
+  fabbro stats
+
+  # Which Go files attract unclear and delete annotations this quarter?
+  fabbro stats --type unclear,delete --source '**/*.go' --since 2026-07-01
+
+  # Machine-readable, with the top 20 hotspots
+  fabbro stats --top 20 --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+			if topFlag < 1 {
+				return output.Errorf(output.CodeUsage, "--top must be at least 1")
+			}
+			types, err := parseTypesFlag(typesFlag)
+			if err != nil {
+				return err
+			}
+			filter, err := parseSessionFilter(sourceFlag, sinceFlag, untilFlag, statusFlag)
+			if err != nil {
+				return err
+			}
+
+			all, err := session.List()
+			if err != nil {
+				return fmt.Errorf("failed to list sessions: %w", err)
+			}
+			var sessions []*session.Session
+			for _, s := range all {
+				if filter.Match(s) {
+					sessions = append(sessions, s)
+				}
+			}
+			report := stats.Compute(sessions, stats.Options{Types: types, Hotspots: topFlag})
+
+			if jsonFlag {
+				return output.Write(stdout, output.Stats{SchemaVersion: output.SchemaVersion, Report: *report}, false)
+			}
+			writeStats(stdout, report, topFlag)
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&typesFlag, "type", nil, "Only count annotations of these types")
+	cmd.Flags().StringVar(&sourceFlag, "source", "", "Only sessions whose source file matches this glob (e.g. 'internal/**/*.go')")
+	cmd.Flags().StringVar(&sinceFlag, "since", "", "Only sessions created on or after this date (YYYY-MM-DD, or Nd for N days ago)")
+	cmd.Flags().StringVar(&untilFlag, "until", "", "Only sessions created on or before this date (YYYY-MM-DD, or Nd for N days ago)")
+	cmd.Flags().StringSliceVar(&statusFlag, "status", nil, "Only sessions with these statuses (in_progress, ready, applied, closed)")
+	cmd.Flags().IntVar(&topFlag, "top", stats.DefaultHotspots, "Number of hotspots to list, and of files in the table")
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "Output the report as JSON")
+	return cmd
+}
+
+// writeStats prints a stats report as plain-text tables, listing at most
+// top files.
+func writeStats(w io.Writer, r *stats.Report, top int) {
+	fmt.Fprintf(w, "Sessions: %d  Annotations: %d  Median per session: %g\n", r.Sessions, r.Annotations, r.MedianPerSession)
+	if r.Sessions == 0 {
+		return
+	}
+
+	if len(r.ByType) > 0 {
+		fmt.Fprintln(w, "\nBy type:")
+		for _, c := range r.ByType {
+			fmt.Fprintf(w, "  %-10s %5d\n", c.Type, c.Count)
+		}
+	}
+
+	if len(r.ByFile) > 0 {
+		fmt.Fprintln(w, "\nBy file:")
+		limit := min(len(r.ByFile), top)
+		for _, f := range r.ByFile[:limit] {
+			fmt.Fprintf(w, "  %-40s %5d annotations  %3d sessions  %s\n", f.File, f.Annotations, f.Sessions, typeBreakdown(f.ByType))
+		}
+		if limit < len(r.ByFile) {
+			fmt.Fprintf(w, "  ... %d more (use --json for all)\n", len(r.ByFile)-limit)
+		}
+	}
+
+	fmt.Fprintln(w, "\nBy week:")
+	for _, wk := range r.ByWeek {
+		fmt.Fprintf(w, "  %s %5d annotations  %3d sessions\n", wk.Week, wk.Annotations, wk.Sessions)
+	}
+
+	if len(r.Hotspots) > 0 {
+		fmt.Fprintln(w, "\nHotspots:")
+		for _, h := range r.Hotspots {
+			loc := fmt.Sprintf("%s:%d-%d", h.File, h.StartLine, h.EndLine)
+			fmt.Fprintf(w, "  %-40s %5d annotations  %3d sessions  %s\n", loc, h.Annotations, h.Sessions, typeBreakdown(h.ByType))
+		}
+	}
+}
+
+// typeBreakdown formats per-type counts in fem.AnnotationTypes order, e.g.
+// "delete 2, unclear 1".
+func typeBreakdown(byType map[string]int) string {
+	var parts []string
+	for _, at := range fem.AnnotationTypes {
+		if n := byType[at.Name]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", at.Name, n))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func buildScanCmd(stdout io.Writer) *cobra.Command {
+	var stagedFlag bool
+	var allowFlag []string
+	var jsonFlag bool
+	cmd := &cobra.Command{
+		Use:   "scan [paths...]",
+		Short: "Find FEM markers that leaked into source files",
+		Long: `Scan files for FEM annotation markers ({>> <<}, {-- --}, ...) outside
+.fabbro/, e.g. left behind after editing a session in $EDITOR and copying
+the text back.
+
+In a git repository only tracked and unignored files are scanned, so
+.gitignore is respected. Files that legitimately show FEM, such as
+documentation of the syntax, can be allowlisted with glob patterns in
+"scan.allow" of .fabbro/config.json or with --allow.
+
+Pre-conditions:
+  - None; works without 'fabbro init' (the config allowlist is then empty).
+  - --staged requires a git repository.
+
+Post-conditions:
+  - Each leaked marker is printed as path:line:column (or as JSON with --json).
+  - Exits 1 when any marker is found, 0 otherwise.`,
+		Example: `  # Scan the whole repository
+  fabbro scan
+
+  # Scan some directories, allowing FEM in the docs
+  fabbro scan src docs --allow 'docs/**/*.md'
+
+  # What the pre-commit hook runs
+  fabbro scan --staged`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			root := scan.RepoRoot(cwd)
+
+			var paths []string
+			for _, arg := range args {
+				abs, err := filepath.Abs(arg)
+				if err != nil {
+					return err
+				}
+				rel, err := filepath.Rel(root, abs)
+				if err != nil || strings.HasPrefix(rel, "..") {
+					return output.Errorf(output.CodeUsage, "%s is outside %s", arg, root)
+				}
+				paths = append(paths, filepath.ToSlash(rel))
+			}
+
+			// Allow patterns are relative to the fabbro project, which may be
+			// nested in the repository.
+			scanner := &scan.Scanner{Dir: root, Allow: allowFlag, Staged: stagedFlag}
+			if config.IsInitialized() {
+				settings, err := config.LoadSettings()
+				if err != nil {
+					return output.Wrap(output.CodeData, err)
+				}
+				projectRoot, err := config.FindProjectRoot()
+				if err != nil {
+					return err
+				}
+				scanner.Allow = append(settings.Scan.Allow, allowFlag...)
+				scanner.AllowBase = scan.RepoPath(projectRoot)
+			}
+			res, err := scanner.Run(paths)
+			if err != nil {
+				return err
+			}
+
+			if jsonFlag {
+				if err := output.Write(stdout, output.Scan{SchemaVersion: output.SchemaVersion, Files: res.Files, Findings: res.Findings}, false); err != nil {
+					return err
+				}
+			} else {
+				for _, f := range res.Findings {
+					fmt.Fprintln(stdout, f)
+				}
+				if len(res.Findings) == 0 {
+					fmt.Fprintf(stdout, "No FEM markers found in %d files.\n", res.Files)
+				}
+			}
+
+			if len(res.Findings) > 0 {
+				files := make(map[string]bool)
+				for _, f := range res.Findings {
+					files[f.Path] = true
+				}
+				cmd.SilenceUsage = true
+				return output.Errorf(output.CodeLeaked, "%d leaked FEM marker(s) in %d file(s). Remove them, or allowlist the file with 'fabbro hooks install --allow <glob>'",
+					len(res.Findings), len(files))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&stagedFlag, "staged", false, "Scan the content staged for commit")
+	cmd.Flags().StringSliceVar(&allowFlag, "allow", nil, "Glob patterns of files allowed to contain FEM (adds to scan.allow)")
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "Output findings as JSON")
+	return cmd
+}
+
+func buildHooksCmd(stdout io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Manage fabbro's git hooks",
+	}
+	cmd.AddCommand(buildHooksInstallCmd(stdout))
+	cmd.AddCommand(buildHooksUninstallCmd(stdout))
+	return cmd
+}
+
+func buildHooksInstallCmd(stdout io.Writer) *cobra.Command {
+	var allowFlag []string
+	var forceFlag bool
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install a pre-commit hook that blocks leaked FEM markers",
+		Long: `Install a git pre-commit hook that runs 'fabbro scan --staged' and blocks
+commits containing FEM markers outside .fabbro/.
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+  - The project must be a git repository.
+
+Post-conditions:
+  - The hook is written to git's hooks directory (honouring core.hooksPath).
+  - An existing hook not written by fabbro is kept unless --force is given.
+  - --allow patterns are added to "scan.allow" in .fabbro/config.json.`,
+		Example: `  # Install the hook
+  fabbro hooks install
+
+  # Allow the FEM documentation to show markers
+  fabbro hooks install --allow docs/fem.md --allow 'docs/**/*.md'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+			root, err := config.FindProjectRoot()
+			if err != nil {
+				return err
+			}
+
+			if len(allowFlag) > 0 {
+				settings, err := config.LoadSettings()
+				if err != nil {
+					return output.Wrap(output.CodeData, err)
+				}
+				for _, pattern := range allowFlag {
+					if !slices.Contains(settings.Scan.Allow, pattern) {
+						settings.Scan.Allow = append(settings.Scan.Allow, pattern)
+					}
+				}
+				if err := config.SaveSettings(settings); err != nil {
+					return output.Wrap(output.CodeIO, err)
+				}
+				fmt.Fprintf(stdout, "Allowed FEM in: %s\n", strings.Join(settings.Scan.Allow, ", "))
+			}
+
+			path, err := scan.InstallHook(root, forceFlag)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "Installed pre-commit hook: %s\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&allowFlag, "allow", nil, "Glob patterns of files allowed to contain FEM")
+	cmd.Flags().BoolVar(&forceFlag, "force", false, "Replace an existing pre-commit hook")
+	return cmd
+}
+
+func buildHooksUninstallCmd(stdout io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the pre-commit hook installed by fabbro",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+			root, err := config.FindProjectRoot()
+			if err != nil {
+				return err
+			}
+			path, err := scan.UninstallHook(root)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "Removed pre-commit hook: %s\n", path)
+			return nil
+		},
+	}
+}
+
+func buildSchemaCmd(stdout io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema [command]",
+		Short: "Print the JSON Schema of a command's --json output",
+		Long: `Print the JSON Schema (draft 2020-12) describing a command's --json output,
+or of the error document written to stderr when a --json command fails.
+
+Every JSON document fabbro writes has a schemaVersion field. Adding fields
+keeps the version; renaming, removing or retyping a field increments it.
+
+Pre-conditions:
+  - None; works outside a fabbro project.
+
+Post-conditions:
+  - With a command, its schema is printed to stdout.
+  - Without one, the commands that have a schema are listed.`,
+		Example: `  # List the available schemas
+  fabbro schema
+
+  # Validate apply output in CI
+  fabbro schema apply > apply.schema.json
+
+  # Subcommands can be given with a space or a dash
+  fabbro schema session list
+  fabbro schema error`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				for _, name := range output.SchemaNames() {
+					fmt.Fprintln(stdout, name)
+				}
+				return nil
+			}
+			data, err := output.Schema(strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			_, err = stdout.Write(data)
+			return err
+		},
+	}
+}
+
+func buildReportCmd(stdout io.Writer) *cobra.Command {
+	var formatFlag string
+	var outputFlag string
+	cmd := &cobra.Command{
+		Use:   "report <session-id>",
+		Short: "Render a session as an HTML or Markdown report",
+		Long: `Render a review session as a readable document for people who don't use
+the CLI.
+
+The report includes session metadata, summary statistics, the
+syntax-highlighted source with annotations as margin notes, and the
+annotations grouped by type. HTML reports are a single file with inline
+styles and no external assets, so they open offline.
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+  - The session ID must exist (use 'fabbro session list' to find IDs).
+
+Post-conditions:
+  - The report is printed to stdout or written to --output.`,
+		Example: `  # Write an HTML report to share
+  fabbro report abc123 --output review.html
+
+  # Markdown for a pull request comment
+  fabbro report abc123 --format markdown`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+
+			var render func(io.Writer, *session.Session) error
+			switch formatFlag {
+			case "html":
+				render = report.HTML
+			case "markdown", "md":
+				render = report.Markdown
+			default:
+				return output.Errorf(output.CodeUsage, "invalid format %q: must be html or markdown", formatFlag)
+			}
+
+			sess, err := session.LoadPartial(args[0])
+			if err != nil {
+				return err
+			}
+
+			if outputFlag == "" {
+				return render(stdout, sess)
+			}
+			f, err := os.Create(outputFlag)
+			if err != nil {
+				return output.Errorf(output.CodeIO, "failed to create output file: %w", err)
+			}
+			if err := render(f, sess); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return output.Errorf(output.CodeIO, "failed to write output file: %w", err)
+			}
+			fmt.Fprintf(stdout, "Wrote report for session %s to %s\n", sess.ID, outputFlag)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&formatFlag, "format", "html", "Report format: html or markdown")
+	cmd.Flags().StringVar(&outputFlag, "output", "", "Write the report to a file instead of stdout")
+	return cmd
+}
+
+// waitPollInterval is how often `fabbro wait` re-reads the session file.
+var waitPollInterval = 500 * time.Millisecond
+
+func buildWaitCmd(stdout io.Writer) *cobra.Command {
+	var timeoutFlag time.Duration
+	var compactFlag bool
+	cmd := &cobra.Command{
+		Use:   "wait <session-id>",
+		Short: "Block until a review is submitted",
+		Long: `Wait for a human to finish reviewing a session, then print its annotations.
+
+The review is finished when the reviewer submits it in the TUI (Space → s)
+or runs 'fabbro session finish'. Sessions that are already ready or applied
+return immediately.
+
+Pre-conditions:
+  - fabbro must be initialized (run 'fabbro init' first).
+  - The session ID must exist.
+
+Post-conditions:
+  - On success, the 'apply --json' payload is printed to stdout (exit 0).
+  - If --timeout elapses first, exits with code 75.
+  - If the session is closed or deleted while waiting, exits with code 69.
+  - Errors are always printed as the JSON error document ('fabbro schema error').`,
+		Example: `  # Start a review, hand it to the human, and wait for the result
+  id=$(fabbro review plan.md --no-interactive)
+  fabbro wait "$id" --timeout 30m`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.IsInitialized() {
+				return errNotInitialized
+			}
+
+			sess, err := session.LoadPartial(args[0])
+			if err != nil {
+				return err
+			}
+
+			var deadline <-chan time.Time
+			if timeoutFlag > 0 {
+				timer := time.NewTimer(timeoutFlag)
+				defer timer.Stop()
+				deadline = timer.C
+			}
+			ticker := time.NewTicker(waitPollInterval)
+			defer ticker.Stop()
+
+			for {
+				switch sess.Status {
+				case session.StatusReady, session.StatusApplied:
+					annotations, _, err := fem.Parse(sess.Content)
+					if err != nil {
+						return output.Errorf(output.CodeData, "failed to parse FEM in session %q: %w", sess.ID, err)
+					}
+					return writeApplyJSON(stdout, sess, annotations, compactFlag)
+				case session.StatusClosed:
+					return output.Errorf(output.CodeUnavailable, "session %s was closed without being submitted", sess.ID)
+				}
+
+				select {
+				case <-deadline:
+					return output.Errorf(output.CodeTimeout, "timed out after %s waiting for session %s", timeoutFlag, sess.ID)
+				case <-ticker.C:
+				}
+
+				// A session that fails to read or parse is retried on the next
+				// tick; only a missing file means the review was abandoned.
+				next, err := session.Load(sess.ID)
+				if errors.Is(err, os.ErrNotExist) {
+					return output.Errorf(output.CodeUnavailable, "session %s was deleted while waiting", sess.ID)
+				}
+				if err == nil {
+					sess = next
+				}
+			}
+		},
+	}
+	cmd.Flags().DurationVar(&timeoutFlag, "timeout", 0, "Give up after this long (e.g. 30s, 10m); 0 waits forever")
+	cmd.Flags().BoolVar(&compactFlag, "compact", false, "Output minified JSON")
+	return cmd
+}
+
+func buildTutorCmd(stdout io.Writer, tuiRun TUIRunner) *cobra.Command {
+	return &cobra.Command{
+		Use:   "tutor",
+		Short: "Start the interactive tutorial",
+		Long: `Launch an interactive tutorial that teaches fabbro basics.
+
+The tutor opens a guided lesson file in the TUI where you can
+practice navigation, selection, and annotation. Like vimtutor,
+this is hands-on learning.
+
+Your practice session is temporary and won't be saved.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sess := &session.Session{
+				ID:        tutor.SessionID,
+				Content:   tutor.Content,
+				CreatedAt: time.Now(),
 			}
 
 			fmt.Fprintln(stdout, "Welcome to the fabbro tutor!")
@@ -1001,20 +2735,31 @@ This command provides an AI-optimized overview of fabbro's purpose,
 key commands, and FEM syntax. Designed to quickly onboard AI coding
 assistants to the fabbro workflow.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			primeInfo := PrimeInfo{
-				Purpose: "fabbro is a local-first code review annotation tool with a terminal UI. It lets you annotate code using FEM (Fabbro Editing Markup) syntax, designed for human-AI review workflows.",
-				Commands: []CommandInfo{
+			primeInfo := output.Prime{
+				SchemaVersion: output.SchemaVersion,
+				Purpose:       "fabbro is a local-first code review annotation tool with a terminal UI. It lets you annotate code using FEM (Fabbro Editing Markup) syntax, designed for human-AI review workflows.",
+				Commands: []output.Command{
 					{Name: "fabbro init", Description: "Initialize fabbro in current directory (creates .fabbro/)"},
 					{Name: "fabbro review <file>", Description: "Start review session with file content"},
 					{Name: "fabbro review --stdin", Description: "Start review session from stdin (e.g., git diff | fabbro review --stdin)"},
 					{Name: "fabbro apply <session-id>", Description: "Show annotations from a session"},
 					{Name: "fabbro apply <session-id> --json", Description: "Output annotations as JSON for programmatic use"},
+					{Name: "fabbro apply <session-id> --format prompt", Description: "Output each annotation as an instruction with surrounding source (--context N, --max-tokens N)"},
 					{Name: "fabbro apply --file <path>", Description: "Find and apply latest session for a source file"},
 					{Name: "fabbro session list", Description: "List all editing sessions"},
 					{Name: "fabbro session resume <id>", Description: "Resume a previous session in TUI"},
+					{Name: "fabbro session annotate <id> <lines> [text] [--type <type>] [--snippet <name>]", Description: "Add an annotation to a session without the TUI"},
+					{Name: "fabbro snippets", Description: "List the configured annotation snippets and their placeholders"},
+					{Name: "fabbro wait <session-id> [--timeout 30m]", Description: "Block until the human submits the review, then print the apply --json payload (exit 75 on timeout, 69 if abandoned)"},
+					{Name: "fabbro check [--types delete,change] [--file <path>]", Description: "Exit 1 while open sessions have unaddressed annotations (CI gate)"},
+					{Name: "fabbro search <query> [--mode fuzzy|regex] [--type ...] [--source <glob>]", Description: "Search annotations and reviewed content across sessions"},
+					{Name: "fabbro stats [--type ...] [--source <glob>] [--since <date>]", Description: "Annotation counts by type, file and week, and review hotspots"},
+					{Name: "fabbro scan [paths...] [--staged]", Description: "Find FEM markers leaked into source files"},
+					{Name: "fabbro hooks install [--allow <glob>]", Description: "Install a pre-commit hook that blocks leaked FEM markers"},
+					{Name: "fabbro schema <command>", Description: "Print the JSON Schema of a command's --json output; failures under --json write {\"error\":{\"code\",\"message\"}} to stderr"},
 					{Name: "fabbro tutor", Description: "Interactive tutorial (like vimtutor)"},
 				},
-				FEMSyntax: []FEMInfo{
+				FEMSyntax: []output.FEMSyntax{
 					{Syntax: "{>> text <<}", Type: "comment", Description: "General comment"},
 					{Syntax: "{-- text --}", Type: "delete", Description: "Mark for deletion"},
 					{Syntax: "{?? text ??}", Type: "question", Description: "Ask a question"},
@@ -1023,12 +2768,13 @@ assistants to the fabbro workflow.`,
 					{Syntax: "{~~ text ~~}", Type: "unclear", Description: "Mark as unclear"},
 					{Syntax: "{++ text ++}", Type: "change", Description: "Replacement text"},
 				},
-				TUIKeys: []KeyInfo{
+				TUIKeys: []output.Key{
 					{Key: "j/k", Action: "Navigate up/down"},
 					{Key: "v", Action: "Toggle line selection"},
 					{Key: "c", Action: "Add comment annotation"},
 					{Key: "Space", Action: "Open annotation palette"},
 					{Key: "w", Action: "Save session"},
+					{Key: "Space s", Action: "Submit review (marks session ready for fabbro wait)"},
 					{Key: "q", Action: "Quit"},
 				},
 				Docs: []string{
@@ -1040,9 +2786,7 @@ assistants to the fabbro workflow.`,
 			}
 
 			if jsonFlag {
-				enc := json.NewEncoder(stdout)
-				enc.SetIndent("", "  ")
-				return enc.Encode(primeInfo)
+				return output.Write(stdout, primeInfo, false)
 			}
 
 			fmt.Fprintln(stdout, "# fabbro — AI Workflow Context")
@@ -1076,27 +2820,3 @@ assistants to the fabbro workflow.`,
 	cmd.Flags().BoolVar(&jsonFlag, "json", false, "Output as JSON")
 	return cmd
 }
-
-type PrimeInfo struct {
-	Purpose   string        `json:"purpose"`
-	Commands  []CommandInfo `json:"commands"`
-	FEMSyntax []FEMInfo     `json:"femSyntax"`
-	TUIKeys   []KeyInfo     `json:"tuiKeys"`
-	Docs      []string      `json:"docs"`
-}
-
-type CommandInfo struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-}
-
-type FEMInfo struct {
-	Syntax      string `json:"syntax"`
-	Type        string `json:"type"`
-	Description string `json:"description"`
-}
-
-type KeyInfo struct {
-	Key    string `json:"key"`
-	Action string `json:"action"`
-}
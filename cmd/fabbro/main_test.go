@@ -1,17 +1,22 @@
 package main
 
 import (
+	"archive/zip"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charly-vibes/fabbro/internal/config"
 	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/output"
 	"github.com/charly-vibes/fabbro/internal/session"
+	"github.com/charly-vibes/fabbro/internal/tui"
 )
 
 func noopTUI(tea.Model) error { return nil }
@@ -88,9 +93,9 @@ Third line {>> consider refactoring <<}`
 	}
 
 	// Verify clean content strips markers
-	expectedClean := `First line 
+	expectedClean := `First line
 Second line
-Third line `
+Third line`
 	if cleanContent != expectedClean {
 		t.Errorf("expected clean content:\n%q\ngot:\n%q", expectedClean, cleanContent)
 	}
@@ -913,11 +918,14 @@ Line two has {>> unclosed annotation`
 
 	code := realMain([]string{"apply", sess.ID, "--json"}, stdin, &stdout, &stderr, noopTUI)
 
-	if code != 1 {
-		t.Errorf("expected exit code 1, got %d", code)
+	if code != output.CodeData.ExitCode() {
+		t.Errorf("expected exit code %d, got %d", output.CodeData.ExitCode(), code)
 	}
 
 	errOutput := stderr.String()
+	if !strings.Contains(errOutput, `"code":"data_error"`) {
+		t.Errorf("expected a data_error document, got %q", errOutput)
+	}
 	if !strings.Contains(errOutput, "parse") {
 		t.Errorf("expected error to mention parsing, got %q", errOutput)
 	}
@@ -1303,7 +1311,7 @@ func TestReviewCommandJSONOutput(t *testing.T) {
 	}
 
 	// Parse and validate JSON structure
-	var result map[string]string
+	var result map[string]interface{}
 	// Find the JSON line (first line before any TUI errors)
 	lines := strings.Split(output, "\n")
 	if len(lines) == 0 {
@@ -1312,7 +1320,7 @@ func TestReviewCommandJSONOutput(t *testing.T) {
 	if err := json.Unmarshal([]byte(lines[0]), &result); err != nil {
 		t.Errorf("expected valid JSON, got parse error: %v for output: %q", err, lines[0])
 	}
-	if result["sessionId"] == "" {
+	if id, _ := result["sessionId"].(string); id == "" {
 		t.Error("expected sessionId to be non-empty")
 	}
 }
@@ -1413,14 +1421,24 @@ Test content {>> a comment <<}`
 		t.Errorf("expected exit code 0, got %d; stderr: %s", code, stderr.String())
 	}
 
-	var result []map[string]interface{}
-	if err := json.Unmarshal([]byte(stdout.String()), &result); err != nil {
+	var list struct {
+		SchemaVersion int                      `json:"schemaVersion"`
+		Sessions      []map[string]interface{} `json:"sessions"`
+	}
+	if err := json.Unmarshal([]byte(stdout.String()), &list); err != nil {
 		t.Fatalf("failed to parse JSON output: %v", err)
 	}
+	result := list.Sessions
 
+	if list.SchemaVersion != 1 {
+		t.Errorf("expected schemaVersion 1, got %d", list.SchemaVersion)
+	}
 	if len(result) != 1 {
 		t.Fatalf("expected 1 session, got %d", len(result))
 	}
+	if result[0]["createdAt"] != "2026-01-11T22:00:00Z" {
+		t.Errorf("expected RFC 3339 createdAt, got %v", result[0]["createdAt"])
+	}
 
 	annotations, ok := result[0]["annotations"]
 	if !ok {
@@ -1835,6 +1853,31 @@ func TestSessionResumeNonexistent(t *testing.T) {
 	}
 }
 
+func TestSessionResumeRestoresState(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := session.Create("a\nb\nc", "main.go")
+	session.SaveState(sess.ID, &session.State{Cursor: 3, Marks: map[string]int{"a": 2}})
+
+	var got *session.State
+	captureTUI := func(model tea.Model) error {
+		got = model.(tui.Model).State()
+		return nil
+	}
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"session", "resume", sess.ID}, strings.NewReader(""), &stdout, &stderr, captureTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d; stderr: %s", code, stderr.String())
+	}
+	if got == nil || got.Cursor != 3 || got.Marks["a"] != 2 {
+		t.Errorf("expected the saved cursor and marks restored, got %+v", got)
+	}
+}
+
 func TestSessionResumeWithEditor(t *testing.T) {
 	tmpDir := t.TempDir()
 	origDir, _ := os.Getwd()
@@ -1884,6 +1927,41 @@ func TestSessionResumeWithEditorNoEditorSet(t *testing.T) {
 	}
 }
 
+func TestSessionShowAndApplyReportCoverage(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := session.Create("a\nb\nc\nd", "main.go")
+	sess.Reviewed = []int{1, 2}
+	session.Save(sess)
+
+	var stdout, stderr strings.Builder
+	if code := realMain([]string{"session", "show", sess.ID}, strings.NewReader(""), &stdout, &stderr, noopTUI); code != 0 {
+		t.Fatalf("expected exit code 0, got %d; stderr: %s", code, stderr.String())
+	}
+	for _, want := range []string{"Reviewed:       50% (2 of 4 lines)", "Not reviewed:   lines 3-4"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("expected %q in output, got %q", want, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	if code := realMain([]string{"apply", sess.ID, "--json"}, strings.NewReader(""), &stdout, &stderr, noopTUI); code != 0 {
+		t.Fatalf("expected exit code 0, got %d; stderr: %s", code, stderr.String())
+	}
+	var payload output.Apply
+	if err := json.Unmarshal([]byte(stdout.String()), &payload); err != nil {
+		t.Fatal(err)
+	}
+	want := session.Coverage{Lines: 4, Reviewed: 2, Percent: 50, Unreviewed: []session.LineRange{{StartLine: 3, EndLine: 4}}}
+	if c := payload.Coverage; c.Lines != want.Lines || c.Reviewed != want.Reviewed || c.Percent != want.Percent || len(c.Unreviewed) != 1 || c.Unreviewed[0] != want.Unreviewed[0] {
+		t.Errorf("got coverage %+v, want %+v", c, want)
+	}
+}
+
 func TestSessionShowDisplaysDetails(t *testing.T) {
 	tmpDir := t.TempDir()
 	origDir, _ := os.Getwd()
@@ -2047,3 +2125,1208 @@ func TestPrimeCommandJSON(t *testing.T) {
 		t.Error("expected 'femSyntax' key in JSON output")
 	}
 }
+
+func TestSyncPushPullRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	root := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+
+	git := func(dir string, args ...string) {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	origin := filepath.Join(root, "origin.git")
+	git(root, "init", "--quiet", "--bare", origin)
+	for _, name := range []string{"alice", "bob"} {
+		dir := filepath.Join(root, name)
+		git(root, "init", "--quiet", dir)
+		git(dir, "remote", "add", "origin", origin)
+	}
+
+	os.Chdir(filepath.Join(root, "alice"))
+	config.Init()
+	sess, _ := session.Create("shared plan", "plan.md")
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"sync", "push"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("sync push failed: %s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Pushed 1 session(s) to origin") {
+		t.Errorf("unexpected push output: %q", stdout.String())
+	}
+
+	os.Chdir(filepath.Join(root, "bob"))
+	config.Init()
+	stdout.Reset()
+	code = realMain([]string{"sync", "pull"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("sync pull failed: %s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Added "+sess.ID) {
+		t.Errorf("expected pulled session in output, got %q", stdout.String())
+	}
+	if _, err := session.Load(sess.ID); err != nil {
+		t.Errorf("expected session to be loadable after pull: %v", err)
+	}
+}
+
+func TestSyncNotInitialized(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	t.Setenv("FABBRO_PROJECT_ROOT_STOP", tmpDir)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"sync", "pull"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 {
+		t.Error("expected non-zero exit code when not initialized")
+	}
+}
+
+func TestSessionHistoryDiffRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	t.Setenv("FABBRO_AUTHOR", "reviewer")
+
+	config.Init()
+	sess, _ := session.Create("alpha\nbeta", "")
+	sess.Content = "alpha {>> first pass <<}\nbeta"
+	session.Save(sess)
+	sess.Content = "alpha\nbeta {?? second pass ??}"
+	session.Save(sess)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"session", "history", sess.ID}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("history failed: %s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "r1") || !strings.Contains(stdout.String(), "r2") {
+		t.Errorf("expected both revisions listed, got %q", stdout.String())
+	}
+	if !strings.Contains(stdout.String(), "reviewer") {
+		t.Errorf("expected author in history, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"session", "diff", sess.ID, "1", "2"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("diff failed: %s", stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "- Line 1: [comment] first pass") {
+		t.Errorf("expected removed annotation in diff, got %q", out)
+	}
+	if !strings.Contains(out, "+ Line 2: [question] second pass") {
+		t.Errorf("expected added annotation in diff, got %q", out)
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"session", "restore", sess.ID, "1"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("restore failed: %s", stderr.String())
+	}
+	loaded, _ := session.Load(sess.ID)
+	if loaded.Content != "alpha {>> first pass <<}\nbeta" {
+		t.Errorf("expected revision 1 content after restore, got %q", loaded.Content)
+	}
+}
+
+func TestSessionDiffInvalidRevision(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := session.Create("content", "")
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"session", "diff", sess.ID, "one", "2"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 {
+		t.Error("expected non-zero exit code for invalid revision")
+	}
+	if !strings.Contains(stderr.String(), "invalid revision") {
+		t.Errorf("expected invalid revision error, got %q", stderr.String())
+	}
+}
+
+func TestSessionMerge(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	t.Setenv("FABBRO_AUTHOR", "alice")
+	a, _ := session.Create("one\ntwo", "plan.md")
+	a.Content = "one {++ -> uno ++}\ntwo {>> same <<}"
+	session.Save(a)
+	t.Setenv("FABBRO_AUTHOR", "bob")
+	b, _ := session.Create("one\ntwo", "plan.md")
+	b.Content = "one {++ -> eins ++}\ntwo {>> same <<}"
+	session.Save(b)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"session", "merge", a.ID, b.ID, "--json"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+
+	var out struct {
+		SessionID  string   `json:"sessionId"`
+		Sources    []string `json:"sources"`
+		Duplicates int      `json:"duplicates"`
+		Conflicts  []struct {
+			A fem.Annotation `json:"a"`
+			B fem.Annotation `json:"b"`
+		} `json:"conflicts"`
+	}
+	if err := json.Unmarshal([]byte(stdout.String()), &out); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if out.Duplicates != 1 || len(out.Conflicts) != 1 || len(out.Sources) != 2 {
+		t.Errorf("unexpected merge result %+v", out)
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"apply", out.SessionID}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("apply failed: %s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Line 2: [comment] same (by alice, bob)") {
+		t.Errorf("expected attributed duplicate in apply output, got %q", stdout.String())
+	}
+}
+
+func TestSessionMergeNeedsTwoSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := session.Create("content", "")
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"session", "merge", sess.ID}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 {
+		t.Error("expected non-zero exit code for a single session")
+	}
+}
+
+func TestSessionStatusLifecycle(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	ready, _ := session.Create("ready {>> note <<}", "")
+	pending, _ := session.Create("pending", "")
+
+	var stdout, stderr strings.Builder
+	if code := realMain([]string{"session", "finish", ready.ID}, strings.NewReader(""), &stdout, &stderr, noopTUI); code != 0 {
+		t.Fatalf("finish failed: %s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "is now ready") {
+		t.Errorf("unexpected finish output %q", stdout.String())
+	}
+
+	stdout.Reset()
+	realMain([]string{"session", "list", "--status", "ready"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if !strings.Contains(stdout.String(), ready.ID) || strings.Contains(stdout.String(), pending.ID) {
+		t.Errorf("expected only the ready session, got %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := realMain([]string{"apply", ready.ID, "--mark-applied"}, strings.NewReader(""), &stdout, &stderr, noopTUI); code != 0 {
+		t.Fatalf("apply failed: %s", stderr.String())
+	}
+	loaded, _ := session.Load(ready.ID)
+	if loaded.Status != session.StatusApplied {
+		t.Errorf("expected applied status, got %q", loaded.Status)
+	}
+
+	stdout.Reset()
+	realMain([]string{"session", "show", ready.ID}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if !strings.Contains(stdout.String(), "Status:         applied") {
+		t.Errorf("expected status in show output, got %q", stdout.String())
+	}
+
+	stderr.Reset()
+	if code := realMain([]string{"session", "finish", ready.ID}, strings.NewReader(""), &stdout, &stderr, noopTUI); code == 0 {
+		t.Error("expected finishing an applied session to fail")
+	}
+	if !strings.Contains(stderr.String(), "cannot move") {
+		t.Errorf("expected transition error, got %q", stderr.String())
+	}
+}
+
+func TestSessionCleanByStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	closed, _ := session.Create("closed", "")
+	session.SetStatus(closed, session.StatusClosed)
+	kept, _ := session.Create("kept", "")
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"session", "clean", "--status", "closed", "--force"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("clean failed: %s", stderr.String())
+	}
+	if _, err := session.Load(closed.ID); err == nil {
+		t.Error("expected closed session to be deleted")
+	}
+	if _, err := session.Load(kept.ID); err != nil {
+		t.Error("expected in-progress session to be kept")
+	}
+}
+
+func TestSessionCleanRequiresFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"session", "clean"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 {
+		t.Error("expected non-zero exit code without filters")
+	}
+	if !strings.Contains(stderr.String(), "--older-than, --status") {
+		t.Errorf("unexpected error %q", stderr.String())
+	}
+}
+
+func TestWaitReturnsWhenSubmitted(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	waitPollInterval = 10 * time.Millisecond
+	sess, _ := session.Create("line {>> fix <<}", "")
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		s, _ := session.Load(sess.ID)
+		session.SetStatus(s, session.StatusReady)
+	}()
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"wait", sess.ID, "--timeout", "5s"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+
+	var out struct {
+		SessionID   string           `json:"sessionId"`
+		Status      string           `json:"status"`
+		Annotations []fem.Annotation `json:"annotations"`
+	}
+	if err := json.Unmarshal([]byte(stdout.String()), &out); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if out.SessionID != sess.ID || out.Status != "ready" || len(out.Annotations) != 1 {
+		t.Errorf("unexpected payload %+v", out)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	waitPollInterval = 10 * time.Millisecond
+	sess, _ := session.Create("content", "")
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"wait", sess.ID, "--timeout", "50ms"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != output.CodeTimeout.ExitCode() {
+		t.Errorf("expected exit code %d, got %d", output.CodeTimeout.ExitCode(), code)
+	}
+	if !strings.Contains(stderr.String(), "timed out") {
+		t.Errorf("expected timeout message, got %q", stderr.String())
+	}
+}
+
+func TestWaitAbandoned(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	waitPollInterval = 10 * time.Millisecond
+	closed, _ := session.Create("content", "")
+	session.SetStatus(closed, session.StatusClosed)
+	deleted, _ := session.Create("content", "")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		session.Delete(deleted.ID)
+	}()
+
+	for _, id := range []string{closed.ID, deleted.ID} {
+		var stdout, stderr strings.Builder
+		code := realMain([]string{"wait", id, "--timeout", "5s"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+		if code != output.CodeUnavailable.ExitCode() {
+			t.Errorf("session %s: expected exit code %d, got %d (%s)", id, output.CodeUnavailable.ExitCode(), code, stderr.String())
+		}
+	}
+}
+
+func TestWaitRetriesUnreadableSession(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	waitPollInterval = 10 * time.Millisecond
+	sess, _ := session.Create("line {>> fix <<}", "")
+	path := filepath.Join(config.SessionsDir, sess.ID+".fem")
+
+	go func() {
+		// A half-written file must not end the wait.
+		time.Sleep(30 * time.Millisecond)
+		os.WriteFile(path, []byte("---\nsession_id: "+sess.ID+"\ncreated_"), 0600)
+		time.Sleep(50 * time.Millisecond)
+		session.SetStatus(sess, session.StatusReady)
+	}()
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"wait", sess.ID, "--timeout", "5s"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"status": "ready"`) {
+		t.Errorf("expected the submitted session, got %s", stdout.String())
+	}
+}
+
+func TestApplyFormatSarif(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	os.WriteFile("main.go", []byte("package main\nfunc main() {}\n"), 0644)
+	sess, _ := session.Create("package main\nfunc main() {}\n", "main.go")
+	sess.Content = "package main {>> doc? <<}\nfunc main() {} {++ -> func main() { run() } ++}\n"
+	session.Save(sess)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"apply", sess.ID, "--format", "sarif"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				RuleID string            `json:"ruleId"`
+				Fixes  []json.RawMessage `json:"fixes"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(stdout.String()), &log); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 2 {
+		t.Fatalf("unexpected SARIF log %s", stdout.String())
+	}
+	if log.Runs[0].Results[1].RuleID != "change" || len(log.Runs[0].Results[1].Fixes) != 1 {
+		t.Errorf("expected change result with a fix, got %+v", log.Runs[0].Results[1])
+	}
+}
+
+func TestApplyFormatPrompt(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := session.Create("a\nb\nc\nd\ne\n", "")
+	sess.Content = "a\nb\nc {++ -> C ++}\nd\ne\n"
+	session.Save(sess)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"apply", sess.ID, "--format", "prompt", "--context", "1"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "Replace line 3 with:\n\n```\nC\n```") {
+		t.Errorf("expected change instruction, got:\n%s", out)
+	}
+	if !strings.Contains(out, "  2 | b\n> 3 | c\n  4 | d\n") || strings.Contains(out, "| a") {
+		t.Errorf("expected one line of context, got:\n%s", out)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = realMain([]string{"apply", sess.ID, "--json", "--context", "1"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 || !strings.Contains(stderr.String(), "require --format prompt") {
+		t.Errorf("expected --context without --format prompt to fail, got %d: %s", code, stderr.String())
+	}
+}
+
+func TestApplyFormatConflictsWithJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := session.Create("content", "")
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"apply", sess.ID, "--json", "--format", "sarif"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 {
+		t.Error("expected --json with --format sarif to fail")
+	}
+	code = realMain([]string{"apply", sess.ID, "--format", "xml"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 || !strings.Contains(stderr.String(), "invalid format") {
+		t.Errorf("expected invalid format error, got %q", stderr.String())
+	}
+}
+
+func TestReport(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := session.Create("line one\nline two", "notes.md")
+	sess.Content = "line one {>> tighten <<}\nline two"
+	session.Save(sess)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"report", sess.ID, "--format", "markdown"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "# Review: notes.md") || !strings.Contains(stdout.String(), "tighten") {
+		t.Errorf("unexpected markdown report %q", stdout.String())
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"report", sess.ID, "--output", "review.html"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	data, err := os.ReadFile("review.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "<!DOCTYPE html>") {
+		t.Errorf("expected HTML report, got %q", string(data)[:40])
+	}
+}
+
+func TestReportInvalidFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := session.Create("content", "")
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"report", sess.ID, "--format", "pdf"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 || !strings.Contains(stderr.String(), "invalid format") {
+		t.Errorf("expected invalid format error, got %d %q", code, stderr.String())
+	}
+}
+
+func TestJSONErrorsAreStructured(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := session.Create("content", "main.go")
+
+	tests := []struct {
+		name string
+		args []string
+		code output.Code
+	}{
+		{"conflicting args", []string{"apply", sess.ID, "--file", "main.go", "--json"}, output.CodeUsage},
+		{"unknown flag", []string{"apply", sess.ID, "--json", "--bogus"}, output.CodeUsage},
+		{"too many args", []string{"apply", "a", "b", "--json"}, output.CodeUsage},
+		{"missing session", []string{"apply", "nope", "--json"}, output.CodeNotFound},
+		{"missing file", []string{"review", "missing.go", "--json"}, output.CodeNotFound},
+		{"bad status filter", []string{"session", "list", "--status", "done", "--json"}, output.CodeUsage},
+		{"format json", []string{"apply", "nope", "--format", "json"}, output.CodeNotFound},
+		{"format=json", []string{"apply", "nope", "--format=json"}, output.CodeNotFound},
+		{"wait", []string{"wait", "nope"}, output.CodeNotFound},
+		{"wait timeout", []string{"wait", sess.ID, "--timeout", "10ms"}, output.CodeTimeout},
+		{"wait usage", []string{"wait"}, output.CodeUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			code := realMain(tt.args, strings.NewReader(""), &stdout, &stderr, noopTUI)
+			if code != tt.code.ExitCode() {
+				t.Errorf("expected exit code %d, got %d", tt.code.ExitCode(), code)
+			}
+
+			var doc output.ErrorDocument
+			if err := json.Unmarshal([]byte(stderr.String()), &doc); err != nil {
+				t.Fatalf("expected a JSON error on stderr, got %q", stderr.String())
+			}
+			if doc.SchemaVersion != 1 || doc.Error.Code != tt.code || doc.Error.Message == "" {
+				t.Errorf("unexpected error document %+v", doc)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("expected empty stdout, got %q", stdout.String())
+			}
+		})
+	}
+}
+
+func TestErrorsWithoutJSONStayPlain(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"apply", "nope"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	if !strings.HasPrefix(stderr.String(), "Error: ") {
+		t.Errorf("expected a plain error, got %q", stderr.String())
+	}
+}
+
+func TestSchemaCommand(t *testing.T) {
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"schema"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	for _, name := range []string{"apply", "error", "prime", "review", "session-list"} {
+		if !strings.Contains(stdout.String(), name+"\n") {
+			t.Errorf("expected %q in schema list, got:\n%s", name, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"schema", "session", "list"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout.String()), &schema); err != nil {
+		t.Fatalf("expected a JSON schema, got %v", err)
+	}
+	if schema["title"] != "fabbro session list --json" {
+		t.Errorf("unexpected schema title %v", schema["title"])
+	}
+
+	stderr.Reset()
+	code = realMain([]string{"schema", "bogus"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 || !strings.Contains(stderr.String(), "no schema") {
+		t.Errorf("expected unknown schema to fail, got %d: %s", code, stderr.String())
+	}
+}
+
+func TestPrimeJSONHasSchemaVersion(t *testing.T) {
+	var stdout, stderr strings.Builder
+	realMain([]string{"prime", "--json"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+
+	var prime output.Prime
+	if err := json.Unmarshal([]byte(stdout.String()), &prime); err != nil {
+		t.Fatal(err)
+	}
+	if prime.SchemaVersion != output.SchemaVersion || len(prime.Commands) == 0 {
+		t.Errorf("unexpected prime output %+v", prime)
+	}
+}
+
+func TestCheckFailsOnOpenAnnotations(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	os.WriteFile("plan.md", []byte("one\ntwo\n"), 0644)
+	open, _ := session.Create("one\ntwo\n", "plan.md")
+	open.Content = "one {-- drop --}\ntwo {== good ==}\n"
+	session.Save(open)
+	applied, _ := session.Create("x\n", "other.md")
+	applied.Content = "x {++ -> y ++}\n"
+	session.Save(applied)
+	session.SetStatus(applied, session.StatusApplied)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"check"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Line 1: [delete] drop") || strings.Contains(stdout.String(), "good") {
+		t.Errorf("expected only the open delete, got:\n%s", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "1 open annotation(s) in 1 session(s)") || strings.Contains(stderr.String(), "Usage:") {
+		t.Errorf("expected a summary without usage, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = realMain([]string{"check", "--types", "question,change"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Errorf("expected check to pass for other types, got %d: %s%s", code, stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = realMain([]string{"check", "--file", "other.md"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Errorf("expected applied sessions to pass, got %d: %s", code, stdout.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = realMain([]string{"check", "--types", "bogus"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 || !strings.Contains(stderr.String(), "invalid annotation type") {
+		t.Errorf("expected invalid type to fail, got %d: %s", code, stderr.String())
+	}
+}
+
+func TestCheckJSONReportsDrift(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	os.WriteFile("main.go", []byte("package main\n"), 0644)
+	sess, _ := session.Create("package main\n", "main.go")
+	sess.Content = "package main {?? why ??}\n"
+	session.Save(sess)
+	os.WriteFile("main.go", []byte("package app\n"), 0644)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"check", "--json"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d: %s", code, stderr.String())
+	}
+
+	var report output.Check
+	if err := json.Unmarshal([]byte(stdout.String()), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if report.Passed || report.Findings != 1 || len(report.Sessions) != 1 || !report.Sessions[0].Drifted {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if !strings.Contains(stderr.String(), "Warning: main.go changed") || !strings.Contains(stderr.String(), `"code":"open_findings"`) {
+		t.Errorf("expected a drift warning and an error document, got %q", stderr.String())
+	}
+}
+
+func TestScanFindsLeakedMarkers(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	os.WriteFile("main.go", []byte("package main\n\n// fix {>> this <<}\n"), 0644)
+	os.WriteFile("fem.md", []byte("Comments look like {>> text <<}.\n"), 0644)
+	sess, _ := session.Create("x\n", "main.go")
+	sess.Content = "x {-- drop --}\n"
+	session.Save(sess)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"scan"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "main.go:3:8: leaked comment marker {>> this <<}") || strings.Contains(stdout.String(), ".fabbro") {
+		t.Errorf("unexpected findings:\n%s", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "2 leaked FEM marker(s) in 2 file(s)") || strings.Contains(stderr.String(), "Usage:") {
+		t.Errorf("expected a summary without usage, got %q", stderr.String())
+	}
+
+	settings, _ := config.LoadSettings()
+	settings.Scan.Allow = []string{"*.md"}
+	config.SaveSettings(settings)
+
+	stdout.Reset()
+	stderr.Reset()
+	code = realMain([]string{"scan", "--json"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d: %s", code, stderr.String())
+	}
+	var report output.Scan
+	if err := json.Unmarshal([]byte(stdout.String()), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if len(report.Findings) != 1 || report.Findings[0].Path != "main.go" {
+		t.Errorf("expected only main.go with fem.md allowed, got %+v", report)
+	}
+	if !strings.Contains(stderr.String(), `"code":"leaked_markers"`) {
+		t.Errorf("expected an error document, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = realMain([]string{"scan", "fem.md"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 || !strings.Contains(stdout.String(), "No FEM markers found") {
+		t.Errorf("expected a clean scan, got %d: %s%s", code, stdout.String(), stderr.String())
+	}
+}
+
+func TestHooksInstallAndUninstall(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	if out, err := exec.Command("git", "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	config.Init()
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"hooks", "install", "--allow", "docs/*.md"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	hook, err := os.ReadFile(filepath.Join(".git", "hooks", "pre-commit"))
+	if err != nil || !strings.Contains(string(hook), "fabbro scan --staged") {
+		t.Errorf("expected the hook to run fabbro scan, got %q (%v)", hook, err)
+	}
+	settings, _ := config.LoadSettings()
+	if len(settings.Scan.Allow) != 1 || settings.Scan.Allow[0] != "docs/*.md" {
+		t.Errorf("expected the allowlist to be saved, got %v", settings.Scan.Allow)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = realMain([]string{"hooks", "uninstall"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(".git", "hooks", "pre-commit")); !os.IsNotExist(err) {
+		t.Errorf("expected the hook to be removed, got %v", err)
+	}
+}
+
+func TestScanAllowInNestedProject(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	defer os.Chdir(origDir)
+
+	if out, err := exec.Command("git", "init", "-q", tmpDir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	project := filepath.Join(tmpDir, "tools", "review")
+	os.MkdirAll(filepath.Join(project, "docs"), 0755)
+	os.Chdir(project)
+	config.Init()
+	os.WriteFile(filepath.Join("docs", "fem.md"), []byte("Comments look like {>> text <<}.\n"), 0644)
+	os.WriteFile("main.go", []byte("// fix {>> this <<}\n"), 0644)
+
+	var stdout, stderr strings.Builder
+	if code := realMain([]string{"hooks", "install", "--allow", "docs/fem.md"}, strings.NewReader(""), &stdout, &stderr, noopTUI); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code := realMain([]string{"scan"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "tools/review/main.go") || strings.Contains(stdout.String(), "fem.md") {
+		t.Errorf("expected docs/fem.md allowed relative to the project, got:\n%s", stdout.String())
+	}
+}
+
+func TestSearchAcrossSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	client, _ := session.Create("retry(3)\n", "internal/client.go")
+	client.Content = "retry(3) {>> retry logic should back off <<}\n"
+	session.Save(client)
+	plan, _ := session.Create("# Plan\n", "docs/plan.md")
+	plan.Content = "# Plan {?? what about retry? ??}\n"
+	session.Save(plan)
+	session.SetStatus(plan, session.StatusApplied)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"search", "retry"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	for _, want := range []string{
+		client.ID + "  internal/client.go:1  [comment] retry logic should back off",
+		client.ID + "  internal/client.go:1  retry(3)",
+		plan.ID + "  docs/plan.md:1  [question] what about retry?",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"search", "retry", "--source", "*.go", "--status", "in_progress", "--in", "annotations", "--json"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var result output.Search
+	if err := json.Unmarshal([]byte(stdout.String()), &result); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if len(result.Hits) != 1 || result.Hits[0].SessionID != client.ID || result.Hits[0].StartLine != 1 || result.Hits[0].Type != "comment" {
+		t.Errorf("unexpected hits %+v", result.Hits)
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"search", "rlsbo", "--mode", "fuzzy", "--until", "2000-01-01"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 || !strings.Contains(stdout.String(), "No matches.") {
+		t.Errorf("expected no sessions before 2000, got %d: %s", code, stdout.String())
+	}
+
+	stderr.Reset()
+	code = realMain([]string{"search", "(", "--mode", "regex", "--json"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != output.CodeUsage.ExitCode() || !strings.Contains(stderr.String(), "invalid regular expression") {
+		t.Errorf("expected a usage error, got %d: %s", code, stderr.String())
+	}
+}
+
+func TestStatsCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	first, _ := session.Create("one\ntwo\n", "main.go")
+	first.Content = "one {~~ vague ~~}\ntwo {-- drop --}\n"
+	session.Save(first)
+	second, _ := session.Create("one\ntwo\n", "main.go")
+	second.Content = "one {~~ still vague ~~}\ntwo\n"
+	session.Save(second)
+	other, _ := session.Create("# Plan\n", "docs/plan.md")
+	other.Content = "# Plan {?? why ??}\n"
+	session.Save(other)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"stats"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	for _, want := range []string{
+		"Sessions: 3  Annotations: 4  Median per session: 1",
+		"unclear        2",
+		"main.go",
+		"delete 1, unclear 2",
+		"Hotspots:",
+		"main.go:1-2",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"stats", "--type", "question", "--source", "docs/*", "--json"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	var report output.Stats
+	if err := json.Unmarshal([]byte(stdout.String()), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, stdout.String())
+	}
+	if report.Sessions != 1 || report.Annotations != 1 || len(report.ByFile) != 1 || report.ByFile[0].File != "docs/plan.md" || len(report.Hotspots) != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	code = realMain([]string{"stats", "--since", "yesterday"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 || !strings.Contains(stderr.String(), "invalid --since") {
+		t.Errorf("expected an invalid date error, got %d: %s", code, stderr.String())
+	}
+}
+
+func TestReviewConvertsHTML(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	page := "<html><body>\n<nav>Menu</nav>\n<h1>Title</h1>\n<p>Hello <b>world</b></p>\n</body></html>\n"
+	os.WriteFile("page.html", []byte(page), 0644)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"review", "page.html", "--no-interactive"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	sess, err := session.Load(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.Content != "# Title\n\nHello **world**\n" {
+		t.Errorf("expected Markdown content, got %q", sess.Content)
+	}
+	if sess.Conversion == nil || sess.Conversion.From != "html" || len(sess.Conversion.LineMap) != 3 || sess.Conversion.LineMap[2] != 4 {
+		t.Errorf("unexpected conversion %+v", sess.Conversion)
+	}
+
+	sess.Content = "# Title\n\nHello **world** {>> greet better <<}\n"
+	session.Save(sess)
+	stdout.Reset()
+	code = realMain([]string{"apply", sess.ID}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Line 3: [comment] greet better (html line 4)") || strings.Contains(stderr.String(), "changed") {
+		t.Errorf("expected the annotation mapped to the HTML without drift, got:\n%s%s", stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"apply", sess.ID, "--json"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	var payload output.Apply
+	if err := json.Unmarshal([]byte(stdout.String()), &payload); err != nil || payload.Conversion == nil || payload.Conversion.From != "html" {
+		t.Errorf("expected conversion metadata in JSON, got %s (%v)", stdout.String(), err)
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"review", "--stdin", "--input-format", "html", "--no-interactive"}, strings.NewReader("<p>From &lt;stdin&gt;</p>"), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	stdinSess, _ := session.Load(strings.TrimSpace(stdout.String()))
+	if stdinSess == nil || stdinSess.Content != "From <stdin>\n" {
+		t.Errorf("expected converted stdin, got %+v", stdinSess)
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"review", "page.html", "--input-format", "text", "--no-interactive"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	raw, _ := session.Load(strings.TrimSpace(stdout.String()))
+	if code != 0 || raw == nil || raw.Content != page || raw.Conversion != nil {
+		t.Errorf("expected --input-format text to keep the raw HTML, got %d %+v", code, raw)
+	}
+}
+
+func TestReviewConvertsDOCX(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	f, _ := os.Create("spec.docx")
+	zw := zip.NewWriter(f)
+	parts := map[string]string{
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Spec</w:t></w:r></w:p>
+<w:p><w:r><w:t>Ship it.</w:t></w:r><w:r><w:commentReference w:id="0"/></w:r></w:p>
+</w:body></w:document>`,
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments" Target="comments.xml"/>
+</Relationships>`,
+		"word/comments.xml": `<w:comments xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:comment w:id="0" w:author="Ada"><w:p><w:r><w:t>When?</w:t></w:r></w:p></w:comment>
+</w:comments>`,
+	}
+	for name, data := range parts {
+		w, _ := zw.Create(name)
+		w.Write([]byte(data))
+	}
+	zw.Close()
+	f.Close()
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"review", "spec.docx", "--no-interactive"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	sess, err := session.Load(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.Content != "# Spec\n\nShip it. {>> [by Ada] When? <<}\n" {
+		t.Errorf("expected Markdown with the imported comment, got %q", sess.Content)
+	}
+	if sess.Conversion == nil || sess.Conversion.From != "docx" || len(sess.Conversion.LineMap) != 0 {
+		t.Errorf("unexpected conversion %+v", sess.Conversion)
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"apply", sess.ID}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 || !strings.Contains(stdout.String(), "Line 3: [comment] When? (by Ada)") || strings.Contains(stderr.String(), "changed") {
+		t.Errorf("expected the imported comment without drift, got %d:\n%s%s", code, stdout.String(), stderr.String())
+	}
+
+	os.WriteFile("legacy.doc", []byte{0xd0, 0xcf, 0x11, 0xe0}, 0644)
+	stderr.Reset()
+	code = realMain([]string{"review", "legacy.doc", "--no-interactive"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 || !strings.Contains(stderr.String(), "save the document as .docx") {
+		t.Errorf("expected legacy .doc to be rejected, got %d: %s", code, stderr.String())
+	}
+
+	os.WriteFile("broken.docx", []byte("not a zip"), 0644)
+	stderr.Reset()
+	code = realMain([]string{"review", "broken.docx", "--no-interactive"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code == 0 || !strings.Contains(stderr.String(), "may be corrupt") {
+		t.Errorf("expected a corrupt .docx to be rejected, got %d: %s", code, stderr.String())
+	}
+}
+
+func TestReviewEscapesMarkersInConvertedContent(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	os.WriteFile("page.html", []byte("<p>a {>> x <<} b</p>\n"), 0644)
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"review", "page.html", "--no-interactive"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	sess, err := session.Load(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	anns, clean, err := fem.Parse(sess.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anns) != 0 || !strings.Contains(clean, "a {>> x <<} b") {
+		t.Errorf("expected the markers kept as text, got %+v in %q", anns, clean)
+	}
+}
+
+func TestSessionAnnotate(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	config.Init()
+	os.WriteFile(config.SettingsFile, []byte(`{"snippets": [{"name": "why", "type": "question", "text": "Why {text} in {file}:{lines}?"}]}`), 0600)
+	// Line 2 holds a FEM closer, which {text} must not let end the annotation.
+	sess, _ := session.Create("a\nb := f(\"??}\")\nc", "main.go")
+
+	var stdout, stderr strings.Builder
+	code := realMain([]string{"session", "annotate", sess.ID, "1-2", "Needs error wrapping"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("annotate failed: %s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "Lines 1-2: [comment] Needs error wrapping") {
+		t.Errorf("unexpected output %q", stdout.String())
+	}
+
+	stdout.Reset()
+	code = realMain([]string{"session", "annotate", sess.ID, "2", "--snippet", "why"}, strings.NewReader(""), &stdout, &stderr, noopTUI)
+	if code != 0 {
+		t.Fatalf("annotate with a snippet failed: %s", stderr.String())
+	}
+
+	loaded, _ := session.Load(sess.ID)
+	annotations, clean, _ := fem.Parse(loaded.Content)
+	if len(annotations) != 2 {
+		t.Fatalf("expected 2 annotations saved, got %+v", annotations)
+	}
+	if clean != "a\nb := f(\"??}\")\nc" {
+		t.Errorf("repeated annotate should leave the content as it was, got %q", clean)
+	}
+	if a := annotations[1]; a.Type != "question" || a.Text != `Why b := f("??}") in main.go:2?` || a.StartLine != 2 {
+		t.Errorf("unexpected snippet annotation %+v", a)
+	}
+
+	for _, args := range [][]string{
+		{"session", "annotate", sess.ID, "4", "x"},
+		{"session", "annotate", sess.ID, "2-1", "x"},
+		{"session", "annotate", sess.ID, "1"},
+		{"session", "annotate", sess.ID, "1", "x", "--snippet", "why"},
+		{"session", "annotate", sess.ID, "1", "x", "--type", "praise"},
+	} {
+		stderr.Reset()
+		if code := realMain(args, strings.NewReader(""), &stdout, &stderr, noopTUI); code != 1 {
+			t.Errorf("%v: expected exit code 1, got %d (%s)", args, code, stderr.String())
+		}
+	}
+	stderr.Reset()
+	if code := realMain([]string{"session", "annotate", sess.ID, "1", "--snippet", "nope"}, strings.NewReader(""), &stdout, &stderr, noopTUI); code == 0 || !strings.Contains(stderr.String(), `unknown snippet "nope"`) {
+		t.Errorf("expected an unknown snippet error, got %d %q", code, stderr.String())
+	}
+}
+
+func TestSnippetsCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	t.Setenv("FABBRO_PROJECT_ROOT_STOP", tmpDir)
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	var stdout, stderr strings.Builder
+	if code := realMain([]string{"snippets"}, strings.NewReader(""), &stdout, &stderr, noopTUI); code != 0 {
+		t.Fatalf("snippets failed: %s", stderr.String())
+	}
+	if !strings.HasPrefix(stdout.String(), "No snippets.") {
+		t.Errorf("expected no snippets, got %q", stdout.String())
+	}
+
+	os.MkdirAll(filepath.Join(configHome, "fabbro"), 0700)
+	os.WriteFile(filepath.Join(configHome, config.UserSettingsFile), []byte(`{"snippets": [{"name": "test", "text": "Add a test for {lines}"}]}`), 0600)
+	stdout.Reset()
+	if code := realMain([]string{"snippets"}, strings.NewReader(""), &stdout, &stderr, noopTUI); code != 0 {
+		t.Fatalf("snippets failed: %s", stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "test         comment   Add a test for {lines}") || !strings.Contains(stdout.String(), "{text}") {
+		t.Errorf("expected the snippet and placeholders listed, got %q", stdout.String())
+	}
+}
@@ -159,3 +159,100 @@ func TestFindProjectRoot_ReturnsErrorWhenNotFound(t *testing.T) {
 		t.Error("expected FindProjectRoot() to return error when no .fabbro exists")
 	}
 }
+
+func TestLoadSettings_DefaultsWhenMissing(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	Init()
+
+	settings, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings() error: %v", err)
+	}
+	if got := settings.HistoryRetention(); got != DefaultHistoryRetention {
+		t.Errorf("expected default retention %d, got %d", DefaultHistoryRetention, got)
+	}
+}
+
+func TestLoadSettings_ReadsRetention(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	Init()
+	os.WriteFile(SettingsFile, []byte(`{"history": {"retention": -1}}`), 0600)
+
+	settings, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings() error: %v", err)
+	}
+	if got := settings.HistoryRetention(); got != -1 {
+		t.Errorf("expected unlimited retention, got %d", got)
+	}
+}
+
+func TestLoadSettings_RejectsInvalidJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	Init()
+	os.WriteFile(SettingsFile, []byte(`{history`), 0600)
+
+	if _, err := LoadSettings(); err == nil {
+		t.Error("expected error for invalid settings file")
+	}
+}
+
+func TestSaveSettings_RoundTrips(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	Init()
+	if err := SaveSettings(&Settings{History: HistorySettings{Retention: 5}, Scan: ScanSettings{Allow: []string{"docs/*.md"}}}); err != nil {
+		t.Fatalf("SaveSettings() error: %v", err)
+	}
+
+	settings, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings() error: %v", err)
+	}
+	if settings.HistoryRetention() != 5 || len(settings.Scan.Allow) != 1 || settings.Scan.Allow[0] != "docs/*.md" {
+		t.Errorf("unexpected settings %+v", settings)
+	}
+}
+
+func TestLoadUserSettings_ReadsSnippets(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	settings, err := LoadUserSettings()
+	if err != nil || len(settings.Snippets) != 0 {
+		t.Fatalf("expected no user settings, got %+v, %v", settings, err)
+	}
+
+	os.MkdirAll(filepath.Join(configHome, "fabbro"), 0700)
+	os.WriteFile(filepath.Join(configHome, UserSettingsFile), []byte(`{"snippets": [{"name": "test", "text": "Add a test for this"}]}`), 0600)
+
+	settings, err = LoadUserSettings()
+	if err != nil {
+		t.Fatalf("LoadUserSettings() error: %v", err)
+	}
+	if len(settings.Snippets) != 1 || settings.Snippets[0] != (Snippet{Name: "test", Text: "Add a test for this"}) {
+		t.Errorf("unexpected snippets %+v", settings.Snippets)
+	}
+}
+
+func TestAuthor_PrefersEnv(t *testing.T) {
+	t.Setenv("FABBRO_AUTHOR", "Ada")
+	if got := Author(); got != "Ada" {
+		t.Errorf("Author() = %q, want Ada", got)
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SettingsFile is the project settings file, relative to the project root.
+const SettingsFile = ".fabbro/config.json"
+
+// UserSettingsFile is the user settings file, relative to the user
+// configuration directory (os.UserConfigDir, e.g. ~/.config). It has the
+// same fields as the project settings, but only its snippets are used.
+const UserSettingsFile = "fabbro/config.json"
+
+// DefaultHistoryRetention is how many snapshots are kept per session when
+// the project does not configure a limit.
+const DefaultHistoryRetention = 20
+
+// Settings holds optional project configuration from SettingsFile.
+type Settings struct {
+	History  HistorySettings `json:"history"`
+	Scan     ScanSettings    `json:"scan"`
+	Snippets []Snippet       `json:"snippets,omitempty"`
+}
+
+// HistorySettings controls session snapshots.
+type HistorySettings struct {
+	// Retention is the number of snapshots kept per session.
+	// Zero means DefaultHistoryRetention; a negative value keeps all.
+	Retention int `json:"retention"`
+}
+
+// ScanSettings configures `fabbro scan`.
+type ScanSettings struct {
+	// Allow lists glob patterns of files that may contain FEM markers,
+	// such as documentation of the syntax.
+	Allow []string `json:"allow,omitempty"`
+}
+
+// Snippet is a canned annotation text, such as "Add a test for this",
+// inserted by name instead of typed. Its text may contain placeholders
+// like {lines}, filled in by the snippet package.
+type Snippet struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"` // annotation type; comment when empty
+	Text string `json:"text"`
+}
+
+// LoadSettings reads the project settings. A missing file yields defaults.
+func LoadSettings() (*Settings, error) {
+	root, err := FindProjectRoot()
+	if err != nil {
+		return nil, err
+	}
+	return LoadSettingsAt(root)
+}
+
+// LoadSettingsAt reads the settings of the project at root, which need not
+// be the current one. A missing file yields defaults.
+func LoadSettingsAt(root string) (*Settings, error) {
+	return readSettings(filepath.Join(root, SettingsFile), SettingsFile)
+}
+
+// UserSettingsPath returns the path of the user settings file.
+func UserSettingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find user config directory: %w", err)
+	}
+	return filepath.Join(dir, UserSettingsFile), nil
+}
+
+// LoadUserSettings reads the user settings. A missing file, or no user
+// config directory, yields defaults.
+func LoadUserSettings() (*Settings, error) {
+	path, err := UserSettingsPath()
+	if err != nil {
+		return &Settings{}, nil
+	}
+	return readSettings(path, path)
+}
+
+// readSettings reads a settings file, naming it name in errors.
+func readSettings(path, name string) (*Settings, error) {
+	settings := &Settings{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return settings, nil
+}
+
+// SaveSettings writes settings to SettingsFile.
+func SaveSettings(settings *Settings) error {
+	root, err := FindProjectRoot()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(root, SettingsFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", SettingsFile, err)
+	}
+	return nil
+}
+
+// HistoryRetention returns the effective snapshot retention, or -1 for unlimited.
+func (s *Settings) HistoryRetention() int {
+	switch {
+	case s.History.Retention < 0:
+		return -1
+	case s.History.Retention == 0:
+		return DefaultHistoryRetention
+	default:
+		return s.History.Retention
+	}
+}
+
+// Author returns the name to attribute reviews to: $FABBRO_AUTHOR, then
+// git's user.name, then $USER. It returns "" if none is set.
+func Author() string {
+	if author := os.Getenv("FABBRO_AUTHOR"); author != "" {
+		return author
+	}
+	if out, err := exec.Command("git", "config", "user.name").Output(); err == nil {
+		if name := strings.TrimSpace(string(out)); name != "" {
+			return name
+		}
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,149 @@
+// Package diff computes minimal edit scripts between two sequences of
+// strings, such as the lines of two files or the words of two lines.
+package diff
+
+import "unicode"
+
+// Op is the kind of an Edit.
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Edit is one element of an edit script. A and B are the 0-indexed
+// positions in the old and new sequences; the one that does not apply to
+// the operation (B for Delete, A for Insert) is -1.
+type Edit struct {
+	Op   Op
+	Text string
+	A, B int
+}
+
+// maxCells bounds the LCS table. Inputs whose differing middle is larger
+// than this are reported as a wholesale replacement.
+const maxCells = 4_000_000
+
+// Compute returns an edit script that turns a into b. Common prefixes and
+// suffixes are matched first; the remainder uses a longest common
+// subsequence table.
+func Compute(a, b []string) []Edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var edits []Edit
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, Edit{Op: Equal, Text: a[i], A: i, B: i})
+	}
+	edits = append(edits, middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for i := 0; i < suffix; i++ {
+		ai, bi := len(a)-suffix+i, len(b)-suffix+i
+		edits = append(edits, Edit{Op: Equal, Text: a[ai], A: ai, B: bi})
+	}
+	return edits
+}
+
+func middle(a, b []string, offA, offB int) []Edit {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 || n*m > maxCells {
+		return replace(a, b, offA, offB)
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var edits []Edit
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, Edit{Op: Equal, Text: a[i], A: offA + i, B: offB + j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, Edit{Op: Delete, Text: a[i], A: offA + i, B: -1})
+			i++
+		default:
+			edits = append(edits, Edit{Op: Insert, Text: b[j], A: -1, B: offB + j})
+			j++
+		}
+	}
+	edits = append(edits, replace(a[i:], b[j:], offA+i, offB+j)...)
+	return edits
+}
+
+func replace(a, b []string, offA, offB int) []Edit {
+	edits := make([]Edit, 0, len(a)+len(b))
+	for i, s := range a {
+		edits = append(edits, Edit{Op: Delete, Text: s, A: offA + i, B: -1})
+	}
+	for j, s := range b {
+		edits = append(edits, Edit{Op: Insert, Text: s, A: -1, B: offB + j})
+	}
+	return edits
+}
+
+// Changed reports whether edits contain any insertion or deletion.
+func Changed(edits []Edit) bool {
+	for _, e := range edits {
+		if e.Op != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Words splits s into runs of letters and digits, runs of spaces, and
+// single other characters, for diffing two lines word by word. The words
+// join back into s.
+func Words(s string) []string {
+	var words []string
+	start, prev := 0, -1
+	for i, r := range s {
+		c := class(r)
+		if i > start && (c != prev || c == 2) {
+			words = append(words, s[start:i])
+			start = i
+		}
+		prev = c
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
+
+// class groups characters that make up one word: 0 for letters, digits and
+// underscores, 1 for spaces, 2 for anything else.
+func class(r rune) int {
+	switch {
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return 0
+	case unicode.IsSpace(r):
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,85 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+// apply rebuilds the new sequence from an edit script.
+func apply(edits []Edit) (old, new []string) {
+	for _, e := range edits {
+		if e.Op != Insert {
+			old = append(old, e.Text)
+		}
+		if e.Op != Delete {
+			new = append(new, e.Text)
+		}
+	}
+	return old, new
+}
+
+func TestCompute_ReconstructsBothSides(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b []string
+	}{
+		{"identical", []string{"a", "b"}, []string{"a", "b"}},
+		{"empty old", nil, []string{"x", "y"}},
+		{"empty new", []string{"x", "y"}, nil},
+		{"middle change", []string{"a", "b", "c"}, []string{"a", "x", "c"}},
+		{"insert", []string{"a", "c"}, []string{"a", "b", "c"}},
+		{"interleaved", []string{"a", "b", "c", "d"}, []string{"b", "x", "d", "e"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			old, new := apply(Compute(tc.a, tc.b))
+			if len(old) != len(tc.a) || (len(old) > 0 && !reflect.DeepEqual(old, tc.a)) {
+				t.Errorf("old side = %v, want %v", old, tc.a)
+			}
+			if len(new) != len(tc.b) || (len(new) > 0 && !reflect.DeepEqual(new, tc.b)) {
+				t.Errorf("new side = %v, want %v", new, tc.b)
+			}
+		})
+	}
+}
+
+func TestCompute_IsMinimal(t *testing.T) {
+	edits := Compute([]string{"a", "b", "c", "d"}, []string{"a", "c", "d", "e"})
+	want := []Edit{
+		{Op: Equal, Text: "a", A: 0, B: 0},
+		{Op: Delete, Text: "b", A: 1, B: -1},
+		{Op: Equal, Text: "c", A: 2, B: 1},
+		{Op: Equal, Text: "d", A: 3, B: 2},
+		{Op: Insert, Text: "e", A: -1, B: 3},
+	}
+	if !reflect.DeepEqual(edits, want) {
+		t.Errorf("Compute() =\n%+v\nwant\n%+v", edits, want)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	if Changed(Compute([]string{"a"}, []string{"a"})) {
+		t.Error("identical inputs should not be changed")
+	}
+	if !Changed(Compute([]string{"a"}, []string{"b"})) {
+		t.Error("different inputs should be changed")
+	}
+}
+
+func TestWords(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"foo bar", []string{"foo", " ", "bar"}},
+		{"x := f(a_1,  b)", []string{"x", " ", ":", "=", " ", "f", "(", "a_1", ",", "  ", "b", ")"}},
+		{"héllo wörld", []string{"héllo", " ", "wörld"}},
+	}
+	for _, tc := range cases {
+		got := Words(tc.in)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Words(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
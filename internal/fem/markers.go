@@ -2,29 +2,31 @@ package fem
 
 import (
 	"regexp"
+	"sort"
 	"strings"
 )
 
 // AnnotationType defines a single annotation type with its delimiters and prompt.
 type AnnotationType struct {
-	Name   string
-	Open   string // Opening delimiter without spaces, e.g. "{>>"
-	Close  string // Closing delimiter without spaces, e.g. "<<}"
-	Prompt string
+	Name        string
+	Open        string // Opening delimiter without spaces, e.g. "{>>"
+	Close       string // Closing delimiter without spaces, e.g. "<<}"
+	Prompt      string
+	Description string // one-line summary for docs and exports
 }
 
 // AnnotationTypes is the single source of truth for all FEM annotation types.
 // Order matters for deterministic parsing.
 var AnnotationTypes = []AnnotationType{
-	{Name: "comment", Open: "{>>", Close: "<<}", Prompt: "Comment:"},
-	{Name: "delete", Open: "{--", Close: "--}", Prompt: "Reason for deletion:"},
-	{Name: "question", Open: "{??", Close: "??}", Prompt: "Question:"},
-	{Name: "expand", Open: "{!!", Close: "!!}", Prompt: "What to expand:"},
-	{Name: "keep", Open: "{==", Close: "==}", Prompt: "Reason to keep:"},
-	{Name: "unclear", Open: "{~~", Close: "~~}", Prompt: "What's unclear:"},
-	{Name: "change", Open: "{++", Close: "++}", Prompt: "Replacement text:"},
-	{Name: "emphasize", Open: "{**", Close: "**}", Prompt: "What to emphasize:"},
-	{Name: "section", Open: "{##", Close: "##}", Prompt: "Section feedback:"},
+	{Name: "comment", Open: "{>>", Close: "<<}", Prompt: "Comment:", Description: "General comment"},
+	{Name: "delete", Open: "{--", Close: "--}", Prompt: "Reason for deletion:", Description: "Mark for deletion"},
+	{Name: "question", Open: "{??", Close: "??}", Prompt: "Question:", Description: "Ask a question"},
+	{Name: "expand", Open: "{!!", Close: "!!}", Prompt: "What to expand:", Description: "Request more detail"},
+	{Name: "keep", Open: "{==", Close: "==}", Prompt: "Reason to keep:", Description: "Mark as good/keep"},
+	{Name: "unclear", Open: "{~~", Close: "~~}", Prompt: "What's unclear:", Description: "Mark as unclear"},
+	{Name: "change", Open: "{++", Close: "++}", Prompt: "Replacement text:", Description: "Replacement text"},
+	{Name: "emphasize", Open: "{**", Close: "**}", Prompt: "What to emphasize:", Description: "Emphasize a point"},
+	{Name: "section", Open: "{##", Close: "##}", Prompt: "Section feedback:", Description: "Feedback on a whole section"},
 }
 
 // Markers maps annotation type to opening and closing delimiters (with spaces for rendering).
@@ -85,3 +87,25 @@ func ValidAnnotationType(typ string) bool {
 	_, ok := Markers[typ]
 	return ok
 }
+
+// MarkerMatch is a complete annotation found in a line of text.
+type MarkerMatch struct {
+	Type       string
+	Start, End int // byte offsets of the marker in the line
+}
+
+// FindMarkers returns the complete single-line annotations in line, in the
+// order they appear. It is used to find FEM that leaked out of sessions.
+func FindMarkers(line string) []MarkerMatch {
+	var matches []MarkerMatch
+	for _, at := range AnnotationTypes {
+		if !strings.Contains(line, at.Open) {
+			continue
+		}
+		for _, loc := range patterns[at.Name].FindAllStringIndex(line, -1) {
+			matches = append(matches, MarkerMatch{Type: at.Name, Start: loc[0], End: loc[1]})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	return matches
+}
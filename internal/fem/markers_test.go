@@ -47,3 +47,20 @@ func TestValidAnnotationType(t *testing.T) {
 		}
 	}
 }
+
+func TestFindMarkers(t *testing.T) {
+	line := "x := 1 {-- remove --} // {>> why? <<}"
+	got := FindMarkers(line)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 markers, got %+v", got)
+	}
+	if got[0].Type != "delete" || line[got[0].Start:got[0].End] != "{-- remove --}" {
+		t.Errorf("unexpected first marker %+v", got[0])
+	}
+	if got[1].Type != "comment" || line[got[1].Start:got[1].End] != "{>> why? <<}" {
+		t.Errorf("unexpected second marker %+v", got[1])
+	}
+	if m := FindMarkers("for {--i} and {>> unclosed"); len(m) != 0 {
+		t.Errorf("expected no complete markers, got %+v", m)
+	}
+}
@@ -12,6 +12,7 @@ type Annotation struct {
 	Text      string `json:"text"`
 	StartLine int    `json:"startLine"`
 	EndLine   int    `json:"endLine"`
+	Author    string `json:"author,omitempty"` // reviewer(s), from a [by name] tag
 }
 
 // blockDeleteOpen matches a line that is only a block delete opener: {-- text --}
@@ -23,6 +24,10 @@ var blockDeleteClose = regexp.MustCompile(`^\s*\{--/--\}\s*$`)
 // sidecarLineRef matches [line N] or [lines N-M] at the start of annotation text.
 var sidecarLineRef = regexp.MustCompile(`^\[lines?\s+(\d+)(?:-(\d+))?\]\s*`)
 
+// authorTag matches [by name] or [by name, name] at the start of annotation
+// text, after any sidecar line reference.
+var authorTag = regexp.MustCompile(`^\[by ([^\]]+)\]\s*`)
+
 // Sentinels for escaped braces during parsing.
 const escapeOpenBrace = "\x00ESC_OPEN\x00"
 const escapeCloseBrace = "\x00ESC_CLOSE\x00"
@@ -190,7 +195,7 @@ func Parse(content string) ([]Annotation, string, error) {
 					if containsNestedMarker(match[1]) {
 						continue
 					}
-					cleanLine = pattern.ReplaceAllString(cleanLine, "")
+					cleanLine = stripMarkers(pattern, cleanLine)
 					lineNum := i + 1
 					annotations = append(annotations, Annotation{
 						Type:      at.Name,
@@ -216,7 +221,7 @@ func Parse(content string) ([]Annotation, string, error) {
 		}
 	}
 
-	// Post-process: resolve sidecar [line N] / [lines N-M] references.
+	// Post-process: resolve sidecar [line N] / [lines N-M] references and [by name] tags.
 	for i := range annotations {
 		if m := sidecarLineRef.FindStringSubmatch(annotations[i].Text); m != nil {
 			start, _ := strconv.Atoi(m[1])
@@ -228,11 +233,35 @@ func Parse(content string) ([]Annotation, string, error) {
 			annotations[i].EndLine = end
 			annotations[i].Text = strings.TrimSpace(annotations[i].Text[len(m[0]):])
 		}
+		if m := authorTag.FindStringSubmatch(annotations[i].Text); m != nil {
+			annotations[i].Author = strings.TrimSpace(m[1])
+			annotations[i].Text = strings.TrimSpace(annotations[i].Text[len(m[0]):])
+		}
+		annotations[i].Text = unescapeBraces(annotations[i].Text)
 	}
 
-	cleanContent := strings.Join(cleanLines, "\n")
-	// Restore escaped braces to literal characters in clean output.
-	cleanContent = strings.ReplaceAll(cleanContent, escapeOpenBrace, "{")
-	cleanContent = strings.ReplaceAll(cleanContent, escapeCloseBrace, "}")
-	return annotations, cleanContent, nil
+	return annotations, unescapeBraces(strings.Join(cleanLines, "\n")), nil
+}
+
+// stripMarkers removes every marker pattern matches in line, along with the
+// space Render puts before each one, so Parse and Render round-trip cleanly.
+func stripMarkers(pattern *regexp.Regexp, line string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range pattern.FindAllStringIndex(line, -1) {
+		start := m[0]
+		if start > last && line[start-1] == ' ' {
+			start--
+		}
+		b.WriteString(line[last:start])
+		last = m[1]
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// unescapeBraces restores escaped braces to literal characters.
+func unescapeBraces(s string) string {
+	s = strings.ReplaceAll(s, escapeOpenBrace, "{")
+	return strings.ReplaceAll(s, escapeCloseBrace, "}")
 }
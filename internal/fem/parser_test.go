@@ -25,8 +25,8 @@ func TestParse_ExtractsSingleComment(t *testing.T) {
 		t.Errorf("expected Type='comment', got %q", annotations[0].Type)
 	}
 
-	if clean != "Hello  world" {
-		t.Errorf("expected clean='Hello  world', got %q", clean)
+	if clean != "Hello world" {
+		t.Errorf("expected clean='Hello world', got %q", clean)
 	}
 }
 
@@ -62,7 +62,7 @@ Second line`
 		t.Fatalf("Parse() returned error: %v", err)
 	}
 
-	expected := `First line 
+	expected := `First line
 Second line`
 	if clean != expected {
 		t.Errorf("expected clean=%q, got %q", expected, clean)
@@ -168,8 +168,8 @@ func TestParse_AllAnnotationTypes(t *testing.T) {
 				t.Errorf("expected Text=%q, got %q", tt.wantText, annotations[0].Text)
 			}
 
-			if clean != "text " {
-				t.Errorf("expected clean='text ', got %q", clean)
+			if clean != "text" {
+				t.Errorf("expected clean='text', got %q", clean)
 			}
 		})
 	}
@@ -298,8 +298,8 @@ func TestParse_MultipleAnnotationsOnSameLine(t *testing.T) {
 	}
 
 	// Clean should have both removed
-	if clean != "text  middle  end" {
-		t.Errorf("expected clean='text  middle  end', got %q", clean)
+	if clean != "text middle end" {
+		t.Errorf("expected clean='text middle end', got %q", clean)
 	}
 }
 
@@ -347,7 +347,7 @@ func TestParse_NestedDifferentTypes_InnerExtracted(t *testing.T) {
 	}
 
 	// The delete annotation is removed, but the malformed outer comment remains
-	expected := "text {>> outer  comment <<} end"
+	expected := "text {>> outer comment <<} end"
 	if clean != expected {
 		t.Errorf("expected %q, got %q", expected, clean)
 	}
@@ -369,8 +369,8 @@ func TestParse_EmptyAnnotationText(t *testing.T) {
 		t.Errorf("expected empty text, got %q", annotations[0].Text)
 	}
 
-	if clean != "text  with empty" {
-		t.Errorf("expected clean='text  with empty', got %q", clean)
+	if clean != "text with empty" {
+		t.Errorf("expected clean='text with empty', got %q", clean)
 	}
 }
 
@@ -578,7 +578,7 @@ func TestParse_EscapedAndRealAnnotationOnSameLine(t *testing.T) {
 		t.Errorf("expected text 'real comment', got %q", annotations[0].Text)
 	}
 
-	expected := `Use {>> like this <<} for literal. `
+	expected := `Use {>> like this <<} for literal.`
 	if clean != expected {
 		t.Errorf("expected clean=%q, got %q", expected, clean)
 	}
@@ -600,8 +600,8 @@ func TestParse_NestedBracesInAnnotationText(t *testing.T) {
 		t.Errorf("expected text 'Use {curly braces} in the output', got %q", annotations[0].Text)
 	}
 
-	if clean != "Code example. " {
-		t.Errorf("expected clean='Code example. ', got %q", clean)
+	if clean != "Code example." {
+		t.Errorf("expected clean='Code example.', got %q", clean)
 	}
 }
 
@@ -0,0 +1,231 @@
+package fem
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Render is the inverse of Parse: it appends each annotation as an inline
+// marker at the end of its start line in content. Multi-line annotations get
+// a sidecar [lines N-M] prefix so their range survives a round trip, unless
+// the text already carries a line reference. Attributed annotations get a
+// [by name] tag after the line reference. FEM delimiters in content and in
+// annotation text are escaped so they read back literally.
+func Render(content string, annotations []Annotation) string {
+	byLine := make(map[int][]Annotation)
+	for _, a := range annotations {
+		byLine[a.StartLine] = append(byLine[a.StartLine], a)
+	}
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		line = escapeMarkers(line)
+		for _, a := range byLine[i+1] {
+			marker, ok := Markers[a.Type]
+			if !ok {
+				continue
+			}
+			line = line + " " + marker[0] + markerText(a) + marker[1]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markerText returns the text to place between an annotation's delimiters.
+func markerText(a Annotation) string {
+	text := a.Text
+	ref := sidecarLineRef.FindString(text)
+	if ref != "" {
+		text = text[len(ref):]
+	} else if a.EndLine > a.StartLine {
+		ref = fmt.Sprintf("[lines %d-%d] ", a.StartLine, a.EndLine)
+	}
+	text = escapeMarkers(text)
+	if a.Author != "" {
+		text = "[by " + a.Author + "] " + text
+	}
+	return ref + text
+}
+
+// Replacement returns the proposed text of a change annotation: the text
+// after a leading "->" (as written by the TUI's inline editor), with escaped
+// newlines expanded. A leading [line N] or [lines N-M] reference is dropped.
+func (a Annotation) Replacement() string {
+	text := strings.TrimSpace(a.Text)
+	text = strings.TrimSpace(text[len(sidecarLineRef.FindString(text)):])
+	if rest, ok := strings.CutPrefix(text, "->"); ok {
+		text = strings.TrimPrefix(rest, " ")
+	}
+	return strings.ReplaceAll(text, `\n`, "\n")
+}
+
+// Replaced returns the 1-based lines a change annotation replaces: those of
+// a leading [line N] or [lines N-M] reference in its text, as the TUI
+// writes before a replacement, or else the annotation's own range.
+func (a Annotation) Replaced() (start, end int) {
+	m := sidecarLineRef.FindStringSubmatch(strings.TrimSpace(a.Text))
+	if m == nil {
+		return a.StartLine, a.EndLine
+	}
+	start, _ = strconv.Atoi(m[1])
+	end = start
+	if m[2] != "" {
+		end, _ = strconv.Atoi(m[2])
+	}
+	return start, end
+}
+
+// LineRef formats the annotation's range as "Line N" or "Lines N-M".
+func (a Annotation) LineRef() string {
+	if a.EndLine > a.StartLine {
+		return fmt.Sprintf("Lines %d-%d", a.StartLine, a.EndLine)
+	}
+	return fmt.Sprintf("Line %d", a.StartLine)
+}
+
+// Distinct returns annotations with duplicates dropped. The TUI's inline
+// editor writes a change annotation once per line it covers, and reports
+// and prompts show each change only once.
+func Distinct(annotations []Annotation) []Annotation {
+	return MergeAnnotations(nil, annotations)
+}
+
+// Lines splits the clean content returned by Parse into lines, without
+// trailing whitespace or trailing empty lines, for quoting annotated lines.
+func Lines(clean string) []string {
+	lines := strings.Split(strings.TrimRight(clean, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return lines
+}
+
+// Fence wraps text in a Markdown code fence longer than any backtick run
+// inside it, so reviewed Markdown can be quoted safely.
+func Fence(text, lang string) string {
+	longest, run := 0, 0
+	for _, c := range text {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + text + "\n" + fence + "\n"
+}
+
+// escapeMarkers escapes the braces of any FEM delimiters in s as \{ and \},
+// so that source lines and annotation text, such as a quoted Word comment,
+// read back from Parse literally instead of opening or closing an annotation.
+func escapeMarkers(s string) string {
+	for _, at := range AnnotationTypes {
+		s = strings.ReplaceAll(s, at.Open, `\`+at.Open)
+	}
+	for _, at := range AnnotationTypes {
+		s = strings.ReplaceAll(s, at.Close, at.Close[:len(at.Close)-1]+`\}`)
+	}
+	return s
+}
+
+// annotationKey identifies an annotation independently of who wrote it.
+type annotationKey struct {
+	typ, text  string
+	start, end int
+}
+
+func keyOf(a Annotation) annotationKey {
+	return annotationKey{typ: a.Type, text: a.Text, start: a.StartLine, end: a.EndLine}
+}
+
+// MergeAnnotations returns the annotations of a followed by those of b that
+// are not already present in a. Two annotations are duplicates when type,
+// text and range are identical; the duplicate's reviewers are added to the
+// kept annotation's Author.
+func MergeAnnotations(a, b []Annotation) []Annotation {
+	index := make(map[annotationKey]int, len(a))
+	merged := make([]Annotation, 0, len(a)+len(b))
+	for _, ann := range append(append([]Annotation{}, a...), b...) {
+		key := keyOf(ann)
+		if i, ok := index[key]; ok {
+			merged[i].Author = joinAuthors(merged[i].Author, ann.Author)
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, ann)
+	}
+	return merged
+}
+
+// joinAuthors combines two comma-separated reviewer lists without repeats.
+func joinAuthors(a, b string) string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, list := range []string{a, b} {
+		for _, name := range strings.Split(list, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Conflict is a pair of change annotations whose line ranges overlap but
+// whose replacement text differs.
+type Conflict struct {
+	A, B Annotation
+}
+
+// FindChangeConflicts returns every pair of conflicting change annotations.
+func FindChangeConflicts(annotations []Annotation) []Conflict {
+	var conflicts []Conflict
+	for i, a := range annotations {
+		if a.Type != "change" {
+			continue
+		}
+		for _, b := range annotations[i+1:] {
+			if b.Type != "change" || b.Text == a.Text {
+				continue
+			}
+			if a.StartLine <= b.EndLine && b.StartLine <= a.EndLine {
+				conflicts = append(conflicts, Conflict{A: a, B: b})
+			}
+		}
+	}
+	return conflicts
+}
+
+// DiffAnnotations compares two annotation sets and returns the annotations
+// only present in new (added) and only present in old (removed).
+func DiffAnnotations(old, new []Annotation) (added, removed []Annotation) {
+	inOld := make(map[Annotation]int)
+	for _, a := range old {
+		inOld[a]++
+	}
+	inNew := make(map[Annotation]int)
+	for _, a := range new {
+		inNew[a]++
+	}
+	for _, a := range new {
+		if inOld[a] > 0 {
+			inOld[a]--
+			continue
+		}
+		added = append(added, a)
+	}
+	for _, a := range old {
+		if inNew[a] > 0 {
+			inNew[a]--
+			continue
+		}
+		removed = append(removed, a)
+	}
+	return added, removed
+}
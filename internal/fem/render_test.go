@@ -0,0 +1,225 @@
+package fem
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender_AppendsMarkersToStartLine(t *testing.T) {
+	content := "line1\nline2\nline3"
+	annotations := []Annotation{
+		{Type: "comment", Text: "first", StartLine: 1, EndLine: 1},
+		{Type: "question", Text: "why?", StartLine: 3, EndLine: 3},
+	}
+
+	got := Render(content, annotations)
+	want := "line1 {>> first <<}\nline2\nline3 {?? why? ??}"
+	if got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestRender_RoundTripsThroughParse(t *testing.T) {
+	content := "a\nb\nc\nd"
+	annotations := []Annotation{
+		{Type: "comment", Text: "one", StartLine: 1, EndLine: 1},
+		{Type: "delete", Text: "drop these", StartLine: 2, EndLine: 4},
+		{Type: "change", Text: "[lines 2-3] -> x", StartLine: 2, EndLine: 2},
+	}
+
+	parsed, clean, err := Parse(Render(content, annotations))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if clean != content {
+		t.Errorf("unexpected clean content %q", clean)
+	}
+
+	want := []Annotation{
+		{Type: "comment", Text: "one", StartLine: 1, EndLine: 1},
+		{Type: "delete", Text: "drop these", StartLine: 2, EndLine: 4},
+		{Type: "change", Text: "-> x", StartLine: 2, EndLine: 3},
+	}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", parsed, want)
+	}
+}
+
+func TestRender_EscapesMarkersInTextAndContent(t *testing.T) {
+	content := "use {>> like this <<}\nplain"
+	annotations := []Annotation{
+		{Type: "comment", Text: "write {>> x <<} or {--/--}", StartLine: 1, EndLine: 1},
+		{Type: "change", Text: "-> a ++} b", StartLine: 2, EndLine: 2},
+	}
+
+	rendered := Render(content, annotations)
+	if !strings.HasPrefix(rendered, `use \{>> like this <<\} {>> write \{>> x <<\} or \{--/--\} <<}`) {
+		t.Errorf("expected the markers escaped, got %q", rendered)
+	}
+	parsed, clean, err := Parse(rendered)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if clean != content {
+		t.Errorf("unexpected clean content %q", clean)
+	}
+	if !reflect.DeepEqual(parsed, annotations) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", parsed, annotations)
+	}
+}
+
+func TestMergeAnnotations_DropsDuplicates(t *testing.T) {
+	a := []Annotation{
+		{Type: "comment", Text: "same", StartLine: 1, EndLine: 1},
+		{Type: "question", Text: "mine", StartLine: 2, EndLine: 2},
+	}
+	b := []Annotation{
+		{Type: "comment", Text: "same", StartLine: 1, EndLine: 1},
+		{Type: "comment", Text: "theirs", StartLine: 1, EndLine: 1},
+	}
+
+	got := MergeAnnotations(a, b)
+	want := []Annotation{a[0], a[1], b[1]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeAnnotations() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffAnnotations(t *testing.T) {
+	kept := Annotation{Type: "comment", Text: "kept", StartLine: 1, EndLine: 1}
+	gone := Annotation{Type: "delete", Text: "gone", StartLine: 2, EndLine: 3}
+	fresh := Annotation{Type: "question", Text: "new?", StartLine: 4, EndLine: 4}
+
+	added, removed := DiffAnnotations([]Annotation{kept, gone}, []Annotation{kept, fresh})
+	if !reflect.DeepEqual(added, []Annotation{fresh}) {
+		t.Errorf("added = %+v, want %+v", added, fresh)
+	}
+	if !reflect.DeepEqual(removed, []Annotation{gone}) {
+		t.Errorf("removed = %+v, want %+v", removed, gone)
+	}
+}
+
+func TestRender_AuthorTagRoundTrips(t *testing.T) {
+	annotations := []Annotation{
+		{Type: "comment", Text: "looks off", StartLine: 1, EndLine: 1, Author: "Ada Lovelace"},
+		{Type: "change", Text: "[lines 2-3] -> new", StartLine: 2, EndLine: 2, Author: "bob"},
+	}
+
+	rendered := Render("a\nb\nc", annotations)
+	if rendered != "a {>> [by Ada Lovelace] looks off <<}\nb {++ [lines 2-3] [by bob] -> new ++}\nc" {
+		t.Errorf("unexpected render %q", rendered)
+	}
+
+	parsed, _, err := Parse(rendered)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	want := []Annotation{
+		{Type: "comment", Text: "looks off", StartLine: 1, EndLine: 1, Author: "Ada Lovelace"},
+		{Type: "change", Text: "-> new", StartLine: 2, EndLine: 3, Author: "bob"},
+	}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", parsed, want)
+	}
+}
+
+func TestMergeAnnotations_CombinesReviewersOfDuplicates(t *testing.T) {
+	a := []Annotation{{Type: "comment", Text: "same", StartLine: 1, EndLine: 1, Author: "alice"}}
+	b := []Annotation{
+		{Type: "comment", Text: "same", StartLine: 1, EndLine: 1, Author: "bob"},
+		{Type: "comment", Text: "same", StartLine: 1, EndLine: 1, Author: "alice"},
+	}
+
+	got := MergeAnnotations(a, b)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 merged annotation, got %+v", got)
+	}
+	if got[0].Author != "alice, bob" {
+		t.Errorf("expected combined reviewers, got %q", got[0].Author)
+	}
+}
+
+func TestFindChangeConflicts(t *testing.T) {
+	annotations := []Annotation{
+		{Type: "change", Text: "-> x", StartLine: 2, EndLine: 4, Author: "alice"},
+		{Type: "change", Text: "-> y", StartLine: 4, EndLine: 5, Author: "bob"},
+		{Type: "change", Text: "-> z", StartLine: 7, EndLine: 7, Author: "bob"},
+		{Type: "change", Text: "-> x", StartLine: 5, EndLine: 5, Author: "carol"},
+		{Type: "comment", Text: "not a change", StartLine: 2, EndLine: 2},
+	}
+
+	conflicts := FindChangeConflicts(annotations)
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %+v", conflicts)
+	}
+	if conflicts[0].A.Author != "alice" || conflicts[0].B.Author != "bob" {
+		t.Errorf("unexpected first conflict %+v", conflicts[0])
+	}
+	if conflicts[1].A.Author != "bob" || conflicts[1].B.Author != "carol" {
+		t.Errorf("unexpected second conflict %+v", conflicts[1])
+	}
+}
+
+func TestAnnotationReplacement(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"-> new line", "new line"},
+		{`-> first\nsecond`, "first\nsecond"},
+		{"plain suggestion", "plain suggestion"},
+		{"->", ""},
+		{"[line 3] -> new line", "new line"},
+		{`[lines 2-4] -> a\nb`, "a\nb"},
+	}
+	for _, tt := range tests {
+		got := Annotation{Type: "change", Text: tt.text}.Replacement()
+		if got != tt.want {
+			t.Errorf("Replacement(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestAnnotationReplaced(t *testing.T) {
+	tests := []struct {
+		ann        Annotation
+		start, end int
+	}{
+		{Annotation{Text: "-> x", StartLine: 4, EndLine: 6}, 4, 6},
+		{Annotation{Text: "[line 3] -> x", StartLine: 3, EndLine: 3}, 3, 3},
+		{Annotation{Text: "[lines 2-4] -> x", StartLine: 3, EndLine: 3}, 2, 4},
+	}
+	for _, tt := range tests {
+		start, end := tt.ann.Replaced()
+		if start != tt.start || end != tt.end {
+			t.Errorf("Replaced() of %q = %d-%d, want %d-%d", tt.ann.Text, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestAnnotationLineRef(t *testing.T) {
+	if got := (Annotation{StartLine: 3, EndLine: 3}).LineRef(); got != "Line 3" {
+		t.Errorf("LineRef() = %q, want Line 3", got)
+	}
+	if got := (Annotation{StartLine: 3, EndLine: 5}).LineRef(); got != "Lines 3-5" {
+		t.Errorf("LineRef() = %q, want Lines 3-5", got)
+	}
+}
+
+func TestLines(t *testing.T) {
+	got := Lines("a  \n\tb\t\n\n")
+	if want := []string{"a", "\tb"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines() = %q, want %q", got, want)
+	}
+}
+
+func TestFenceLongerThanInnerBackticks(t *testing.T) {
+	got := Fence("```go\nx\n```", "md")
+	if want := "````md\n```go\nx\n```\n````\n"; got != want {
+		t.Errorf("Fence() = %q, want %q", got, want)
+	}
+	if got := Fence("plain", ""); got != "```\nplain\n```\n" {
+		t.Errorf("unexpected fence %q", got)
+	}
+}
@@ -0,0 +1,33 @@
+// Package fuzzy implements the subsequence matching used by the TUI's
+// search and by fabbro search: a pattern matches when its characters
+// appear in the text in order, not necessarily adjacent.
+package fuzzy
+
+// Match reports whether every byte of pattern appears in text in order.
+// Callers lower-case both sides for case-insensitive matching.
+func Match(text, pattern string) bool {
+	pIdx := 0
+	for i := 0; i < len(text) && pIdx < len(pattern); i++ {
+		if text[i] == pattern[pIdx] {
+			pIdx++
+		}
+	}
+	return pIdx == len(pattern)
+}
+
+// Positions returns the byte offsets in text of the first in-order match of
+// pattern's bytes, or nil when pattern doesn't match.
+func Positions(text, pattern string) []int {
+	var positions []int
+	pIdx := 0
+	for i := 0; i < len(text) && pIdx < len(pattern); i++ {
+		if text[i] == pattern[pIdx] {
+			positions = append(positions, i)
+			pIdx++
+		}
+	}
+	if pIdx == len(pattern) {
+		return positions
+	}
+	return nil
+}
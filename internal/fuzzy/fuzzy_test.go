@@ -0,0 +1,33 @@
+package fuzzy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		text, pattern string
+		want          bool
+	}{
+		{"retry logic", "rtl", true},
+		{"retry logic", "retry", true},
+		{"retry logic", "lr", false},
+		{"anything", "", true},
+		{"", "x", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.text, tt.pattern); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.text, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	if got := Positions("hello world", "hwd"); !reflect.DeepEqual(got, []int{0, 6, 10}) {
+		t.Errorf("Positions = %v", got)
+	}
+	if got := Positions("hello", "z"); got != nil {
+		t.Errorf("expected nil for no match, got %v", got)
+	}
+}
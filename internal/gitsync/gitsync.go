@@ -0,0 +1,360 @@
+// Package gitsync shares fabbro sessions between clones of a git repository.
+//
+// Sessions are stored as a flat tree of .fem files on a dedicated ref
+// (refs/fabbro/sessions) that never touches the working tree or branches.
+// Everything goes through the local git binary, so any remote git can reach
+// — including a bare repository on disk — works as a sync target.
+package gitsync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+// Ref is the git ref that holds the shared session tree.
+const Ref = "refs/fabbro/sessions"
+
+// remoteRef returns the local ref where the remote's session tree is fetched
+// to. The remote may be a path or URL, which is not a valid ref component, so
+// it is named by a hash of the remote instead.
+func remoteRef(remote string) string {
+	sum := sha256.Sum256([]byte(remote))
+	return "refs/fabbro/remotes/" + hex.EncodeToString(sum[:8]) + "/sessions"
+}
+
+// Result summarises what a push or pull did to the local sessions.
+type Result struct {
+	Added     []string // sessions copied from the remote
+	Merged    []string // sessions whose annotations were combined
+	Conflicts []string // sessions left untouched because their content differs or does not parse
+	Pushed    int      // number of sessions in the pushed tree (push only)
+	UpToDate  bool     // true when the remote already had everything (push only)
+}
+
+// Syncer runs git commands in a repository and reads/writes the session
+// files in sessionsDir.
+type Syncer struct {
+	RepoDir     string
+	SessionsDir string
+}
+
+// New returns a Syncer for the git repository at repoDir.
+func New(repoDir, sessionsDir string) *Syncer {
+	return &Syncer{RepoDir: repoDir, SessionsDir: sessionsDir}
+}
+
+func (s *Syncer) git(stdin []byte, args ...string) (string, error) {
+	out, err := s.gitRaw(stdin, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// gitRaw is git without trimming, for reading blobs byte for byte.
+func (s *Syncer) gitRaw(stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", s.RepoDir}, args...)...)
+	cmd.Env = append(os.Environ(), s.identityEnv()...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+// identityEnv supplies a fallback committer identity so commit-tree works in
+// repositories without user.name/user.email configured.
+func (s *Syncer) identityEnv() []string {
+	var env []string
+	if os.Getenv("GIT_AUTHOR_NAME") == "" && os.Getenv("GIT_COMMITTER_NAME") == "" {
+		out, _ := exec.Command("git", "-C", s.RepoDir, "config", "user.name").Output()
+		if len(bytes.TrimSpace(out)) == 0 {
+			env = append(env, "GIT_AUTHOR_NAME=fabbro", "GIT_COMMITTER_NAME=fabbro")
+		}
+	}
+	if os.Getenv("GIT_AUTHOR_EMAIL") == "" && os.Getenv("GIT_COMMITTER_EMAIL") == "" {
+		out, _ := exec.Command("git", "-C", s.RepoDir, "config", "user.email").Output()
+		if len(bytes.TrimSpace(out)) == 0 {
+			env = append(env, "GIT_AUTHOR_EMAIL=fabbro@localhost", "GIT_COMMITTER_EMAIL=fabbro@localhost")
+		}
+	}
+	return env
+}
+
+// CheckRepo verifies that git is installed and RepoDir is inside a work tree.
+func (s *Syncer) CheckRepo() error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("git not found in PATH")
+	}
+	if _, err := s.git(nil, "rev-parse", "--git-dir"); err != nil {
+		return fmt.Errorf("not a git repository: %s", s.RepoDir)
+	}
+	return nil
+}
+
+// fetch copies the remote's session ref to remoteRef(remote). It returns the
+// fetched commit, or "" when the remote has no sessions yet.
+func (s *Syncer) fetch(remote string) (string, error) {
+	out, err := s.git(nil, "ls-remote", remote, Ref)
+	if err != nil {
+		return "", err
+	}
+	if out == "" {
+		return "", nil
+	}
+	if _, err := s.git(nil, "fetch", "--quiet", remote, "+"+Ref+":"+remoteRef(remote)); err != nil {
+		return "", err
+	}
+	return s.git(nil, "rev-parse", remoteRef(remote))
+}
+
+// Pull fetches the remote's sessions and merges them into the local ones.
+func (s *Syncer) Pull(remote string) (*Result, error) {
+	if err := s.CheckRepo(); err != nil {
+		return nil, err
+	}
+	commit, err := s.fetch(remote)
+	if err != nil {
+		return nil, err
+	}
+	if commit == "" {
+		return nil, fmt.Errorf("remote %q has no fabbro sessions (run 'fabbro sync push' first)", remote)
+	}
+	result := &Result{}
+	if err := s.mergeCommit(commit, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Push merges the remote's sessions into the local ones, then publishes the
+// combined set as a new commit on Ref that fast-forwards the remote.
+func (s *Syncer) Push(remote string) (*Result, error) {
+	if err := s.CheckRepo(); err != nil {
+		return nil, err
+	}
+	remoteTip, err := s.fetch(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	parent := remoteTip
+	if remoteTip != "" {
+		if err := s.mergeCommit(remoteTip, result); err != nil {
+			return nil, err
+		}
+	} else if local, err := s.git(nil, "rev-parse", "--verify", "--quiet", Ref); err == nil {
+		parent = local
+	}
+
+	// A conflicting session keeps the remote's copy in the pushed tree, so
+	// the teammate's review is not replaced by the local one.
+	keep := make(map[string]string)
+	for _, id := range result.Conflicts {
+		blob, err := s.git(nil, "rev-parse", remoteTip+":"+id+".fem")
+		if err != nil {
+			return nil, err
+		}
+		keep[id+".fem"] = blob
+	}
+	tree, count, err := s.writeTree(keep)
+	if err != nil {
+		return nil, err
+	}
+	result.Pushed = count
+
+	if remoteTip != "" {
+		remoteTree, err := s.git(nil, "rev-parse", remoteTip+"^{tree}")
+		if err != nil {
+			return nil, err
+		}
+		if remoteTree == tree {
+			result.UpToDate = true
+			if _, err := s.git(nil, "update-ref", Ref, remoteTip); err != nil {
+				return nil, err
+			}
+			return result, nil
+		}
+	}
+
+	args := []string{"commit-tree", tree, "-m", fmt.Sprintf("fabbro: sync %d session(s)", count)}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	commit, err := s.git(nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.git(nil, "update-ref", Ref, commit); err != nil {
+		return nil, err
+	}
+	if _, err := s.git(nil, "push", "--quiet", remote, Ref+":"+Ref); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// writeTree stores every local session file as a blob and returns the tree
+// that lists them. Files named in keep are listed with the given blob
+// instead of the local content.
+func (s *Syncer) writeTree(keep map[string]string) (string, int, error) {
+	entries, err := os.ReadDir(s.SessionsDir)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read sessions directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".fem") {
+			continue
+		}
+		if _, ok := keep[entry.Name()]; !ok {
+			names = append(names, entry.Name())
+		}
+	}
+	for name := range keep {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var listing strings.Builder
+	for _, name := range names {
+		blob, ok := keep[name]
+		if !ok {
+			var err error
+			blob, err = s.git(nil, "hash-object", "-w", filepath.Join(s.SessionsDir, name))
+			if err != nil {
+				return "", 0, err
+			}
+		}
+		fmt.Fprintf(&listing, "100644 blob %s\t%s\n", blob, name)
+	}
+
+	tree, err := s.git([]byte(listing.String()), "mktree")
+	if err != nil {
+		return "", 0, err
+	}
+	return tree, len(names), nil
+}
+
+// mergeCommit merges every session file in commit's tree into SessionsDir.
+func (s *Syncer) mergeCommit(commit string, result *Result) error {
+	listing, err := s.git(nil, "ls-tree", commit)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(listing, "\n") {
+		// Format: <mode> SP <type> SP <object> TAB <name>
+		meta, name, ok := strings.Cut(line, "\t")
+		if !ok || !strings.HasSuffix(name, ".fem") {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		if err := session.ValidateSessionID(strings.TrimSuffix(name, ".fem")); err != nil {
+			continue
+		}
+		data, err := s.gitRaw(nil, "cat-file", "blob", fields[2])
+		if err != nil {
+			return err
+		}
+		if err := s.mergeFile(name, data, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeFile merges a remote session file into the local copy of the same name.
+func (s *Syncer) mergeFile(name string, remoteData []byte, result *Result) error {
+	id := strings.TrimSuffix(name, ".fem")
+	path := filepath.Join(s.SessionsDir, name)
+
+	localData, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		remote, err := session.Parse(remoteData)
+		if err != nil || remote.ID != id {
+			result.Conflicts = append(result.Conflicts, id)
+			return nil
+		}
+		if err := session.SaveIn(s.SessionsDir, remote); err != nil {
+			return fmt.Errorf("failed to write session %s: %w", id, err)
+		}
+		result.Added = append(result.Added, id)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read session %s: %w", id, err)
+	}
+	if bytes.Equal(localData, remoteData) {
+		return nil
+	}
+
+	merged, changed, err := MergeSessions(localData, remoteData)
+	if err != nil {
+		result.Conflicts = append(result.Conflicts, id)
+		return nil
+	}
+	if !changed {
+		return nil
+	}
+	if err := session.SaveIn(s.SessionsDir, merged); err != nil {
+		return fmt.Errorf("failed to write session %s: %w", id, err)
+	}
+	result.Merged = append(result.Merged, id)
+	return nil
+}
+
+// MergeSessions combines the annotations of two copies of the same session.
+// The local frontmatter is kept. It returns an error when the reviewed
+// content differs, since annotations cannot be reconciled line by line.
+func MergeSessions(localData, remoteData []byte) (*session.Session, bool, error) {
+	local, err := session.Parse(localData)
+	if err != nil {
+		return nil, false, fmt.Errorf("local session: %w", err)
+	}
+	remote, err := session.Parse(remoteData)
+	if err != nil {
+		return nil, false, fmt.Errorf("remote session: %w", err)
+	}
+
+	localAnns, localClean, err := fem.Parse(local.Content)
+	if err != nil {
+		return nil, false, fmt.Errorf("local session: %w", err)
+	}
+	remoteAnns, remoteClean, err := fem.Parse(remote.Content)
+	if err != nil {
+		return nil, false, fmt.Errorf("remote session: %w", err)
+	}
+	if !session.SameContent(localClean, remoteClean) {
+		return nil, false, fmt.Errorf("session %s: content differs between local and remote", local.ID)
+	}
+
+	// Identical local annotations collapse in the merge too, so compare
+	// against the local annotations merged with nothing.
+	merged := fem.MergeAnnotations(localAnns, remoteAnns)
+	if len(merged) == len(fem.MergeAnnotations(nil, localAnns)) {
+		return local, false, nil
+	}
+	local.Content = fem.Render(localClean, merged)
+	return local, true, nil
+}
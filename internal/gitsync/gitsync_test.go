@@ -0,0 +1,273 @@
+package gitsync
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+// setupClones creates a bare "origin" repository and two clones of it, each
+// with an empty .fabbro/sessions directory.
+func setupClones(t *testing.T) (a, b *Syncer) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin.git")
+	runGit(t, root, "init", "--quiet", "--bare", origin)
+
+	clone := func(name string) *Syncer {
+		dir := filepath.Join(root, name)
+		runGit(t, root, "init", "--quiet", dir)
+		runGit(t, dir, "remote", "add", "origin", origin)
+		sessionsDir := filepath.Join(dir, ".fabbro", "sessions")
+		if err := os.MkdirAll(sessionsDir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		return New(dir, sessionsDir)
+	}
+	return clone("alice"), clone("bob")
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func writeSession(t *testing.T, s *Syncer, id, content string) {
+	t.Helper()
+	sess := &session.Session{
+		ID:        id,
+		Content:   content,
+		CreatedAt: time.Date(2026, 1, 11, 12, 0, 0, 0, time.UTC),
+	}
+	path := filepath.Join(s.SessionsDir, id+".fem")
+	if err := os.WriteFile(path, []byte(session.Format(sess)), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readAnnotations(t *testing.T, s *Syncer, id string) []fem.Annotation {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(s.SessionsDir, id+".fem"))
+	if err != nil {
+		t.Fatalf("session %s missing: %v", id, err)
+	}
+	sess, err := session.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anns, _, err := fem.Parse(sess.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return anns
+}
+
+func TestPushThenPullCopiesSessions(t *testing.T) {
+	alice, bob := setupClones(t)
+	writeSession(t, alice, "plan-review", "step one {>> why? <<}\nstep two")
+
+	res, err := alice.Push("origin")
+	if err != nil {
+		t.Fatalf("Push() error: %v", err)
+	}
+	if res.Pushed != 1 || res.UpToDate {
+		t.Errorf("unexpected push result: %+v", res)
+	}
+
+	res, err = bob.Pull("origin")
+	if err != nil {
+		t.Fatalf("Pull() error: %v", err)
+	}
+	if len(res.Added) != 1 || res.Added[0] != "plan-review" {
+		t.Errorf("expected plan-review to be added, got %+v", res)
+	}
+	if anns := readAnnotations(t, bob, "plan-review"); len(anns) != 1 || anns[0].Text != "why?" {
+		t.Errorf("unexpected annotations after pull: %+v", anns)
+	}
+}
+
+func TestPushMergesAnnotationsFromBothReviewers(t *testing.T) {
+	alice, bob := setupClones(t)
+	writeSession(t, alice, "shared", "alpha {>> from alice <<}\nbeta")
+	writeSession(t, bob, "shared", "alpha\nbeta {?? from bob ??}")
+
+	if _, err := alice.Push("origin"); err != nil {
+		t.Fatalf("alice push: %v", err)
+	}
+	res, err := bob.Push("origin")
+	if err != nil {
+		t.Fatalf("bob push: %v", err)
+	}
+	if len(res.Merged) != 1 {
+		t.Errorf("expected bob's push to merge alice's annotations, got %+v", res)
+	}
+	if _, err := alice.Pull("origin"); err != nil {
+		t.Fatalf("alice pull: %v", err)
+	}
+
+	for name, s := range map[string]*Syncer{"alice": alice, "bob": bob} {
+		anns := readAnnotations(t, s, "shared")
+		if len(anns) != 2 {
+			t.Errorf("%s: expected 2 merged annotations, got %+v", name, anns)
+		}
+	}
+}
+
+func TestSyncWithPathRemote(t *testing.T) {
+	alice, bob := setupClones(t)
+	origin := filepath.Join(filepath.Dir(alice.RepoDir), "origin.git")
+	writeSession(t, alice, "s1", "alpha {>> first <<}")
+
+	if _, err := alice.Push(origin); err != nil {
+		t.Fatalf("first push: %v", err)
+	}
+	writeSession(t, alice, "s2", "beta")
+	if res, err := alice.Push(origin); err != nil || res.Pushed != 2 {
+		t.Fatalf("second push: %+v, %v", res, err)
+	}
+
+	res, err := bob.Pull(origin)
+	if err != nil {
+		t.Fatalf("Pull() error: %v", err)
+	}
+	if len(res.Added) != 2 {
+		t.Errorf("expected both sessions added, got %+v", res)
+	}
+	if anns := readAnnotations(t, bob, "s1"); len(anns) != 1 || anns[0].Text != "first" {
+		t.Errorf("unexpected annotations after pull: %+v", anns)
+	}
+}
+
+func TestPullRecordsHistory(t *testing.T) {
+	alice, bob := setupClones(t)
+	writeSession(t, alice, "shared", "alpha {>> from alice <<}\nbeta")
+	writeSession(t, bob, "shared", "alpha\nbeta {?? from bob ??}")
+
+	if _, err := alice.Push("origin"); err != nil {
+		t.Fatalf("alice push: %v", err)
+	}
+	if _, err := bob.Pull("origin"); err != nil {
+		t.Fatalf("bob pull: %v", err)
+	}
+	snapshots, err := os.ReadDir(filepath.Join(bob.SessionsDir, "shared.history"))
+	if err != nil || len(snapshots) != 1 {
+		t.Errorf("expected the merge recorded as a snapshot, got %v, %v", snapshots, err)
+	}
+}
+
+func TestPullReportsConflictWhenContentDiffers(t *testing.T) {
+	alice, bob := setupClones(t)
+	writeSession(t, alice, "shared", "original text {>> a <<}")
+	writeSession(t, bob, "shared", "edited text {>> b <<}")
+
+	if _, err := alice.Push("origin"); err != nil {
+		t.Fatalf("alice push: %v", err)
+	}
+	res, err := bob.Pull("origin")
+	if err != nil {
+		t.Fatalf("bob pull: %v", err)
+	}
+	if len(res.Conflicts) != 1 || res.Conflicts[0] != "shared" {
+		t.Errorf("expected a conflict on shared, got %+v", res)
+	}
+	if anns := readAnnotations(t, bob, "shared"); len(anns) != 1 || anns[0].Text != "b" {
+		t.Errorf("conflicting local session should be untouched, got %+v", anns)
+	}
+}
+
+func TestPullMergesIntoDuplicatedLocalAnnotations(t *testing.T) {
+	alice, bob := setupClones(t)
+	writeSession(t, alice, "shared", "alpha {>> one <<}\nbeta {>> two <<}")
+	writeSession(t, bob, "shared", "alpha {++ -> x ++} {++ -> x ++} {++ -> x ++}\nbeta")
+
+	if _, err := alice.Push("origin"); err != nil {
+		t.Fatalf("alice push: %v", err)
+	}
+	res, err := bob.Pull("origin")
+	if err != nil {
+		t.Fatalf("bob pull: %v", err)
+	}
+	if len(res.Merged) != 1 {
+		t.Errorf("expected alice's comments merged, got %+v", res)
+	}
+	if anns := readAnnotations(t, bob, "shared"); len(anns) != 3 {
+		t.Errorf("expected the change and both comments, got %+v", anns)
+	}
+}
+
+func TestPushKeepsRemoteCopyOfConflicts(t *testing.T) {
+	alice, bob := setupClones(t)
+	writeSession(t, alice, "shared", "original text {>> a <<}")
+	writeSession(t, bob, "shared", "edited text {>> b <<}")
+
+	if _, err := alice.Push("origin"); err != nil {
+		t.Fatalf("alice push: %v", err)
+	}
+	res, err := bob.Push("origin")
+	if err != nil {
+		t.Fatalf("bob push: %v", err)
+	}
+	if len(res.Conflicts) != 1 {
+		t.Errorf("expected a conflict on shared, got %+v", res)
+	}
+
+	// Alice's copy is still the one on the remote.
+	res, err = alice.Pull("origin")
+	if err != nil {
+		t.Fatalf("alice pull: %v", err)
+	}
+	if len(res.Conflicts) != 0 || len(res.Merged) != 0 {
+		t.Errorf("expected alice's copy kept on the remote, got %+v", res)
+	}
+}
+
+func TestPushIsUpToDateWhenNothingChanged(t *testing.T) {
+	alice, _ := setupClones(t)
+	writeSession(t, alice, "s1", "content")
+
+	if _, err := alice.Push("origin"); err != nil {
+		t.Fatalf("first push: %v", err)
+	}
+	res, err := alice.Push("origin")
+	if err != nil {
+		t.Fatalf("second push: %v", err)
+	}
+	if !res.UpToDate {
+		t.Errorf("expected second push to be up to date, got %+v", res)
+	}
+}
+
+func TestPullWithoutRemoteSessions(t *testing.T) {
+	_, bob := setupClones(t)
+
+	_, err := bob.Pull("origin")
+	if err == nil || !strings.Contains(err.Error(), "no fabbro sessions") {
+		t.Errorf("expected missing-ref error, got %v", err)
+	}
+}
+
+func TestCheckRepoRejectsNonRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	s := New(dir, filepath.Join(dir, ".fabbro", "sessions"))
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+
+	if err := s.CheckRepo(); err == nil {
+		t.Error("expected error outside a git repository")
+	}
+}
@@ -0,0 +1,28 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"docs/fem.md", "docs/fem.md", true},
+		{"./docs/fem.md", "docs/fem.md", true},
+		{"docs/*.md", "docs/fem.md", true},
+		{"docs/*.md", "docs/guide/fem.md", false},
+		{"docs/**/*.md", "docs/guide/fem.md", true},
+		{"docs/**/*.md", "docs/fem.md", true},
+		{"docs/**", "docs/a/b/c.txt", true},
+		{"*.md", "README.md", true},
+		{"*.md", "docs/deep/README.md", true},
+		{"*.md", "main.go", false},
+		{"internal/**/testdata/*", "internal/fem/testdata/x.fem", true},
+		{"[", "[", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
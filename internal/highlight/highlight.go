@@ -64,6 +64,36 @@ func (h *Highlighter) HighlightLine(line string) []Token {
 	return tokens
 }
 
+// Language returns the lowercase name of the detected lexer, suitable as a
+// Markdown code fence info string, or "" for plain text.
+func (h *Highlighter) Language() string {
+	cfg := h.lexer.Config()
+	if cfg == nil || len(cfg.Aliases) == 0 || cfg.Name == "plaintext" {
+		return ""
+	}
+	return cfg.Aliases[0]
+}
+
+// Background returns the style's background colour as "#rrggbb", or "" if
+// the style does not set one.
+func (h *Highlighter) Background() string {
+	entry := h.style.Get(chroma.Background)
+	if !entry.Background.IsSet() {
+		return ""
+	}
+	return entry.Background.String()
+}
+
+// Foreground returns the style's default text colour as "#rrggbb", or "" if
+// the style does not set one.
+func (h *Highlighter) Foreground() string {
+	entry := h.style.Get(chroma.Background)
+	if !entry.Colour.IsSet() {
+		return ""
+	}
+	return entry.Colour.String()
+}
+
 func (h *Highlighter) RenderLine(line string) string {
 	tokens := h.HighlightLine(line)
 	var b strings.Builder
@@ -79,6 +109,42 @@ func (h *Highlighter) RenderLine(line string) string {
 	return b.String()
 }
 
+// RenderSpans is RenderLine with the byte ranges spans of line, in order
+// and not overlapping, set on the background colour "#rrggbb".
+func (h *Highlighter) RenderSpans(line string, spans [][2]int, background string) string {
+	bg := ""
+	if fg := ansiColor(background); fg != "" {
+		bg = "\033[48" + fg[len("\033[38"):]
+	}
+	var b strings.Builder
+	pos := 0
+	for _, t := range h.HighlightLine(line) {
+		for text := t.Text; text != ""; {
+			// Cut the token where a span starts or ends.
+			n, inSpan := len(text), false
+			for _, sp := range spans {
+				if pos >= sp[0] && pos < sp[1] {
+					n, inSpan = min(n, sp[1]-pos), true
+				} else if sp[0] > pos {
+					n = min(n, sp[0]-pos)
+				}
+			}
+			if inSpan {
+				b.WriteString(bg)
+			}
+			if t.Color != "" {
+				b.WriteString(ansiColor(t.Color))
+			}
+			b.WriteString(text[:n])
+			if inSpan || t.Color != "" {
+				b.WriteString("\033[0m")
+			}
+			text, pos = text[n:], pos+n
+		}
+	}
+	return b.String()
+}
+
 func ansiColor(hexColor string) string {
 	if len(hexColor) != 7 || hexColor[0] != '#' {
 		return ""
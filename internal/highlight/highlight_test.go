@@ -3,6 +3,8 @@ package highlight
 import (
 	"strings"
 	"testing"
+
+	"github.com/charmbracelet/x/ansi"
 )
 
 func TestNewWithFilename(t *testing.T) {
@@ -88,3 +90,36 @@ func TestAnsiColor(t *testing.T) {
 		}
 	}
 }
+
+func TestLanguageAndColours(t *testing.T) {
+	h := New("main.go", "package main")
+	if h.Language() != "go" {
+		t.Errorf("expected go, got %q", h.Language())
+	}
+	if h.Background() == "" || h.Foreground() == "" {
+		t.Errorf("expected monokai to set colours, got %q/%q", h.Background(), h.Foreground())
+	}
+
+	plain := New("notes.txt", "just some words")
+	if plain.Language() != "" {
+		t.Errorf("expected no language for plain text, got %q", plain.Language())
+	}
+}
+
+func TestRenderSpans(t *testing.T) {
+	h := New("main.go", "package main")
+	line := "x := oldName(1)"
+	result := h.RenderSpans(line, [][2]int{{5, 12}}, "#5f0000")
+
+	if got := ansi.Strip(result); got != line {
+		t.Errorf("text = %q, want %q", got, line)
+	}
+	bg := "\033[48;2;95;0;0m"
+	if strings.Count(result, bg) != 1 {
+		t.Fatalf("expected the span on the background once, got %q", result)
+	}
+	span, _, _ := strings.Cut(result[strings.Index(result, bg):], "\033[0m")
+	if got := ansi.Strip(span); got != "oldName" {
+		t.Errorf("background covers %q, want oldName", got)
+	}
+}
@@ -0,0 +1,707 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+)
+
+// maxPartBytes caps how much of one part of a .docx is decompressed, so a
+// zip bomb can't exhaust memory.
+const maxPartBytes = 64 << 20
+
+// errLegacyDoc is returned for binary .doc files, which fabbro can't read.
+var errLegacyDoc = errors.New("legacy .doc files are not supported; save the document as .docx and try again")
+
+// Relationship types of the parts DOCX reads, matched by suffix so both
+// the transitional and strict namespaces work.
+const (
+	relOfficeDocument = "/officeDocument"
+	relStyles         = "/styles"
+	relNumbering      = "/numbering"
+	relComments       = "/comments"
+)
+
+// headingStyleID matches the style IDs Word gives its built-in headings,
+// used when a document has no styles part.
+var headingStyleID = regexp.MustCompile(`^(?i:heading)\s*([1-6])$`)
+
+// DOCX converts a Word document (Office Open XML) to Markdown, like the
+// web app does with mammoth.js. Headings, bulleted and numbered lists,
+// tables, links, bold, italic and strikethrough become their Markdown
+// equivalents; other formatting is dropped, as are deleted tracked
+// changes. Word comments become comment annotations on the lines they
+// cover, attributed to their authors. A .docx has no lines, so the
+// document's LineMap is empty.
+func DOCX(data []byte) (*Document, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("could not read .docx file, it may be corrupt: %w", err)
+	}
+	p := &docxPackage{files: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		p.files[f.Name] = f
+	}
+
+	docPath := "word/document.xml"
+	if rels, err := p.rels("_rels/.rels", ""); err == nil {
+		if target, ok := rels.byType(relOfficeDocument); ok {
+			docPath = target
+		}
+	}
+	body, err := p.xml(docPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not read .docx file, it may be corrupt: %w", err)
+	}
+	rels, err := p.rels(path.Join(path.Dir(docPath), "_rels", path.Base(docPath)+".rels"), path.Dir(docPath))
+	if err != nil {
+		return nil, fmt.Errorf("could not read .docx file, it may be corrupt: %w", err)
+	}
+
+	c := &docxConverter{
+		rels:     rels,
+		styles:   map[string]*docxStyle{},
+		counters: map[string][]int{},
+		starts:   map[string]int{},
+		ends:     map[string]int{},
+	}
+	if target, ok := rels.byType(relStyles); ok {
+		if styles, err := p.xml(target); err == nil {
+			c.readStyles(styles)
+		}
+	}
+	if target, ok := rels.byType(relNumbering); ok {
+		if numbering, err := p.xml(target); err == nil {
+			c.numbering = readNumbering(numbering)
+		}
+	}
+	var comments *xnode
+	if target, ok := rels.byType(relComments); ok {
+		comments, _ = p.xml(target)
+	}
+
+	if b := body.find("document", "body"); b != nil {
+		c.blocks(b.children)
+	}
+
+	// Trim blank lines at either end.
+	end := len(c.lines)
+	for end > 0 && c.lines[end-1] == "" {
+		end--
+	}
+	doc := &Document{Format: FormatDOCX}
+	if end > 0 {
+		doc.Markdown = strings.Join(c.lines[:end], "\n") + "\n"
+		doc.Annotations = c.annotations(comments, end)
+	}
+	return doc, nil
+}
+
+// docxPackage is the zip container of a .docx.
+type docxPackage struct {
+	files map[string]*zip.File
+}
+
+// xml reads and parses the part at name.
+func (p *docxPackage) xml(name string) (*xnode, error) {
+	f, ok := p.files[name]
+	if !ok {
+		return nil, fmt.Errorf("missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxPartBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(data) > maxPartBytes {
+		return nil, fmt.Errorf("%s exceeds %d bytes", name, maxPartBytes)
+	}
+	n, err := parseXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return n, nil
+}
+
+type relationship struct {
+	typ, target string
+	external    bool
+}
+
+type relationships map[string]relationship
+
+// rels reads a relationships part, resolving internal targets against
+// dir. A missing part has no relationships.
+func (p *docxPackage) rels(name, dir string) (relationships, error) {
+	rels := relationships{}
+	if _, ok := p.files[name]; !ok {
+		return rels, nil
+	}
+	root, err := p.xml(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range root.all("Relationship") {
+		rel := relationship{typ: r.attr("Type"), target: r.attr("Target"), external: r.attr("TargetMode") == "External"}
+		if !rel.external {
+			if strings.HasPrefix(rel.target, "/") {
+				rel.target = strings.TrimPrefix(rel.target, "/")
+			} else {
+				rel.target = path.Join(dir, rel.target)
+			}
+		}
+		rels[r.attr("Id")] = rel
+	}
+	return rels, nil
+}
+
+// byType returns the target of the first relationship of type suffix.
+func (r relationships) byType(suffix string) (string, bool) {
+	ids := make([]string, 0, len(r))
+	for id := range r {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if strings.HasSuffix(r[id].typ, suffix) {
+			return r[id].target, true
+		}
+	}
+	return "", false
+}
+
+// xnode is an element of a parsed XML part. Names are local names; the
+// WordprocessingML namespace is implied.
+type xnode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xnode
+	text     string // character data of <w:t> elements
+}
+
+func parseXML(data []byte) (*xnode, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	root := &xnode{}
+	stack := []*xnode{root}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xnode{name: t.Name.Local, attrs: t.Attr}
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if top.name == "t" {
+				top.text += string(t)
+			}
+		}
+	}
+	return root, nil
+}
+
+// attr returns the value of the attribute with the given local name.
+func (n *xnode) attr(name string) string {
+	if n == nil {
+		return ""
+	}
+	for _, a := range n.attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// child returns the first child named name, or nil.
+func (n *xnode) child(name string) *xnode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// find follows a path of child names.
+func (n *xnode) find(names ...string) *xnode {
+	for _, name := range names {
+		n = n.child(name)
+	}
+	return n
+}
+
+// all returns the descendants named name, not looking inside matches.
+func (n *xnode) all(name string) []*xnode {
+	var out []*xnode
+	for _, c := range n.children {
+		if c.name == name {
+			out = append(out, c)
+		} else {
+			out = append(out, c.all(name)...)
+		}
+	}
+	return out
+}
+
+// on reports whether a toggle property such as <w:b/> is set.
+func (n *xnode) on() bool {
+	if n == nil {
+		return false
+	}
+	switch n.attr("val") {
+	case "0", "false", "off":
+		return false
+	}
+	return true
+}
+
+// plainText returns the text of n's paragraphs, whitespace-collapsed.
+func (n *xnode) plainText() string {
+	var b strings.Builder
+	var walk func(*xnode)
+	walk = func(n *xnode) {
+		switch n.name {
+		case "t":
+			b.WriteString(n.text)
+		case "tab", "br", "cr", "p":
+			b.WriteByte(' ')
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+type docxStyle struct {
+	basedOn string
+	heading int // heading level, 0 for none
+	numID   string
+	ilvl    int
+}
+
+type numLevel struct {
+	ordered bool
+	start   int
+}
+
+type docxConverter struct {
+	lines []string
+
+	rels      relationships
+	styles    map[string]*docxStyle
+	numbering map[string]map[int]numLevel // numId → level → format
+	counters  map[string][]int            // numId → items seen per level
+
+	// Comment anchors: IDs seen in the paragraph being converted, and the
+	// lines they resolved to.
+	pendingStarts, pendingEnds, pendingRefs []string
+	starts, ends                            map[string]int
+}
+
+func (c *docxConverter) readStyles(root *xnode) {
+	for _, s := range root.all("style") {
+		if s.attr("type") != "paragraph" {
+			continue
+		}
+		st := &docxStyle{basedOn: s.child("basedOn").attr("val")}
+		name := strings.ToLower(s.child("name").attr("val"))
+		if m := headingStyleID.FindStringSubmatch(name); m != nil {
+			st.heading, _ = strconv.Atoi(m[1])
+		} else if name == "title" {
+			st.heading = 1
+		} else if lvl := s.find("pPr", "outlineLvl"); lvl != nil {
+			if v, err := strconv.Atoi(lvl.attr("val")); err == nil && v < 6 {
+				st.heading = v + 1
+			}
+		}
+		if numPr := s.find("pPr", "numPr"); numPr != nil {
+			st.numID = numPr.child("numId").attr("val")
+			st.ilvl, _ = strconv.Atoi(numPr.child("ilvl").attr("val"))
+		}
+		c.styles[s.attr("styleId")] = st
+	}
+}
+
+func readNumbering(root *xnode) map[string]map[int]numLevel {
+	abstract := make(map[string]map[int]numLevel)
+	for _, a := range root.all("abstractNum") {
+		levels := make(map[int]numLevel)
+		for _, l := range a.all("lvl") {
+			ilvl, _ := strconv.Atoi(l.attr("ilvl"))
+			lvl := numLevel{start: 1}
+			switch l.child("numFmt").attr("val") {
+			case "bullet", "none", "":
+			default:
+				lvl.ordered = true
+			}
+			if v, err := strconv.Atoi(l.child("start").attr("val")); err == nil {
+				lvl.start = v
+			}
+			levels[ilvl] = lvl
+		}
+		abstract[a.attr("abstractNumId")] = levels
+	}
+	nums := make(map[string]map[int]numLevel)
+	for _, n := range root.all("num") {
+		nums[n.attr("numId")] = abstract[n.child("abstractNumId").attr("val")]
+	}
+	return nums
+}
+
+// blocks converts the block-level content of the body or a content control.
+func (c *docxConverter) blocks(nodes []*xnode) {
+	for _, n := range nodes {
+		switch n.name {
+		case "p":
+			c.paragraph(n)
+		case "tbl":
+			c.table(n)
+		case "sdt":
+			if content := n.child("sdtContent"); content != nil {
+				c.blocks(content.children)
+			}
+		case "customXml":
+			c.blocks(n.children)
+		}
+	}
+}
+
+func (c *docxConverter) paragraph(p *xnode) {
+	text := strings.TrimSpace(c.inline(p))
+	if text == "" {
+		c.settle()
+		return
+	}
+	heading, numID, ilvl := c.paragraphStyle(p)
+	switch {
+	case heading > 0:
+		c.blank()
+		c.emit(strings.Repeat("#", heading) + " " + text)
+		c.blank()
+	case numID != "" && numID != "0":
+		// List items are only separated from other blocks, not each other.
+		c.emit(strings.Repeat("  ", ilvl) + c.listMarker(numID, ilvl) + text)
+	default:
+		c.blank()
+		c.emit(text)
+		c.blank()
+	}
+}
+
+// paragraphStyle returns the heading level and list numbering of p, from
+// its own properties or its style's.
+func (c *docxConverter) paragraphStyle(p *xnode) (heading int, numID string, ilvl int) {
+	pPr := p.child("pPr")
+	styleID := pPr.child("pStyle").attr("val")
+	if lvl := pPr.child("outlineLvl"); lvl != nil {
+		if v, err := strconv.Atoi(lvl.attr("val")); err == nil && v < 6 {
+			heading = v + 1
+		}
+	}
+	if numPr := pPr.child("numPr"); numPr != nil {
+		numID = numPr.child("numId").attr("val")
+		ilvl, _ = strconv.Atoi(numPr.child("ilvl").attr("val"))
+	}
+
+	// Follow the basedOn chain, guarding against cycles.
+	for depth, id := 0, styleID; id != "" && depth < 10; depth++ {
+		st := c.styles[id]
+		if st == nil {
+			if depth == 0 && len(c.styles) == 0 {
+				if m := headingStyleID.FindStringSubmatch(id); m != nil {
+					heading, _ = strconv.Atoi(m[1])
+				} else if strings.EqualFold(id, "Title") {
+					heading = 1
+				}
+			}
+			break
+		}
+		if heading == 0 {
+			heading = st.heading
+		}
+		if numID == "" && st.numID != "" {
+			numID, ilvl = st.numID, st.ilvl
+		}
+		id = st.basedOn
+	}
+	return heading, numID, ilvl
+}
+
+// listMarker returns the marker of the next item of list numID at level
+// ilvl. Numbering restarts for levels deeper than ilvl.
+func (c *docxConverter) listMarker(numID string, ilvl int) string {
+	lvl, ok := c.numbering[numID][ilvl]
+	if !ok || !lvl.ordered {
+		return "- "
+	}
+	counts := c.counters[numID]
+	for len(counts) <= ilvl {
+		counts = append(counts, 0)
+	}
+	counts = counts[:ilvl+1]
+	counts[ilvl]++
+	c.counters[numID] = counts
+	return strconv.Itoa(lvl.start+counts[ilvl]-1) + ". "
+}
+
+func (c *docxConverter) table(tbl *xnode) {
+	c.blank()
+	rows := 0
+	for _, tr := range tbl.all("tr") {
+		var cells []string
+		for _, tc := range tr.all("tc") {
+			var parts []string
+			for _, p := range tc.all("p") {
+				if text := strings.TrimSpace(c.inline(p)); text != "" {
+					parts = append(parts, text)
+				}
+			}
+			cells = append(cells, strings.ReplaceAll(strings.Join(parts, " "), "|", `\|`))
+		}
+		if len(cells) == 0 {
+			continue
+		}
+		c.emit("| " + strings.Join(cells, " | ") + " |")
+		if rows == 0 {
+			sep := make([]string, len(cells))
+			for i := range sep {
+				sep[i] = "---"
+			}
+			c.emit("| " + strings.Join(sep, " | ") + " |")
+		}
+		rows++
+	}
+	c.blank()
+}
+
+// segment is a run of text with its formatting.
+type segment struct {
+	text                 string
+	bold, italic, strike bool
+}
+
+// inline converts the runs of a paragraph to Markdown text, noting the
+// comment anchors it contains.
+func (c *docxConverter) inline(p *xnode) string {
+	var segs []segment
+	c.runs(p.children, &segs)
+	return renderSegments(segs)
+}
+
+func (c *docxConverter) runs(nodes []*xnode, segs *[]segment) {
+	for _, n := range nodes {
+		switch n.name {
+		case "r":
+			c.run(n, segs)
+		case "hyperlink":
+			var inner []segment
+			c.runs(n.children, &inner)
+			text := strings.TrimSpace(renderSegments(inner))
+			target := ""
+			if rel, ok := c.rels[n.attr("id")]; ok && rel.external {
+				target = rel.target
+			} else if anchor := n.attr("anchor"); anchor != "" {
+				target = "#" + anchor
+			}
+			if text != "" && target != "" {
+				text = "[" + text + "](" + target + ")"
+			}
+			*segs = append(*segs, segment{text: text})
+		case "commentRangeStart":
+			c.pendingStarts = append(c.pendingStarts, n.attr("id"))
+		case "commentRangeEnd":
+			c.pendingEnds = append(c.pendingEnds, n.attr("id"))
+		case "ins", "moveTo", "smartTag", "customXml", "fldSimple", "dir", "bdo":
+			c.runs(n.children, segs)
+		case "sdt":
+			if content := n.child("sdtContent"); content != nil {
+				c.runs(content.children, segs)
+			}
+		}
+	}
+}
+
+func (c *docxConverter) run(r *xnode, segs *[]segment) {
+	rPr := r.child("rPr")
+	seg := segment{
+		bold:   rPr.child("b").on(),
+		italic: rPr.child("i").on(),
+		strike: rPr.child("strike").on() || rPr.child("dstrike").on(),
+	}
+	var b strings.Builder
+	for _, n := range r.children {
+		switch n.name {
+		case "t":
+			b.WriteString(n.text)
+		case "tab":
+			b.WriteByte('\t')
+		case "br", "cr":
+			if n.attr("type") != "page" {
+				b.WriteByte(' ')
+			}
+		case "noBreakHyphen":
+			b.WriteByte('-')
+		case "commentReference":
+			c.pendingRefs = append(c.pendingRefs, n.attr("id"))
+		}
+	}
+	seg.text = b.String()
+	*segs = append(*segs, seg)
+}
+
+// renderSegments joins segments, merging neighbours with the same
+// formatting and keeping whitespace outside the emphasis delimiters.
+func renderSegments(segs []segment) string {
+	var merged []segment
+	for _, s := range segs {
+		if s.text == "" {
+			continue
+		}
+		if n := len(merged); n > 0 && merged[n-1].bold == s.bold && merged[n-1].italic == s.italic && merged[n-1].strike == s.strike {
+			merged[n-1].text += s.text
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	var b strings.Builder
+	for _, s := range merged {
+		var open string
+		if s.bold {
+			open += "**"
+		}
+		if s.italic {
+			open += "*"
+		}
+		if s.strike {
+			open += "~~"
+		}
+		core := strings.TrimSpace(s.text)
+		if open == "" || core == "" {
+			b.WriteString(s.text)
+			continue
+		}
+		lead := s.text[:strings.Index(s.text, core)]
+		trail := s.text[len(lead)+len(core):]
+		closing := []byte(open)
+		for i, j := 0, len(closing)-1; i < j; i, j = i+1, j-1 {
+			closing[i], closing[j] = closing[j], closing[i]
+		}
+		b.WriteString(lead + open + core + string(closing) + trail)
+	}
+	return b.String()
+}
+
+func (c *docxConverter) emit(line string) {
+	c.lines = append(c.lines, line)
+	n := len(c.lines)
+	for _, id := range c.pendingStarts {
+		c.starts[id] = n
+	}
+	for _, id := range c.pendingEnds {
+		c.ends[id] = n
+	}
+	c.resolveRefs(n)
+	c.pendingStarts, c.pendingEnds = nil, nil
+}
+
+// settle resolves the anchors of an empty paragraph: comment ranges that
+// end in it end on the previous line, and ones starting in it start on the
+// next.
+func (c *docxConverter) settle() {
+	last := len(c.lines)
+	for last > 0 && c.lines[last-1] == "" {
+		last--
+	}
+	if last == 0 {
+		return
+	}
+	for _, id := range c.pendingEnds {
+		c.ends[id] = last
+	}
+	c.pendingEnds = nil
+	c.resolveRefs(last)
+}
+
+// resolveRefs anchors comments referenced without a range to line n.
+func (c *docxConverter) resolveRefs(n int) {
+	for _, id := range c.pendingRefs {
+		if _, ok := c.starts[id]; !ok {
+			c.starts[id] = n
+		}
+		if _, ok := c.ends[id]; !ok {
+			c.ends[id] = n
+		}
+	}
+	c.pendingRefs = nil
+}
+
+// blank ends the output with a single blank line.
+func (c *docxConverter) blank() {
+	if len(c.lines) > 0 && c.lines[len(c.lines)-1] != "" {
+		c.lines = append(c.lines, "")
+	}
+}
+
+// annotations turns the document's comments into comment annotations.
+// Comments whose anchor wasn't found are attached to the first line.
+func (c *docxConverter) annotations(comments *xnode, lines int) []fem.Annotation {
+	if comments == nil {
+		return nil
+	}
+	author := strings.NewReplacer("[", "", "]", "")
+	var out []fem.Annotation
+	for _, cm := range comments.all("comment") {
+		text := cm.plainText()
+		if text == "" {
+			continue
+		}
+		id := cm.attr("id")
+		start, ok := c.starts[id]
+		if !ok {
+			start = 1
+		}
+		end, ok := c.ends[id]
+		if !ok || end < start {
+			end = start
+		}
+		end = min(end, lines)
+		out = append(out, fem.Annotation{
+			Type:      "comment",
+			Text:      text,
+			StartLine: start,
+			EndLine:   end,
+			Author:    strings.TrimSpace(author.Replace(cm.attr("author"))),
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].StartLine < out[j].StartLine })
+	return out
+}
@@ -0,0 +1,216 @@
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+)
+
+const wordNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+
+// buildDOCX zips parts into a .docx, adding the package relationships.
+func buildDOCX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	all := map[string]string{
+		"_rels/.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`,
+	}
+	for name, data := range parts {
+		all[name] = data
+	}
+	for name, data := range all {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDOCXConvertsStructure(t *testing.T) {
+	data := buildDOCX(t, map[string]string{
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering" Target="numbering.xml"/>
+<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments" Target="comments.xml"/>
+<Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com" TargetMode="External"/>
+</Relationships>`,
+		"word/styles.xml": `<w:styles ` + wordNS + `>
+<w:style w:type="paragraph" w:styleId="Titel"><w:name w:val="Title"/></w:style>
+<w:style w:type="paragraph" w:styleId="berschrift2"><w:name w:val="heading 2"/></w:style>
+<w:style w:type="paragraph" w:styleId="Custom"><w:name w:val="Custom"/><w:basedOn w:val="berschrift2"/></w:style>
+<w:style w:type="paragraph" w:styleId="ListBullet"><w:name w:val="List Bullet"/><w:pPr><w:numPr><w:numId w:val="1"/></w:numPr></w:pPr></w:style>
+</w:styles>`,
+		"word/numbering.xml": `<w:numbering ` + wordNS + `>
+<w:abstractNum w:abstractNumId="0"><w:lvl w:ilvl="0"><w:numFmt w:val="bullet"/></w:lvl></w:abstractNum>
+<w:abstractNum w:abstractNumId="1">
+  <w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/></w:lvl>
+  <w:lvl w:ilvl="1"><w:start w:val="1"/><w:numFmt w:val="lowerLetter"/></w:lvl>
+</w:abstractNum>
+<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num>
+<w:num w:numId="2"><w:abstractNumId w:val="1"/></w:num>
+</w:numbering>`,
+		"word/comments.xml": `<w:comments ` + wordNS + `>
+<w:comment w:id="0" w:author="Ada [Reviewer]"><w:p><w:r><w:t>Too vague,</w:t></w:r></w:p><w:p><w:r><w:t>say how.</w:t></w:r></w:p></w:comment>
+<w:comment w:id="1" w:author="Bob"><w:p><w:r><w:t>Check these</w:t></w:r></w:p></w:comment>
+<w:comment w:id="2" w:author="Bob"><w:p><w:r><w:t>Nice</w:t></w:r></w:p></w:comment>
+</w:comments>`,
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document ` + wordNS + `><w:body>
+<w:p><w:pPr><w:pStyle w:val="Titel"/></w:pPr><w:r><w:t>Spec</w:t></w:r></w:p>
+<w:p>
+  <w:commentRangeStart w:id="0"/>
+  <w:r><w:t xml:space="preserve">Some </w:t></w:r>
+  <w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">bold </w:t></w:r>
+  <w:r><w:rPr><w:b/></w:rPr><w:t>text</w:t></w:r>
+  <w:r><w:t xml:space="preserve"> and </w:t></w:r>
+  <w:r><w:rPr><w:i/><w:b w:val="0"/></w:rPr><w:t>italic</w:t></w:r>
+  <w:r><w:t xml:space="preserve"> with </w:t></w:r>
+  <w:hyperlink r:id="rId4"><w:r><w:t>a link</w:t></w:r></w:hyperlink>
+  <w:del><w:r><w:delText>gone</w:delText></w:r></w:del>
+  <w:ins><w:r><w:t>.</w:t></w:r></w:ins>
+  <w:commentRangeEnd w:id="0"/>
+  <w:r><w:commentReference w:id="0"/></w:r>
+</w:p>
+<w:p/>
+<w:p><w:pPr><w:pStyle w:val="Custom"/></w:pPr><w:r><w:t>Steps</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="2"/></w:numPr></w:pPr><w:commentRangeStart w:id="1"/><w:r><w:t>First</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="1"/><w:numId w:val="2"/></w:numPr></w:pPr><w:r><w:t>Detail</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="2"/></w:numPr></w:pPr><w:r><w:t>Second</w:t></w:r><w:commentRangeEnd w:id="1"/><w:r><w:commentReference w:id="1"/></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="ListBullet"/></w:pPr><w:r><w:t>Bullet</w:t></w:r></w:p>
+<w:tbl>
+  <w:tr><w:tc><w:p><w:r><w:t>Name</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Value</w:t></w:r></w:p></w:tc></w:tr>
+  <w:tr><w:tc><w:p><w:r><w:t>a|b</w:t></w:r></w:p><w:p><w:r><w:t>c</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>1</w:t></w:r><w:r><w:commentReference w:id="2"/></w:r></w:p></w:tc></w:tr>
+</w:tbl>
+<w:sectPr/>
+</w:body></w:document>`,
+	})
+
+	doc, err := DOCX(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"# Spec",
+		"",
+		"Some **bold text** and *italic* with [a link](https://example.com).",
+		"",
+		"## Steps",
+		"",
+		"1. First",
+		"  1. Detail",
+		"2. Second",
+		"- Bullet",
+		"",
+		"| Name | Value |",
+		"| --- | --- |",
+		`| a\|b c | 1 |`,
+	}, "\n") + "\n"
+	if doc.Markdown != want {
+		t.Errorf("got:\n%s\nwant:\n%s", doc.Markdown, want)
+	}
+	if doc.Format != FormatDOCX || len(doc.LineMap) != 0 {
+		t.Errorf("unexpected format %q or line map %v", doc.Format, doc.LineMap)
+	}
+
+	wantAnns := []fem.Annotation{
+		{Type: "comment", Text: "Too vague, say how.", StartLine: 3, EndLine: 3, Author: "Ada Reviewer"},
+		{Type: "comment", Text: "Check these", StartLine: 7, EndLine: 9, Author: "Bob"},
+		{Type: "comment", Text: "Nice", StartLine: 14, EndLine: 14, Author: "Bob"},
+	}
+	if !reflect.DeepEqual(doc.Annotations, wantAnns) {
+		t.Errorf("Annotations = %+v, want %+v", doc.Annotations, wantAnns)
+	}
+
+	// The comments survive a round trip through FEM.
+	parsed, _, err := fem.Parse(fem.Render(doc.Markdown, doc.Annotations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, wantAnns) {
+		t.Errorf("parsed %+v, want %+v", parsed, wantAnns)
+	}
+}
+
+func TestDOCXCommentWithMarkers(t *testing.T) {
+	data := buildDOCX(t, map[string]string{
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments" Target="comments.xml"/>
+</Relationships>`,
+		"word/comments.xml": `<w:comments ` + wordNS + `>
+<w:comment w:id="0" w:author="Ada"><w:p><w:r><w:t>Write it as {&gt;&gt; x &lt;&lt;} or end with --}</w:t></w:r></w:p></w:comment>
+</w:comments>`,
+		"word/document.xml": `<w:document ` + wordNS + `><w:body>
+<w:p><w:r><w:t>FEM comments look like {&gt;&gt; this &lt;&lt;}.</w:t></w:r><w:r><w:commentReference w:id="0"/></w:r></w:p>
+</w:body></w:document>`,
+	})
+
+	doc, err := DOCX(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, clean, err := fem.Parse(fem.Render(doc.Markdown, doc.Annotations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []fem.Annotation{{Type: "comment", Text: "Write it as {>> x <<} or end with --}", StartLine: 1, EndLine: 1, Author: "Ada"}}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("parsed %+v, want %+v", parsed, want)
+	}
+	if clean != doc.Markdown {
+		t.Errorf("clean content %q, want %q", clean, doc.Markdown)
+	}
+}
+
+func TestDOCXWithoutStylesPart(t *testing.T) {
+	data := buildDOCX(t, map[string]string{
+		"word/document.xml": `<w:document ` + wordNS + `><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Plain</w:t></w:r></w:p>
+<w:p><w:r><w:t>Body</w:t></w:r><w:r><w:tab/><w:t>tabbed</w:t></w:r></w:p>
+</w:body></w:document>`,
+	})
+	doc, err := DOCX(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Markdown != "# Plain\n\nBody\ttabbed\n" || doc.Annotations != nil {
+		t.Errorf("got %q, %v", doc.Markdown, doc.Annotations)
+	}
+}
+
+func TestDOCXErrors(t *testing.T) {
+	if _, err := DOCX([]byte("not a zip")); err == nil || !strings.Contains(err.Error(), "may be corrupt") {
+		t.Errorf("expected a corrupt-file error, got %v", err)
+	}
+	if _, err := DOCX(buildDOCX(t, nil)); err == nil {
+		t.Error("expected an error for a package without a document")
+	}
+
+	doc, err := DOCX(buildDOCX(t, map[string]string{
+		"word/document.xml": `<w:document ` + wordNS + `><w:body><w:p/></w:body></w:document>`,
+	}))
+	if err != nil || doc.Markdown != "" {
+		t.Errorf("expected an empty document, got %q, %v", doc.Markdown, err)
+	}
+
+	if Detect("Spec.DOCX") != FormatDOCX {
+		t.Error("expected .docx to be detected")
+	}
+	if _, err := Convert(Detect("old.doc"), []byte{0xd0, 0xcf}); err == nil || !strings.Contains(err.Error(), "save the document as .docx") {
+		t.Errorf("expected legacy .doc to be rejected, got %v", err)
+	}
+}
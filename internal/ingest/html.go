@@ -0,0 +1,527 @@
+package ingest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skipped elements are dropped with their content, like the web app's
+// HTML-to-text fallback does.
+var skipped = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Svg: true, atom.Nav: true, atom.Header: true, atom.Footer: true,
+}
+
+// blocks are elements that start and end a paragraph.
+var blocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Aside: true, atom.Figure: true, atom.Figcaption: true, atom.Address: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Details: true, atom.Summary: true,
+	atom.Form: true, atom.Fieldset: true, atom.Body: true, atom.Caption: true,
+}
+
+var headings = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+// emphasis maps inline elements to their Markdown delimiters.
+var emphasis = map[atom.Atom]string{
+	atom.Strong: "**", atom.B: "**", atom.Em: "*", atom.I: "*", atom.Code: "`",
+	atom.Del: "~~", atom.S: "~~",
+}
+
+// HTML converts an HTML document to Markdown. Like the web app, it keeps
+// the first <article>, else the first <main>, else the whole body, and
+// drops scripts, styles, navigation, headers and footers. Headings, lists,
+// block quotes, code blocks, tables, links and emphasis become their
+// Markdown equivalents; other markup is reduced to its text.
+func HTML(data []byte) (*Document, error) {
+	root := contentRoot(data)
+	c := &converter{line: 1, root: root}
+	if root == 0 {
+		c.inRoot = 1
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				return nil, fmt.Errorf("failed to parse HTML: %w", err)
+			}
+			break
+		}
+		newlines := bytes.Count(z.Raw(), []byte("\n"))
+		c.token(tt, z.Token())
+		c.line += newlines
+	}
+	c.flush()
+
+	// Trim blank lines at either end.
+	start, end := 0, len(c.lines)
+	for start < end && c.lines[start] == "" {
+		start++
+	}
+	for end > start && c.lines[end-1] == "" {
+		end--
+	}
+	doc := &Document{Format: FormatHTML, LineMap: c.lineMap[start:end]}
+	if end > start {
+		doc.Markdown = strings.Join(c.lines[start:end], "\n") + "\n"
+	}
+	return doc, nil
+}
+
+// contentRoot returns atom.Article or atom.Main if the document has one,
+// preferring article, or 0 to convert the whole body.
+func contentRoot(data []byte) atom.Atom {
+	z := html.NewTokenizer(bytes.NewReader(data))
+	var root atom.Atom
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return root
+		case html.StartTagToken:
+			switch z.Token().DataAtom {
+			case atom.Article:
+				return atom.Article
+			case atom.Main:
+				root = atom.Main
+			}
+		}
+	}
+}
+
+type list struct {
+	ordered bool
+	n       int
+}
+
+type converter struct {
+	lines   []string
+	lineMap []int
+
+	buf     []byte // inline text of the line being built
+	bufLine int    // source line of the first text in buf
+	space   bool   // whitespace seen since the last text in buf
+
+	line int // source line of the current token
+
+	root   atom.Atom // element holding the content, 0 for the whole body
+	inRoot int       // 0 before root, >0 inside it (nesting depth), -1 after
+
+	skip     int // depth inside skipped elements
+	pre      int
+	preStart bool // nothing read yet inside <pre>
+	quote    int
+	lists    []list
+	marker   string   // list or heading marker for the next line
+	links    []string // hrefs of open <a> elements, "" when not rendered as a link
+
+	table     int      // depth inside <table>
+	rows      int      // rows emitted in the current table
+	cells     []string // cells of the current row
+	cellOpen  bool
+	rowSource int
+}
+
+func (c *converter) token(tt html.TokenType, tok html.Token) {
+	if c.root != 0 && tok.DataAtom == c.root && c.inRoot >= 0 {
+		switch tt {
+		case html.StartTagToken:
+			c.inRoot++
+		case html.EndTagToken:
+			if c.inRoot > 0 {
+				c.inRoot--
+				if c.inRoot == 0 {
+					c.flush()
+					c.inRoot = -1
+				}
+			}
+		}
+	}
+	if c.inRoot <= 0 {
+		return
+	}
+
+	if skipped[tok.DataAtom] {
+		switch tt {
+		case html.StartTagToken:
+			c.skip++
+		case html.EndTagToken:
+			if c.skip > 0 {
+				c.skip--
+			}
+		}
+		return
+	}
+	if c.skip > 0 {
+		return
+	}
+
+	switch tt {
+	case html.TextToken:
+		c.text(tok.Data)
+	case html.StartTagToken, html.SelfClosingTagToken:
+		c.start(tok, tt == html.SelfClosingTagToken)
+	case html.EndTagToken:
+		c.end(tok)
+	}
+}
+
+func (c *converter) start(tok html.Token, selfClosing bool) {
+	a := tok.DataAtom
+	if n, ok := headings[a]; ok {
+		c.block()
+		c.marker = strings.Repeat("#", n) + " "
+		return
+	}
+	if d, ok := emphasis[a]; ok {
+		if c.pre == 0 {
+			c.open(d)
+		}
+		return
+	}
+	switch a {
+	case atom.Br:
+		if c.pre > 0 {
+			c.text("\n")
+		} else {
+			c.flush()
+		}
+	case atom.Hr:
+		c.block()
+		c.emit("---", c.line)
+		c.blank()
+	case atom.Pre:
+		c.block()
+		c.emit("```", c.line)
+		c.pre++
+		c.preStart = true
+	case atom.Blockquote:
+		c.block()
+		c.quote++
+	case atom.Ul, atom.Ol:
+		if len(c.lists) == 0 {
+			c.block()
+		} else {
+			c.flush()
+		}
+		l := list{ordered: a == atom.Ol}
+		if n, err := strconv.Atoi(attr(tok, "start")); err == nil && l.ordered {
+			l.n = n - 1
+		}
+		c.lists = append(c.lists, l)
+	case atom.Li:
+		c.flush()
+		indent := ""
+		marker := "- "
+		if len(c.lists) > 0 {
+			indent = strings.Repeat("  ", len(c.lists)-1)
+			if l := &c.lists[len(c.lists)-1]; l.ordered {
+				l.n++
+				marker = strconv.Itoa(l.n) + ". "
+			}
+		}
+		c.marker = indent + marker
+	case atom.Table:
+		if c.table == 0 {
+			c.block()
+			c.rows = 0
+		}
+		c.table++
+	case atom.Tr:
+		if c.table == 1 {
+			c.cells = nil
+			c.rowSource = 0
+		}
+	case atom.Td, atom.Th:
+		if c.table == 1 {
+			c.closeCell()
+			c.cellOpen = true
+		}
+	case atom.A:
+		href := attr(tok, "href")
+		if href == "" || c.pre > 0 || strings.HasPrefix(strings.ToLower(href), "javascript:") || selfClosing {
+			c.links = append(c.links, "")
+			return
+		}
+		c.links = append(c.links, href)
+		c.open("[")
+	case atom.Img:
+		if alt := attr(tok, "alt"); alt != "" {
+			c.open("![" + alt + "](" + attr(tok, "src") + ")")
+		}
+	default:
+		if blocks[a] {
+			c.block()
+		}
+	}
+}
+
+func (c *converter) end(tok html.Token) {
+	a := tok.DataAtom
+	if _, ok := headings[a]; ok {
+		c.flush()
+		c.marker = ""
+		c.blank()
+		return
+	}
+	if d, ok := emphasis[a]; ok {
+		if c.pre == 0 {
+			c.close(d, d)
+		}
+		return
+	}
+	switch a {
+	case atom.Pre:
+		if c.pre == 0 {
+			return
+		}
+		if len(c.buf) > 0 {
+			c.emitRaw()
+		}
+		c.pre--
+		c.emit("```", c.line)
+		c.blank()
+	case atom.Blockquote:
+		c.flush()
+		if c.quote > 0 {
+			c.quote--
+		}
+		c.blank()
+	case atom.Ul, atom.Ol:
+		c.flush()
+		if len(c.lists) > 0 {
+			c.lists = c.lists[:len(c.lists)-1]
+		}
+		c.marker = ""
+		if len(c.lists) == 0 {
+			c.blank()
+		}
+	case atom.Li:
+		c.flush()
+		c.marker = ""
+	case atom.Td, atom.Th:
+		if c.table == 1 {
+			c.closeCell()
+		}
+	case atom.Tr:
+		if c.table == 1 {
+			c.endRow()
+		}
+	case atom.Table:
+		if c.table == 1 {
+			c.endRow()
+			c.blank()
+		}
+		if c.table > 0 {
+			c.table--
+		}
+	case atom.A:
+		if len(c.links) == 0 {
+			return
+		}
+		href := c.links[len(c.links)-1]
+		c.links = c.links[:len(c.links)-1]
+		if href != "" {
+			c.close("[", "]("+href+")")
+		}
+	default:
+		if blocks[a] {
+			c.block()
+		}
+	}
+}
+
+// text adds character data: verbatim inside <pre>, whitespace-collapsed
+// elsewhere.
+func (c *converter) text(s string) {
+	line := c.line
+	if c.pre > 0 {
+		if c.preStart && strings.HasPrefix(s, "\n") {
+			// A newline right after <pre> isn't content.
+			s = s[1:]
+			line++
+		}
+		c.preStart = false
+		for _, r := range s {
+			if r == '\n' {
+				c.emitRaw()
+				line++
+				continue
+			}
+			if c.bufLine == 0 {
+				c.bufLine = line
+			}
+			c.buf = utf8.AppendRune(c.buf, r)
+		}
+		return
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if r == '\n' {
+				line++
+			}
+			c.space = true
+			continue
+		}
+		c.pending()
+		if c.bufLine == 0 {
+			c.bufLine = line
+		}
+		c.buf = utf8.AppendRune(c.buf, r)
+	}
+}
+
+// pending writes a collapsed space if whitespace separates new text from
+// the text already on the line.
+func (c *converter) pending() {
+	if c.space && len(c.buf) > 0 {
+		c.buf = append(c.buf, ' ')
+	}
+	c.space = false
+}
+
+// open writes an opening delimiter as if it were text.
+func (c *converter) open(d string) {
+	c.pending()
+	if c.bufLine == 0 {
+		c.bufLine = c.line
+	}
+	c.buf = append(c.buf, d...)
+}
+
+// close writes a closing delimiter right after the preceding text, or
+// removes the opening one when nothing was written in between.
+func (c *converter) close(opening, closing string) {
+	if bytes.HasSuffix(c.buf, []byte(opening)) {
+		c.buf = c.buf[:len(c.buf)-len(opening)]
+		return
+	}
+	c.buf = append(c.buf, closing...)
+}
+
+// block ends the current paragraph and separates it from the next one
+// with a blank line, except between the items of a list.
+func (c *converter) block() {
+	c.flush()
+	if len(c.lists) == 0 {
+		c.blank()
+	}
+}
+
+// flush emits the line being built, if any. Inside a table cell it only
+// separates the cell's paragraphs with a space.
+func (c *converter) flush() {
+	if c.cellOpen {
+		c.space = len(c.buf) > 0
+		return
+	}
+	if c.pre > 0 {
+		return
+	}
+	text := strings.TrimSpace(string(c.buf))
+	if text != "" {
+		c.emit(text, c.bufLine)
+	}
+	c.buf = c.buf[:0]
+	c.bufLine = 0
+	c.space = false
+}
+
+// emitRaw emits the line being built inside <pre>, keeping its spacing.
+func (c *converter) emitRaw() {
+	line := c.bufLine
+	if line == 0 {
+		line = c.line
+	}
+	c.lines = append(c.lines, c.prefix()+strings.TrimRight(string(c.buf), " \t\r"))
+	c.lineMap = append(c.lineMap, line)
+	c.buf = c.buf[:0]
+	c.bufLine = 0
+}
+
+func (c *converter) closeCell() {
+	if !c.cellOpen {
+		return
+	}
+	c.cellOpen = false
+	if c.rowSource == 0 {
+		c.rowSource = c.bufLine
+	}
+	cell := strings.TrimSpace(string(c.buf))
+	c.cells = append(c.cells, strings.ReplaceAll(cell, "|", `\|`))
+	c.buf = c.buf[:0]
+	c.bufLine = 0
+	c.space = false
+}
+
+func (c *converter) endRow() {
+	c.closeCell()
+	if len(c.cells) == 0 {
+		return
+	}
+	line := c.rowSource
+	if line == 0 {
+		line = c.line
+	}
+	c.emit("| "+strings.Join(c.cells, " | ")+" |", line)
+	if c.rows == 0 {
+		sep := make([]string, len(c.cells))
+		for i := range sep {
+			sep[i] = "---"
+		}
+		c.emit("| "+strings.Join(sep, " | ")+" |", line)
+	}
+	c.rows++
+	c.cells = nil
+	c.rowSource = 0
+}
+
+// prefix is what starts every line at the current nesting: block quote
+// markers and list indentation.
+func (c *converter) prefix() string {
+	p := strings.Repeat("> ", c.quote)
+	if len(c.lists) > 0 {
+		p += strings.Repeat("  ", len(c.lists))
+	}
+	return p
+}
+
+func (c *converter) emit(text string, source int) {
+	line := strings.Repeat("> ", c.quote)
+	if c.marker != "" {
+		line += c.marker
+		c.marker = ""
+	} else if len(c.lists) > 0 {
+		line += strings.Repeat("  ", len(c.lists))
+	}
+	c.lines = append(c.lines, line+text)
+	c.lineMap = append(c.lineMap, source)
+}
+
+// blank ends the output with a single blank line.
+func (c *converter) blank() {
+	if len(c.lines) > 0 && c.lines[len(c.lines)-1] != "" {
+		c.lines = append(c.lines, "")
+		c.lineMap = append(c.lineMap, 0)
+	}
+}
+
+func attr(tok html.Token, key string) string {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package ingest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHTMLConvertsStructure(t *testing.T) {
+	src := `<!DOCTYPE html>
+<html>
+<head><title>Ignored</title><style>p { color: red }</style></head>
+<body>
+<nav><a href="/">Home</a></nav>
+<h1>Title</h1>
+<p>Some <strong>bold</strong> and <em>italic</em>
+text with <a href="https://example.com">a link</a>.</p>
+<ul>
+  <li>one</li>
+  <li>two
+    <ol><li>nested</li></ol>
+  </li>
+</ul>
+<blockquote><p>Quoted</p></blockquote>
+<pre><code>func main() {
+	fmt.Println("hi")
+}
+</code></pre>
+<table>
+  <tr><th>Name</th><th>Value</th></tr>
+  <tr><td>a|b</td><td>1</td></tr>
+</table>
+<script>alert("x")</script>
+<footer>Copyright</footer>
+</body>
+</html>
+`
+	doc, err := HTML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"# Title",
+		"",
+		"Some **bold** and *italic* text with [a link](https://example.com).",
+		"",
+		"- one",
+		"- two",
+		"  1. nested",
+		"",
+		"> Quoted",
+		"",
+		"```",
+		"func main() {",
+		"\tfmt.Println(\"hi\")",
+		"}",
+		"```",
+		"",
+		"| Name | Value |",
+		"| --- | --- |",
+		`| a\|b | 1 |`,
+	}, "\n") + "\n"
+	if doc.Markdown != want {
+		t.Errorf("got:\n%s\nwant:\n%s", doc.Markdown, want)
+	}
+
+	wantMap := []int{6, 0, 7, 0, 10, 11, 12, 0, 15, 0, 16, 16, 17, 18, 19, 0, 21, 21, 22}
+	if !reflect.DeepEqual(doc.LineMap, wantMap) {
+		t.Errorf("LineMap = %v, want %v", doc.LineMap, wantMap)
+	}
+	if len(doc.LineMap) != strings.Count(doc.Markdown, "\n") {
+		t.Errorf("LineMap has %d entries for %d lines", len(doc.LineMap), strings.Count(doc.Markdown, "\n"))
+	}
+}
+
+func TestHTMLPrefersArticleThenMain(t *testing.T) {
+	doc, err := HTML([]byte(`<body><p>Menu</p><main><p>Main</p><article><p>Story</p></article></main></body>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Markdown != "Story\n" {
+		t.Errorf("expected only the article, got %q", doc.Markdown)
+	}
+
+	doc, _ = HTML([]byte(`<body><p>Menu</p><main><p>Main</p></main><p>After</p></body>`))
+	if doc.Markdown != "Main\n" {
+		t.Errorf("expected only main, got %q", doc.Markdown)
+	}
+
+	doc, _ = HTML([]byte(`<p>Just &amp; a <b></b>fragment</p>`))
+	if doc.Markdown != "Just & a fragment\n" {
+		t.Errorf("expected the fragment, got %q", doc.Markdown)
+	}
+}
+
+func TestDetectAndParseFormat(t *testing.T) {
+	if Detect("docs/Page.HTM") != FormatHTML || Detect("main.go") != FormatText {
+		t.Error("unexpected Detect result")
+	}
+	if f, err := ParseFormat("HTML"); err != nil || f != FormatHTML {
+		t.Errorf("ParseFormat(HTML) = %q, %v", f, err)
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if doc, err := Convert(FormatText, []byte("x")); doc != nil || err != nil {
+		t.Errorf("expected text to need no conversion, got %v, %v", doc, err)
+	}
+}
@@ -0,0 +1,75 @@
+// Package ingest converts documents that are awkward to review as raw
+// text, such as HTML and Word documents, into Markdown, keeping track of
+// which line of the original each Markdown line came from.
+package ingest
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+)
+
+// Formats fabbro can convert. FormatText means no conversion.
+const (
+	FormatText = "text"
+	FormatHTML = "html"
+	FormatDOCX = "docx"
+
+	// formatLegacyDoc is detected only to be rejected.
+	formatLegacyDoc = "doc"
+)
+
+// Document is a converted document.
+type Document struct {
+	Format   string // the format converted from
+	Markdown string
+	// LineMap[i] is the line of the original that Markdown line i+1 came
+	// from, or 0 for lines the converter added, such as blank separators.
+	// It is empty for formats without lines, such as DOCX.
+	LineMap []int
+	// Annotations found in the original, such as Word comments, on the
+	// lines of Markdown they cover.
+	Annotations []fem.Annotation
+}
+
+// ParseFormat parses an --input-format value: "auto", "text", "html" or
+// "docx".
+func ParseFormat(s string) (string, error) {
+	switch f := strings.ToLower(s); f {
+	case "auto", FormatText, FormatHTML, FormatDOCX:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid input format %q: must be auto, text, html, or docx", s)
+}
+
+// Detect returns the format of a file from its extension; FormatText for
+// anything fabbro reviews as-is.
+func Detect(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm", ".xhtml":
+		return FormatHTML
+	case ".docx":
+		return FormatDOCX
+	case ".doc":
+		return formatLegacyDoc
+	}
+	return FormatText
+}
+
+// Convert converts data from format to Markdown. It returns nil for
+// FormatText.
+func Convert(format string, data []byte) (*Document, error) {
+	switch format {
+	case FormatText, "":
+		return nil, nil
+	case FormatHTML:
+		return HTML(data)
+	case FormatDOCX:
+		return DOCX(data)
+	case formatLegacyDoc:
+		return nil, errLegacyDoc
+	}
+	return nil, fmt.Errorf("cannot convert from %q", format)
+}
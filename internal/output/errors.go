@@ -0,0 +1,113 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+// Code classifies a failure for agents. Codes are stable: new ones may be
+// added, existing ones keep their meaning and exit code.
+type Code string
+
+const (
+	CodeUsage       Code = "usage_error"    // invalid flags or arguments
+	CodeNotFound    Code = "not_found"      // session, file or project does not exist
+	CodeData        Code = "data_error"     // malformed input: empty stdin, bad FEM, invalid session file
+	CodeIO          Code = "io_error"       // filesystem or permission failure
+	CodeDrift       Code = "drift_error"    // source changed since the session was created
+	CodeUnavailable Code = "unavailable"    // the session was abandoned
+	CodeTimeout     Code = "timeout"        // gave up waiting; retrying may succeed
+	CodeFindings    Code = "open_findings"  // fabbro check found unaddressed annotations
+	CodeLeaked      Code = "leaked_markers" // fabbro scan found FEM outside sessions
+	CodeInternal    Code = "error"          // anything not classified above
+)
+
+// Codes lists every error code in documentation order.
+var Codes = []Code{CodeUsage, CodeNotFound, CodeData, CodeIO, CodeDrift, CodeUnavailable, CodeTimeout, CodeFindings, CodeLeaked, CodeInternal}
+
+// ExitCode returns the sysexits.h-style process exit code for c.
+func (c Code) ExitCode() int {
+	switch c {
+	case CodeUsage:
+		return 64 // EX_USAGE
+	case CodeData, CodeDrift:
+		return 65 // EX_DATAERR
+	case CodeNotFound:
+		return 66 // EX_NOINPUT
+	case CodeUnavailable:
+		return 69 // EX_UNAVAILABLE
+	case CodeIO:
+		return 74 // EX_IOERR
+	case CodeTimeout:
+		return 75 // EX_TEMPFAIL
+	case CodeFindings, CodeLeaked:
+		return 1 // like a linter reporting problems
+	default:
+		return 1
+	}
+}
+
+// Error is an error with a code. Its message is the wrapped error's.
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+func (e *Error) Unwrap() error { return e.Err }
+
+// Errorf returns an *Error with code and a message formatted by fmt.Errorf,
+// so %w wraps as usual.
+func Errorf(code Code, format string, args ...any) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap attaches code to err, keeping its message. It returns nil for nil.
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// CodeOf classifies err: an explicit *Error code wins, then known sentinel
+// and filesystem errors, then CodeInternal.
+func CodeOf(err error) Code {
+	var coded *Error
+	var pathErr *fs.PathError
+	switch {
+	case errors.As(err, &coded):
+		return coded.Code
+	case errors.Is(err, session.ErrNotFound), errors.Is(err, config.ErrNotInitialized), errors.Is(err, fs.ErrNotExist):
+		return CodeNotFound
+	case errors.As(err, &pathErr):
+		return CodeIO
+	default:
+		return CodeInternal
+	}
+}
+
+// ErrorDocument is what WriteError writes.
+type ErrorDocument struct {
+	SchemaVersion int         `json:"schemaVersion"`
+	Error         ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+// WriteError writes err as a compact JSON error document, one line, so
+// stderr stays parseable even if warnings precede it.
+func WriteError(w io.Writer, err error) error {
+	return Write(w, ErrorDocument{
+		SchemaVersion: SchemaVersion,
+		Error:         ErrorDetail{Code: CodeOf(err), Message: err.Error()},
+	}, true)
+}
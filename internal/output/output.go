@@ -0,0 +1,194 @@
+// Package output defines the JSON documents fabbro writes for machines: the
+// payloads of --json commands, their published JSON Schemas, and the
+// structured errors written to stderr when --json is set.
+//
+// Every document carries schemaVersion. Adding fields is backward compatible
+// and keeps the version; renaming, removing or retyping a field bumps it.
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/scan"
+	"github.com/charly-vibes/fabbro/internal/search"
+	"github.com/charly-vibes/fabbro/internal/session"
+	"github.com/charly-vibes/fabbro/internal/stats"
+)
+
+// SchemaVersion is the version of every document in this package.
+const SchemaVersion = 1
+
+// Apply is the payload of `apply --json` and `wait`.
+type Apply struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	SessionID     string           `json:"sessionId"`
+	SourceFile    string           `json:"sourceFile"`
+	CreatedAt     string           `json:"createdAt"`
+	Status        string           `json:"status"`
+	Annotations   []fem.Annotation `json:"annotations"`
+	Conversion    *Conversion      `json:"conversion,omitempty"`
+	// Coverage is how much of the content the reviewer marked reviewed,
+	// so that agents know which parts were never looked at.
+	Coverage session.Coverage `json:"coverage"`
+}
+
+// Conversion describes how a converted session's content maps back to its
+// source file.
+type Conversion struct {
+	From    string `json:"from"`
+	LineMap []int  `json:"lineMap"`
+}
+
+// NewApply describes sess and its annotations.
+func NewApply(sess *session.Session, annotations []fem.Annotation) *Apply {
+	if annotations == nil {
+		annotations = []fem.Annotation{}
+	}
+	apply := &Apply{
+		SchemaVersion: SchemaVersion,
+		SessionID:     sess.ID,
+		SourceFile:    sess.SourceFile,
+		CreatedAt:     Timestamp(sess.CreatedAt),
+		Status:        string(sess.Status),
+		Annotations:   annotations,
+		Coverage:      sess.Coverage(),
+	}
+	if c := sess.Conversion; c != nil {
+		apply.Conversion = &Conversion{From: c.From, LineMap: c.LineMap}
+		if apply.Conversion.LineMap == nil {
+			apply.Conversion.LineMap = []int{}
+		}
+	}
+	return apply
+}
+
+// Review is the payload of `review --json`.
+type Review struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	SessionID     string `json:"sessionId"`
+}
+
+// SessionList is the payload of `session list --json`.
+type SessionList struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Sessions      []SessionSummary `json:"sessions"`
+}
+
+type SessionSummary struct {
+	ID          string `json:"id"`
+	CreatedAt   string `json:"createdAt"`
+	SourceFile  string `json:"sourceFile,omitempty"`
+	Status      string `json:"status"`
+	Annotations int    `json:"annotations"`
+}
+
+// SessionHistory is the payload of `session history --json`.
+type SessionHistory struct {
+	SchemaVersion int        `json:"schemaVersion"`
+	SessionID     string     `json:"sessionId"`
+	Revisions     []Revision `json:"revisions"`
+}
+
+type Revision struct {
+	Revision    int    `json:"revision"`
+	SavedAt     string `json:"savedAt"`
+	Author      string `json:"author,omitempty"`
+	Annotations int    `json:"annotations"`
+}
+
+// SessionMerge is the payload of `session merge --json`.
+type SessionMerge struct {
+	SchemaVersion int        `json:"schemaVersion"`
+	SessionID     string     `json:"sessionId"`
+	Sources       []string   `json:"sources"`
+	Duplicates    int        `json:"duplicates"`
+	Conflicts     []Conflict `json:"conflicts"`
+}
+
+type Conflict struct {
+	A fem.Annotation `json:"a"`
+	B fem.Annotation `json:"b"`
+}
+
+// Check is the payload of `check --json`.
+type Check struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Passed        bool           `json:"passed"`
+	Types         []string       `json:"types"`
+	Findings      int            `json:"findings"`
+	Sessions      []CheckSession `json:"sessions"`
+}
+
+// CheckSession is an open session with findings, or whose source drifted.
+type CheckSession struct {
+	SessionID  string           `json:"sessionId"`
+	SourceFile string           `json:"sourceFile"`
+	Status     string           `json:"status"`
+	Drifted    bool             `json:"drifted"`
+	Findings   []fem.Annotation `json:"findings"`
+}
+
+// Scan is the payload of `scan --json`.
+type Scan struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Files         int            `json:"files"`
+	Findings      []scan.Finding `json:"findings"`
+}
+
+// Search is the payload of `search --json`.
+type Search struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	Query         string       `json:"query"`
+	Mode          search.Mode  `json:"mode"`
+	Hits          []search.Hit `json:"hits"`
+}
+
+// Stats is the payload of `stats --json`.
+type Stats struct {
+	SchemaVersion int `json:"schemaVersion"`
+	stats.Report
+}
+
+// Prime is the payload of `prime --json`.
+type Prime struct {
+	SchemaVersion int         `json:"schemaVersion"`
+	Purpose       string      `json:"purpose"`
+	Commands      []Command   `json:"commands"`
+	FEMSyntax     []FEMSyntax `json:"femSyntax"`
+	TUIKeys       []Key       `json:"tuiKeys"`
+	Docs          []string    `json:"docs"`
+}
+
+type Command struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type FEMSyntax struct {
+	Syntax      string `json:"syntax"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+type Key struct {
+	Key    string `json:"key"`
+	Action string `json:"action"`
+}
+
+// Timestamp formats t as RFC 3339, the format of every time in fabbro's
+// JSON output.
+func Timestamp(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
+
+// Write encodes v as JSON on w, indented unless compact is set.
+func Write(w io.Writer, v any, compact bool) error {
+	enc := json.NewEncoder(w)
+	if !compact {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
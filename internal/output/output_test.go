@@ -0,0 +1,144 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/scan"
+	"github.com/charly-vibes/fabbro/internal/search"
+	"github.com/charly-vibes/fabbro/internal/session"
+	"github.com/charly-vibes/fabbro/internal/stats"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+func validate(t *testing.T, name string, v any) {
+	t.Helper()
+	schemaData, err := Schema(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat = true
+	if err := compiler.AddResource(name+".json", bytes.NewReader(schemaData)); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := compiler.Compile(name + ".json")
+	if err != nil {
+		t.Fatalf("compile %s schema: %v", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, v, true); err != nil {
+		t.Fatal(err)
+	}
+	var doc any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Validate(doc); err != nil {
+		t.Errorf("%s output does not match its schema: %#v\n%s", name, err, buf.String())
+	}
+}
+
+func TestPayloadsMatchSchemas(t *testing.T) {
+	created := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	sess := &session.Session{ID: "20260101-abc", CreatedAt: created, Status: session.StatusReady}
+	ann := fem.Annotation{Type: "change", Text: "-> x", StartLine: 2, EndLine: 3, Author: "alice"}
+
+	validate(t, "apply", NewApply(sess, []fem.Annotation{ann}))
+	validate(t, "apply", NewApply(sess, nil))
+	converted := *sess
+	converted.Conversion = &session.Conversion{From: "html", LineMap: []int{3, 0, 4}}
+	validate(t, "apply", NewApply(&converted, []fem.Annotation{ann}))
+	converted.Conversion = &session.Conversion{From: "docx", LineMap: []int{1, 2, 3}}
+	validate(t, "apply", NewApply(&converted, []fem.Annotation{ann}))
+	reviewed := *sess
+	reviewed.Content, reviewed.Reviewed = "a\nb\nc", []int{2}
+	validate(t, "apply", NewApply(&reviewed, nil))
+	validate(t, "wait", NewApply(sess, nil))
+	validate(t, "review", Review{SchemaVersion: SchemaVersion, SessionID: sess.ID})
+	validate(t, "session list", SessionList{SchemaVersion: SchemaVersion, Sessions: []SessionSummary{
+		{ID: sess.ID, CreatedAt: Timestamp(created), SourceFile: "main.go", Status: "in_progress", Annotations: 2},
+	}})
+	validate(t, "session-history", SessionHistory{SchemaVersion: SchemaVersion, SessionID: sess.ID, Revisions: []Revision{
+		{Revision: 1, SavedAt: Timestamp(created), Annotations: 0},
+	}})
+	validate(t, "session merge", SessionMerge{SchemaVersion: SchemaVersion, SessionID: sess.ID, Sources: []string{"a", "b"}, Conflicts: []Conflict{{A: ann, B: ann}}})
+	validate(t, "prime", Prime{SchemaVersion: SchemaVersion, Purpose: "p", Commands: []Command{{Name: "n", Description: "d"}}, FEMSyntax: []FEMSyntax{}, TUIKeys: []Key{}, Docs: []string{}})
+	validate(t, "check", Check{SchemaVersion: SchemaVersion, Types: []string{"delete"}, Findings: 1, Sessions: []CheckSession{
+		{SessionID: sess.ID, Status: "ready", Drifted: true, Findings: []fem.Annotation{ann}},
+	}})
+	validate(t, "scan", Scan{SchemaVersion: SchemaVersion, Files: 2, Findings: []scan.Finding{
+		{Path: "main.go", Line: 3, Column: 7, Type: "comment", Marker: "{>> x <<}"},
+	}})
+	validate(t, "search", Search{SchemaVersion: SchemaVersion, Query: "retry", Mode: search.ModeLiteral, Hits: []search.Hit{
+		{SessionID: sess.ID, SourceFile: "main.go", Kind: search.KindAnnotation, Type: "comment", StartLine: 2, EndLine: 3, Text: "retry"},
+		{SessionID: sess.ID, Kind: search.KindContent, StartLine: 4, EndLine: 4, Text: "retry()"},
+	}})
+	report := stats.Compute([]*session.Session{
+		{ID: "a", SourceFile: "main.go", CreatedAt: created, Content: "x {~~ vague ~~}\ny {-- drop --}\n"},
+		{ID: "b", CreatedAt: created.AddDate(0, 0, 10), Content: "stdin\n"},
+	}, stats.Options{})
+	validate(t, "stats", Stats{SchemaVersion: SchemaVersion, Report: *report})
+	validate(t, "stats", Stats{SchemaVersion: SchemaVersion, Report: *stats.Compute(nil, stats.Options{})})
+	validate(t, "error", ErrorDocument{SchemaVersion: SchemaVersion, Error: ErrorDetail{Code: CodeUsage, Message: "bad flag"}})
+}
+
+func TestErrorSchemaListsEveryCode(t *testing.T) {
+	data, _ := Schema("error")
+	for _, c := range Codes {
+		if !strings.Contains(string(data), `"`+string(c)+`"`) {
+			t.Errorf("error schema is missing code %q", c)
+		}
+	}
+}
+
+func TestSchemaUnknownCommand(t *testing.T) {
+	_, err := Schema("nope")
+	if CodeOf(err) != CodeNotFound || !strings.Contains(err.Error(), "session-list") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	_, statErr := os.Stat("/nonexistent/fabbro")
+	tests := []struct {
+		err  error
+		want Code
+	}{
+		{Errorf(CodeUsage, "bad"), CodeUsage},
+		{fmt.Errorf("wrapped: %w", Errorf(CodeData, "bad")), CodeData},
+		{fmt.Errorf("failed to load: %w", session.ErrNotFound), CodeNotFound},
+		{config.ErrNotInitialized, CodeNotFound},
+		{statErr, CodeNotFound},
+		{&os.PathError{Op: "write", Path: "x", Err: os.ErrPermission}, CodeIO},
+		{errors.New("boom"), CodeInternal},
+	}
+	for _, tt := range tests {
+		if got := CodeOf(tt.err); got != tt.want {
+			t.Errorf("CodeOf(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteError(&buf, Errorf(CodeUsage, "cannot use both")); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"schemaVersion":1,"error":{"code":"usage_error","message":"cannot use both"}}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	if CodeUsage.ExitCode() != 64 || CodeNotFound.ExitCode() != 66 || CodeInternal.ExitCode() != 1 {
+		t.Error("unexpected exit codes")
+	}
+}
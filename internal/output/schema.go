@@ -0,0 +1,43 @@
+package output
+
+import (
+	"embed"
+	"sort"
+	"strings"
+)
+
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+// schemaAliases maps commands that share another command's payload.
+var schemaAliases = map[string]string{
+	"wait": "apply",
+}
+
+// SchemaNames lists the commands with a published schema, plus "error".
+func SchemaNames() []string {
+	entries, _ := schemaFS.ReadDir("schemas")
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	for alias := range schemaAliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Schema returns the JSON Schema for a command's --json output. Subcommands
+// may be given with a space or a dash: "session list" or "session-list".
+func Schema(command string) ([]byte, error) {
+	name := strings.Join(strings.Fields(command), "-")
+	if alias, ok := schemaAliases[name]; ok {
+		name = alias
+	}
+	data, err := schemaFS.ReadFile("schemas/" + name + ".json")
+	if err != nil {
+		return nil, Errorf(CodeNotFound, "no schema for %q: must be one of %s", command, strings.Join(SchemaNames(), ", "))
+	}
+	return data, nil
+}
@@ -0,0 +1,182 @@
+// Package prompt renders review annotations as instructions for a coding
+// agent, quoting the reviewed source around each one so the agent does not
+// have to re-read the file.
+package prompt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/highlight"
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+// DefaultContext is the number of lines quoted above and below each
+// annotation when none is requested.
+const DefaultContext = 3
+
+// Options controls how much source is quoted.
+type Options struct {
+	Context int // lines of context above and below each annotation
+	Budget  int // approximate token limit for the whole prompt; 0 means unlimited
+}
+
+// Result describes how the prompt was fitted to the budget.
+type Result struct {
+	Text      string
+	Context   int  // context lines actually used
+	Excerpts  bool // whether annotated lines are quoted at all
+	Trimmed   bool // context was reduced to fit the budget
+	OverLimit bool // still over budget with no excerpts
+}
+
+// EstimateTokens approximates the token count of s at four characters per
+// token, which is close enough to budget prompts for current models.
+func EstimateTokens(s string) int {
+	return (len([]rune(s)) + 3) / 4
+}
+
+// Render builds the prompt for sess. When opts.Budget is set and the prompt
+// would exceed it, context lines are dropped first, then the quoted
+// annotated lines themselves.
+func Render(sess *session.Session, annotations []fem.Annotation, opts Options) (*Result, error) {
+	_, clean, err := fem.Parse(sess.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", sess.ID, err)
+	}
+	lines := fem.Lines(clean)
+	annotations = fem.Distinct(annotations)
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return annotations[i].StartLine < annotations[j].StartLine
+	})
+	r := &renderer{
+		sess:        sess,
+		lines:       lines,
+		annotations: annotations,
+		lang:        highlight.New(sess.SourceFile, clean).Language(),
+	}
+
+	ctx := opts.Context
+	if ctx < 0 {
+		ctx = 0
+	}
+	for c := ctx; c >= 0; c-- {
+		text := r.render(c, true, c < ctx)
+		if opts.Budget <= 0 || EstimateTokens(text) <= opts.Budget {
+			return &Result{Text: text, Context: c, Excerpts: true, Trimmed: c < ctx}, nil
+		}
+	}
+	text := r.render(0, false, true)
+	return &Result{
+		Text:      text,
+		Trimmed:   true,
+		OverLimit: EstimateTokens(text) > opts.Budget,
+	}, nil
+}
+
+type renderer struct {
+	sess        *session.Session
+	lines       []string
+	annotations []fem.Annotation
+	lang        string
+}
+
+func (r *renderer) render(context int, excerpts, trimmed bool) string {
+	var b strings.Builder
+
+	source := "reviewed content (from stdin)"
+	if r.sess.SourceFile != "" {
+		source = r.sess.SourceFile
+	}
+	fmt.Fprintf(&b, "# Review feedback for %s\n\n", source)
+	fmt.Fprintf(&b, "A reviewer left %d annotation(s) on %s (fabbro session %s). ", len(r.annotations), source, r.sess.ID)
+	b.WriteString("Address each one in order. Line numbers refer to the reviewed version quoted below")
+	if c := r.sess.Conversion; c != nil {
+		fmt.Fprintf(&b, ", which was converted from %s to Markdown for review; apply the feedback to the %s source", strings.ToUpper(c.From), strings.ToUpper(c.From))
+	} else if r.sess.SourceFile != "" {
+		b.WriteString("; the file may have changed since, so match on content rather than line numbers alone")
+	}
+	b.WriteString(".\n")
+	if trimmed {
+		if excerpts {
+			fmt.Fprintf(&b, "\nSource context was reduced to %d line(s) per annotation to fit the token budget.\n", context)
+		} else {
+			b.WriteString("\nSource excerpts were omitted to fit the token budget.\n")
+		}
+	}
+
+	for i, a := range r.annotations {
+		fmt.Fprintf(&b, "\n## %d. %s — %s\n\n", i+1, a.Type, a.LineRef())
+		b.WriteString(instruction(a))
+		b.WriteString("\n")
+		if a.Author != "" {
+			fmt.Fprintf(&b, "\n(Reviewer: %s)\n", a.Author)
+		}
+		if excerpts {
+			if ex := r.excerpt(a, context); ex != "" {
+				b.WriteString("\n")
+				b.WriteString(ex)
+			}
+		}
+	}
+	return b.String()
+}
+
+// excerpt quotes the annotated lines with context lines on either side,
+// marking the annotated ones with ">".
+func (r *renderer) excerpt(a fem.Annotation, context int) string {
+	if a.StartLine < 1 || a.StartLine > len(r.lines) {
+		return ""
+	}
+	from := max(1, a.StartLine-context)
+	to := min(len(r.lines), max(a.EndLine, a.StartLine)+context)
+	width := len(fmt.Sprint(to))
+
+	var b strings.Builder
+	for n := from; n <= to; n++ {
+		marker := " "
+		if n >= a.StartLine && n <= a.EndLine {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, n, r.lines[n-1])
+	}
+	return fem.Fence(strings.TrimSuffix(b.String(), "\n"), r.lang)
+}
+
+// instruction tells the agent what each annotation type asks for.
+func instruction(a fem.Annotation) string {
+	text := strings.ReplaceAll(a.Text, `\n`, "\n")
+	lines := strings.ToLower(a.LineRef())
+	withText := func(prefix string) string {
+		if strings.TrimSpace(text) == "" {
+			return prefix + "."
+		}
+		return prefix + ": " + text
+	}
+
+	switch a.Type {
+	case "change":
+		return fmt.Sprintf("Replace %s with:\n\n%s", lines, strings.TrimSuffix(fem.Fence(a.Replacement(), ""), "\n"))
+	case "delete":
+		if strings.TrimSpace(text) == "" {
+			return fmt.Sprintf("Delete %s.", lines)
+		}
+		return fmt.Sprintf("Delete %s. Reason: %s", lines, text)
+	case "question":
+		return withText(fmt.Sprintf("Answer the reviewer's question about %s (reply before changing anything)", lines))
+	case "expand":
+		return withText(fmt.Sprintf("Expand %s with more detail", lines))
+	case "keep":
+		return withText(fmt.Sprintf("Keep %s as they are; do not change them", lines))
+	case "unclear":
+		return withText(fmt.Sprintf("Rewrite %s to be clearer; the reviewer found them unclear", lines))
+	case "emphasize":
+		return withText(fmt.Sprintf("Give %s more emphasis", lines))
+	case "section":
+		return withText(fmt.Sprintf("Apply this feedback to the section at %s", lines))
+	default:
+		return withText(fmt.Sprintf("Address this comment on %s", lines))
+	}
+}
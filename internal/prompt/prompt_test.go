@@ -0,0 +1,99 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+func testSession() *session.Session {
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	lines[9] += " {-- [lines 10-11] obsolete --}"
+	lines[14] += " {++ -> fresh line ++}"
+	lines[4] += " {?? why? ??}"
+	return &session.Session{ID: "20260101-abc", SourceFile: "notes.txt", Content: strings.Join(lines, "\n") + "\n"}
+}
+
+func render(t *testing.T, sess *session.Session, opts Options) *Result {
+	t.Helper()
+	annotations, _, err := fem.Parse(sess.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Render(sess, annotations, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestRenderQuotesContextAndInstructions(t *testing.T) {
+	res := render(t, testSession(), Options{Context: 2})
+	out := res.Text
+
+	for _, want := range []string{
+		"# Review feedback for notes.txt",
+		"## 1. question — Line 5\n\nAnswer the reviewer's question about line 5 (reply before changing anything): why?",
+		"## 2. delete — Lines 10-11\n\nDelete lines 10-11. Reason: obsolete",
+		"   8 | line 8\n   9 | line 9\n> 10 | line 10\n> 11 | line 11\n  12 | line 12\n  13 | line 13\n",
+		"## 3. change — Line 15\n\nReplace line 15 with:\n\n```\nfresh line\n```",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected prompt to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "line 18\n") {
+		t.Errorf("expected only 2 lines of context, got:\n%s", out)
+	}
+	if res.Trimmed || res.Context != 2 {
+		t.Errorf("expected untrimmed result, got %+v", res)
+	}
+}
+
+func TestRenderStdinSession(t *testing.T) {
+	sess := &session.Session{ID: "x", Content: "hello {>> hi <<}\n"}
+	out := render(t, sess, Options{}).Text
+	if !strings.Contains(out, "reviewed content (from stdin)") || !strings.Contains(out, "> 1 | hello") {
+		t.Errorf("unexpected prompt for stdin session:\n%s", out)
+	}
+}
+
+func TestRenderConvertedSession(t *testing.T) {
+	sess := &session.Session{ID: "x", SourceFile: "page.html", Content: "# Title {>> hi <<}\n",
+		Conversion: &session.Conversion{From: "html", LineMap: []int{3}}}
+	out := render(t, sess, Options{}).Text
+	if !strings.Contains(out, "converted from HTML to Markdown for review; apply the feedback to the HTML source") {
+		t.Errorf("expected a note on the conversion, got:\n%s", out)
+	}
+}
+
+func TestRenderTrimsContextToBudget(t *testing.T) {
+	full := render(t, testSession(), Options{Context: 5})
+	none := render(t, testSession(), Options{Context: 0})
+
+	budget := (EstimateTokens(full.Text) + EstimateTokens(none.Text)) / 2
+	res := render(t, testSession(), Options{Context: 5, Budget: budget})
+	if !res.Trimmed || !res.Excerpts || res.Context >= 5 {
+		t.Fatalf("expected reduced context, got %+v", res)
+	}
+	if EstimateTokens(res.Text) > budget {
+		t.Errorf("prompt of %d tokens exceeds budget %d", EstimateTokens(res.Text), budget)
+	}
+	if !strings.Contains(res.Text, "reduced to") {
+		t.Errorf("expected a note about trimmed context:\n%s", res.Text)
+	}
+
+	res = render(t, testSession(), Options{Context: 5, Budget: 1})
+	if res.Excerpts || !res.OverLimit {
+		t.Errorf("expected excerpts dropped and over-limit flagged, got %+v", res)
+	}
+	if strings.Contains(res.Text, "| line") {
+		t.Errorf("expected no source excerpts:\n%s", res.Text)
+	}
+}
@@ -0,0 +1,218 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/highlight"
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+// typeColors are the accent colours of each annotation type in HTML reports.
+var typeColors = map[string]string{
+	"comment":   "#66d9ef",
+	"delete":    "#f92672",
+	"question":  "#ae81ff",
+	"expand":    "#fd971f",
+	"keep":      "#a6e22e",
+	"unclear":   "#e6db74",
+	"change":    "#38ccd1",
+	"emphasize": "#f8f8f2",
+	"section":   "#75715e",
+}
+
+type htmlLine struct {
+	Number    int
+	Tokens    []highlight.Token
+	Annotated bool
+	Notes     []htmlNote
+}
+
+type htmlNote struct {
+	Type   string
+	Color  string
+	Start  int
+	Ref    string // "Line N" or "Lines N-M"
+	Multi  bool   // spans more than one line
+	Text   string
+	Author string
+}
+
+type htmlGroup struct {
+	Group
+	Color string
+	Notes []htmlNote
+}
+
+type htmlData struct {
+	Title      string
+	Session    *session.Session
+	Reviewers  string
+	Stats      Stats
+	Background string
+	Foreground string
+	Lines      []htmlLine
+	Groups     []htmlGroup
+	TypeColors map[string]string
+}
+
+// HTML writes sess as a single self-contained HTML page: summary statistics,
+// the syntax-highlighted source with annotations as margin notes next to the
+// lines they start on, and the annotations grouped by type. All styling is
+// inline so the file works offline.
+func HTML(w io.Writer, sess *session.Session) error {
+	r, err := load(sess)
+	if err != nil {
+		return err
+	}
+	h := highlight.New(sess.SourceFile, strings.Join(r.lines, "\n"))
+
+	data := htmlData{
+		Title:      r.title(),
+		Session:    sess,
+		Reviewers:  r.reviewers(),
+		Stats:      r.stats,
+		Background: h.Background(),
+		Foreground: h.Foreground(),
+		TypeColors: typeColors,
+	}
+
+	notesAt := make(map[int][]htmlNote)
+	annotated := make(map[int]bool)
+	for _, a := range r.annotations {
+		notesAt[a.StartLine] = append(notesAt[a.StartLine], toNote(a))
+		for l := a.StartLine; l <= a.EndLine; l++ {
+			annotated[l] = true
+		}
+	}
+	for i, line := range r.lines {
+		n := i + 1
+		data.Lines = append(data.Lines, htmlLine{
+			Number:    n,
+			Tokens:    h.HighlightLine(line),
+			Annotated: annotated[n],
+			Notes:     notesAt[n],
+		})
+	}
+	for _, g := range r.groups {
+		hg := htmlGroup{Group: g, Color: typeColors[g.Type]}
+		for _, a := range g.Annotations {
+			hg.Notes = append(hg.Notes, toNote(a))
+		}
+		data.Groups = append(data.Groups, hg)
+	}
+
+	return htmlTemplate.Execute(w, data)
+}
+
+func toNote(a fem.Annotation) htmlNote {
+	return htmlNote{
+		Type:   a.Type,
+		Color:  typeColors[a.Type],
+		Start:  a.StartLine,
+		Ref:    a.LineRef(),
+		Multi:  a.EndLine > a.StartLine,
+		Text:   noteText(a),
+		Author: a.Author,
+	}
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Review: {{.Title}}</title>
+<style>
+body { margin: 0 auto; max-width: 1200px; padding: 1.5rem; font: 15px/1.5 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; background: #fafafa; }
+h1 { font-size: 1.6rem; margin: 0 0 .5rem; }
+h2 { font-size: 1.2rem; margin: 2rem 0 .75rem; border-bottom: 1px solid #ddd; padding-bottom: .25rem; }
+h3 { font-size: 1rem; margin: 1.25rem 0 .5rem; }
+dl.meta { display: grid; grid-template-columns: max-content auto; gap: .15rem 1rem; margin: 0; }
+dl.meta dt { color: #666; }
+dl.meta dd { margin: 0; font-family: ui-monospace, Menlo, Consolas, monospace; }
+ul.counts { list-style: none; padding: 0; display: flex; flex-wrap: wrap; gap: .5rem; }
+ul.counts li { border-left: 4px solid; padding: .1rem .6rem; background: #fff; border-radius: 3px; }
+table.code { width: 100%; border-collapse: collapse; font: 13px/1.45 ui-monospace, Menlo, Consolas, monospace; }
+table.code td { vertical-align: top; padding: 0 .5rem; }
+td.ln { text-align: right; color: #75715e; user-select: none; width: 1%; white-space: nowrap; }
+td.src { white-space: pre-wrap; word-break: break-all; }
+tr.annotated td.ln { box-shadow: inset 3px 0 0 #e6db74; }
+td.notes { width: 35%; background: #fafafa; font: 13px/1.4 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; }
+.note { border-left: 4px solid; background: #fff; margin: .15rem 0; padding: .2rem .5rem; white-space: pre-wrap; border-radius: 3px; }
+.tag { font-weight: 600; text-transform: uppercase; font-size: 11px; letter-spacing: .03em; }
+.range, .author { color: #666; font-size: 12px; }
+ul.group { list-style: none; padding: 0; }
+a { color: inherit; }
+footer { margin-top: 2rem; color: #888; font-size: 12px; }
+</style>
+</head>
+<body>
+<header>
+<h1>Review: {{.Title}}</h1>
+<dl class="meta">
+<dt>Session</dt><dd>{{.Session.ID}}</dd>
+{{- if .Session.SourceFile}}
+<dt>Source</dt><dd>{{.Session.SourceFile}}</dd>
+{{- end}}
+<dt>Created</dt><dd>{{.Session.CreatedAt.Format "2006-01-02 15:04"}}</dd>
+{{- if .Session.Status}}
+<dt>Status</dt><dd>{{.Session.Status.Label}}</dd>
+{{- end}}
+{{- if .Reviewers}}
+<dt>Reviewers</dt><dd>{{.Reviewers}}</dd>
+{{- end}}
+</dl>
+</header>
+
+<section class="summary">
+<h2>Summary</h2>
+<p><strong>{{.Stats.Annotations}} annotations</strong> on {{.Stats.AnnotatedLines}} of {{.Stats.TotalLines}} lines ({{.Stats.AnnotatedPercent}}%).</p>
+{{- if .Stats.ByType}}
+<ul class="counts">
+{{- range .Stats.ByType}}
+<li style="border-color: {{index $.TypeColors .Type}}">{{.Type}}: {{.Count}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</section>
+
+<section class="source">
+<h2>Source</h2>
+<table class="code">
+{{- range .Lines}}
+<tr id="L{{.Number}}"{{if .Annotated}} class="annotated"{{end}}>
+<td class="ln">{{.Number}}</td>
+<td class="src"{{if $.Background}} style="background: {{$.Background}}; color: {{$.Foreground}}"{{end}}>{{range .Tokens}}{{if .Color}}<span style="color: {{.Color}}">{{.Text}}</span>{{else}}{{.Text}}{{end}}{{end}}</td>
+<td class="notes">
+{{- range .Notes}}
+<div class="note" style="border-color: {{.Color}}"><span class="tag">{{.Type}}</span>{{if .Multi}} <span class="range">{{.Ref}}</span>{{end}}{{if .Author}} <span class="author">{{.Author}}</span>{{end}}
+{{.Text}}</div>
+{{- end}}
+</td>
+</tr>
+{{- end}}
+</table>
+</section>
+{{- if .Groups}}
+
+<section class="by-type">
+<h2>Annotations by type</h2>
+{{- range .Groups}}
+<h3 style="color: {{.Color}}">{{.Label}} ({{len .Notes}})</h3>
+<ul class="group">
+{{- range .Notes}}
+<li class="note" style="border-color: {{.Color}}"><a href="#L{{.Start}}">{{.Ref}}</a>{{if .Author}} <span class="author">{{.Author}}</span>{{end}}
+{{.Text}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</section>
+{{- end}}
+
+<footer>Generated by fabbro</footer>
+</body>
+</html>
+`))
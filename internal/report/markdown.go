@@ -0,0 +1,117 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/highlight"
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+// maxExcerptLines caps the source quoted under each annotation.
+const maxExcerptLines = 12
+
+// Markdown writes sess as a Markdown report: metadata, summary statistics,
+// annotations grouped by type with the lines they refer to, and the full
+// source in a fenced block tagged with its language.
+func Markdown(w io.Writer, sess *session.Session) error {
+	r, err := load(sess)
+	if err != nil {
+		return err
+	}
+	lang := highlight.New(sess.SourceFile, strings.Join(r.lines, "\n")).Language()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Review: %s\n\n", r.title())
+
+	b.WriteString("| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Session | `%s` |\n", sess.ID)
+	if sess.SourceFile != "" {
+		fmt.Fprintf(&b, "| Source | `%s` |\n", sess.SourceFile)
+	}
+	fmt.Fprintf(&b, "| Created | %s |\n", sess.CreatedAt.Format("2006-01-02 15:04"))
+	if sess.Status != "" {
+		fmt.Fprintf(&b, "| Status | %s |\n", sess.Status.Label())
+	}
+	if reviewers := r.reviewers(); reviewers != "" {
+		fmt.Fprintf(&b, "| Reviewers | %s |\n", reviewers)
+	}
+
+	b.WriteString("\n## Summary\n\n")
+	fmt.Fprintf(&b, "**%d annotations** on %d of %d lines (%d%%).\n", r.stats.Annotations, r.stats.AnnotatedLines, r.stats.TotalLines, r.stats.AnnotatedPercent())
+	if len(r.stats.ByType) > 0 {
+		b.WriteString("\n| Type | Count |\n|---|---|\n")
+		for _, tc := range r.stats.ByType {
+			fmt.Fprintf(&b, "| %s | %d |\n", tc.Type, tc.Count)
+		}
+	}
+
+	if len(r.groups) > 0 {
+		b.WriteString("\n## Annotations\n\n")
+		for _, g := range r.groups {
+			fmt.Fprintf(&b, "### %s (%d)\n\n_%s_\n\n", g.Label(), len(g.Annotations), g.Description)
+			for _, a := range g.Annotations {
+				fmt.Fprintf(&b, "- **%s**", a.LineRef())
+				if a.Author != "" {
+					fmt.Fprintf(&b, " _(%s)_", a.Author)
+				}
+				excerpt := r.excerpt(a.StartLine, a.EndLine)
+				if a.Type == "change" {
+					b.WriteString(": replace\n\n")
+					b.WriteString(indent(fem.Fence(excerpt, lang), "  "))
+					b.WriteString("\n  with\n\n")
+					b.WriteString(indent(fem.Fence(noteText(a), lang), "  "))
+					b.WriteString("\n")
+					continue
+				}
+				fmt.Fprintf(&b, ": %s\n\n", strings.ReplaceAll(escapeMarkdown(noteText(a)), "\n", "\n  "))
+				if excerpt != "" {
+					b.WriteString(indent(fem.Fence(excerpt, lang), "  "))
+					b.WriteString("\n")
+				}
+			}
+		}
+	}
+
+	if len(r.groups) == 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("## Source\n\n")
+	b.WriteString(fem.Fence(strings.Join(r.lines, "\n"), lang))
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// excerpt returns source lines start..end (1-indexed), truncated to
+// maxExcerptLines.
+func (r *review) excerpt(start, end int) string {
+	if start < 1 || start > len(r.lines) {
+		return ""
+	}
+	if end > len(r.lines) {
+		end = len(r.lines)
+	}
+	lines := r.lines[start-1 : end]
+	if len(lines) > maxExcerptLines {
+		lines = append(lines[:maxExcerptLines:maxExcerptLines], "…")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeMarkdown keeps annotation text from being read as inline HTML.
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func indent(text, prefix string) string {
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,150 @@
+// Package report renders review sessions as standalone documents for readers
+// who don't use the CLI.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+// Stats summarises the annotations of a session.
+type Stats struct {
+	Annotations    int
+	ByType         []TypeCount // in fem.AnnotationTypes order, non-zero only
+	AnnotatedLines int
+	TotalLines     int
+	Reviewers      []string
+}
+
+type TypeCount struct {
+	Type  string
+	Count int
+}
+
+// AnnotatedPercent returns the percentage of lines covered by an annotation.
+// It is not the session's review coverage, which counts lines marked
+// reviewed.
+func (s Stats) AnnotatedPercent() int {
+	if s.TotalLines == 0 {
+		return 0
+	}
+	return s.AnnotatedLines * 100 / s.TotalLines
+}
+
+// Group is the annotations of one type, in line order.
+type Group struct {
+	Type        string
+	Description string
+	Annotations []fem.Annotation
+}
+
+// Label returns the group's type capitalised for headings.
+func (g Group) Label() string {
+	return strings.ToUpper(g.Type[:1]) + g.Type[1:]
+}
+
+// review is the parsed form of a session shared by the renderers.
+type review struct {
+	sess        *session.Session
+	lines       []string
+	annotations []fem.Annotation
+	stats       Stats
+	groups      []Group
+}
+
+func load(sess *session.Session) (*review, error) {
+	annotations, clean, err := fem.Parse(sess.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", sess.ID, err)
+	}
+	annotations = fem.Distinct(annotations)
+	lines := fem.Lines(clean)
+
+	return &review{
+		sess:        sess,
+		lines:       lines,
+		annotations: annotations,
+		stats:       computeStats(annotations, len(lines)),
+		groups:      groupByType(annotations),
+	}, nil
+}
+
+func computeStats(annotations []fem.Annotation, totalLines int) Stats {
+	stats := Stats{Annotations: len(annotations), TotalLines: totalLines}
+
+	counts := make(map[string]int)
+	covered := make(map[int]bool)
+	reviewers := make(map[string]bool)
+	for _, a := range annotations {
+		counts[a.Type]++
+		for l := a.StartLine; l <= a.EndLine && l <= totalLines; l++ {
+			covered[l] = true
+		}
+		for _, name := range strings.Split(a.Author, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				reviewers[name] = true
+			}
+		}
+	}
+	for _, at := range fem.AnnotationTypes {
+		if counts[at.Name] > 0 {
+			stats.ByType = append(stats.ByType, TypeCount{Type: at.Name, Count: counts[at.Name]})
+		}
+	}
+	stats.AnnotatedLines = len(covered)
+	for name := range reviewers {
+		stats.Reviewers = append(stats.Reviewers, name)
+	}
+	sort.Strings(stats.Reviewers)
+	return stats
+}
+
+func groupByType(annotations []fem.Annotation) []Group {
+	var groups []Group
+	for _, at := range fem.AnnotationTypes {
+		g := Group{Type: at.Name, Description: at.Description}
+		for _, a := range annotations {
+			if a.Type == at.Name {
+				g.Annotations = append(g.Annotations, a)
+			}
+		}
+		if len(g.Annotations) == 0 {
+			continue
+		}
+		sort.SliceStable(g.Annotations, func(i, j int) bool {
+			return g.Annotations[i].StartLine < g.Annotations[j].StartLine
+		})
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// title names the reviewed content: its source file, or the session ID.
+func (r *review) title() string {
+	if r.sess.SourceFile != "" {
+		return r.sess.SourceFile
+	}
+	return "session " + r.sess.ID
+}
+
+// reviewers lists who reviewed the session: annotation authors, falling back
+// to the session's author.
+func (r *review) reviewers() string {
+	if len(r.stats.Reviewers) > 0 {
+		return strings.Join(r.stats.Reviewers, ", ")
+	}
+	return r.sess.Author
+}
+
+// noteText returns an annotation's text for display, with escaped newlines
+// expanded and change annotations shown as their replacement.
+func noteText(a fem.Annotation) string {
+	if a.Type == "change" {
+		return a.Replacement()
+	}
+	return strings.ReplaceAll(a.Text, `\n`, "\n")
+}
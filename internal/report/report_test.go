@@ -0,0 +1,95 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+func testSession() *session.Session {
+	return &session.Session{
+		ID:         "20260101-abc",
+		CreatedAt:  time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+		SourceFile: "main.go",
+		Status:     session.StatusReady,
+		Content: "package main {>> [by alice] needs a doc comment <<}\n" +
+			"\n" +
+			"func main() { {-- [lines 3-4] [by bob] dead code --}\n" +
+			"\tprintln(\"hi\") {?? why <script>? ??}\n" +
+			"} {++ -> } // end ++}",
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	r, err := load(testSession())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := r.stats
+	if s.Annotations != 4 || s.TotalLines != 5 || s.AnnotatedLines != 4 {
+		t.Errorf("unexpected stats %+v", s)
+	}
+	if s.AnnotatedPercent() != 80 {
+		t.Errorf("expected 80%% of lines annotated, got %d", s.AnnotatedPercent())
+	}
+	if len(s.ByType) != 4 || s.ByType[0].Type != "comment" || s.ByType[1].Type != "delete" {
+		t.Errorf("expected counts in annotation type order, got %+v", s.ByType)
+	}
+	if strings.Join(s.Reviewers, ",") != "alice,bob" {
+		t.Errorf("unexpected reviewers %v", s.Reviewers)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	var b strings.Builder
+	if err := Markdown(&b, testSession()); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# Review: main.go",
+		"| Status | ready |",
+		"| Reviewers | alice, bob |",
+		"**4 annotations** on 4 of 5 lines (80%).",
+		"### Delete (1)",
+		"- **Lines 3-4** _(bob)_: dead code",
+		"- **Line 5**: replace",
+		"```go\npackage main\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "### Comment") > strings.Index(out, "### Question") {
+		t.Error("expected groups in annotation type order")
+	}
+}
+
+func TestHTML_IsSelfContained(t *testing.T) {
+	var b strings.Builder
+	if err := HTML(&b, testSession()); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, forbidden := range []string{"<script", "<link", "src=", "http://", "https://", "@import", "url("} {
+		if strings.Contains(out, forbidden) {
+			t.Errorf("HTML report should not reference external assets, found %q", forbidden)
+		}
+	}
+	for _, want := range []string{
+		"<title>Review: main.go</title>",
+		`<span style="color: #f92672">package</span>`,
+		`<tr id="L3" class="annotated">`,
+		"why &lt;script&gt;?",
+		`<span class="range">Lines 3-4</span>`,
+		"Annotations by type",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("HTML missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Package sarif converts review sessions to SARIF 2.1.0 logs so annotations
+// can be shown by code-scanning viewers alongside linter findings.
+package sarif
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+const (
+	Version   = "2.1.0"
+	SchemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	informationURI = "https://github.com/charly-vibes/fabbro"
+	srcRoot        = "%SRCROOT%"
+)
+
+// Log is the top-level SARIF document.
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool              Tool               `json:"tool"`
+	AutomationDetails *AutomationDetails `json:"automationDetails,omitempty"`
+	Results           []Result           `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string `json:"name"`
+	Version        string `json:"version,omitempty"`
+	InformationURI string `json:"informationUri"`
+	Rules          []Rule `json:"rules"`
+}
+
+type Rule struct {
+	ID                   string        `json:"id"`
+	Name                 string        `json:"name"`
+	ShortDescription     Message       `json:"shortDescription"`
+	DefaultConfiguration Configuration `json:"defaultConfiguration"`
+}
+
+type Configuration struct {
+	Level string `json:"level"`
+}
+
+type AutomationDetails struct {
+	ID string `json:"id"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Result struct {
+	RuleID     string            `json:"ruleId"`
+	RuleIndex  int               `json:"ruleIndex"`
+	Level      string            `json:"level"`
+	Message    Message           `json:"message"`
+	Locations  []Location        `json:"locations,omitempty"`
+	Fixes      []Fix             `json:"fixes,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           Region           `json:"region"`
+}
+
+type ArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// Region is a whole-line range; without columns SARIF covers entire lines.
+type Region struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+type Fix struct {
+	Description     Message          `json:"description"`
+	ArtifactChanges []ArtifactChange `json:"artifactChanges"`
+}
+
+type ArtifactChange struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Replacements     []Replacement    `json:"replacements"`
+}
+
+type Replacement struct {
+	DeletedRegion   Region   `json:"deletedRegion"`
+	InsertedContent *Content `json:"insertedContent,omitempty"`
+}
+
+type Content struct {
+	Text string `json:"text"`
+}
+
+// Level returns the SARIF level for an annotation type: requested edits are
+// warnings, requests for discussion are notes, and keep is informational.
+func Level(annType string) string {
+	switch annType {
+	case "delete", "change", "unclear":
+		return "warning"
+	case "keep":
+		return "none"
+	default:
+		return "note"
+	}
+}
+
+// FromSession builds a SARIF log with one result per annotation of sess.
+// Each annotation type is a rule. Results carry a location only when the
+// session was created from a file, since stdin content has nowhere to point.
+func FromSession(sess *session.Session, annotations []fem.Annotation, toolVersion string) *Log {
+	rules := make([]Rule, len(fem.AnnotationTypes))
+	ruleIndex := make(map[string]int, len(fem.AnnotationTypes))
+	for i, at := range fem.AnnotationTypes {
+		rules[i] = Rule{
+			ID:                   at.Name,
+			Name:                 strings.ToUpper(at.Name[:1]) + at.Name[1:],
+			ShortDescription:     Message{Text: at.Description},
+			DefaultConfiguration: Configuration{Level: Level(at.Name)},
+		}
+		ruleIndex[at.Name] = i
+	}
+
+	var artifact *ArtifactLocation
+	if sess.SourceFile != "" {
+		artifact = artifactFor(sess.SourceFile)
+	}
+
+	results := []Result{}
+	for _, a := range fem.MergeAnnotations(nil, annotations) {
+		idx, ok := ruleIndex[a.Type]
+		if !ok {
+			continue
+		}
+		r := Result{
+			RuleID:    a.Type,
+			RuleIndex: idx,
+			Level:     Level(a.Type),
+			Message:   Message{Text: messageFor(a)},
+		}
+		if a.Author != "" {
+			r.Properties = map[string]string{"author": a.Author}
+		}
+		if start, end, ok := sess.SourceLines(a.StartLine, a.EndLine); artifact != nil && ok {
+			region := Region{StartLine: start, EndLine: end}
+			r.Locations = []Location{{PhysicalLocation: PhysicalLocation{ArtifactLocation: *artifact, Region: region}}}
+			// A converted session's replacement text is Markdown, not the
+			// source's format, so it can't be offered as a fix.
+			if a.Type == "change" && sess.Conversion == nil {
+				r.Fixes = []Fix{{
+					Description: Message{Text: "Apply the reviewer's replacement"},
+					ArtifactChanges: []ArtifactChange{{
+						ArtifactLocation: *artifact,
+						Replacements:     []Replacement{{DeletedRegion: region, InsertedContent: &Content{Text: a.Replacement()}}},
+					}},
+				}}
+			}
+		}
+		results = append(results, r)
+	}
+
+	return &Log{
+		Schema:  SchemaURI,
+		Version: Version,
+		Runs: []Run{{
+			Tool: Tool{Driver: Driver{
+				Name:           "fabbro",
+				Version:        toolVersion,
+				InformationURI: informationURI,
+				Rules:          rules,
+			}},
+			AutomationDetails: &AutomationDetails{ID: "fabbro/" + sess.ID},
+			Results:           results,
+		}},
+	}
+}
+
+// artifactFor returns the location of a session's source file. Relative
+// paths are resolved against the project root by consumers via %SRCROOT%.
+func artifactFor(sourceFile string) *ArtifactLocation {
+	if filepath.IsAbs(sourceFile) {
+		u := url.URL{Scheme: "file", Path: filepath.ToSlash(sourceFile)}
+		return &ArtifactLocation{URI: u.String()}
+	}
+	return &ArtifactLocation{URI: filepath.ToSlash(sourceFile), URIBaseID: srcRoot}
+}
+
+// messageFor returns a result message; SARIF requires non-empty text.
+func messageFor(a fem.Annotation) string {
+	text := strings.ReplaceAll(a.Text, `\n`, "\n")
+	if a.Type == "change" {
+		text = "Replace with: " + a.Replacement()
+	}
+	if strings.TrimSpace(text) == "" {
+		for _, at := range fem.AnnotationTypes {
+			if at.Name == a.Type {
+				return at.Description
+			}
+		}
+	}
+	return text
+}
@@ -0,0 +1,135 @@
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/session"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// schemaPath is the official OASIS SARIF 2.1.0 (errata01) schema.
+const schemaPath = "testdata/sarif-schema-2.1.0.json"
+
+func validate(t *testing.T, log *Log) {
+	t.Helper()
+	data, err := json.Marshal(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schemaData, err := os.ReadFile(schemaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaPath, bytes.NewReader(schemaData)); err != nil {
+		t.Fatal(err)
+	}
+	schema, err := compiler.Compile(schemaPath)
+	if err != nil {
+		t.Fatalf("compile schema: %v", err)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Validate(doc); err != nil {
+		t.Errorf("SARIF output does not match schema: %#v\n%s", err, data)
+	}
+}
+
+func testSession(sourceFile string) *session.Session {
+	return &session.Session{
+		ID:         "20260101-abc",
+		CreatedAt:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		SourceFile: sourceFile,
+	}
+}
+
+func TestFromSession_ValidatesAgainstSchema(t *testing.T) {
+	annotations := []fem.Annotation{
+		{Type: "comment", Text: "check this", StartLine: 1, EndLine: 1, Author: "alice"},
+		{Type: "delete", Text: "", StartLine: 2, EndLine: 4},
+		{Type: "change", Text: `-> one\ntwo`, StartLine: 5, EndLine: 6},
+		{Type: "keep", Text: "good", StartLine: 7, EndLine: 7},
+	}
+
+	validate(t, FromSession(testSession("src/main.go"), annotations, "1.2.3"))
+	validate(t, FromSession(testSession(""), annotations, "dev"))
+	validate(t, FromSession(testSession("/abs/path.go"), nil, "dev"))
+}
+
+func TestFromSession_MapsAnnotations(t *testing.T) {
+	annotations := []fem.Annotation{
+		{Type: "question", Text: "why?", StartLine: 3, EndLine: 3},
+		{Type: "change", Text: "-> better", StartLine: 5, EndLine: 6},
+		{Type: "change", Text: "-> better", StartLine: 5, EndLine: 6},
+	}
+
+	log := FromSession(testSession("src/main.go"), annotations, "1.0.0")
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(fem.AnnotationTypes) {
+		t.Errorf("expected a rule per annotation type, got %d", len(run.Tool.Driver.Rules))
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("expected duplicate change annotations to collapse, got %d results", len(run.Results))
+	}
+
+	q := run.Results[0]
+	if q.RuleID != "question" || q.Level != "note" || run.Tool.Driver.Rules[q.RuleIndex].ID != "question" {
+		t.Errorf("unexpected question result %+v", q)
+	}
+	loc := q.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "src/main.go" || loc.ArtifactLocation.URIBaseID != "%SRCROOT%" || loc.Region.StartLine != 3 {
+		t.Errorf("unexpected location %+v", loc)
+	}
+
+	c := run.Results[1]
+	if c.Level != "warning" || len(c.Fixes) != 1 {
+		t.Fatalf("expected change to be a warning with a fix, got %+v", c)
+	}
+	rep := c.Fixes[0].ArtifactChanges[0].Replacements[0]
+	if rep.DeletedRegion.StartLine != 5 || rep.DeletedRegion.EndLine != 6 || rep.InsertedContent.Text != "better" {
+		t.Errorf("unexpected replacement %+v", rep)
+	}
+}
+
+func TestFromSession_StdinHasNoLocations(t *testing.T) {
+	log := FromSession(testSession(""), []fem.Annotation{{Type: "change", Text: "-> x", StartLine: 1, EndLine: 1}}, "dev")
+	r := log.Runs[0].Results[0]
+	if len(r.Locations) != 0 || len(r.Fixes) != 0 {
+		t.Errorf("stdin sessions should have no locations or fixes, got %+v", r)
+	}
+}
+
+func TestFromSession_ConvertedMapsToSourceLines(t *testing.T) {
+	sess := testSession("docs/page.html")
+	sess.Conversion = &session.Conversion{From: "html", LineMap: []int{4, 0, 9, 10}}
+	log := FromSession(sess, []fem.Annotation{
+		{Type: "change", Text: "-> x", StartLine: 3, EndLine: 4},
+		{Type: "comment", Text: "blank", StartLine: 2, EndLine: 2},
+	}, "dev")
+	results := log.Runs[0].Results
+	region := results[0].Locations[0].PhysicalLocation.Region
+	if region.StartLine != 9 || region.EndLine != 10 || len(results[0].Fixes) != 0 {
+		t.Errorf("expected lines 9-10 of the HTML without a fix, got %+v", results[0])
+	}
+	if len(results[1].Locations) != 0 {
+		t.Errorf("expected an unmapped line to have no location, got %+v", results[1].Locations)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	want := map[string]string{"delete": "warning", "change": "warning", "unclear": "warning", "keep": "none", "comment": "note", "question": "note"}
+	for typ, level := range want {
+		if got := Level(typ); got != level {
+			t.Errorf("Level(%q) = %q, want %q", typ, got, level)
+		}
+	}
+}
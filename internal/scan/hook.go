@@ -0,0 +1,110 @@
+package scan
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// hookMarker identifies pre-commit hooks written by InstallHook.
+const hookMarker = "# Installed by fabbro hooks install."
+
+const hookScript = `#!/bin/sh
+` + hookMarker + `
+# Blocks commits that contain FEM review markers outside .fabbro/.
+# Files allowed to show FEM are listed under "scan.allow" in .fabbro/config.json.
+if ! command -v fabbro >/dev/null 2>&1; then
+	echo "fabbro not found in PATH; skipping FEM marker scan" >&2
+	exit 0
+fi
+%sexec fabbro scan --staged
+`
+
+// RepoRoot returns the top level of the git work tree containing dir, or
+// dir itself outside git.
+func RepoRoot(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return dir
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// RepoPath returns dir relative to the top level of its git work tree,
+// slash-separated, or "" for the top level itself and outside git.
+func RepoPath(dir string) string {
+	root := RepoRoot(dir)
+	// git reports the top level with symlinks resolved.
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// hookPath returns where git looks for the pre-commit hook, honouring
+// core.hooksPath.
+func hookPath(dir string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--git-path", "hooks/pre-commit").Output()
+	if err != nil {
+		return "", fmt.Errorf("not a git repository: %s", dir)
+	}
+	path := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
+
+// InstallHook writes a pre-commit hook running `fabbro scan --staged` in
+// the repository at dir and returns its path. Git runs hooks at the top
+// level, so when dir is nested the hook changes to it first to find the
+// fabbro project. An existing hook that fabbro did not write is only
+// replaced when force is set.
+func InstallHook(dir string, force bool) (string, error) {
+	path, err := hookPath(dir)
+	if err != nil {
+		return "", err
+	}
+	if existing, err := os.ReadFile(path); err == nil && !strings.Contains(string(existing), hookMarker) && !force {
+		return "", fmt.Errorf("%s already exists. Add 'fabbro scan --staged' to it (or to your hook manager), or rerun with --force to replace it", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	var cd string
+	if rel := RepoPath(dir); rel != "" {
+		cd = fmt.Sprintf("cd '%s' || exit 1\n", strings.ReplaceAll(rel, "'", `'\''`))
+	}
+	if err := os.WriteFile(path, []byte(fmt.Sprintf(hookScript, cd)), 0755); err != nil {
+		return "", fmt.Errorf("failed to write hook: %w", err)
+	}
+	return path, nil
+}
+
+// UninstallHook removes the pre-commit hook if fabbro installed it.
+func UninstallHook(dir string) (string, error) {
+	path, err := hookPath(dir)
+	if err != nil {
+		return "", err
+	}
+	existing, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no pre-commit hook installed")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read hook: %w", err)
+	}
+	if !strings.Contains(string(existing), hookMarker) {
+		return "", fmt.Errorf("%s was not installed by fabbro; leaving it in place", path)
+	}
+	return path, os.Remove(path)
+}
@@ -0,0 +1,230 @@
+// Package scan finds FEM markers that leaked out of review sessions into
+// ordinary files, e.g. after editing a session in $EDITOR and copying the
+// text back.
+package scan
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/glob"
+)
+
+// maxFileBytes skips files too large to be hand-edited text.
+const maxFileBytes = 5 * 1024 * 1024
+
+// Finding is one leaked marker.
+type Finding struct {
+	Path   string `json:"path"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"` // 1-based byte offset in the line
+	Type   string `json:"type"`
+	Marker string `json:"marker"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: leaked %s marker %s", f.Path, f.Line, f.Column, f.Type, f.Marker)
+}
+
+// Scanner scans the files of a directory tree.
+type Scanner struct {
+	// Dir is the tree's root; paths are relative to it.
+	Dir string
+	// Allow lists glob patterns of files that may legitimately contain FEM,
+	// such as documentation of the syntax.
+	Allow []string
+	// AllowBase is the slash-separated directory under Dir that Allow
+	// patterns are relative to, such as a fabbro project nested in the
+	// repository. Empty means Dir itself.
+	AllowBase string
+	// Staged scans the content staged in git's index instead of the
+	// working tree.
+	Staged bool
+}
+
+// Result is what a scan found.
+type Result struct {
+	Files    int // files scanned
+	Findings []Finding
+}
+
+// Run scans paths (relative to s.Dir; all of it when empty). In a git work
+// tree only tracked and unignored files are scanned, honouring .gitignore.
+// .fabbro/ and allowlisted files are always skipped.
+func (s *Scanner) Run(paths []string) (*Result, error) {
+	files, err := s.files(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Findings: []Finding{}}
+	for _, name := range files {
+		if s.skip(name) {
+			continue
+		}
+		data, err := s.read(name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // tracked but deleted in the working tree
+			}
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+		res.Files++
+		res.Findings = append(res.Findings, File(name, data)...)
+	}
+	return res, nil
+}
+
+// File returns the leaked markers in data, reported under name.
+func File(name string, data []byte) []Finding {
+	var findings []Finding
+	for i, line := range strings.Split(string(data), "\n") {
+		for _, m := range fem.FindMarkers(line) {
+			findings = append(findings, Finding{
+				Path:   name,
+				Line:   i + 1,
+				Column: m.Start + 1,
+				Type:   m.Type,
+				Marker: line[m.Start:m.End],
+			})
+		}
+	}
+	return findings
+}
+
+func (s *Scanner) skip(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if part == ".fabbro" || part == ".git" {
+			return true
+		}
+	}
+	if s.AllowBase != "" {
+		rel, ok := strings.CutPrefix(name, s.AllowBase+"/")
+		if !ok {
+			return false
+		}
+		name = rel
+	}
+	for _, pattern := range s.Allow {
+		if glob.Match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// read returns a file's content, or nil for binary and oversized files.
+func (s *Scanner) read(name string) ([]byte, error) {
+	var data []byte
+	var err error
+	if s.Staged {
+		data, err = s.git("show", ":"+name)
+	} else {
+		var info fs.FileInfo
+		info, err = os.Stat(filepath.Join(s.Dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() || info.Size() > maxFileBytes {
+			return nil, nil
+		}
+		data, err = os.ReadFile(filepath.Join(s.Dir, filepath.FromSlash(name)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFileBytes || bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// files lists candidate files, slash-separated and relative to s.Dir.
+func (s *Scanner) files(paths []string) ([]string, error) {
+	if s.inGitWorkTree() {
+		args := []string{"ls-files", "-z", "--cached", "--others", "--exclude-standard", "--"}
+		if s.Staged {
+			args = []string{"diff", "--cached", "--name-only", "--diff-filter=ACMR", "-z", "--"}
+		}
+		out, err := s.git(append(args, paths...)...)
+		if err != nil {
+			return nil, err
+		}
+		return uniqueNames(strings.Split(string(out), "\x00")), nil
+	}
+	if s.Staged {
+		return nil, fmt.Errorf("--staged requires a git repository")
+	}
+
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	var names []string
+	for _, p := range paths {
+		root := filepath.Join(s.Dir, p)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && (d.Name() == ".git" || d.Name() == ".fabbro") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, err := filepath.Rel(s.Dir, path)
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return uniqueNames(names), nil
+}
+
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var out []string
+	for _, n := range names {
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s *Scanner) inGitWorkTree() bool {
+	out, err := s.git("rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+func (s *Scanner) git(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", s.Dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
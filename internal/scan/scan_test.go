@@ -0,0 +1,183 @@
+package scan
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	runGit(t, dir, "init", "--quiet")
+	return dir
+}
+
+func paths(res *Result) string {
+	var out []string
+	for _, f := range res.Findings {
+		out = append(out, f.Path)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestFileReportsPositions(t *testing.T) {
+	findings := File("main.go", []byte("package main\nvar x = 1 {-- drop --}\n"))
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %+v", findings)
+	}
+	got := findings[0].String()
+	if got != "main.go:2:11: leaked delete marker {-- drop --}" {
+		t.Errorf("unexpected finding %q", got)
+	}
+}
+
+func TestRunWalksTreeOutsideGit(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a.md", "fine\n")
+	writeFile(t, dir, "src/b.go", "x {>> todo <<}\n")
+	writeFile(t, dir, ".fabbro/sessions/s.fem", "x {>> ok <<}\n")
+	writeFile(t, dir, "bin/blob", "\x00{>> binary <<}")
+	writeFile(t, dir, "docs/fem.md", "{== example ==}\n")
+
+	res, err := (&Scanner{Dir: dir, Allow: []string{"docs/*.md"}}).Run(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths(res) != "src/b.go" {
+		t.Errorf("expected only src/b.go, got %q", paths(res))
+	}
+}
+
+func TestRunAllowRelativeToBase(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "docs/fem.md", "{== example ==}\n")
+	writeFile(t, dir, "tools/review/docs/fem.md", "{== example ==}\n")
+
+	res, err := (&Scanner{Dir: dir, Allow: []string{"docs/*.md"}, AllowBase: "tools/review"}).Run(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths(res) != "docs/fem.md" {
+		t.Errorf("expected only the top-level docs/fem.md, got %q", paths(res))
+	}
+}
+
+func TestRunRespectsGitignore(t *testing.T) {
+	dir := initRepo(t)
+	writeFile(t, dir, ".gitignore", "build/\n")
+	writeFile(t, dir, "build/out.txt", "{++ -> x ++}\n")
+	writeFile(t, dir, "tracked.go", "a {?? why ??}\n")
+	writeFile(t, dir, "untracked.md", "b {~~ hm ~~}\n")
+	runGit(t, dir, "add", ".gitignore", "tracked.go")
+
+	res, err := (&Scanner{Dir: dir}).Run(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths(res) != "tracked.go,untracked.md" {
+		t.Errorf("expected tracked and untracked files only, got %q", paths(res))
+	}
+
+	res, err = (&Scanner{Dir: dir}).Run([]string{"tracked.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths(res) != "tracked.go" || res.Files != 1 {
+		t.Errorf("expected only the given path, got %q (%d files)", paths(res), res.Files)
+	}
+}
+
+func TestRunStagedReadsIndex(t *testing.T) {
+	dir := initRepo(t)
+	writeFile(t, dir, "a.go", "x {-- leaked --}\n")
+	writeFile(t, dir, "b.go", "y {-- unstaged --}\n")
+	runGit(t, dir, "add", "a.go")
+	writeFile(t, dir, "a.go", "x\n") // fixed in the working tree, not staged
+
+	res, err := (&Scanner{Dir: dir, Staged: true}).Run(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths(res) != "a.go" || res.Findings[0].Marker != "{-- leaked --}" {
+		t.Errorf("expected the staged marker only, got %+v", res.Findings)
+	}
+}
+
+func TestInstallHook(t *testing.T) {
+	dir := initRepo(t)
+
+	path, err := InstallHook(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "fabbro scan --staged") {
+		t.Errorf("unexpected hook:\n%s", data)
+	}
+	if strings.Contains(string(data), "cd ") {
+		t.Errorf("expected no cd at the top level:\n%s", data)
+	}
+	if _, err := InstallHook(dir, false); err != nil {
+		t.Errorf("expected reinstalling our own hook to succeed, got %v", err)
+	}
+
+	os.WriteFile(path, []byte("#!/bin/sh\nlefthook run pre-commit\n"), 0755)
+	if _, err := InstallHook(dir, false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("expected a foreign hook to be kept, got %v", err)
+	}
+	if _, err := UninstallHook(dir); err == nil {
+		t.Error("expected uninstall to keep a foreign hook")
+	}
+	if _, err := InstallHook(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UninstallHook(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected hook to be removed")
+	}
+}
+
+func TestInstallHookInNestedProject(t *testing.T) {
+	dir := initRepo(t)
+	nested := filepath.Join(dir, "tools", "review")
+	os.MkdirAll(nested, 0755)
+
+	if got := RepoPath(nested); got != "tools/review" {
+		t.Errorf("RepoPath() = %q, want tools/review", got)
+	}
+	path, err := InstallHook(nested, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "cd 'tools/review' || exit 1\nexec fabbro scan --staged") {
+		t.Errorf("expected the hook to change to the project:\n%s", data)
+	}
+}
@@ -0,0 +1,83 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fuzzy"
+)
+
+// Matcher matches a compiled pattern and finds where it matches.
+type Matcher struct {
+	mode    Mode
+	pattern string         // fuzzy pattern, lower-cased when ignoring case
+	fold    bool           // ignore case
+	re      *regexp.Regexp // literal and regex patterns
+}
+
+// Compile prepares pattern for matching in mode. ignoreCase folds case for
+// every mode, regular expressions included.
+func Compile(pattern string, mode Mode, ignoreCase bool) (*Matcher, error) {
+	m := &Matcher{mode: mode, pattern: pattern, fold: ignoreCase}
+	switch mode {
+	case ModeFuzzy:
+		if ignoreCase {
+			m.pattern = strings.ToLower(pattern)
+		}
+		return m, nil
+	case ModeRegex, ModeLiteral, "":
+		expr := pattern
+		if mode != ModeRegex {
+			expr = regexp.QuoteMeta(pattern)
+		}
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		m.re = re
+		return m, nil
+	}
+	return nil, fmt.Errorf("invalid search mode %q", mode)
+}
+
+// Match reports whether s matches.
+func (m *Matcher) Match(s string) bool {
+	if m.re != nil {
+		return m.re.MatchString(s)
+	}
+	if m.fold {
+		s = strings.ToLower(s)
+	}
+	return fuzzy.Match(s, m.pattern)
+}
+
+// Spans returns the byte ranges of s that match, in order: every match of
+// a literal or regular expression, or each matched character of a fuzzy
+// pattern. Empty matches are left out.
+func (m *Matcher) Spans(s string) [][2]int {
+	var spans [][2]int
+	if m.re != nil {
+		for _, loc := range m.re.FindAllStringIndex(s, -1) {
+			if loc[0] < loc[1] {
+				spans = append(spans, [2]int{loc[0], loc[1]})
+			}
+		}
+		return spans
+	}
+	text := s
+	if m.fold {
+		text = strings.ToLower(s)
+	}
+	if len(text) != len(s) {
+		// Lower-casing changed byte offsets; there is nothing safe to mark.
+		return nil
+	}
+	for _, pos := range fuzzy.Positions(text, m.pattern) {
+		spans = append(spans, [2]int{pos, pos + 1})
+	}
+	return spans
+}
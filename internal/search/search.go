@@ -0,0 +1,132 @@
+// Package search finds text across review sessions: in annotation text and
+// in the reviewed content itself.
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+// Mode selects how a query pattern is matched.
+type Mode string
+
+const (
+	ModeLiteral Mode = "literal" // case-insensitive substring
+	ModeFuzzy   Mode = "fuzzy"   // case-insensitive subsequence, as in the TUI's / search
+	ModeRegex   Mode = "regex"   // Go regular expression
+)
+
+// ParseMode parses a --mode value.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(s)); m {
+	case ModeLiteral, ModeFuzzy, ModeRegex:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid search mode %q: must be literal, fuzzy, or regex", s)
+}
+
+// Hit kinds.
+const (
+	KindAnnotation = "annotation"
+	KindContent    = "content"
+)
+
+// Hit is one match. Lines are 1-based lines of the session's content.
+type Hit struct {
+	SessionID  string `json:"sessionId"`
+	SourceFile string `json:"sourceFile,omitempty"`
+	Kind       string `json:"kind"`
+	Type       string `json:"type,omitempty"` // annotation type, for annotation hits
+	StartLine  int    `json:"startLine"`
+	EndLine    int    `json:"endLine"`
+	Text       string `json:"text"`
+}
+
+// Query is a search with its filters. Zero-valued filters match everything.
+type Query struct {
+	Pattern string
+	Mode    Mode
+
+	// Types limits hits to annotations of these types; content is then not
+	// searched.
+	Types []string
+	// Filter selects the sessions searched.
+	session.Filter
+	// Annotations and Content select what is searched; both when neither is set.
+	Annotations, Content bool
+}
+
+// Run searches sessions and returns hits ordered by session (newest first),
+// then line, annotations before content.
+func Run(sessions []*session.Session, q Query) ([]Hit, error) {
+	match, err := matcher(q.Pattern, q.Mode)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]bool, len(q.Types))
+	for _, t := range q.Types {
+		types[t] = true
+	}
+	inAnnotations := q.Annotations || !q.Content
+	inContent := (q.Content || !q.Annotations) && len(types) == 0
+
+	sorted := append([]*session.Session(nil), sessions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CreatedAt.After(sorted[j].CreatedAt) })
+
+	hits := []Hit{}
+	for _, sess := range sorted {
+		if !q.Match(sess) {
+			continue
+		}
+		annotations, clean, err := fem.Parse(sess.Content)
+		if err != nil {
+			// A malformed session still has searchable content.
+			clean = sess.Content
+		}
+
+		var found []Hit
+		if inAnnotations {
+			for _, a := range fem.MergeAnnotations(nil, annotations) {
+				if len(types) > 0 && !types[a.Type] {
+					continue
+				}
+				if match(a.Text) {
+					found = append(found, Hit{Kind: KindAnnotation, Type: a.Type, StartLine: a.StartLine, EndLine: a.EndLine, Text: a.Text})
+				}
+			}
+		}
+		if inContent {
+			for i, line := range strings.Split(strings.TrimSuffix(clean, "\n"), "\n") {
+				if strings.TrimSpace(line) != "" && match(line) {
+					found = append(found, Hit{Kind: KindContent, StartLine: i + 1, EndLine: i + 1, Text: strings.TrimSpace(line)})
+				}
+			}
+		}
+		sort.SliceStable(found, func(i, j int) bool {
+			if found[i].StartLine != found[j].StartLine {
+				return found[i].StartLine < found[j].StartLine
+			}
+			return found[i].Kind == KindAnnotation && found[j].Kind != KindAnnotation
+		})
+		for _, h := range found {
+			h.SessionID = sess.ID
+			h.SourceFile = sess.SourceFile
+			hits = append(hits, h)
+		}
+	}
+	return hits, nil
+}
+
+// matcher matches like the CLI always has: literal and fuzzy patterns
+// ignore case, regular expressions don't.
+func matcher(pattern string, mode Mode) (func(string) bool, error) {
+	m, err := Compile(pattern, mode, mode != ModeRegex)
+	if err != nil {
+		return nil, err
+	}
+	return m.Match, nil
+}
@@ -0,0 +1,134 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+func testSessions() []*session.Session {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC) }
+	return []*session.Session{
+		{
+			ID: "older", SourceFile: "internal/client/http.go", CreatedAt: day(1), Status: session.StatusApplied,
+			Content: "func get() {\n\tretry(3) {>> retry logic should back off <<}\n}\n",
+		},
+		{
+			ID: "newer", SourceFile: "docs/plan.md", CreatedAt: day(10), Status: session.StatusInProgress,
+			Content: "# Plan\nNo retry here. {?? why not retry? ??}\n",
+		},
+	}
+}
+
+func TestRunFindsAnnotationsAndContent(t *testing.T) {
+	hits, err := Run(testSessions(), Query{Pattern: "RETRY"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		id, kind string
+		line     int
+	}{
+		{"newer", KindAnnotation, 2},
+		{"newer", KindContent, 2},
+		{"older", KindAnnotation, 2},
+		{"older", KindContent, 2},
+	}
+	if len(hits) != len(want) {
+		t.Fatalf("got %d hits, want %d: %+v", len(hits), len(want), hits)
+	}
+	for i, w := range want {
+		if hits[i].SessionID != w.id || hits[i].Kind != w.kind || hits[i].StartLine != w.line {
+			t.Errorf("hit %d = %+v, want %+v", i, hits[i], w)
+		}
+	}
+	if hits[2].Type != "comment" || hits[2].Text != "retry logic should back off" || hits[2].SourceFile != "internal/client/http.go" {
+		t.Errorf("unexpected annotation hit %+v", hits[2])
+	}
+	if hits[3].Text != "retry(3)" {
+		t.Errorf("expected the clean content line, got %q", hits[3].Text)
+	}
+}
+
+func TestRunFilters(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		want  []string // session IDs of hits
+	}{
+		{"type", Query{Pattern: "retry", Types: []string{"question"}}, []string{"newer"}},
+		{"source glob", Query{Pattern: "retry", Filter: session.Filter{Source: "internal/**/*.go"}, Annotations: true}, []string{"older"}},
+		{"since", Query{Pattern: "retry", Filter: session.Filter{Since: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)}, Content: true}, []string{"newer"}},
+		{"until", Query{Pattern: "retry", Filter: session.Filter{Until: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)}, Annotations: true}, []string{"older"}},
+		{"status", Query{Pattern: "retry", Filter: session.Filter{Statuses: map[session.Status]bool{session.StatusApplied: true}}, Annotations: true}, []string{"older"}},
+		{"fuzzy", Query{Pattern: "rlsbo", Mode: ModeFuzzy}, []string{"older"}},
+		{"regex", Query{Pattern: `retry\(\d\)`, Mode: ModeRegex}, []string{"older"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := Run(testSessions(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, h := range hits {
+				got = append(got, h.SessionID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunInvalidRegex(t *testing.T) {
+	if _, err := Run(testSessions(), Query{Pattern: "(", Mode: ModeRegex}); err == nil {
+		t.Error("expected an error for an invalid regex")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if m, err := ParseMode("Fuzzy"); err != nil || m != ModeFuzzy {
+		t.Errorf("ParseMode(Fuzzy) = %q, %v", m, err)
+	}
+	if _, err := ParseMode("glob"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestMatcherSpans(t *testing.T) {
+	cases := []struct {
+		pattern    string
+		mode       Mode
+		ignoreCase bool
+		text       string
+		want       [][2]int
+	}{
+		{"todo", ModeLiteral, true, "TODO: fix todo", [][2]int{{0, 4}, {10, 14}}},
+		{"todo", ModeLiteral, false, "TODO: fix todo", [][2]int{{10, 14}}},
+		{"a.c", ModeLiteral, true, "abc a.c", [][2]int{{4, 7}}},
+		{`\d+`, ModeRegex, false, "v1.23", [][2]int{{1, 2}, {3, 5}}},
+		{"^x*", ModeRegex, false, "abc", nil}, // matches, but only empty text
+		{"fb", ModeFuzzy, true, "FooBar", [][2]int{{0, 1}, {3, 4}}},
+		{"fb", ModeFuzzy, false, "FooBar", nil},
+	}
+	for _, c := range cases {
+		m, err := Compile(c.pattern, c.mode, c.ignoreCase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Spans(c.text); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s %q (ignore case %v) in %q: got %v, want %v", c.mode, c.pattern, c.ignoreCase, c.text, got, c.want)
+		}
+	}
+	if _, err := Compile("(", ModeRegex, true); err == nil {
+		t.Error("expected an invalid regular expression error")
+	}
+}
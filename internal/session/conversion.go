@@ -0,0 +1,110 @@
+package session
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Conversion records that a session's content was converted from another
+// format, and where each line of the content came from.
+type Conversion struct {
+	From string // source format, e.g. "html"
+	// LineMap[i] is the line of the source file that content line i+1 was
+	// converted from, or 0 when unknown. It is empty for sources without
+	// lines, such as .docx files.
+	LineMap []int
+}
+
+// SourceLines maps a range of content lines to the range of source lines
+// they were converted from. ok is false when the range isn't mapped.
+func (c *Conversion) SourceLines(start, end int) (from, to int, ok bool) {
+	for line := start; line <= end; line++ {
+		if line < 1 || line > len(c.LineMap) || c.LineMap[line-1] == 0 {
+			continue
+		}
+		src := c.LineMap[line-1]
+		if from == 0 || src < from {
+			from = src
+		}
+		if src > to {
+			to = src
+		}
+	}
+	return from, to, from != 0
+}
+
+// FormatLineMap encodes a line map compactly for session frontmatter:
+// comma-separated entries where "a-b" is the ascending run a..b and "a*n"
+// is a repeated n times.
+func FormatLineMap(lines []int) string {
+	var parts []string
+	for i := 0; i < len(lines); {
+		j := i + 1
+		for j < len(lines) && lines[j] == lines[i] {
+			j++
+		}
+		if j-i > 1 {
+			parts = append(parts, fmt.Sprintf("%d*%d", lines[i], j-i))
+			i = j
+			continue
+		}
+		for j < len(lines) && lines[j] == lines[j-1]+1 && (j+1 == len(lines) || lines[j+1] != lines[j]) {
+			j++
+		}
+		if j-i > 1 {
+			parts = append(parts, fmt.Sprintf("%d-%d", lines[i], lines[j-1]))
+		} else {
+			parts = append(parts, strconv.Itoa(lines[i]))
+		}
+		i = j
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseLineMap decodes a line map written by FormatLineMap.
+func ParseLineMap(s string) ([]int, error) {
+	var lines []int
+	if s == "" {
+		return lines, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		if a, n, ok := strings.Cut(part, "*"); ok {
+			v, err1 := strconv.Atoi(a)
+			count, err2 := strconv.Atoi(n)
+			if err1 != nil || err2 != nil || count < 1 {
+				return nil, fmt.Errorf("malformed line_map entry %q", part)
+			}
+			for k := 0; k < count; k++ {
+				lines = append(lines, v)
+			}
+			continue
+		}
+		if a, b, ok := strings.Cut(part, "-"); ok {
+			from, err1 := strconv.Atoi(a)
+			to, err2 := strconv.Atoi(b)
+			if err1 != nil || err2 != nil || to < from {
+				return nil, fmt.Errorf("malformed line_map entry %q", part)
+			}
+			for v := from; v <= to; v++ {
+				lines = append(lines, v)
+			}
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("malformed line_map entry %q", part)
+		}
+		lines = append(lines, v)
+	}
+	return lines, nil
+}
+
+// SourceLines maps a range of content lines to lines of the source file:
+// through the line map for converted sessions, unchanged otherwise.
+func (s *Session) SourceLines(start, end int) (from, to int, ok bool) {
+	if s.Conversion == nil {
+		return start, end, true
+	}
+	return s.Conversion.SourceLines(start, end)
+}
@@ -0,0 +1,102 @@
+package session
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+)
+
+func TestLineMapRoundTrip(t *testing.T) {
+	tests := []struct {
+		lines []int
+		want  string
+	}{
+		{[]int{1, 2, 3, 4}, "1-4"},
+		{[]int{1, 1, 1, 5, 7, 8, 9, 9}, "1*3,5,7-8,9*2"},
+		{[]int{0, 0, 3}, "0*2,3"},
+		{[]int{4}, "4"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		got := FormatLineMap(tt.lines)
+		if got != tt.want {
+			t.Errorf("FormatLineMap(%v) = %q, want %q", tt.lines, got, tt.want)
+		}
+		back, err := ParseLineMap(got)
+		if err != nil {
+			t.Fatalf("ParseLineMap(%q): %v", got, err)
+		}
+		if len(back) != len(tt.lines) || (len(back) > 0 && !reflect.DeepEqual(back, tt.lines)) {
+			t.Errorf("ParseLineMap(%q) = %v, want %v", got, back, tt.lines)
+		}
+	}
+
+	for _, bad := range []string{"x", "3-1", "2*0", "1,,2"} {
+		if _, err := ParseLineMap(bad); err == nil {
+			t.Errorf("ParseLineMap(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestSourceLines(t *testing.T) {
+	c := &Conversion{From: "html", LineMap: []int{3, 3, 0, 7, 9}}
+	if from, to, ok := c.SourceLines(1, 2); !ok || from != 3 || to != 3 {
+		t.Errorf("SourceLines(1, 2) = %d, %d, %v", from, to, ok)
+	}
+	if from, to, ok := c.SourceLines(2, 5); !ok || from != 3 || to != 9 {
+		t.Errorf("SourceLines(2, 5) = %d, %d, %v", from, to, ok)
+	}
+	if _, _, ok := c.SourceLines(3, 3); ok {
+		t.Error("expected an unmapped line to report !ok")
+	}
+	if _, _, ok := c.SourceLines(10, 12); ok {
+		t.Error("expected lines past the map to report !ok")
+	}
+}
+
+func TestCreateConverted(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	config.Init()
+
+	original := "<h1>Title</h1>\n<p>Hello</p>\n"
+	os.WriteFile("page.html", []byte(original), 0644)
+	conv := &Conversion{From: "html", LineMap: []int{1, 0, 2}}
+	sess, err := CreateConverted("", "# Title\n\nHello\n", "page.html", original, conv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Conversion == nil || loaded.Conversion.From != "html" || !reflect.DeepEqual(loaded.Conversion.LineMap, conv.LineMap) {
+		t.Errorf("conversion not round-tripped: %+v", loaded.Conversion)
+	}
+	if loaded.Content != "# Title\n\nHello\n" {
+		t.Errorf("unexpected content %q", loaded.Content)
+	}
+	if valid, err := loaded.VerifySourceHash(); err != nil || !valid {
+		t.Errorf("expected the hash of the original document to verify, got %v, %v", valid, err)
+	}
+
+	if _, err := CreateConverted("bad id!", "x", "page.html", original, conv); err == nil {
+		t.Error("expected an invalid ID to be rejected")
+	}
+	docx, err := CreateConverted("spec", "# Spec\n", "spec.docx", "PK\x03\x04", &Conversion{From: "docx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(Format(docx), "line_map") {
+		t.Error("expected no line_map for a conversion without lines")
+	}
+	if loaded, err := Load("spec"); err != nil || loaded.Conversion == nil || loaded.Conversion.From != "docx" || len(loaded.Conversion.LineMap) != 0 {
+		t.Errorf("unexpected docx conversion %+v, %v", loaded, err)
+	}
+}
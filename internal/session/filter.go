@@ -0,0 +1,36 @@
+package session
+
+import (
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/glob"
+)
+
+// Filter selects sessions by source file, creation time and status, as the
+// filter flags of fabbro search and fabbro stats do. Zero-valued fields
+// match every session.
+type Filter struct {
+	// Source is a glob pattern the session's source file must match.
+	Source string
+	// Since and Until bound the session's creation time (inclusive).
+	Since, Until time.Time
+	// Statuses limits the statuses matched.
+	Statuses map[Status]bool
+}
+
+// Match reports whether s passes every filter.
+func (f Filter) Match(s *Session) bool {
+	if len(f.Statuses) > 0 && !f.Statuses[s.Status] {
+		return false
+	}
+	if f.Source != "" && !glob.Match(f.Source, s.SourceFile) {
+		return false
+	}
+	if !f.Since.IsZero() && s.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && s.CreatedAt.After(f.Until) {
+		return false
+	}
+	return true
+}
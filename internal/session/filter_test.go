@@ -0,0 +1,31 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilterMatch(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC) }
+	s := &Session{SourceFile: "internal/client/http.go", CreatedAt: day(5), Status: StatusReady}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"source", Filter{Source: "internal/**/*.go"}, true},
+		{"other source", Filter{Source: "docs/*"}, false},
+		{"since", Filter{Since: day(5)}, true},
+		{"after since", Filter{Since: day(6)}, false},
+		{"until", Filter{Until: day(4)}, false},
+		{"status", Filter{Statuses: map[Status]bool{StatusReady: true}}, true},
+		{"other status", Filter{Statuses: map[Status]bool{StatusApplied: true}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(s); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
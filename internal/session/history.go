@@ -0,0 +1,172 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+	"github.com/charly-vibes/fabbro/internal/fem"
+)
+
+// Snapshot is a saved revision of a session's content and annotations.
+type Snapshot struct {
+	Revision    int              `json:"revision"`
+	SavedAt     time.Time        `json:"savedAt"`
+	Author      string           `json:"author,omitempty"`
+	Content     string           `json:"content"`
+	Annotations []fem.Annotation `json:"annotations"`
+}
+
+// historyDir returns the directory holding snapshots for a session, which
+// lives next to the session file as <id>.history/.
+func historyDir(id string) (string, error) {
+	sessionsDir, err := config.GetSessionsDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find project root: %w", err)
+	}
+	return historyDirIn(sessionsDir, id), nil
+}
+
+func historyDirIn(sessionsDir, id string) string {
+	return filepath.Join(sessionsDir, id+".history")
+}
+
+func snapshotName(rev int) string {
+	return fmt.Sprintf("%06d.json", rev)
+}
+
+// revisions returns the snapshot revision numbers for a session, ascending.
+func revisions(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	var revs []int
+	for _, entry := range entries {
+		rev, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		revs = append(revs, rev)
+	}
+	sort.Ints(revs)
+	return revs, nil
+}
+
+// recordSnapshot stores sess as the next revision in dir and prunes old
+// revisions beyond retention (negative for unlimited).
+func recordSnapshot(dir string, retention int, sess *Session) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	revs, err := revisions(dir)
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(revs) > 0 {
+		next = revs[len(revs)-1] + 1
+	}
+
+	annotations, _, err := fem.Parse(sess.Content)
+	if err != nil {
+		annotations = nil
+	}
+	snap := Snapshot{
+		Revision:    next,
+		SavedAt:     time.Now().UTC(),
+		Author:      config.Author(),
+		Content:     sess.Content,
+		Annotations: annotations,
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, snapshotName(next)), data, 0600); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return prune(dir, append(revs, next), retention)
+}
+
+// prune deletes the oldest snapshots so at most keep remain. A negative
+// keep disables pruning.
+func prune(dir string, revs []int, keep int) error {
+	if keep < 0 || len(revs) <= keep {
+		return nil
+	}
+	for _, rev := range revs[:len(revs)-keep] {
+		if err := os.Remove(filepath.Join(dir, snapshotName(rev))); err != nil {
+			return fmt.Errorf("failed to prune snapshot %d: %w", rev, err)
+		}
+	}
+	return nil
+}
+
+// History returns all retained snapshots of a session, oldest first.
+func History(id string) ([]*Snapshot, error) {
+	dir, err := historyDir(id)
+	if err != nil {
+		return nil, err
+	}
+	revs, err := revisions(dir)
+	if err != nil {
+		return nil, err
+	}
+	snaps := make([]*Snapshot, 0, len(revs))
+	for _, rev := range revs {
+		snap, err := LoadSnapshot(id, rev)
+		if err != nil {
+			return nil, err
+		}
+		snaps = append(snaps, snap)
+	}
+	return snaps, nil
+}
+
+// LoadSnapshot reads one revision of a session.
+func LoadSnapshot(id string, rev int) (*Snapshot, error) {
+	dir, err := historyDir(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, snapshotName(rev)))
+	if os.IsNotExist(err) {
+		return nil, notFoundf("session %s has no revision %d", id, rev)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %d: %w", rev, err)
+	}
+	return &snap, nil
+}
+
+// Restore replaces the session's content with the given revision. The
+// restore is itself saved as a new revision, so it can be undone.
+func Restore(id string, rev int) (*Session, error) {
+	sess, err := Load(id)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := LoadSnapshot(id, rev)
+	if err != nil {
+		return nil, err
+	}
+	sess.Content = snap.Content
+	if err := Save(sess); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
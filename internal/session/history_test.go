@@ -0,0 +1,165 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+)
+
+func TestSave_RecordsSnapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	t.Setenv("FABBRO_AUTHOR", "alice")
+
+	config.Init()
+	sess, _ := Create("line1\nline2", "")
+
+	sess.Content = "line1 {>> first <<}\nline2"
+	if err := Save(sess); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	sess.Content = "line1 {>> first <<}\nline2 {?? second ??}"
+	if err := Save(sess); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	snaps, err := History(sess.ID)
+	if err != nil {
+		t.Fatalf("History() error: %v", err)
+	}
+	if len(snaps) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(snaps))
+	}
+	if snaps[0].Revision != 1 || snaps[1].Revision != 2 {
+		t.Errorf("unexpected revisions %d, %d", snaps[0].Revision, snaps[1].Revision)
+	}
+	if snaps[1].Author != "alice" {
+		t.Errorf("expected author alice, got %q", snaps[1].Author)
+	}
+	if len(snaps[1].Annotations) != 2 {
+		t.Errorf("expected 2 annotations in latest snapshot, got %d", len(snaps[1].Annotations))
+	}
+	if snaps[1].SavedAt.IsZero() {
+		t.Error("expected SavedAt to be set")
+	}
+}
+
+func TestSave_PrunesToRetention(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	os.WriteFile(config.SettingsFile, []byte(`{"history": {"retention": 2}}`), 0600)
+	sess, _ := Create("content", "")
+
+	for i := 0; i < 4; i++ {
+		if err := Save(sess); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+	}
+
+	snaps, _ := History(sess.ID)
+	if len(snaps) != 2 {
+		t.Fatalf("expected 2 retained snapshots, got %d", len(snaps))
+	}
+	if snaps[0].Revision != 3 || snaps[1].Revision != 4 {
+		t.Errorf("expected revisions 3 and 4 to be kept, got %d and %d", snaps[0].Revision, snaps[1].Revision)
+	}
+}
+
+func TestSaveIn_UsesTargetProjectRetention(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	// The current project keeps one snapshot; the other project keeps two.
+	config.Init()
+	os.WriteFile(config.SettingsFile, []byte(`{"history": {"retention": 1}}`), 0600)
+	other := t.TempDir()
+	sessionsDir := filepath.Join(other, config.SessionsDir)
+	os.MkdirAll(sessionsDir, 0700)
+	os.WriteFile(filepath.Join(other, config.SettingsFile), []byte(`{"history": {"retention": 2}}`), 0600)
+
+	sess := &Session{ID: "synced", Content: "content", CreatedAt: time.Now().UTC()}
+	for i := 0; i < 3; i++ {
+		if err := SaveIn(sessionsDir, sess); err != nil {
+			t.Fatalf("SaveIn() error: %v", err)
+		}
+	}
+
+	snaps, _ := os.ReadDir(filepath.Join(sessionsDir, "synced.history"))
+	if len(snaps) != 2 {
+		t.Errorf("expected 2 retained snapshots, got %d", len(snaps))
+	}
+}
+
+func TestRestore_RevertsContentAndRecordsNewRevision(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := Create("text", "")
+	sess.Content = "text {>> keep me <<}"
+	Save(sess)
+	sess.Content = "text {-- agent overwrote --}"
+	Save(sess)
+
+	restored, err := Restore(sess.ID, 1)
+	if err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if restored.Content != "text {>> keep me <<}" {
+		t.Errorf("unexpected restored content %q", restored.Content)
+	}
+
+	loaded, _ := Load(sess.ID)
+	if loaded.Content != "text {>> keep me <<}" {
+		t.Errorf("restore not persisted, got %q", loaded.Content)
+	}
+	snaps, _ := History(sess.ID)
+	if len(snaps) != 3 {
+		t.Errorf("expected restore to add a revision, got %d snapshots", len(snaps))
+	}
+}
+
+func TestLoadSnapshot_MissingRevision(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := Create("text", "")
+
+	if _, err := LoadSnapshot(sess.ID, 7); err == nil {
+		t.Error("expected error for missing revision")
+	}
+}
+
+func TestDelete_RemovesHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := Create("text", "")
+	Save(sess)
+
+	if err := Delete(sess.ID); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(config.SessionsDir, sess.ID+".history")); !os.IsNotExist(err) {
+		t.Error("expected history directory to be removed")
+	}
+}
@@ -0,0 +1,130 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+)
+
+// MergeResult describes the session produced by Merge.
+type MergeResult struct {
+	Session    *Session
+	Sources    []string // IDs of the merged sessions, in order
+	Duplicates int      // annotations also made in an earlier session, folded into one
+	Conflicts  []fem.Conflict
+}
+
+// Merge combines the annotations of sessions that reviewed the same content
+// into a new session. Each annotation is attributed to the reviewer of the
+// session it came from, identical annotations are kept once, and every pair
+// of overlapping change annotations with different replacements is flagged
+// with an unclear annotation. A line is reviewed in the merged session if
+// any reviewer marked it.
+func Merge(sessions []*Session) (*MergeResult, error) {
+	if len(sessions) < 2 {
+		return nil, fmt.Errorf("need at least two sessions to merge")
+	}
+
+	first := sessions[0]
+	_, baseContent, err := fem.Parse(first.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", first.ID, err)
+	}
+
+	result := &MergeResult{}
+	var merged []fem.Annotation
+	reviewed := map[int]bool{}
+	total := 0
+	for _, sess := range sessions {
+		if sess.SourceFile != first.SourceFile {
+			return nil, fmt.Errorf("cannot merge %s (%s) with %s (%s): different source files",
+				sess.ID, describeSource(sess), first.ID, describeSource(first))
+		}
+		if sess.ContentHash != "" && first.ContentHash != "" && sess.ContentHash != first.ContentHash {
+			return nil, fmt.Errorf("cannot merge %s with %s: they reviewed different versions of the content", sess.ID, first.ID)
+		}
+
+		annotations, clean, err := fem.Parse(sess.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse session %s: %w", sess.ID, err)
+		}
+		if !SameContent(clean, baseContent) {
+			return nil, fmt.Errorf("cannot merge %s with %s: reviewed content differs", sess.ID, first.ID)
+		}
+
+		reviewer := sess.Author
+		if reviewer == "" {
+			reviewer = sess.ID
+		}
+		for i := range annotations {
+			if annotations[i].Author == "" {
+				annotations[i].Author = reviewer
+			}
+		}
+		// A session's own repeated copies are not duplicates between
+		// reviewers, so count each distinct annotation once per session.
+		total += len(fem.MergeAnnotations(nil, annotations))
+		merged = fem.MergeAnnotations(merged, annotations)
+		for _, line := range sess.Reviewed {
+			reviewed[line] = true
+		}
+		result.Sources = append(result.Sources, sess.ID)
+	}
+	result.Duplicates = total - len(merged)
+
+	result.Conflicts = fem.FindChangeConflicts(merged)
+	for _, c := range result.Conflicts {
+		start, end := c.A.StartLine, c.A.EndLine
+		if c.B.StartLine < start {
+			start = c.B.StartLine
+		}
+		if c.B.EndLine > end {
+			end = c.B.EndLine
+		}
+		merged = append(merged, fem.Annotation{
+			Type:      "unclear",
+			Text:      fmt.Sprintf("CONFLICT: %s and %s propose different changes here", c.A.Author, c.B.Author),
+			StartLine: start,
+			EndLine:   end,
+		})
+	}
+
+	sess, sessionPath, err := newSession("", baseContent, first.SourceFile, "", first.Conversion)
+	if err != nil {
+		return nil, err
+	}
+	sess.ContentHash = first.ContentHash
+	for line := range reviewed {
+		sess.Reviewed = append(sess.Reviewed, line)
+	}
+	sort.Ints(sess.Reviewed)
+	if _, err := writeSession(sess, sessionPath, fem.Render(baseContent, merged)); err != nil {
+		return nil, err
+	}
+	result.Session = sess
+	return result, nil
+}
+
+func describeSource(sess *Session) string {
+	if sess.SourceFile == "" {
+		return "stdin"
+	}
+	return sess.SourceFile
+}
+
+// SameContent reports whether two clean session bodies hold the same text,
+// ignoring trailing whitespace, such as the spaces hand-written markers or
+// sessions saved by older versions leave where markers were.
+func SameContent(a, b string) bool {
+	return trimLines(a) == trimLines(b)
+}
+
+func trimLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
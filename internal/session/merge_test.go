@@ -0,0 +1,170 @@
+package session
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+	"github.com/charly-vibes/fabbro/internal/fem"
+)
+
+// reviewAs creates a session for content as the given reviewer and saves it
+// with the annotated body.
+func reviewAs(t *testing.T, author, content, annotated, source string) *Session {
+	t.Helper()
+	t.Setenv("FABBRO_AUTHOR", author)
+	sess, err := Create(content, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess.Content = annotated
+	if err := Save(sess); err != nil {
+		t.Fatal(err)
+	}
+	return sess
+}
+
+func TestMerge_CombinesAndAttributesAnnotations(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	config.Init()
+
+	content := "one\ntwo\nthree"
+	a := reviewAs(t, "alice", content, "one {>> shared <<}\ntwo {?? alice asks ??}\nthree", "plan.md")
+	b := reviewAs(t, "bob", content, "one {>> shared <<}\ntwo\nthree {-- bob cuts --}", "plan.md")
+
+	res, err := Merge([]*Session{a, b})
+	if err != nil {
+		t.Fatalf("Merge() error: %v", err)
+	}
+	if res.Duplicates != 1 {
+		t.Errorf("expected 1 duplicate, got %d", res.Duplicates)
+	}
+
+	loaded, err := Load(res.Session.ID)
+	if err != nil {
+		t.Fatalf("merged session not saved: %v", err)
+	}
+	if loaded.SourceFile != "plan.md" || loaded.ContentHash != a.ContentHash {
+		t.Errorf("merged session should keep source and hash, got %q %q", loaded.SourceFile, loaded.ContentHash)
+	}
+
+	anns, _, _ := fem.Parse(loaded.Content)
+	authors := map[string]string{}
+	for _, ann := range anns {
+		authors[ann.Text] = ann.Author
+	}
+	want := map[string]string{"shared": "alice, bob", "alice asks": "alice", "bob cuts": "bob"}
+	for text, author := range want {
+		if authors[text] != author {
+			t.Errorf("annotation %q: author %q, want %q", text, authors[text], author)
+		}
+	}
+	if len(anns) != 3 {
+		t.Errorf("expected 3 merged annotations, got %+v", anns)
+	}
+}
+
+func TestMerge_CountsOnlyDuplicatesAcrossSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	config.Init()
+
+	content := "one\ntwo"
+	a := reviewAs(t, "alice", content, "one {>> again <<} {>> again <<}\ntwo", "plan.md")
+	b := reviewAs(t, "bob", content, "one\ntwo {?? bob asks ??}", "plan.md")
+
+	res, err := Merge([]*Session{a, b})
+	if err != nil {
+		t.Fatalf("Merge() error: %v", err)
+	}
+	if res.Duplicates != 0 {
+		t.Errorf("repeats within one session are not duplicates, got %d", res.Duplicates)
+	}
+
+	history, err := History(res.Session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 0 {
+		t.Errorf("merged session should be written once without snapshots, got %d", len(history))
+	}
+}
+
+func TestMerge_FlagsConflictingChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	config.Init()
+
+	content := "one\ntwo"
+	a := reviewAs(t, "alice", content, "one {++ -> uno ++}\ntwo", "")
+	b := reviewAs(t, "bob", content, "one {++ -> eins ++}\ntwo", "")
+
+	res, err := Merge([]*Session{a, b})
+	if err != nil {
+		t.Fatalf("Merge() error: %v", err)
+	}
+	if len(res.Conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %+v", res.Conflicts)
+	}
+
+	loaded, _ := Load(res.Session.ID)
+	if !strings.Contains(loaded.Content, "{~~ CONFLICT: alice and bob propose different changes here ~~}") {
+		t.Errorf("expected conflict flag in merged session, got %q", loaded.Content)
+	}
+}
+
+func TestMerge_RejectsDifferentSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	config.Init()
+
+	a := reviewAs(t, "alice", "text", "text", "a.md")
+	b := reviewAs(t, "bob", "text", "text", "b.md")
+
+	if _, err := Merge([]*Session{a, b}); err == nil || !strings.Contains(err.Error(), "different source files") {
+		t.Errorf("expected different source files error, got %v", err)
+	}
+}
+
+func TestMerge_RejectsDifferentContent(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	config.Init()
+
+	a := reviewAs(t, "alice", "version one", "version one", "a.md")
+	b := reviewAs(t, "bob", "version two", "version two", "a.md")
+
+	if _, err := Merge([]*Session{a, b}); err == nil || !strings.Contains(err.Error(), "different versions") {
+		t.Errorf("expected different versions error, got %v", err)
+	}
+}
+
+func TestCreate_RecordsAuthor(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	config.Init()
+	t.Setenv("FABBRO_AUTHOR", "O'Brien")
+
+	sess, _ := Create("text", "")
+	loaded, err := Load(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Author != "O'Brien" {
+		t.Errorf("expected author to round trip, got %q", loaded.Author)
+	}
+}
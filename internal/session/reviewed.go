@@ -0,0 +1,77 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LineRange is an inclusive range of 1-based content lines.
+type LineRange struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+// Coverage is how much of a session's content the reviewer marked as
+// reviewed.
+type Coverage struct {
+	Lines    int `json:"lines"`
+	Reviewed int `json:"reviewed"`
+	// Percent is Reviewed as a whole percentage of Lines, rounded down so
+	// that 100 means every line.
+	Percent int `json:"percent"`
+	// Unreviewed lists the ranges never marked reviewed.
+	Unreviewed []LineRange `json:"unreviewed"`
+}
+
+// Coverage reports which of the session's content lines are marked
+// reviewed.
+func (s *Session) Coverage() Coverage {
+	lines := strings.Count(s.Content, "\n") + 1
+	reviewed := make(map[int]bool, len(s.Reviewed))
+	for _, line := range s.Reviewed {
+		if line >= 1 && line <= lines {
+			reviewed[line] = true
+		}
+	}
+	c := Coverage{Lines: lines, Reviewed: len(reviewed), Percent: len(reviewed) * 100 / lines, Unreviewed: []LineRange{}}
+	for line := 1; line <= lines; line++ {
+		if reviewed[line] {
+			continue
+		}
+		if n := len(c.Unreviewed); n > 0 && c.Unreviewed[n-1].EndLine == line-1 {
+			c.Unreviewed[n-1].EndLine = line
+		} else {
+			c.Unreviewed = append(c.Unreviewed, LineRange{StartLine: line, EndLine: line})
+		}
+	}
+	return c
+}
+
+// FormatRanges formats ranges as "1-40, 52" for humans.
+func FormatRanges(ranges []LineRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		if r.StartLine == r.EndLine {
+			parts[i] = fmt.Sprint(r.StartLine)
+		} else {
+			parts[i] = fmt.Sprintf("%d-%d", r.StartLine, r.EndLine)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseReviewed decodes the reviewed frontmatter field, written like a
+// line map: "1-40,52".
+func parseReviewed(s string) ([]int, error) {
+	lines, err := ParseLineMap(s)
+	if err != nil || !sort.IntsAreSorted(lines) {
+		return nil, fmt.Errorf("malformed reviewed lines %q", s)
+	}
+	for i, line := range lines {
+		if line < 1 || (i > 0 && line == lines[i-1]) {
+			return nil, fmt.Errorf("malformed reviewed lines %q", s)
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,79 @@
+package session
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+)
+
+func TestReviewed_RoundTripsThroughFrontmatter(t *testing.T) {
+	sess := &Session{ID: "r", CreatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Content: "a\nb\nc\nd\ne", Reviewed: []int{1, 2, 3, 5}}
+	formatted := Format(sess)
+	if !strings.Contains(formatted, "reviewed: 1-3,5\n") {
+		t.Fatalf("expected the reviewed lines in the frontmatter:\n%s", formatted)
+	}
+	parsed, err := Parse([]byte(formatted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed.Reviewed, sess.Reviewed) {
+		t.Errorf("got %v, want %v", parsed.Reviewed, sess.Reviewed)
+	}
+
+	sess.Reviewed = nil
+	if strings.Contains(Format(sess), "reviewed:") {
+		t.Error("expected no reviewed field when nothing is reviewed")
+	}
+}
+
+func TestReviewed_RejectsMalformed(t *testing.T) {
+	for _, value := range []string{"3,1", "0-2", "1,1", "x"} {
+		data := "---\nsession_id: r\ncreated_at: 2026-01-01T00:00:00Z\nreviewed: " + value + "\n---\n\nbody"
+		if _, err := Parse([]byte(data)); err == nil || !strings.Contains(err.Error(), "malformed reviewed lines") {
+			t.Errorf("%q: expected a malformed error, got %v", value, err)
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	sess := &Session{Content: "a\nb {>> note <<}\nc\nd\ne\nf", Reviewed: []int{2, 3, 5, 99}}
+	got := sess.Coverage()
+	want := Coverage{Lines: 6, Reviewed: 3, Percent: 50, Unreviewed: []LineRange{{1, 1}, {4, 4}, {6, 6}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if s := FormatRanges(got.Unreviewed); s != "1, 4, 6" {
+		t.Errorf("unexpected ranges %q", s)
+	}
+
+	sess.Reviewed = []int{1, 2, 3, 4, 5, 6}
+	if c := sess.Coverage(); c.Percent != 100 || len(c.Unreviewed) != 0 {
+		t.Errorf("expected full coverage, got %+v", c)
+	}
+}
+
+func TestMerge_UnitesReviewedLines(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	config.Init()
+
+	content := "one\ntwo\nthree\nfour"
+	a := reviewAs(t, "alice", content, content, "plan.md")
+	b := reviewAs(t, "bob", content, content, "plan.md")
+	a.Reviewed = []int{1, 2}
+	b.Reviewed = []int{2, 4}
+
+	res, err := Merge([]*Session{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res.Session.Reviewed, []int{1, 2, 4}) {
+		t.Errorf("expected the union of reviewed lines, got %v", res.Session.Reviewed)
+	}
+}
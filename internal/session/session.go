@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -18,6 +19,20 @@ import (
 var validSessionID = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
 var reservedIDs = map[string]bool{"tutor": true, "_tutor_": true}
 
+// ErrNotFound matches, via errors.Is, the errors returned when a session or
+// revision does not exist.
+var ErrNotFound = errors.New("session not found")
+
+// notFoundError keeps a specific message while matching ErrNotFound.
+type notFoundError struct{ msg string }
+
+func (e *notFoundError) Error() string        { return e.msg }
+func (e *notFoundError) Is(target error) bool { return target == ErrNotFound }
+
+func notFoundf(format string, args ...any) error {
+	return &notFoundError{msg: fmt.Sprintf(format, args...)}
+}
+
 // ValidateSessionID checks that a custom session ID is valid.
 func ValidateSessionID(id string) error {
 	if !validSessionID.MatchString(id) {
@@ -35,6 +50,14 @@ type Session struct {
 	CreatedAt   time.Time
 	SourceFile  string
 	ContentHash string
+	Author      string // reviewer who created the session
+	Status      Status
+	// Conversion is set when Content was converted from the source file's
+	// format rather than copied from it.
+	Conversion *Conversion
+	// Reviewed lists the content lines (1-based, ascending) the reviewer
+	// marked as reviewed.
+	Reviewed []int
 }
 
 func computeHash(content string) string {
@@ -89,86 +112,179 @@ func CreateWithID(id string, content string, sourceFile string) (*Session, error
 	if err := ValidateSessionID(id); err != nil {
 		return nil, err
 	}
+	return create(id, content, sourceFile, content, nil)
+}
 
-	sessionsDir, err := config.GetSessionsDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to find project root: %w", err)
-	}
+func Create(content string, sourceFile string) (*Session, error) {
+	return create("", content, sourceFile, content, nil)
+}
 
-	sessionPath := filepath.Join(sessionsDir, id+".fem")
-	if _, err := os.Stat(sessionPath); err == nil {
-		return nil, fmt.Errorf("session ID %q already exists", id)
+// CreateConverted creates a session whose content was converted from
+// original, the source's own format (e.g. HTML to Markdown). The content
+// hash is that of original, so drift is checked against the source file.
+// An empty id generates one.
+func CreateConverted(id string, content string, sourceFile string, original string, conv *Conversion) (*Session, error) {
+	if id != "" {
+		if err := ValidateSessionID(id); err != nil {
+			return nil, err
+		}
 	}
+	return create(id, content, sourceFile, original, conv)
+}
 
-	normalizedSource := normalizeSourceFile(sourceFile)
-	sess := &Session{
-		ID:          id,
-		Content:     content,
-		CreatedAt:   time.Now().UTC(),
-		SourceFile:  normalizedSource,
-		ContentHash: computeHash(content),
+// create writes a new session with the given ID, or a generated one when
+// id is empty. original is what the source file held, for drift checks.
+func create(id string, content string, sourceFile string, original string, conv *Conversion) (*Session, error) {
+	sess, sessionPath, err := newSession(id, content, sourceFile, original, conv)
+	if err != nil {
+		return nil, err
 	}
-
 	return writeSession(sess, sessionPath, content)
 }
 
-func Create(content string, sourceFile string) (*Session, error) {
+// newSession is create without writing the session: it returns the new
+// session and the path to write it to.
+func newSession(id string, content string, sourceFile string, original string, conv *Conversion) (*Session, string, error) {
 	sessionsDir, err := config.GetSessionsDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to find project root: %w", err)
+		return nil, "", fmt.Errorf("failed to find project root: %w", err)
 	}
 
-	var session *Session
 	var sessionPath string
-
-	normalizedSource := normalizeSourceFile(sourceFile)
-
-	for attempt := 0; attempt < maxCollisionRetries; attempt++ {
-		id, err := generateID()
-		if err != nil {
-			return nil, err
-		}
-
+	if id != "" {
 		sessionPath = filepath.Join(sessionsDir, id+".fem")
-
-		if _, err := os.Stat(sessionPath); os.IsNotExist(err) {
-			session = &Session{
-				ID:          id,
-				Content:     content,
-				CreatedAt:   time.Now().UTC(),
-				SourceFile:  normalizedSource,
-				ContentHash: computeHash(content),
+		if _, err := os.Stat(sessionPath); err == nil {
+			return nil, "", fmt.Errorf("session ID %q already exists", id)
+		}
+	} else {
+		for attempt := 0; attempt < maxCollisionRetries; attempt++ {
+			candidate, err := generateID()
+			if err != nil {
+				return nil, "", err
+			}
+			sessionPath = filepath.Join(sessionsDir, candidate+".fem")
+			if _, err := os.Stat(sessionPath); os.IsNotExist(err) {
+				id = candidate
+				break
 			}
-			break
+		}
+		if id == "" {
+			return nil, "", fmt.Errorf("failed to generate unique session ID after %d attempts", maxCollisionRetries)
 		}
 	}
 
-	if session == nil {
-		return nil, fmt.Errorf("failed to generate unique session ID after %d attempts", maxCollisionRetries)
+	sess := &Session{
+		ID:          id,
+		Content:     content,
+		CreatedAt:   time.Now().UTC(),
+		SourceFile:  normalizeSourceFile(sourceFile),
+		ContentHash: computeHash(original),
+		Author:      config.Author(),
+		Status:      StatusInProgress,
+		Conversion:  conv,
 	}
 
-	return writeSession(session, sessionPath, content)
+	return sess, sessionPath, nil
 }
 
 func writeSession(sess *Session, sessionPath string, content string) (*Session, error) {
+	sess.Content = content
+	if err := writeFileAtomic(sessionPath, []byte(Format(sess))); err != nil {
+		return nil, fmt.Errorf("failed to write session file: %w", err)
+	}
+
+	return sess, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers such as 'fabbro wait' never see a partly
+// written session.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Format renders sess as a session file: frontmatter followed by Content.
+func Format(sess *Session) string {
 	var sourceFileLine string
 	if sess.SourceFile != "" {
 		sourceFileLine = fmt.Sprintf("source_file: %s\n", quoteYAMLString(sess.SourceFile))
 	}
 
-	fileContent := fmt.Sprintf(`---
+	var contentHashLine string
+	if sess.ContentHash != "" {
+		contentHashLine = fmt.Sprintf("content_hash: %s\n", sess.ContentHash)
+	}
+
+	var authorLine string
+	if sess.Author != "" {
+		authorLine = fmt.Sprintf("author: %s\n", quoteYAMLString(sess.Author))
+	}
+
+	var statusLine string
+	if sess.Status != "" {
+		statusLine = fmt.Sprintf("status: %s\n", sess.Status)
+	}
+
+	var conversionLines string
+	if c := sess.Conversion; c != nil {
+		conversionLines = fmt.Sprintf("converted_from: %s\n", c.From)
+		if len(c.LineMap) > 0 {
+			conversionLines += fmt.Sprintf("line_map: %s\n", FormatLineMap(c.LineMap))
+		}
+	}
+
+	var reviewedLine string
+	if len(sess.Reviewed) > 0 {
+		reviewedLine = fmt.Sprintf("reviewed: %s\n", FormatLineMap(sess.Reviewed))
+	}
+
+	return fmt.Sprintf(`---
 session_id: %s
 created_at: %s
-content_hash: %s
-%s---
+%s%s%s%s%s%s---
 
-%s`, sess.ID, sess.CreatedAt.Format(time.RFC3339), sess.ContentHash, sourceFileLine, content)
+%s`, sess.ID, sess.CreatedAt.Format(time.RFC3339), contentHashLine, sourceFileLine, authorLine, statusLine, conversionLines, reviewedLine, sess.Content)
+}
 
-	if err := os.WriteFile(sessionPath, []byte(fileContent), 0600); err != nil {
-		return nil, fmt.Errorf("failed to write session file: %w", err)
+// Save overwrites the session file for sess with its current Content and
+// records the result as a new snapshot in the session's history.
+func Save(sess *Session) error {
+	sessionsDir, err := config.GetSessionsDir()
+	if err != nil {
+		return fmt.Errorf("failed to find project root: %w", err)
 	}
+	return SaveIn(sessionsDir, sess)
+}
 
-	return sess, nil
+// SaveIn is Save for the sessions directory sessionsDir rather than the
+// current project's, as used when syncing another clone's sessions. Its
+// history follows the retention configured for that project.
+func SaveIn(sessionsDir string, sess *Session) error {
+	sessionPath := filepath.Join(sessionsDir, sess.ID+".fem")
+	if _, err := writeSession(sess, sessionPath, sess.Content); err != nil {
+		return err
+	}
+	retention := config.DefaultHistoryRetention
+	// sessionsDir is <root>/.fabbro/sessions.
+	if settings, err := config.LoadSettingsAt(filepath.Dir(filepath.Dir(sessionsDir))); err == nil {
+		retention = settings.HistoryRetention()
+	}
+	if err := recordSnapshot(historyDirIn(sessionsDir, sess.ID), retention, sess); err != nil {
+		return fmt.Errorf("session saved but snapshot failed: %w", err)
+	}
+	return nil
 }
 
 func Load(id string) (*Session, error) {
@@ -182,6 +298,11 @@ func Load(id string) (*Session, error) {
 		return nil, fmt.Errorf("failed to read session file: %w", err)
 	}
 
+	return Parse(data)
+}
+
+// Parse decodes a session file's raw bytes into a Session.
+func Parse(data []byte) (*Session, error) {
 	content := string(data)
 
 	// Parse frontmatter
@@ -197,10 +318,14 @@ func Load(id string) (*Session, error) {
 	frontmatter := parts[1]
 	body := strings.TrimPrefix(parts[2], "\n")
 
-	// Extract session_id, created_at, source_file, and content_hash from frontmatter
+	// Extract session_id, created_at, source_file, content_hash, author, status, conversion and reviewed lines from frontmatter
 	var sessionID string
 	var sourceFile string
 	var contentHash string
+	var author string
+	status := StatusInProgress // sessions written before statuses existed
+	var conversion *Conversion
+	var reviewed []int
 	var createdAt time.Time
 	var parseErr error
 
@@ -221,6 +346,39 @@ func Load(id string) (*Session, error) {
 		if strings.HasPrefix(line, "content_hash: ") {
 			contentHash = strings.TrimPrefix(line, "content_hash: ")
 		}
+		if strings.HasPrefix(line, "author: ") {
+			author = unquoteYAMLString(strings.TrimPrefix(line, "author: "))
+		}
+		if strings.HasPrefix(line, "status: ") {
+			st, err := ParseStatus(strings.TrimPrefix(line, "status: "))
+			if err != nil {
+				return nil, fmt.Errorf("invalid session file: %w", err)
+			}
+			status = st
+		}
+		if strings.HasPrefix(line, "converted_from: ") {
+			if conversion == nil {
+				conversion = &Conversion{}
+			}
+			conversion.From = strings.TrimPrefix(line, "converted_from: ")
+		}
+		if strings.HasPrefix(line, "line_map: ") {
+			lineMap, err := ParseLineMap(strings.TrimPrefix(line, "line_map: "))
+			if err != nil {
+				return nil, fmt.Errorf("invalid session file: %w", err)
+			}
+			if conversion == nil {
+				conversion = &Conversion{}
+			}
+			conversion.LineMap = lineMap
+		}
+		if strings.HasPrefix(line, "reviewed: ") {
+			lines, err := parseReviewed(strings.TrimPrefix(line, "reviewed: "))
+			if err != nil {
+				return nil, fmt.Errorf("invalid session file: %w", err)
+			}
+			reviewed = lines
+		}
 	}
 
 	if sessionID == "" {
@@ -236,6 +394,10 @@ func Load(id string) (*Session, error) {
 		CreatedAt:   createdAt,
 		SourceFile:  sourceFile,
 		ContentHash: contentHash,
+		Author:      author,
+		Status:      status,
+		Conversion:  conversion,
+		Reviewed:    reviewed,
 	}, nil
 }
 
@@ -328,7 +490,7 @@ func LoadPartial(partial string) (*Session, error) {
 
 	switch len(matches) {
 	case 0:
-		return nil, fmt.Errorf("no session matching %q", partial)
+		return nil, notFoundf("no session matching %q", partial)
 	case 1:
 		return Load(matches[0])
 	default:
@@ -336,7 +498,7 @@ func LoadPartial(partial string) (*Session, error) {
 	}
 }
 
-// Delete removes a session file by ID.
+// Delete removes a session file, its history and its TUI state by ID.
 func Delete(id string) error {
 	sessionsDir, err := config.GetSessionsDir()
 	if err != nil {
@@ -344,11 +506,22 @@ func Delete(id string) error {
 	}
 	sessionPath := filepath.Join(sessionsDir, id+".fem")
 	if _, err := os.Stat(sessionPath); os.IsNotExist(err) {
-		return fmt.Errorf("session not found: %s", id)
+		return notFoundf("session not found: %s", id)
+	}
+	if err := os.RemoveAll(filepath.Join(sessionsDir, id+".history")); err != nil {
+		return fmt.Errorf("failed to remove session history: %w", err)
+	}
+	if err := os.Remove(filepath.Join(sessionsDir, id+".state.json")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove session state: %w", err)
 	}
 	return os.Remove(sessionPath)
 }
 
+// IsFrom reports whether the session was created from the file at path.
+func (s *Session) IsFrom(path string) bool {
+	return s.SourceFile != "" && s.SourceFile == normalizeSourceFile(path)
+}
+
 // FindBySourceFile finds the latest session created from the given source file.
 // Returns an error if no matching session is found.
 func FindBySourceFile(sourceFile string) (*Session, error) {
@@ -389,7 +562,7 @@ func FindBySourceFile(sourceFile string) (*Session, error) {
 	}
 
 	if latestSession == nil {
-		return nil, fmt.Errorf("no session found for file: %s", sourceFile)
+		return nil, notFoundf("no session found for file: %s", sourceFile)
 	}
 
 	return latestSession, nil
@@ -431,6 +431,16 @@ func TestFindBySourceFile_NormalizesPath(t *testing.T) {
 	}
 }
 
+func TestIsFrom(t *testing.T) {
+	sess := &Session{SourceFile: "docs/readme.md"}
+	if !sess.IsFrom("./docs/readme.md") || sess.IsFrom("readme.md") {
+		t.Error("expected IsFrom to compare normalized paths")
+	}
+	if (&Session{}).IsFrom("") {
+		t.Error("expected stdin sessions to match no file")
+	}
+}
+
 func TestList_ReturnsEmptyForNoSessions(t *testing.T) {
 	tmpDir := t.TempDir()
 	origDir, _ := os.Getwd()
@@ -698,3 +708,30 @@ func TestGenerateID_ReturnsDateBasedFormat(t *testing.T) {
 		}
 	}
 }
+
+func TestSave_LeavesNoTemporaryFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := Create("first", "")
+	sess.Content = "second"
+	if err := Save(sess); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+
+	entries, _ := os.ReadDir(config.SessionsDir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	want := []string{sess.ID + ".fem", sess.ID + ".history"}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Errorf("expected only %v, got %v", want, names)
+	}
+	if loaded, err := Load(sess.ID); err != nil || loaded.Content != "second" {
+		t.Errorf("expected the saved content, got %+v, %v", loaded, err)
+	}
+}
@@ -0,0 +1,65 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+)
+
+// State is where the reviewer left off in the TUI, restored on resume.
+// Lines are 1-based. It is kept beside the session file as <id>.state.json
+// and, unlike the session, is not part of its history.
+type State struct {
+	Cursor int            `json:"cursor,omitempty"`
+	Marks  map[string]int `json:"marks,omitempty"` // mark letter to line
+	Jumps  []int          `json:"jumps,omitempty"` // jumplist, oldest first
+}
+
+func statePath(id string) (string, error) {
+	sessionsDir, err := config.GetSessionsDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find project root: %w", err)
+	}
+	return filepath.Join(sessionsDir, id+".state.json"), nil
+}
+
+// LoadState reads the TUI state of a session. A session never opened in
+// the TUI has an empty state.
+func LoadState(id string) (*State, error) {
+	path, err := statePath(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session state: %w", err)
+	}
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("invalid session state: %w", err)
+	}
+	return &st, nil
+}
+
+// SaveState writes the TUI state of a session, atomically like the session
+// itself.
+func SaveState(id string, st *State) error {
+	path, err := statePath(id)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode session state: %w", err)
+	}
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write session state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+)
+
+func TestState_RoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+	sess, _ := Create("a\nb\nc", "")
+
+	st, err := LoadState(sess.ID)
+	if err != nil {
+		t.Fatalf("LoadState() error: %v", err)
+	}
+	if !reflect.DeepEqual(st, &State{}) {
+		t.Errorf("expected an empty state before the first save, got %+v", st)
+	}
+
+	want := &State{Cursor: 3, Marks: map[string]int{"a": 2}, Jumps: []int{1, 3}}
+	if err := SaveState(sess.ID, want); err != nil {
+		t.Fatalf("SaveState() error: %v", err)
+	}
+	got, err := LoadState(sess.ID)
+	if err != nil {
+		t.Fatalf("LoadState() error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(config.SessionsDir, ".*.tmp")); len(matches) != 0 {
+		t.Errorf("expected no temporary files left, got %v", matches)
+	}
+
+	if err := Delete(sess.ID); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if got, _ := LoadState(sess.ID); !reflect.DeepEqual(got, &State{}) {
+		t.Errorf("expected Delete to remove the state, got %+v", got)
+	}
+}
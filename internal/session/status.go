@@ -0,0 +1,95 @@
+package session
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+)
+
+// Status is where a session is in its review lifecycle.
+type Status string
+
+const (
+	StatusInProgress Status = "in_progress" // being reviewed
+	StatusReady      Status = "ready"       // review finished, ready for the agent
+	StatusApplied    Status = "applied"     // agent has applied the annotations
+	StatusClosed     Status = "closed"      // abandoned without being applied
+)
+
+// Statuses lists every status in lifecycle order.
+var Statuses = []Status{StatusInProgress, StatusReady, StatusApplied, StatusClosed}
+
+// transitions maps each status to the statuses it may move to.
+var transitions = map[Status][]Status{
+	StatusInProgress: {StatusReady, StatusApplied, StatusClosed},
+	StatusReady:      {StatusInProgress, StatusApplied, StatusClosed},
+	StatusApplied:    {StatusInProgress},
+	StatusClosed:     {StatusInProgress},
+}
+
+// Label returns the status as shown to humans, e.g. "in progress".
+func (s Status) Label() string {
+	return strings.ReplaceAll(string(s), "_", " ")
+}
+
+// Open reports whether the session's annotations are still waiting to be
+// acted on: neither applied nor closed.
+func (s Status) Open() bool {
+	return s == StatusInProgress || s == StatusReady
+}
+
+// ParseStatus parses a status name, accepting "in-progress" and
+// "in progress" as spellings of in_progress.
+func ParseStatus(s string) (Status, error) {
+	normalized := strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(strings.TrimSpace(s)))
+	for _, st := range Statuses {
+		if string(st) == normalized {
+			return st, nil
+		}
+	}
+	names := make([]string, len(Statuses))
+	for i, st := range Statuses {
+		names[i] = string(st)
+	}
+	return "", fmt.Errorf("invalid status %q: must be one of %s", s, strings.Join(names, ", "))
+}
+
+// CanTransition reports whether a session in status from may move to to.
+func CanTransition(from, to Status) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStatus moves sess to status to and persists the change. Moving to the
+// current status is a no-op. Status changes do not record a history snapshot
+// since the reviewed content is unchanged.
+func SetStatus(sess *Session, to Status) error {
+	from := sess.Status
+	if from == "" {
+		from = StatusInProgress
+	}
+	if from == to {
+		sess.Status = to
+		return nil
+	}
+	if !CanTransition(from, to) {
+		return fmt.Errorf("cannot move session %s from %s to %s", sess.ID, from.Label(), to.Label())
+	}
+
+	sessionsDir, err := config.GetSessionsDir()
+	if err != nil {
+		return fmt.Errorf("failed to find project root: %w", err)
+	}
+	sess.Status = to
+	if _, err := writeSession(sess, filepath.Join(sessionsDir, sess.ID+".fem"), sess.Content); err != nil {
+		sess.Status = from
+		return err
+	}
+	return nil
+}
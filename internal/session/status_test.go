@@ -0,0 +1,92 @@
+package session
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Status
+	}{
+		{"ready", StatusReady},
+		{"in-progress", StatusInProgress},
+		{"In Progress", StatusInProgress},
+		{"closed", StatusClosed},
+	}
+	for _, tt := range tests {
+		got, err := ParseStatus(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseStatus(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+		}
+	}
+	if _, err := ParseStatus("done"); err == nil {
+		t.Error("expected error for unknown status")
+	}
+}
+
+func TestStatusOpen(t *testing.T) {
+	for _, st := range Statuses {
+		want := st == StatusInProgress || st == StatusReady
+		if st.Open() != want {
+			t.Errorf("%s.Open() = %v, want %v", st, st.Open(), want)
+		}
+	}
+}
+
+func TestSetStatus_PersistsTransition(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	config.Init()
+
+	sess, _ := Create("text", "")
+	if sess.Status != StatusInProgress {
+		t.Fatalf("new sessions should be in progress, got %q", sess.Status)
+	}
+	if err := SetStatus(sess, StatusReady); err != nil {
+		t.Fatalf("SetStatus() error: %v", err)
+	}
+
+	loaded, _ := Load(sess.ID)
+	if loaded.Status != StatusReady {
+		t.Errorf("expected persisted status ready, got %q", loaded.Status)
+	}
+	if snaps, _ := History(sess.ID); len(snaps) != 0 {
+		t.Errorf("status change should not record a snapshot, got %d", len(snaps))
+	}
+}
+
+func TestSetStatus_RejectsInvalidTransition(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	config.Init()
+
+	sess, _ := Create("text", "")
+	SetStatus(sess, StatusClosed)
+
+	err := SetStatus(sess, StatusApplied)
+	if err == nil || !strings.Contains(err.Error(), "cannot move") {
+		t.Fatalf("expected transition error, got %v", err)
+	}
+	if sess.Status != StatusClosed {
+		t.Errorf("failed transition should leave status unchanged, got %q", sess.Status)
+	}
+}
+
+func TestLoad_DefaultsStatusForOlderSessions(t *testing.T) {
+	sess, err := Parse([]byte("---\nsession_id: old\ncreated_at: 2026-01-11T12:00:00Z\n---\n\ntext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.Status != StatusInProgress {
+		t.Errorf("expected in_progress for session without status, got %q", sess.Status)
+	}
+}
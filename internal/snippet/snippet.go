@@ -0,0 +1,123 @@
+// Package snippet provides the canned annotation texts reviewers configure
+// in their user and project settings, and fills in their placeholders from
+// the lines being annotated.
+package snippet
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/fuzzy"
+)
+
+// Placeholders are the placeholders Expand fills in, with what they hold.
+var Placeholders = [][2]string{
+	{"{file}", "the source file, or stdin"},
+	{"{lines}", "the annotated lines, e.g. 3 or 3-5"},
+	{"{start}", "the first annotated line"},
+	{"{end}", "the last annotated line"},
+	{"{text}", "the text of the annotated lines"},
+}
+
+// Context is what the placeholders of a snippet are filled from.
+type Context struct {
+	File       string   // source file, "" for stdin
+	Start, End int      // 1-based lines annotated
+	Lines      []string // the annotated lines
+}
+
+// Load returns the user's snippets followed by the project's. A project
+// snippet replaces a user snippet of the same name. Outside a fabbro
+// project only the user's snippets are returned.
+func Load() ([]config.Snippet, error) {
+	user, err := config.LoadUserSettings()
+	if err != nil {
+		return nil, err
+	}
+	project, err := config.LoadSettings()
+	if errors.Is(err, config.ErrNotInitialized) {
+		project = &config.Settings{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	snippets := append([]config.Snippet(nil), user.Snippets...)
+	for _, s := range project.Snippets {
+		if i := index(snippets, s.Name); i >= 0 {
+			snippets[i] = s
+		} else {
+			snippets = append(snippets, s)
+		}
+	}
+	for _, s := range snippets {
+		if s.Name == "" || strings.ContainsAny(s.Name, " \t") {
+			return nil, fmt.Errorf("invalid snippet name %q: use a single word", s.Name)
+		}
+		if s.Type != "" && !fem.ValidAnnotationType(s.Type) {
+			return nil, fmt.Errorf("snippet %q has unknown annotation type %q", s.Name, s.Type)
+		}
+	}
+	return snippets, nil
+}
+
+// Find returns the snippet called name.
+func Find(snippets []config.Snippet, name string) (config.Snippet, bool) {
+	if i := index(snippets, name); i >= 0 {
+		return snippets[i], true
+	}
+	return config.Snippet{}, false
+}
+
+func index(snippets []config.Snippet, name string) int {
+	for i, s := range snippets {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Filter returns the snippets whose name or text fuzzy-matches query,
+// ignoring case.
+func Filter(snippets []config.Snippet, query string) []config.Snippet {
+	query = strings.ToLower(query)
+	var matches []config.Snippet
+	for _, s := range snippets {
+		if fuzzy.Match(strings.ToLower(s.Name), query) || fuzzy.Match(strings.ToLower(s.Text), query) {
+			matches = append(matches, s)
+		}
+	}
+	return matches
+}
+
+// Type returns the annotation type a snippet adds.
+func Type(s config.Snippet) string {
+	if s.Type == "" {
+		return "comment"
+	}
+	return s.Type
+}
+
+// Expand fills in the placeholders of text. Unknown placeholders are left
+// as they are.
+func Expand(text string, ctx Context) string {
+	file := ctx.File
+	if file == "" {
+		file = "stdin"
+	}
+	lines := strconv.Itoa(ctx.Start)
+	if ctx.End != ctx.Start {
+		lines += "-" + strconv.Itoa(ctx.End)
+	}
+	return strings.NewReplacer(
+		"{file}", file,
+		"{lines}", lines,
+		"{start}", strconv.Itoa(ctx.Start),
+		"{end}", strconv.Itoa(ctx.End),
+		"{text}", strings.TrimSpace(strings.Join(ctx.Lines, "\n")),
+	).Replace(text)
+}
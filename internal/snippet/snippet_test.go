@@ -0,0 +1,109 @@
+package snippet
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+)
+
+func TestExpand(t *testing.T) {
+	ctx := Context{File: "main.go", Start: 3, End: 5, Lines: []string{"  if err != nil {", "  \treturn err", "  }"}}
+	tests := []struct {
+		text, want string
+	}{
+		{"Add a test for this", "Add a test for this"},
+		{"Explain why {file}:{lines} does this", "Explain why main.go:3-5 does this"},
+		{"from {start} to {end}", "from 3 to 5"},
+		{"Wrap `{text}`", "Wrap `if err != nil {\n  \treturn err\n  }`"},
+		{"{unknown} stays", "{unknown} stays"},
+	}
+	for _, tt := range tests {
+		if got := Expand(tt.text, ctx); got != tt.want {
+			t.Errorf("Expand(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	if got := Expand("{file}:{lines}", Context{Start: 7, End: 7}); got != "stdin:7" {
+		t.Errorf("expected stdin and a single line, got %q", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	snippets := []config.Snippet{
+		{Name: "test", Text: "Add a test for this"},
+		{Name: "wrap", Text: "Needs error wrapping"},
+		{Name: "why", Type: "question", Text: "Explain why"},
+	}
+	var names []string
+	for _, s := range Filter(snippets, "WR") {
+		names = append(names, s.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"wrap"}) {
+		t.Errorf("expected wrap to match WR, got %v", names)
+	}
+	if got := Filter(snippets, "error"); len(got) != 1 || got[0].Name != "wrap" {
+		t.Errorf("expected the text to match too, got %v", got)
+	}
+	if got := Filter(snippets, ""); len(got) != 3 {
+		t.Errorf("expected every snippet for an empty query, got %v", got)
+	}
+
+	if s, ok := Find(snippets, "why"); !ok || Type(s) != "question" {
+		t.Errorf("expected why to be a question, got %+v, %v", s, ok)
+	}
+	if s, _ := Find(snippets, "test"); Type(s) != "comment" {
+		t.Errorf("expected snippets without a type to be comments, got %q", Type(s))
+	}
+	if _, ok := Find(snippets, "nope"); ok {
+		t.Error("expected no snippet called nope")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+	t.Setenv("FABBRO_PROJECT_ROOT_STOP", tmpDir)
+
+	os.MkdirAll(filepath.Join(configHome, "fabbro"), 0700)
+	os.WriteFile(filepath.Join(configHome, config.UserSettingsFile), []byte(`{"snippets": [
+		{"name": "test", "text": "Add a test"},
+		{"name": "why", "type": "question", "text": "Why?"}
+	]}`), 0600)
+
+	// Outside a project only the user's snippets are loaded.
+	snippets, err := Load()
+	if err != nil || len(snippets) != 2 {
+		t.Fatalf("expected the user's snippets, got %+v, %v", snippets, err)
+	}
+
+	config.Init()
+	os.WriteFile(config.SettingsFile, []byte(`{"snippets": [
+		{"name": "why", "type": "question", "text": "Why here?"},
+		{"name": "wrap", "text": "Wrap the error"}
+	]}`), 0600)
+	snippets, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	want := []config.Snippet{
+		{Name: "test", Text: "Add a test"},
+		{Name: "why", Type: "question", Text: "Why here?"},
+		{Name: "wrap", Text: "Wrap the error"},
+	}
+	if !reflect.DeepEqual(snippets, want) {
+		t.Errorf("Load() = %+v, want %+v", snippets, want)
+	}
+
+	os.WriteFile(config.SettingsFile, []byte(`{"snippets": [{"name": "x", "type": "praise", "text": "Nice"}]}`), 0600)
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), `unknown annotation type "praise"`) {
+		t.Errorf("expected an unknown type error, got %v", err)
+	}
+}
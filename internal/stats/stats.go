@@ -0,0 +1,244 @@
+// Package stats aggregates annotations across sessions to show where
+// reviews concentrate and how they trend over time.
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+// DefaultHotspots is how many hotspots a report lists unless told otherwise.
+const DefaultHotspots = 10
+
+// Count is a number of annotations of one type.
+type Count struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// File aggregates the sessions reviewing one source file.
+type File struct {
+	File        string         `json:"file"`
+	Sessions    int            `json:"sessions"`
+	Annotations int            `json:"annotations"`
+	ByType      map[string]int `json:"byType"`
+}
+
+// Week aggregates the sessions created in one ISO week, e.g. "2026-W03".
+type Week struct {
+	Week        string `json:"week"`
+	Sessions    int    `json:"sessions"`
+	Annotations int    `json:"annotations"`
+}
+
+// Hotspot is a range of a source file where annotations from one or more
+// sessions overlap or touch.
+type Hotspot struct {
+	File        string         `json:"file"`
+	StartLine   int            `json:"startLine"`
+	EndLine     int            `json:"endLine"`
+	Sessions    int            `json:"sessions"`
+	Annotations int            `json:"annotations"`
+	ByType      map[string]int `json:"byType"`
+}
+
+// Report is the aggregate over a set of sessions. Sessions reviewing stdin
+// count towards the totals but have no file, so they are left out of
+// ByFile and Hotspots.
+type Report struct {
+	Sessions         int       `json:"sessions"`
+	Annotations      int       `json:"annotations"`
+	MedianPerSession float64   `json:"medianPerSession"`
+	ByType           []Count   `json:"byType"`
+	ByFile           []File    `json:"byFile"`
+	ByWeek           []Week    `json:"byWeek"`
+	Hotspots         []Hotspot `json:"hotspots"`
+}
+
+// Options tune Compute.
+type Options struct {
+	// Types limits the annotations counted; all types when empty.
+	Types []string
+	// Hotspots is the maximum number of hotspots listed.
+	Hotspots int
+}
+
+type located struct {
+	fem.Annotation
+	session string
+}
+
+// Compute aggregates the annotations of sessions. Annotations are merged
+// per session first, so duplicates from git sync count once.
+func Compute(sessions []*session.Session, opts Options) *Report {
+	counted := make(map[string]bool, len(opts.Types))
+	for _, t := range opts.Types {
+		counted[t] = true
+	}
+
+	r := &Report{Sessions: len(sessions), ByType: []Count{}, ByFile: []File{}, ByWeek: []Week{}, Hotspots: []Hotspot{}}
+	byType := make(map[string]int)
+	files := make(map[string]*File)
+	weeks := make(map[string]*Week)
+	regions := make(map[string][]located)
+	perSession := make([]int, 0, len(sessions))
+	var first, last time.Time
+
+	for _, sess := range sessions {
+		annotations, _, _ := fem.Parse(sess.Content)
+		var kept []fem.Annotation
+		for _, a := range fem.MergeAnnotations(nil, annotations) {
+			if len(counted) == 0 || counted[a.Type] {
+				kept = append(kept, a)
+			}
+		}
+		n := len(kept)
+		r.Annotations += n
+		perSession = append(perSession, n)
+		for _, a := range kept {
+			byType[a.Type]++
+		}
+
+		key := weekOf(sess.CreatedAt)
+		if weeks[key] == nil {
+			weeks[key] = &Week{Week: key}
+		}
+		weeks[key].Sessions++
+		weeks[key].Annotations += n
+		if first.IsZero() || sess.CreatedAt.Before(first) {
+			first = sess.CreatedAt
+		}
+		if sess.CreatedAt.After(last) {
+			last = sess.CreatedAt
+		}
+
+		if sess.SourceFile == "" {
+			continue
+		}
+		f := files[sess.SourceFile]
+		if f == nil {
+			f = &File{File: sess.SourceFile, ByType: map[string]int{}}
+			files[sess.SourceFile] = f
+		}
+		f.Sessions++
+		f.Annotations += n
+		for _, a := range kept {
+			f.ByType[a.Type]++
+			regions[sess.SourceFile] = append(regions[sess.SourceFile], located{a, sess.ID})
+		}
+	}
+
+	r.MedianPerSession = median(perSession)
+
+	for _, at := range fem.AnnotationTypes {
+		if byType[at.Name] > 0 {
+			r.ByType = append(r.ByType, Count{Type: at.Name, Count: byType[at.Name]})
+		}
+	}
+	sort.SliceStable(r.ByType, func(i, j int) bool { return r.ByType[i].Count > r.ByType[j].Count })
+
+	for _, f := range files {
+		r.ByFile = append(r.ByFile, *f)
+	}
+	sort.Slice(r.ByFile, func(i, j int) bool {
+		if r.ByFile[i].Annotations != r.ByFile[j].Annotations {
+			return r.ByFile[i].Annotations > r.ByFile[j].Annotations
+		}
+		return r.ByFile[i].File < r.ByFile[j].File
+	})
+
+	// List every week from the first session to the last, so gaps show.
+	if len(sessions) > 0 {
+		for t := first; ; t = t.AddDate(0, 0, 7) {
+			key := weekOf(t)
+			if w := weeks[key]; w != nil {
+				r.ByWeek = append(r.ByWeek, *w)
+			} else {
+				r.ByWeek = append(r.ByWeek, Week{Week: key})
+			}
+			if key == weekOf(last) || t.After(last) {
+				break
+			}
+		}
+	}
+
+	for file, anns := range regions {
+		r.Hotspots = append(r.Hotspots, hotspots(file, anns)...)
+	}
+	sort.Slice(r.Hotspots, func(i, j int) bool {
+		a, b := r.Hotspots[i], r.Hotspots[j]
+		if a.Annotations != b.Annotations {
+			return a.Annotations > b.Annotations
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.StartLine < b.StartLine
+	})
+	limit := opts.Hotspots
+	if limit <= 0 {
+		limit = DefaultHotspots
+	}
+	if len(r.Hotspots) > limit {
+		r.Hotspots = r.Hotspots[:limit]
+	}
+	return r
+}
+
+// hotspots merges the overlapping or adjacent line ranges of one file's
+// annotations and keeps the ranges with more than one annotation.
+func hotspots(file string, anns []located) []Hotspot {
+	sort.Slice(anns, func(i, j int) bool {
+		if anns[i].StartLine != anns[j].StartLine {
+			return anns[i].StartLine < anns[j].StartLine
+		}
+		return anns[i].EndLine < anns[j].EndLine
+	})
+
+	var out []Hotspot
+	var cur *Hotspot
+	var sessions map[string]bool
+	flush := func() {
+		if cur != nil && cur.Annotations > 1 {
+			cur.Sessions = len(sessions)
+			out = append(out, *cur)
+		}
+	}
+	for _, a := range anns {
+		if cur == nil || a.StartLine > cur.EndLine+1 {
+			flush()
+			cur = &Hotspot{File: file, StartLine: a.StartLine, EndLine: a.EndLine, ByType: map[string]int{}}
+			sessions = make(map[string]bool)
+		}
+		if a.EndLine > cur.EndLine {
+			cur.EndLine = a.EndLine
+		}
+		cur.Annotations++
+		cur.ByType[a.Type]++
+		sessions[a.session] = true
+	}
+	flush()
+	return out
+}
+
+func weekOf(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+func median(values []int) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return float64(sorted[mid])
+	}
+	return float64(sorted[mid-1]+sorted[mid]) / 2
+}
@@ -0,0 +1,93 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/session"
+)
+
+func testSessions() []*session.Session {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC) }
+	return []*session.Session{
+		{
+			ID: "a", SourceFile: "main.go", CreatedAt: day(2),
+			Content: "one {~~ vague ~~}\ntwo {-- drop --}\nthree\nfour\nfive {>> ok <<}\n",
+		},
+		{
+			ID: "b", SourceFile: "main.go", CreatedAt: day(3),
+			Content: "one\ntwo {~~ still vague ~~}\nthree {-- and this --}\n",
+		},
+		{
+			ID: "c", SourceFile: "docs/plan.md", CreatedAt: day(17),
+			Content: "# Plan {?? why ??}\n",
+		},
+		{
+			ID: "d", CreatedAt: day(18),
+			Content: "from stdin\n",
+		},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	r := Compute(testSessions(), Options{})
+
+	if r.Sessions != 4 || r.Annotations != 6 {
+		t.Errorf("got %d sessions, %d annotations", r.Sessions, r.Annotations)
+	}
+	if r.MedianPerSession != 1.5 {
+		t.Errorf("median = %v, want 1.5", r.MedianPerSession)
+	}
+
+	wantTypes := []Count{{"delete", 2}, {"unclear", 2}, {"comment", 1}, {"question", 1}}
+	if len(r.ByType) != len(wantTypes) {
+		t.Fatalf("ByType = %+v", r.ByType)
+	}
+	for i, w := range wantTypes {
+		if r.ByType[i] != w {
+			t.Errorf("ByType[%d] = %+v, want %+v", i, r.ByType[i], w)
+		}
+	}
+
+	if len(r.ByFile) != 2 || r.ByFile[0].File != "main.go" || r.ByFile[0].Sessions != 2 || r.ByFile[0].Annotations != 5 || r.ByFile[0].ByType["unclear"] != 2 {
+		t.Errorf("ByFile = %+v", r.ByFile)
+	}
+
+	weeks := []string{"2026-W10", "2026-W11", "2026-W12"}
+	if len(r.ByWeek) != len(weeks) {
+		t.Fatalf("ByWeek = %+v", r.ByWeek)
+	}
+	for i, w := range weeks {
+		if r.ByWeek[i].Week != w {
+			t.Errorf("ByWeek[%d] = %+v, want %s", i, r.ByWeek[i], w)
+		}
+	}
+	if r.ByWeek[0].Sessions != 2 || r.ByWeek[0].Annotations != 5 || r.ByWeek[1].Sessions != 0 {
+		t.Errorf("unexpected week counts %+v", r.ByWeek)
+	}
+
+	if len(r.Hotspots) != 1 {
+		t.Fatalf("Hotspots = %+v", r.Hotspots)
+	}
+	h := r.Hotspots[0]
+	if h.File != "main.go" || h.StartLine != 1 || h.EndLine != 3 || h.Annotations != 4 || h.Sessions != 2 {
+		t.Errorf("unexpected hotspot %+v", h)
+	}
+}
+
+func TestComputeTypesFilter(t *testing.T) {
+	r := Compute(testSessions(), Options{Types: []string{"unclear"}})
+	if r.Annotations != 2 || len(r.ByType) != 1 || r.ByType[0].Type != "unclear" {
+		t.Errorf("unexpected report %+v", r)
+	}
+	if len(r.Hotspots) != 1 || r.Hotspots[0].StartLine != 1 || r.Hotspots[0].EndLine != 2 {
+		t.Errorf("unexpected hotspots %+v", r.Hotspots)
+	}
+}
+
+func TestComputeEmpty(t *testing.T) {
+	r := Compute(nil, Options{})
+	if r.Sessions != 0 || r.MedianPerSession != 0 || r.ByWeek == nil || r.Hotspots == nil {
+		t.Errorf("unexpected empty report %+v", r)
+	}
+}
@@ -0,0 +1,53 @@
+package structure
+
+import "strings"
+
+// headings returns the ATX headings of Markdown lines, each spanning its
+// section: up to the next heading of the same or a higher level. Lines in
+// fenced code blocks aren't headings.
+func headings(lines []string) []Symbol {
+	var symbols []Symbol
+	var levels []int
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		level := headingLevel(trimmed)
+		if level == 0 {
+			continue
+		}
+		title := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(trimmed[level:]), "#"))
+		symbols = append(symbols, Symbol{Kind: KindHeading, Name: title, Start: i, Decl: i, End: len(lines) - 1, BodyStart: i + 1, BodyEnd: len(lines) - 1})
+		levels = append(levels, level)
+	}
+	for i := range symbols {
+		for j := i + 1; j < len(symbols); j++ {
+			if levels[j] <= levels[i] {
+				symbols[i].End, symbols[i].BodyEnd = symbols[j].Start-1, symbols[j].Start-1
+				break
+			}
+		}
+	}
+	return symbols
+}
+
+// headingLevel returns the level of an ATX heading line, or 0.
+func headingLevel(trimmed string) int {
+	n := 0
+	for n < len(trimmed) && trimmed[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 || (n < len(trimmed) && trimmed[n] != ' ' && trimmed[n] != '\t') {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,495 @@
+// Package structure finds the functions, types and blocks of source code,
+// and the headings of Markdown, for text objects and outlines. Go is
+// parsed with go/parser; other languages are approximated from chroma
+// tokens, with braces or indentation delimiting bodies.
+package structure
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+// Kind is the kind of a symbol.
+type Kind string
+
+const (
+	KindFunction Kind = "function"
+	KindType     Kind = "type" // classes, structs, interfaces and the like
+	KindHeading  Kind = "heading"
+)
+
+// Symbol is a function or type declaration, or a Markdown heading spanning
+// its section. Lines are 0-indexed.
+type Symbol struct {
+	Kind  Kind
+	Name  string // "" for anonymous functions
+	Start int    // first line, including doc comments and decorators
+	Decl  int    // line of the declaration itself
+	End   int
+	// BodyStart and BodyEnd are the lines inside the body's delimiters. A
+	// body on the declaration's own line is that line; BodyStart > BodyEnd
+	// when the body is empty.
+	BodyStart, BodyEnd int
+	Depth              int // number of symbols enclosing this one
+}
+
+// Span is a pair of delimiters, by the lines they are on.
+type Span struct {
+	Open, Close int
+}
+
+// File is the structure of one source file.
+type File struct {
+	Symbols []Symbol // ordered by Start
+	Braces  []Span   // {} pairs spanning more than one line
+	Parens  []Span   // () pairs spanning more than one line
+}
+
+// Keywords that introduce declarations and statements, across languages.
+var (
+	funcKeywords    = setOf("def", "function", "func", "fn", "fun", "sub", "proc")
+	typeKeywords    = setOf("class", "struct", "interface", "enum", "trait", "impl", "object", "record", "protocol", "type")
+	controlKeywords = setOf("if", "else", "for", "foreach", "while", "do", "switch", "case", "catch", "try", "finally", "with", "return", "elif", "except", "match", "loop", "unless", "until")
+	modifiers       = setOf("public", "private", "protected", "internal", "static", "async", "export", "default", "abstract", "final", "override", "virtual", "pub", "unsafe", "extern", "inline", "const", "let", "var", "open", "sealed", "data")
+)
+
+// maxHeaderLines is how far a declaration may wrap before its body opens.
+const maxHeaderLines = 5
+
+func setOf(words ...string) map[string]bool {
+	m := make(map[string]bool, len(words))
+	for _, w := range words {
+		m[w] = true
+	}
+	return m
+}
+
+// Parse finds the structure of lines, picking the language from filename,
+// or from the content when filename doesn't tell.
+func Parse(filename string, lines []string) *File {
+	content := strings.Join(lines, "\n")
+	var lexer chroma.Lexer
+	if filename != "" {
+		lexer = lexers.Match(filename)
+	}
+	if lexer == nil {
+		lexer = lexers.Analyse(content)
+	}
+
+	s := scan(lexer, content, len(lines))
+	f := &File{}
+	for _, p := range s.braces {
+		if p.Close > p.Open {
+			f.Braces = append(f.Braces, p)
+		}
+	}
+	for _, p := range s.parens {
+		if p.Close > p.Open {
+			f.Parens = append(f.Parens, p)
+		}
+	}
+
+	var ok bool
+	name := ""
+	if lexer != nil {
+		name = lexer.Config().Name
+	}
+	switch name {
+	case "Go":
+		f.Symbols, ok = goSymbols(filename, content)
+	case "markdown":
+		f.Symbols, ok = headings(lines), true
+	}
+	if !ok {
+		f.Symbols = s.symbols(lines)
+	}
+	if len(f.Symbols) == 0 && name == "" {
+		// Unrecognised text may still be a plan with headings.
+		f.Symbols = headings(lines)
+	}
+	sort.SliceStable(f.Symbols, func(i, j int) bool {
+		a, b := f.Symbols[i], f.Symbols[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		return a.End > b.End
+	})
+	for i := range f.Symbols {
+		for j := range f.Symbols {
+			if i != j && encloses(f.Symbols[j], f.Symbols[i]) {
+				f.Symbols[i].Depth++
+			}
+		}
+	}
+	return f
+}
+
+// encloses reports whether a strictly contains b.
+func encloses(a, b Symbol) bool {
+	if a.Start == b.Start && a.End == b.End {
+		return a.Decl < b.Decl
+	}
+	return a.Start <= b.Start && b.End <= a.End
+}
+
+// Innermost returns the smallest symbol of kind that contains line.
+func (f *File) Innermost(kind Kind, line int) (Symbol, bool) {
+	var best Symbol
+	found := false
+	for _, s := range f.Symbols {
+		if s.Kind != kind || line < s.Start || line > s.End {
+			continue
+		}
+		if !found || s.End-s.Start <= best.End-best.Start {
+			best, found = s, true
+		}
+	}
+	return best, found
+}
+
+// Innermost returns the smallest span that contains line.
+func Innermost(spans []Span, line int) (Span, bool) {
+	var best Span
+	found := false
+	for _, s := range spans {
+		if line < s.Open || line > s.Close {
+			continue
+		}
+		if !found || s.Close-s.Open <= best.Close-best.Open {
+			best, found = s, true
+		}
+	}
+	return best, found
+}
+
+// goSymbols parses Go source with go/parser. It reports false when the
+// content isn't a Go file, such as a snippet without a package clause.
+func goSymbols(filename, content string) ([]Symbol, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, content, parser.ParseComments|parser.SkipObjectResolution)
+	if file == nil || (err != nil && len(file.Decls) == 0) {
+		return nil, false
+	}
+	line := func(p token.Pos) int { return fset.Position(p).Line - 1 }
+	body := func(s *Symbol, open, close token.Pos) {
+		s.BodyStart, s.BodyEnd = line(open)+1, line(close)-1
+		if line(open) == line(close) {
+			s.BodyStart, s.BodyEnd = line(open), line(open)
+		}
+	}
+
+	symbols := []Symbol{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			s := Symbol{Kind: KindFunction, Name: n.Name.Name, Start: line(n.Pos()), Decl: line(n.Pos()), End: line(n.End())}
+			if n.Recv != nil && len(n.Recv.List) > 0 {
+				if recv := receiverName(n.Recv.List[0].Type); recv != "" {
+					s.Name = recv + "." + s.Name
+				}
+			}
+			if n.Doc != nil {
+				s.Start = line(n.Doc.Pos())
+			}
+			if n.Body != nil {
+				body(&s, n.Body.Lbrace, n.Body.Rbrace)
+			} else {
+				s.BodyStart, s.BodyEnd = s.End+1, s.End
+			}
+			symbols = append(symbols, s)
+		case *ast.FuncLit:
+			s := Symbol{Kind: KindFunction, Start: line(n.Pos()), Decl: line(n.Pos()), End: line(n.End())}
+			body(&s, n.Body.Lbrace, n.Body.Rbrace)
+			symbols = append(symbols, s)
+		case *ast.GenDecl:
+			if n.Tok != token.TYPE {
+				return true
+			}
+			for _, spec := range n.Specs {
+				ts := spec.(*ast.TypeSpec)
+				s := Symbol{Kind: KindType, Name: ts.Name.Name, Start: line(ts.Pos()), Decl: line(ts.Pos()), End: line(ts.End())}
+				doc := ts.Doc
+				if !n.Lparen.IsValid() {
+					s.Start, s.Decl, s.End = line(n.Pos()), line(n.Pos()), line(n.End())
+					doc = n.Doc
+				}
+				if doc != nil {
+					s.Start = line(doc.Pos())
+				}
+				switch t := ts.Type.(type) {
+				case *ast.StructType:
+					body(&s, t.Fields.Opening, t.Fields.Closing)
+				case *ast.InterfaceType:
+					body(&s, t.Methods.Opening, t.Methods.Closing)
+				default:
+					s.BodyStart, s.BodyEnd = s.Decl, s.End
+				}
+				symbols = append(symbols, s)
+			}
+		}
+		return true
+	})
+	return symbols, true
+}
+
+// receiverName returns the type name of a method receiver, without
+// pointers or type parameters.
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	}
+	return ""
+}
+
+// tok is a significant token on one line.
+type tok struct {
+	typ chroma.TokenType
+	val string
+}
+
+type scanned struct {
+	lineToks [][]tok
+	comment  []bool // lines holding only comments
+	braces   []Span // every {} pair, in order of opening
+	parens   []Span
+}
+
+// scan tokenizes content, collecting each line's tokens and matching
+// braces and parentheses outside strings and comments.
+func scan(lexer chroma.Lexer, content string, n int) *scanned {
+	s := &scanned{lineToks: make([][]tok, n), comment: make([]bool, n)}
+	hasCode := make([]bool, n)
+	var tokens []chroma.Token
+	if lexer != nil {
+		if it, err := chroma.Coalesce(lexer).Tokenise(nil, content); err == nil {
+			tokens = it.Tokens()
+		}
+	}
+	if tokens == nil {
+		tokens = []chroma.Token{{Type: chroma.Text, Value: content}}
+	}
+
+	var braceStack, parenStack []int
+	line := 0
+	for _, t := range tokens {
+		code := !t.Type.InCategory(chroma.Comment) && !t.Type.InCategory(chroma.LiteralString)
+		for i, part := range strings.Split(t.Value, "\n") {
+			if i > 0 {
+				line++
+			}
+			if line >= n {
+				break
+			}
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			s.lineToks[line] = append(s.lineToks[line], tok{t.Type, strings.TrimSpace(part)})
+			if t.Type.InCategory(chroma.Comment) {
+				s.comment[line] = true
+			} else {
+				hasCode[line] = true
+			}
+			if !code {
+				continue
+			}
+			for _, r := range part {
+				switch r {
+				case '{':
+					braceStack = append(braceStack, line)
+				case '}':
+					if k := len(braceStack); k > 0 {
+						s.braces = append(s.braces, Span{braceStack[k-1], line})
+						braceStack = braceStack[:k-1]
+					}
+				case '(':
+					parenStack = append(parenStack, line)
+				case ')':
+					if k := len(parenStack); k > 0 {
+						s.parens = append(s.parens, Span{parenStack[k-1], line})
+						parenStack = parenStack[:k-1]
+					}
+				}
+			}
+		}
+	}
+	for i := range s.comment {
+		s.comment[i] = s.comment[i] && !hasCode[i]
+	}
+	sort.SliceStable(s.braces, func(i, j int) bool { return s.braces[i].Open < s.braces[j].Open })
+	return s
+}
+
+// symbols finds declarations heuristically: a line with a declaration
+// keyword, a function or class name token, an arrow function or a
+// method-like "name(" header, followed by a brace-delimited body or, after
+// a trailing colon, an indented one.
+func (s *scanned) symbols(lines []string) []Symbol {
+	var symbols []Symbol
+	for i := range lines {
+		kind, name := declaration(s.lineToks[i])
+		if kind == "" {
+			continue
+		}
+		sym, ok := s.body(lines, i)
+		if !ok {
+			continue
+		}
+		sym.Kind, sym.Name = kind, name
+		for sym.Start > 0 && s.isPreamble(sym.Start-1) {
+			sym.Start--
+		}
+		symbols = append(symbols, sym)
+	}
+	return symbols
+}
+
+// declaration classifies the tokens of a line as a function or type
+// declaration, returning its name if it has one.
+func declaration(toks []tok) (Kind, string) {
+	i := 0
+	for i < len(toks) && modifiers[toks[i].val] {
+		i++
+	}
+	if i == len(toks) || controlKeywords[toks[i].val] || toks[i].typ.InCategory(chroma.Comment) {
+		return "", ""
+	}
+	toks = toks[i:]
+	for j, t := range toks {
+		switch {
+		case t.typ.InCategory(chroma.Keyword) && typeKeywords[t.val]:
+			return KindType, nameAfter(toks[j+1:])
+		case t.typ.InCategory(chroma.Keyword) && funcKeywords[t.val]:
+			return KindFunction, nameAfter(toks[j+1:])
+		case t.typ == chroma.NameClass:
+			return KindType, identifier(t.val)
+		case t.typ == chroma.NameFunction:
+			return KindFunction, identifier(t.val)
+		case t.val == "=>":
+			return KindFunction, nameAfter(toks[:1])
+		}
+	}
+	// A method-like header: name(...) { in languages that mark neither.
+	if len(toks) > 1 && toks[0].typ.InCategory(chroma.Name) && strings.HasPrefix(toks[1].val, "(") {
+		return KindFunction, identifier(toks[0].val)
+	}
+	if len(toks) > 0 && toks[0].typ.InCategory(chroma.Name) {
+		if name, rest, ok := strings.Cut(toks[0].val, "("); ok && name != "" && !strings.ContainsAny(rest, "=;") {
+			return KindFunction, identifier(name)
+		}
+	}
+	return "", ""
+}
+
+// nameAfter returns the first name among toks.
+func nameAfter(toks []tok) string {
+	for _, t := range toks {
+		if t.typ.InCategory(chroma.Name) {
+			return identifier(t.val)
+		}
+	}
+	return ""
+}
+
+// identifier trims s at the first character that can't be part of a name.
+func identifier(s string) string {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '$' {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// body finds the body of a declaration on line i: the first brace pair
+// opening within the header, or the indented lines after a header ending
+// with a colon.
+func (s *scanned) body(lines []string, i int) (Symbol, bool) {
+	sym := Symbol{Start: i, Decl: i}
+	for j := i; j < len(lines) && j < i+maxHeaderLines; j++ {
+		if j > i {
+			if kind, _ := declaration(s.lineToks[j]); kind != "" {
+				return sym, false
+			}
+		}
+		for _, p := range s.braces {
+			if p.Open == j {
+				sym.End = p.Close
+				sym.BodyStart, sym.BodyEnd = p.Open+1, p.Close-1
+				if p.Open == p.Close {
+					sym.BodyStart, sym.BodyEnd = p.Open, p.Open
+				}
+				return sym, true
+			}
+			if p.Open > j {
+				break
+			}
+		}
+		trimmed := strings.TrimSpace(lines[j])
+		if toks := s.lineToks[j]; len(toks) > 0 && toks[len(toks)-1].typ.InCategory(chroma.Comment) {
+			trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, toks[len(toks)-1].val))
+		}
+		switch {
+		case strings.HasSuffix(trimmed, ";"):
+			return sym, false
+		case strings.HasSuffix(trimmed, ":"):
+			indent := indentOf(lines[i])
+			end := j
+			for k := j + 1; k < len(lines); k++ {
+				if strings.TrimSpace(lines[k]) == "" {
+					continue
+				}
+				if indentOf(lines[k]) <= indent {
+					break
+				}
+				end = k
+			}
+			if end == j {
+				return sym, false
+			}
+			sym.End, sym.BodyStart, sym.BodyEnd = end, j+1, end
+			return sym, true
+		}
+	}
+	return sym, false
+}
+
+// isPreamble reports whether line belongs to the declaration below it: a
+// comment or a decorator.
+func (s *scanned) isPreamble(line int) bool {
+	if s.comment[line] {
+		return true
+	}
+	toks := s.lineToks[line]
+	return len(toks) > 0 && toks[0].typ == chroma.NameDecorator
+}
+
+// indentOf returns the width of the leading whitespace of line, counting
+// tabs to the next multiple of four.
+func indentOf(line string) int {
+	w := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			w++
+		case '\t':
+			w += 4 - w%4
+		default:
+			return w
+		}
+	}
+	return w
+}
@@ -0,0 +1,201 @@
+package structure
+
+import (
+	"strings"
+	"testing"
+)
+
+func lines(s string) []string {
+	return strings.Split(strings.TrimPrefix(s, "\n"), "\n")
+}
+
+func TestParseGo(t *testing.T) {
+	src := lines(`
+package main
+
+// Server serves.
+type Server struct {
+	addr string
+}
+
+type (
+	// ID identifies.
+	ID int
+)
+
+// Run runs the server.
+func (s *Server) Run() error {
+	go func() {
+		s.addr = "}"
+	}()
+	return nil
+}
+
+func noop() {}
+`)
+	f := Parse("main.go", src)
+
+	want := []Symbol{
+		{Kind: KindType, Name: "Server", Start: 2, Decl: 3, End: 5, BodyStart: 4, BodyEnd: 4},
+		{Kind: KindType, Name: "ID", Start: 8, Decl: 9, End: 9, BodyStart: 9, BodyEnd: 9},
+		{Kind: KindFunction, Name: "Server.Run", Start: 12, Decl: 13, End: 18, BodyStart: 14, BodyEnd: 17},
+		{Kind: KindFunction, Start: 14, Decl: 14, End: 16, BodyStart: 15, BodyEnd: 15, Depth: 1},
+		{Kind: KindFunction, Name: "noop", Start: 20, Decl: 20, End: 20, BodyStart: 20, BodyEnd: 20},
+	}
+	if len(f.Symbols) != len(want) {
+		t.Fatalf("got %d symbols: %+v", len(f.Symbols), f.Symbols)
+	}
+	for i, w := range want {
+		if f.Symbols[i] != w {
+			t.Errorf("symbol %d = %+v, want %+v", i, f.Symbols[i], w)
+		}
+	}
+
+	if s, ok := f.Innermost(KindFunction, 15); !ok || s.Name != "" || s.Start != 14 {
+		t.Errorf("expected the closure around line 15, got %+v, %v", s, ok)
+	}
+	if s, ok := f.Innermost(KindFunction, 12); !ok || s.Name != "Server.Run" {
+		t.Errorf("expected the doc comment to belong to Run, got %+v, %v", s, ok)
+	}
+	if _, ok := f.Innermost(KindFunction, 0); ok {
+		t.Error("expected no function around the package clause")
+	}
+
+	// The brace in the string literal doesn't count.
+	if b, ok := Innermost(f.Braces, 15); !ok || b != (Span{14, 16}) {
+		t.Errorf("Innermost brace = %+v, %v", b, ok)
+	}
+}
+
+func TestParseGoSnippetFallsBack(t *testing.T) {
+	f := Parse("", lines(`
+func add(a, b int) int {
+	return a + b
+}
+`))
+	if len(f.Symbols) != 1 || f.Symbols[0].Name != "add" || f.Symbols[0].End != 2 {
+		t.Errorf("expected the heuristic to find add, got %+v", f.Symbols)
+	}
+}
+
+func TestParsePython(t *testing.T) {
+	f := Parse("app.py", lines(`
+import os
+
+@dataclass
+class Point:
+    x: int
+
+    # Distance from the origin.
+    def norm(self):
+        total = 0
+        for v in (self.x,):
+            total += v
+        return total
+
+def main():
+    print(Point(1).norm())  # }
+`))
+	want := []Symbol{
+		{Kind: KindType, Name: "Point", Start: 2, Decl: 3, End: 11, BodyStart: 4, BodyEnd: 11},
+		{Kind: KindFunction, Name: "norm", Start: 6, Decl: 7, End: 11, BodyStart: 8, BodyEnd: 11, Depth: 1},
+		{Kind: KindFunction, Name: "main", Start: 13, Decl: 13, End: 14, BodyStart: 14, BodyEnd: 14},
+	}
+	if len(f.Symbols) != len(want) {
+		t.Fatalf("got %+v", f.Symbols)
+	}
+	for i, w := range want {
+		if f.Symbols[i] != w {
+			t.Errorf("symbol %d = %+v, want %+v", i, f.Symbols[i], w)
+		}
+	}
+}
+
+func TestParseTypeScript(t *testing.T) {
+	f := Parse("app.ts", lines(`
+export class Greeter extends Base {
+  /** Says hi. */
+  greet(name: string): string {
+    if (name) {
+      return "hi {" + name;
+    }
+    return "hi";
+  }
+}
+
+const shout = (s: string) => {
+  return s.toUpperCase();
+};
+
+function call(
+  a: number,
+  b: number,
+) {
+  shout(String(a + b));
+}
+`))
+	got := map[string]Symbol{}
+	for _, s := range f.Symbols {
+		got[s.Name] = s
+	}
+	checks := []struct {
+		name             string
+		kind             Kind
+		start, decl, end int
+	}{
+		{"Greeter", KindType, 0, 0, 8},
+		{"greet", KindFunction, 1, 2, 7},
+		{"shout", KindFunction, 10, 10, 12},
+		{"call", KindFunction, 14, 14, 19},
+	}
+	for _, c := range checks {
+		s, ok := got[c.name]
+		if !ok || s.Kind != c.kind || s.Start != c.start || s.Decl != c.decl || s.End != c.end {
+			t.Errorf("%s = %+v (found %v), want %s %d/%d-%d", c.name, s, ok, c.kind, c.start, c.decl, c.end)
+		}
+	}
+	if len(f.Symbols) != len(checks) {
+		t.Errorf("unexpected extra symbols %+v", f.Symbols)
+	}
+
+	if b, ok := Innermost(f.Braces, 4); !ok || b != (Span{3, 5}) {
+		t.Errorf("Innermost brace around line 4 = %+v, %v", b, ok)
+	}
+	if p, ok := Innermost(f.Parens, 15); !ok || p != (Span{14, 17}) {
+		t.Errorf("Innermost parens around line 15 = %+v, %v", p, ok)
+	}
+}
+
+func TestParseMarkdownHeadings(t *testing.T) {
+	f := Parse("plan.md", lines(`
+# Plan
+Intro.
+## Goals ##
+- fast
+`+"```sh"+`
+# not a heading
+`+"```"+`
+## Risks
+# Appendix
+#hashtag
+`))
+	want := []Symbol{
+		{Kind: KindHeading, Name: "Plan", Start: 0, Decl: 0, End: 7, BodyStart: 1, BodyEnd: 7},
+		{Kind: KindHeading, Name: "Goals", Start: 2, Decl: 2, End: 6, BodyStart: 3, BodyEnd: 6, Depth: 1},
+		{Kind: KindHeading, Name: "Risks", Start: 7, Decl: 7, End: 7, BodyStart: 8, BodyEnd: 7, Depth: 1},
+		{Kind: KindHeading, Name: "Appendix", Start: 8, Decl: 8, End: 10, BodyStart: 9, BodyEnd: 10},
+	}
+	if len(f.Symbols) != len(want) {
+		t.Fatalf("got %+v", f.Symbols)
+	}
+	for i, w := range want {
+		if f.Symbols[i] != w {
+			t.Errorf("symbol %d = %+v, want %+v", i, f.Symbols[i], w)
+		}
+	}
+
+	// Without a file name, headings are still found in plain text.
+	if f := Parse("", []string{"# Notes", "text"}); len(f.Symbols) != 1 || f.Symbols[0].Name != "Notes" {
+		t.Errorf("expected a heading in unnamed text, got %+v", f.Symbols)
+	}
+}
@@ -0,0 +1,392 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/diff"
+	"github.com/charly-vibes/fabbro/internal/fem"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Backgrounds of the words a change removes and adds.
+const (
+	removedBackground = "#5f0000"
+	addedBackground   = "#005f00"
+)
+
+// diffView is the full-screen diff of a change annotation.
+type diffView struct {
+	ann fem.Annotation
+	top int // first row shown
+}
+
+// diffRow is a row of a change annotation's diff: a line kept, a line
+// replaced (both sides), or a line only removed or only added.
+type diffRow struct {
+	old, new           string
+	oldLine, newLine   int      // 1-based, 0 when the row has no such side
+	oldSpans, newSpans [][2]int // bytes changed, highlighted word by word
+}
+
+// changeDiff diffs the lines a change annotation replaces against its
+// replacement, pairing removed and added lines to diff them word by word.
+func (m Model) changeDiff(ann fem.Annotation) []diffRow {
+	start, end := ann.Replaced()
+	start, end = max(start, 1), min(end, len(m.lines))
+	var old, replacement []string
+	if start <= end {
+		old = m.lines[start-1 : end]
+	}
+	if text := ann.Replacement(); text != "" {
+		replacement = strings.Split(text, "\n")
+	}
+
+	var rows []diffRow
+	edits := diff.Compute(old, replacement)
+	for i := 0; i < len(edits); {
+		if e := edits[i]; e.Op == diff.Equal {
+			rows = append(rows, diffRow{old: e.Text, new: e.Text, oldLine: start + e.A, newLine: e.B + 1})
+			i++
+			continue
+		}
+		var removed, added []diff.Edit
+		for ; i < len(edits) && edits[i].Op != diff.Equal; i++ {
+			if edits[i].Op == diff.Delete {
+				removed = append(removed, edits[i])
+			} else {
+				added = append(added, edits[i])
+			}
+		}
+		for j := 0; j < max(len(removed), len(added)); j++ {
+			var row diffRow
+			if j < len(removed) {
+				row.old, row.oldLine = removed[j].Text, start+removed[j].A
+				row.oldSpans = wholeSpan(row.old)
+			}
+			if j < len(added) {
+				row.new, row.newLine = added[j].Text, added[j].B+1
+				row.newSpans = wholeSpan(row.new)
+			}
+			if row.oldLine > 0 && row.newLine > 0 {
+				row.oldSpans, row.newSpans = wordSpans(row.old, row.new)
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// wordSpans diffs two lines word by word, returning the bytes of a removed
+// and the bytes of b added.
+func wordSpans(a, b string) (removed, added [][2]int) {
+	posA, posB := 0, 0
+	for _, e := range diff.Compute(diff.Words(a), diff.Words(b)) {
+		n := len(e.Text)
+		switch e.Op {
+		case diff.Equal:
+			posA, posB = posA+n, posB+n
+		case diff.Delete:
+			removed = addSpan(removed, posA, posA+n)
+			posA += n
+		case diff.Insert:
+			added = addSpan(added, posB, posB+n)
+			posB += n
+		}
+	}
+	return removed, added
+}
+
+// addSpan appends start-end to spans, extending the last span if they meet.
+func addSpan(spans [][2]int, start, end int) [][2]int {
+	if n := len(spans); n > 0 && spans[n-1][1] == start {
+		spans[n-1][1] = end
+		return spans
+	}
+	return append(spans, [2]int{start, end})
+}
+
+func wholeSpan(s string) [][2]int {
+	if s == "" {
+		return nil
+	}
+	return [][2]int{{0, len(s)}}
+}
+
+// kept reports whether the row is a line the change leaves as it is.
+func (r diffRow) kept() bool {
+	return r.oldLine > 0 && r.newLine > 0 && r.oldSpans == nil && r.newSpans == nil
+}
+
+// renderChangeDiff renders the diff of a change annotation as rows exactly
+// width columns wide: removed lines above added ones, or side by side.
+func (m Model) renderChangeDiff(ann fem.Annotation, width int, split bool) []string {
+	var out []string
+	for _, row := range m.changeDiff(ann) {
+		if !split {
+			switch {
+			case row.kept():
+				out = append(out, m.renderDiffSide(row.oldLine, " ", row.old, nil, "", width))
+			default:
+				if row.oldLine > 0 {
+					out = append(out, m.renderDiffSide(row.oldLine, "-", row.old, row.oldSpans, removedBackground, width))
+				}
+				if row.newLine > 0 {
+					out = append(out, m.renderDiffSide(0, "+", row.new, row.newSpans, addedBackground, width))
+				}
+			}
+			continue
+		}
+
+		leftWidth := (width - 3) / 2
+		rightWidth := width - 3 - leftWidth
+		left, right := strings.Repeat(" ", leftWidth), strings.Repeat(" ", rightWidth)
+		switch {
+		case row.kept():
+			left = m.renderDiffSide(row.oldLine, " ", row.old, nil, "", leftWidth)
+			right = m.renderDiffSide(0, " ", row.new, nil, "", rightWidth)
+		default:
+			if row.oldLine > 0 {
+				left = m.renderDiffSide(row.oldLine, "-", row.old, row.oldSpans, removedBackground, leftWidth)
+			}
+			if row.newLine > 0 {
+				right = m.renderDiffSide(0, "+", row.new, row.newSpans, addedBackground, rightWidth)
+			}
+		}
+		out = append(out, left+" │ "+right)
+	}
+	return out
+}
+
+// diffRowCount is the number of rows renderChangeDiff renders.
+func (m Model) diffRowCount(ann fem.Annotation, split bool) int {
+	rows := m.changeDiff(ann)
+	if split {
+		return len(rows)
+	}
+	n := 0
+	for _, row := range rows {
+		switch {
+		case row.kept():
+			n++
+		default:
+			n += min(row.oldLine, 1) + min(row.newLine, 1)
+		}
+	}
+	return n
+}
+
+// renderDiffSide renders one side of a diff row, "NNN - text", cut or
+// padded to width columns. line 0 leaves the line number blank.
+func (m Model) renderDiffSide(line int, sign, text string, spans [][2]int, background string, width int) string {
+	gutter := "    "
+	if line > 0 {
+		gutter = fmt.Sprintf("%3d ", line)
+	}
+	switch sign {
+	case "-":
+		sign = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(sign)
+	case "+":
+		sign = lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render(sign)
+	}
+	textWidth := max(width-6, 1)
+
+	suffix := ""
+	if r := []rune(text); len(r) > textWidth {
+		text, suffix = string(r[:textWidth-1]), "…"
+		var clipped [][2]int
+		for _, sp := range spans {
+			if sp[0] < len(text) {
+				clipped = append(clipped, [2]int{sp[0], min(sp[1], len(text))})
+			}
+		}
+		spans = clipped
+	}
+	pad := textWidth - len([]rune(text)) - len([]rune(suffix))
+	return gutter + sign + " " + m.renderDiffText(text, spans, background) + suffix + strings.Repeat(" ", max(pad, 0))
+}
+
+// renderDiffText renders a line with syntax highlighting, unless it is
+// off, and the spans on background.
+func (m Model) renderDiffText(line string, spans [][2]int, background string) string {
+	if m.options.syntax {
+		return m.highlighter.RenderSpans(line, spans, background)
+	}
+	style := lipgloss.NewStyle().Background(lipgloss.Color(background))
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		b.WriteString(line[last:sp[0]])
+		b.WriteString(style.Render(line[sp[0]:sp[1]]))
+		last = sp[1]
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// changeAnnotations lists the change annotations shown, in line order. The
+// per-line copies the inline editor makes of one change are listed once.
+func (m Model) changeAnnotations() []fem.Annotation {
+	var changes []fem.Annotation
+	for _, ann := range m.sortedAnnotations() {
+		if ann.Type != "change" {
+			continue
+		}
+		if n := len(changes); n > 0 && sameChange(changes[n-1], ann) {
+			continue
+		}
+		changes = append(changes, ann)
+	}
+	return changes
+}
+
+func sameChange(a, b fem.Annotation) bool {
+	aStart, aEnd := a.Replaced()
+	bStart, bEnd := b.Replaced()
+	return aStart == bStart && aEnd == bEnd && a.Replacement() == b.Replacement()
+}
+
+// toggleDiffPreview switches the preview of the change annotation under
+// the cursor between its text and its diff (D).
+func (m *Model) toggleDiffPreview() {
+	ann := m.previewedAnnotation()
+	if ann == nil || ann.Type != "change" {
+		m.lastError = "No change annotation on this line"
+		return
+	}
+	if m.diffPreviews == nil {
+		m.diffPreviews = map[fem.Annotation]bool{}
+	}
+	if m.diffPreviews[*ann] {
+		delete(m.diffPreviews, *ann)
+	} else {
+		m.diffPreviews[*ann] = true
+	}
+}
+
+// openChangeDiff shows the diff of a change full-screen (gd, :diff): the
+// one previewed, or else the next change from the cursor.
+func (m *Model) openChangeDiff() {
+	changes := m.changeAnnotations()
+	if len(changes) == 0 {
+		m.lastError = "No change annotations"
+		return
+	}
+	ann := changes[0]
+	if prev := m.previewedAnnotation(); prev != nil && prev.Type == "change" {
+		ann = *prev
+	} else {
+		for _, c := range changes {
+			if c.StartLine-1 >= m.cursor {
+				ann = c
+				break
+			}
+		}
+	}
+	m.diff = diffView{ann: ann}
+	m.mode = modeDiff
+}
+
+// diffCommand handles :diff.
+func (m *Model) diffCommand(_ string, _ bool) tea.Cmd {
+	m.openChangeDiff()
+	return nil
+}
+
+// diffPage is the number of diff rows the full-screen diff shows.
+func (m Model) diffPage() int {
+	return max(m.height-3, 5)
+}
+
+func (m Model) handleDiffMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	lastTop := max(m.diffRowCount(m.diff.ann, m.diffSplit)-m.diffPage(), 0)
+	switch msg.String() {
+	case "j", "down":
+		m.diff.top = min(m.diff.top+1, lastTop)
+	case "k", "up":
+		m.diff.top = max(m.diff.top-1, 0)
+	case "ctrl+d":
+		m.diff.top = min(m.diff.top+m.diffPage()/2, lastTop)
+	case "ctrl+u":
+		m.diff.top = max(m.diff.top-m.diffPage()/2, 0)
+	case "s":
+		m.diffSplit = !m.diffSplit
+		m.diff.top = 0
+	case "n":
+		m.stepChangeDiff(1)
+	case "N", "p":
+		m.stepChangeDiff(-1)
+	case "enter":
+		m.jumpTo(m.diff.ann.StartLine - 1)
+		m.mode = modeNormal
+	case "esc", "q":
+		m.mode = modeNormal
+	}
+	return m, nil
+}
+
+// stepChangeDiff shows the next (dir 1) or previous (dir -1) change.
+func (m *Model) stepChangeDiff(dir int) {
+	changes := m.changeAnnotations()
+	pos := -1
+	for i, c := range changes {
+		if sameChange(c, m.diff.ann) {
+			pos = i
+			break
+		}
+	}
+	next := pos + dir
+	if pos < 0 || next < 0 || next >= len(changes) {
+		if dir > 0 {
+			m.lastError = "No next change"
+		} else {
+			m.lastError = "No previous change"
+		}
+		return
+	}
+	m.diff = diffView{ann: changes[next]}
+}
+
+// renderDiffScreen renders the full-screen diff of a change annotation.
+func (m Model) renderDiffScreen(width int) string {
+	var b strings.Builder
+
+	ann := m.diff.ann
+	start, end := ann.Replaced()
+	position := ""
+	changes := m.changeAnnotations()
+	for i, c := range changes {
+		if sameChange(c, ann) {
+			position = fmt.Sprintf("(%d of %d changes) ", i+1, len(changes))
+		}
+	}
+	title := fmt.Sprintf("─── Change %s %s", lineSpan(start-1, end-1), position)
+	if r := []rune(title); len(r) > width {
+		title = string(r[:width])
+	}
+	b.WriteString(title + strings.Repeat("─", max(width-len([]rune(title)), 0)) + "\n")
+
+	rows := m.renderChangeDiff(ann, width, m.diffSplit)
+	page := m.diffPage()
+	for i := m.diff.top; i < len(rows) && i < m.diff.top+page; i++ {
+		b.WriteString(rows[i] + "\n")
+	}
+	for i := len(rows) - m.diff.top; i < page; i++ {
+		b.WriteString("~\n")
+	}
+
+	layout := "[s]ide by side"
+	if m.diffSplit {
+		layout = "[s] interleaved"
+	}
+	help := layout + "  [j/k] scroll  [n/N] next/prev change  [Enter] go to  [Esc] close"
+	if len([]rune(help)) > width {
+		help = string([]rune(help)[:width])
+	}
+	b.WriteString(help + "\n")
+	if m.lastError != "" {
+		b.WriteString(fmt.Sprintf("Error: %s\n", m.lastError))
+	}
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package tui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestChangeDiff(t *testing.T) {
+	m := New(newTestSession("a := 1\nb := oldName(a)\nc := 3\nd := 4"))
+	ann := fem.Annotation{Type: "change", StartLine: 2, EndLine: 2, Text: `[lines 2-4] -> b := newName(a)\nc := 3\ne := 5`}
+
+	rows := m.changeDiff(ann)
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %+v", rows)
+	}
+	if rows[0].oldLine != 2 || rows[0].newLine != 1 {
+		t.Errorf("expected line 2 paired with the first new line, got %+v", rows[0])
+	}
+	if got := rows[0].old[rows[0].oldSpans[0][0]:rows[0].oldSpans[0][1]]; got != "oldName" || len(rows[0].oldSpans) != 1 {
+		t.Errorf("expected oldName removed, got %q of %v", got, rows[0].oldSpans)
+	}
+	if got := rows[0].new[rows[0].newSpans[0][0]:rows[0].newSpans[0][1]]; got != "newName" || len(rows[0].newSpans) != 1 {
+		t.Errorf("expected newName added, got %q of %v", got, rows[0].newSpans)
+	}
+	if !rows[1].kept() || rows[1].oldLine != 3 {
+		t.Errorf("expected line 3 kept, got %+v", rows[1])
+	}
+	if rows[2].oldLine != 4 || rows[2].newLine != 3 {
+		t.Errorf("expected line 4 replaced by the last new line, got %+v", rows[2])
+	}
+}
+
+func TestChangeDiffUnpairedLines(t *testing.T) {
+	m := New(newTestSession("one\ntwo"))
+
+	removed := m.changeDiff(fem.Annotation{Type: "change", StartLine: 1, EndLine: 2, Text: "-> one"})
+	if len(removed) != 2 || !removed[0].kept() || removed[1].newLine != 0 || !reflect.DeepEqual(removed[1].oldSpans, [][2]int{{0, 3}}) {
+		t.Errorf("expected line 2 removed whole, got %+v", removed)
+	}
+
+	added := m.changeDiff(fem.Annotation{Type: "change", StartLine: 2, EndLine: 2, Text: `-> two\nthree`})
+	if len(added) != 2 || !added[0].kept() || added[1].oldLine != 0 || added[1].new != "three" {
+		t.Errorf("expected a line added, got %+v", added)
+	}
+}
+
+func TestRenderChangeDiff(t *testing.T) {
+	m := New(newTestSession("keep\nold text"))
+	m.options.syntax = false
+	ann := fem.Annotation{Type: "change", StartLine: 1, EndLine: 2, Text: `-> keep\nnew text`}
+
+	var got []string
+	for _, row := range m.renderChangeDiff(ann, 30, false) {
+		got = append(got, ansi.Strip(row))
+	}
+	want := []string{
+		"  1   keep                    ",
+		"  2 - old text                ",
+		"    + new text                ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("interleaved diff:\n%q\nwant\n%q", got, want)
+	}
+
+	got = nil
+	for _, row := range m.renderChangeDiff(ann, 33, true) {
+		got = append(got, ansi.Strip(row))
+	}
+	want = []string{
+		"  1   keep      │       keep     ",
+		"  2 - old text  │     + new text ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("side-by-side diff:\n%q\nwant\n%q", got, want)
+	}
+
+	long := m.renderChangeDiff(fem.Annotation{Type: "change", StartLine: 2, EndLine: 2, Text: "-> a much longer replacement"}, 20, false)
+	if got := ansi.Strip(long[1]); got != "    + a much longer…" {
+		t.Errorf("expected the long line cut to the width, got %q", got)
+	}
+}
+
+func TestToggleDiffPreview(t *testing.T) {
+	sess := newTestSession("alpha beta\ngamma")
+	m := NewWithAnnotations(sess, "", []fem.Annotation{
+		{Type: "comment", StartLine: 2, EndLine: 2, Text: "note"},
+		{Type: "change", StartLine: 1, EndLine: 1, Text: "[line 1] -> alpha delta"},
+	})
+	m.width = 80
+	m.height = 24
+
+	if view := m.View(); !strings.Contains(view, "[line 1] -> alpha delta") {
+		t.Fatalf("expected the raw text previewed first, got:\n%s", view)
+	}
+
+	m = sendKey(m, 'D')
+	view := ansi.Strip(m.View())
+	if !strings.Contains(view, "change [1-1] diff") || !strings.Contains(view, "1 - alpha beta") || !strings.Contains(view, "+ alpha delta") {
+		t.Errorf("expected the diff previewed, got:\n%s", view)
+	}
+
+	m = sendKey(m, 'j')
+	if m = sendKey(m, 'D'); m.lastError != "No change annotation on this line" {
+		t.Errorf("expected an error on a comment, got %q", m.lastError)
+	}
+
+	m = sendKey(m, 'k')
+	m = sendKey(m, 'D')
+	if view := m.View(); !strings.Contains(view, "[line 1] -> alpha delta") {
+		t.Errorf("expected D again to show the text, got:\n%s", view)
+	}
+}
+
+func TestDiffMode(t *testing.T) {
+	sess := newTestSession("one\ntwo\nthree\nfour")
+	m := NewWithAnnotations(sess, "", []fem.Annotation{
+		{Type: "change", StartLine: 3, EndLine: 3, Text: "[lines 3-4] -> 3\\n4"},
+		{Type: "change", StartLine: 4, EndLine: 4, Text: "[lines 3-4] -> 3\\n4"},
+		{Type: "change", StartLine: 1, EndLine: 1, Text: "-> ONE"},
+	})
+	m.width = 80
+	m.height = 24
+	m.options.syntax = false
+
+	if m = typeKeys(m, "gd"); m.mode != modeDiff {
+		t.Fatalf("expected gd to open the diff, got mode %d", m.mode)
+	}
+	view := ansi.Strip(m.View())
+	if !strings.Contains(view, "Change line 1 (1 of 2 changes)") || !strings.Contains(view, "+ ONE") {
+		t.Errorf("expected the first change, got:\n%s", view)
+	}
+
+	// The inline editor's per-line copies of a change count once.
+	m = sendKey(m, 'n')
+	view = ansi.Strip(m.View())
+	if !strings.Contains(view, "Change lines 3-4 (2 of 2 changes)") || !strings.Contains(view, "4 - four") {
+		t.Errorf("expected the second change, got:\n%s", view)
+	}
+	if m = sendKey(m, 'n'); m.lastError != "No next change" {
+		t.Errorf("expected no next change, got %q", m.lastError)
+	}
+
+	m = sendKey(m, 's')
+	if view := ansi.Strip(m.View()); !strings.Contains(view, "3 - three") || !strings.Contains(view, "│     + 3") {
+		t.Errorf("expected the diff side by side, got:\n%s", view)
+	}
+
+	m = sendKeyEnter(m)
+	if m.mode != modeNormal || m.cursor != 2 {
+		t.Errorf("expected Enter to go to the change, got mode %d cursor %d", m.mode, m.cursor)
+	}
+
+	m, _ = runEx(m, "diff")
+	if m.mode != modeDiff || m.diff.ann.StartLine != 3 {
+		t.Errorf("expected :diff to show the change under the cursor, got %+v", m.diff)
+	}
+	if m = sendKeyEsc(m); m.mode != modeNormal {
+		t.Errorf("expected Esc to close the diff, got mode %d", m.mode)
+	}
+
+	m = New(newTestSession("plain"))
+	if m, _ = runEx(m, "diff"); m.lastError != "No change annotations" {
+		t.Errorf("expected an error without changes, got %q", m.lastError)
+	}
+}
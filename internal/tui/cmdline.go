@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// cmdlineState is the : command line being typed.
+type cmdlineState struct {
+	input       string
+	completions []string // candidates shown after an ambiguous Tab
+	history     []string // lines run, oldest first
+	historyPos  int      // index into history while browsing, len(history) otherwise
+}
+
+// openCommandLine starts a : command line.
+func (m *Model) openCommandLine() {
+	m.cmdline.input = ""
+	m.cmdline.completions = nil
+	m.cmdline.historyPos = len(m.cmdline.history)
+	m.mode = modeCommand
+}
+
+func (m Model) handleCommandMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		line := m.cmdline.input
+		m.mode = modeNormal
+		m.cmdline.completions = nil
+		if strings.TrimSpace(line) == "" {
+			return m, nil
+		}
+		m.cmdline.history = append(m.cmdline.history, line)
+		return m, m.runCommandLine(line)
+	case tea.KeyEsc:
+		m.mode = modeNormal
+		m.cmdline.completions = nil
+	case tea.KeyBackspace:
+		if m.cmdline.input == "" {
+			m.mode = modeNormal
+			return m, nil
+		}
+		r := []rune(m.cmdline.input)
+		m.cmdline.input = string(r[:len(r)-1])
+		m.cmdline.completions = nil
+	case tea.KeyTab:
+		m.completeCmdline()
+	case tea.KeyUp:
+		if m.cmdline.historyPos > 0 {
+			m.cmdline.historyPos--
+			m.cmdline.input = m.cmdline.history[m.cmdline.historyPos]
+		}
+	case tea.KeyDown:
+		if m.cmdline.historyPos < len(m.cmdline.history) {
+			m.cmdline.historyPos++
+			m.cmdline.input = ""
+			if m.cmdline.historyPos < len(m.cmdline.history) {
+				m.cmdline.input = m.cmdline.history[m.cmdline.historyPos]
+			}
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.cmdline.input += string(msg.Runes)
+		m.cmdline.completions = nil
+	}
+	return m, nil
+}
+
+// completeCmdline completes the last word of the command line: fully when
+// one candidate matches, to the longest common prefix otherwise, listing
+// the candidates.
+func (m *Model) completeCmdline() {
+	matches := completeCommandLine(m, m.cmdline.input)
+	if len(matches) == 0 {
+		m.cmdline.completions = nil
+		return
+	}
+	word := m.cmdline.input
+	if i := strings.LastIndex(word, " "); i >= 0 {
+		word = word[i+1:]
+	}
+	base := strings.TrimSuffix(m.cmdline.input, word)
+
+	common := matches[0]
+	for _, c := range matches[1:] {
+		for !strings.HasPrefix(c, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	m.cmdline.input = base + common
+	if len(matches) == 1 {
+		if !strings.HasSuffix(common, "=") {
+			m.cmdline.input += " "
+		}
+		m.cmdline.completions = nil
+		return
+	}
+	m.cmdline.completions = matches
+}
+
+// renderCommandLine renders the command line and any completions.
+func (m Model) renderCommandLine(width int) string {
+	var b strings.Builder
+	if len(m.cmdline.completions) > 0 {
+		row := strings.Join(m.cmdline.completions, "  ")
+		if r := []rune(row); len(r) > width {
+			row = string(r[:width-1]) + "…"
+		}
+		b.WriteString(row)
+		b.WriteString("\n")
+	}
+	b.WriteString(":" + m.cmdline.input + "█")
+	return b.String()
+}
@@ -0,0 +1,497 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/output"
+	"github.com/charly-vibes/fabbro/internal/prompt"
+	"github.com/charly-vibes/fabbro/internal/report"
+	"github.com/charly-vibes/fabbro/internal/sarif"
+	"github.com/charly-vibes/fabbro/internal/search"
+	"github.com/charly-vibes/fabbro/internal/session"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// command is an action shared by the : command line and the palette.
+type command struct {
+	name      string
+	aliases   []string
+	usage     string // arguments, for completion hints
+	key       string // palette key, "" if the command isn't in the palette
+	selection bool   // the palette offers it only with a selection
+	run       func(m *Model, args string, bang bool) tea.Cmd
+	complete  func(m *Model, args string) []string // candidates for the last argument
+}
+
+// exportFormats are the formats :export writes.
+var exportFormats = []string{"fem", "json", "sarif", "prompt", "html", "markdown"}
+
+// commands is the command registry. Each annotation type is a command too,
+// so that :comment text annotates directly and the palette's c asks for
+// the text.
+var commands = func() []command {
+	cmds := []command{
+		{name: "write", aliases: []string{"w"}, key: "w", run: (*Model).write},
+		{name: "quit", aliases: []string{"q"}, key: "Q", run: func(m *Model, _ string, bang bool) tea.Cmd {
+			if m.dirty && !bang {
+				m.mode = modeQuitConfirm
+				return nil
+			}
+			return m.quit()
+		}},
+		{name: "wq", aliases: []string{"x"}, run: func(m *Model, args string, bang bool) tea.Cmd {
+			if m.write(args, bang); m.lastError != "" {
+				return clearMessageAfter(2 * time.Second)
+			}
+			return m.quit()
+		}},
+		{name: "submit", key: "s", run: func(m *Model, _ string, _ bool) tea.Cmd {
+			if err := m.submit(); err != nil {
+				if errors.Is(err, ErrTutorSession) {
+					m.lastMessage = "Tutorial sessions are not submitted"
+				} else {
+					m.lastError = err.Error()
+				}
+				return clearMessageAfter(2 * time.Second)
+			}
+			return m.quit()
+		}},
+		{name: "annotate", usage: "<type> [text]", run: func(m *Model, args string, _ bool) tea.Cmd {
+			annType, text, _ := strings.Cut(args, " ")
+			if !fem.ValidAnnotationType(annType) {
+				m.lastError = fmt.Sprintf("Unknown annotation type %q", annType)
+				return nil
+			}
+			m.annotateCommand(annType, text)
+			return nil
+		}, complete: func(_ *Model, args string) []string {
+			if strings.Contains(args, " ") {
+				return nil
+			}
+			return annotationTypeNames()
+		}},
+		{name: "edit", key: "i", selection: true, run: func(m *Model, _ string, _ bool) tea.Cmd {
+			if !m.selection.active {
+				m.selection = selection{active: true, anchor: m.cursor, cursor: m.cursor}
+			}
+			m.openEditor()
+			return nil
+		}},
+		{name: "filter", usage: "[type=<type>,...]", run: (*Model).filterCommand, complete: func(_ *Model, args string) []string {
+			var candidates []string
+			for _, t := range annotationTypeNames() {
+				candidates = append(candidates, "type="+t)
+			}
+			return candidates
+		}},
+		{name: "s", usage: "/pattern/", run: (*Model).selectMatches},
+		{name: "annotate-matches", aliases: []string{"am"}, usage: "<type> [text]", run: (*Model).annotateMatches, complete: func(_ *Model, args string) []string {
+			if strings.Contains(args, " ") {
+				return nil
+			}
+			return annotationTypeNames()
+		}},
+		{name: "export", usage: "<format> <file>", run: (*Model).export, complete: func(_ *Model, args string) []string {
+			if strings.Contains(args, " ") {
+				return nil
+			}
+			return exportFormats
+		}},
+		{name: "set", usage: "[option[=value]|nooption]", run: (*Model).setCommand, complete: func(_ *Model, _ string) []string {
+			return []string{"wrap", "nowrap", "syntax", "nosyntax", "scrolloff="}
+		}},
+		{name: "marks", run: (*Model).marksCommand},
+		{name: "diff", run: (*Model).diffCommand},
+		{name: "snippet", usage: "[name]", key: "n", run: (*Model).snippetCommand, complete: snippetNames},
+		{name: "help", run: func(m *Model, _ string, _ bool) tea.Cmd {
+			m.mode = modeHelp
+			return nil
+		}},
+	}
+	for _, t := range fem.AnnotationTypes {
+		annType := t.Name
+		cmds = append(cmds, command{name: annType, usage: "[text]", key: annotationKeys[annType], selection: true, run: func(m *Model, args string, _ bool) tea.Cmd {
+			m.annotateCommand(annType, args)
+			return nil
+		}})
+	}
+	return cmds
+}()
+
+// lookupCommand finds a command by name or alias.
+func lookupCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+		for _, a := range c.aliases {
+			if a == name {
+				return c, true
+			}
+		}
+	}
+	return command{}, false
+}
+
+// paletteCommand finds the command bound to a palette key.
+func paletteCommand(key string) (command, bool) {
+	for _, c := range commands {
+		if c.key != "" && c.key == key {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+func annotationTypeNames() []string {
+	names := make([]string, len(fem.AnnotationTypes))
+	for i, t := range fem.AnnotationTypes {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// parseCommandLine splits a command line into the command name, whether
+// it ends with !, and its arguments. A line number (:42, :$) is returned
+// as the name.
+func parseCommandLine(line string) (name string, bang bool, args string) {
+	line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), ":"))
+	if strings.HasPrefix(line, "s/") {
+		return "s", false, line[1:]
+	}
+	name, args, _ = strings.Cut(line, " ")
+	if strings.HasSuffix(name, "!") {
+		name, bang = strings.TrimSuffix(name, "!"), true
+	}
+	return name, bang, strings.TrimSpace(args)
+}
+
+// runCommandLine runs a : command line.
+func (m *Model) runCommandLine(line string) tea.Cmd {
+	name, bang, args := parseCommandLine(line)
+	if name == "" {
+		return nil
+	}
+	if name == "$" || isDigits(name) {
+		target := len(m.lines)
+		if name != "$" {
+			target, _ = strconv.Atoi(name)
+		}
+		m.jumpTo(max(min(target, len(m.lines)), 1) - 1)
+		return nil
+	}
+	c, ok := lookupCommand(name)
+	if !ok {
+		m.lastError = "Not a command: " + name
+		return nil
+	}
+	return c.run(m, args, bang)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// completeCommandLine returns the completions of line's last word: command
+// names, or the command's argument candidates.
+func completeCommandLine(m *Model, line string) []string {
+	name, rest, hasArgs := strings.Cut(strings.TrimLeft(line, " "), " ")
+	var candidates []string
+	prefix := name
+	if !hasArgs {
+		for _, c := range commands {
+			candidates = append(candidates, c.name)
+		}
+	} else {
+		c, ok := lookupCommand(strings.TrimSuffix(name, "!"))
+		if !ok || c.complete == nil {
+			return nil
+		}
+		candidates = c.complete(m, rest)
+		prefix = rest
+		if i := strings.LastIndex(rest, " "); i >= 0 {
+			prefix = rest[i+1:]
+		}
+	}
+	var matches []string
+	for _, cand := range candidates {
+		if strings.HasPrefix(cand, prefix) {
+			matches = append(matches, cand)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// write saves the session, reporting the outcome in the status bar.
+func (m *Model) write(_ string, _ bool) tea.Cmd {
+	if err := m.save(); err != nil {
+		if errors.Is(err, ErrTutorSession) {
+			m.lastMessage = "Tutorial sessions are not saved"
+		} else {
+			m.lastError = err.Error()
+		}
+		return clearMessageAfter(2 * time.Second)
+	}
+	m.dirty = false
+	m.lastMessage = "Saved!"
+	return clearMessageAfter(2 * time.Second)
+}
+
+// annotateCommand annotates the selection, or the cursor line, with text,
+// or opens the input for the text when there is none.
+func (m *Model) annotateCommand(annType, text string) {
+	if !m.selection.active {
+		m.selection = selection{active: true, anchor: m.cursor, cursor: m.cursor}
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		m.openInputMode(annType)
+		return
+	}
+	start, end := m.selection.lines()
+	m.addAnnotation(annType, encodeAnnText(text), start, end)
+	m.lastAnnotation = &repeatAnnotation{annType: annType, text: encodeAnnText(text), lines: end - start + 1}
+	m.selection = selection{}
+}
+
+// filterCommand shows only annotations of the listed types; without
+// arguments it shows them all again.
+func (m *Model) filterCommand(args string, _ bool) tea.Cmd {
+	var types []string
+	for _, field := range strings.Fields(args) {
+		key, value, _ := strings.Cut(field, "=")
+		if key != "type" || value == "" {
+			m.lastError = fmt.Sprintf("Unknown filter %q: use type=<type>[,<type>...]", field)
+			return nil
+		}
+		for _, t := range strings.Split(value, ",") {
+			if !fem.ValidAnnotationType(t) {
+				m.lastError = fmt.Sprintf("Unknown annotation type %q", t)
+				return nil
+			}
+			types = append(types, t)
+		}
+	}
+	m.typeFilter = types
+	m.resetPreviewIndex()
+	m.annotationsCursor = 0
+	if len(types) == 0 {
+		m.lastMessage = "Showing all annotations"
+	} else {
+		m.lastMessage = fmt.Sprintf("Showing %d of %d annotations", len(m.sortedAnnotations()), len(m.annotations))
+	}
+	return nil
+}
+
+// selectMatches handles :s/pattern/: lines matching the regular
+// expression become the search matches, and the run of adjacent matching
+// lines at or after the cursor is selected.
+func (m *Model) selectMatches(args string, _ bool) tea.Cmd {
+	if !strings.HasPrefix(args, "/") {
+		m.lastError = "Usage: :s/pattern/"
+		return nil
+	}
+	pattern := strings.TrimSuffix(args[1:], "/")
+	matcher, err := search.Compile(pattern, search.ModeRegex, false)
+	if err != nil {
+		m.lastError = "Invalid pattern: " + err.Error()
+		return nil
+	}
+
+	m.search = searchState{query: pattern, matcher: matcher}
+	for i, line := range m.lines {
+		if matcher.Match(line) {
+			m.search.matches = append(m.search.matches, i)
+		}
+	}
+	if len(m.search.matches) == 0 {
+		m.search = searchState{}
+		m.lastError = "No matches found"
+		return nil
+	}
+	for m.search.current < len(m.search.matches)-1 && m.search.matches[m.search.current] < m.cursor {
+		m.search.current++
+	}
+	if m.search.matches[m.search.current] < m.cursor {
+		m.search.current = 0
+	}
+
+	start := m.search.matches[m.search.current]
+	end := start
+	for i := m.search.current + 1; i < len(m.search.matches) && m.search.matches[i] == end+1; i++ {
+		end++
+	}
+	if end != m.cursor {
+		m.pushJump()
+	}
+	m.revealLine(start)
+	m.selection = selection{active: true, anchor: start, cursor: end}
+	m.cursor = end
+	m.viewportTop = -1
+	m.ensureCursorVisible()
+	m.resetPreviewIndex()
+	m.lastMessage = fmt.Sprintf("%d matching lines; selected %s", len(m.search.matches), lineSpan(start, end))
+	return nil
+}
+
+// annotateMatches handles :annotate-matches <type> [text]: one annotation
+// on every line the last search matched, as in flagging every TODO.
+// Without text, the input opens for it.
+func (m *Model) annotateMatches(args string, _ bool) tea.Cmd {
+	annType, text, _ := strings.Cut(args, " ")
+	if !fem.ValidAnnotationType(annType) {
+		m.lastError = fmt.Sprintf("Unknown annotation type %q", annType)
+		return nil
+	}
+	if len(m.search.matches) == 0 {
+		m.lastError = "No search matches: search with / or :s first"
+		return nil
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		m.openInputMode(annType)
+		m.inputMatches = true
+		return nil
+	}
+	m.annotateMatchLines(annType, encodeAnnText(text))
+	return nil
+}
+
+// annotateMatchLines adds an annotation with text on each search match.
+func (m *Model) annotateMatchLines(annType, text string) {
+	for _, line := range m.search.matches {
+		m.addAnnotation(annType, text, line, line)
+	}
+	m.lastAnnotation = &repeatAnnotation{annType: annType, text: text, lines: 1}
+	m.selection = selection{}
+	m.lastMessage = fmt.Sprintf("Added %d %s annotations", len(m.search.matches), annType)
+}
+
+// lineSpan formats 0-indexed lines as "line N" or "lines N-M".
+func lineSpan(start, end int) string {
+	if start == end {
+		return fmt.Sprintf("line %d", start+1)
+	}
+	return fmt.Sprintf("lines %d-%d", start+1, end+1)
+}
+
+// export writes the session with its current, possibly unsaved,
+// annotations to a file in one of the exportFormats.
+func (m *Model) export(args string, _ bool) tea.Cmd {
+	format, path, _ := strings.Cut(args, " ")
+	path = strings.TrimSpace(path)
+	if format == "" || path == "" {
+		m.lastError = "Usage: :export <" + strings.Join(exportFormats, "|") + "> <file>"
+		return nil
+	}
+
+	snapshot := m.snapshot()
+	var render func(io.Writer) error
+	switch format {
+	case "fem":
+		render = func(w io.Writer) error {
+			_, err := io.WriteString(w, session.Format(snapshot))
+			return err
+		}
+	case "json":
+		render = func(w io.Writer) error { return output.Write(w, output.NewApply(snapshot, m.annotations), false) }
+	case "sarif":
+		render = func(w io.Writer) error {
+			return output.Write(w, sarif.FromSession(snapshot, m.annotations, m.version), false)
+		}
+	case "prompt":
+		render = func(w io.Writer) error {
+			res, err := prompt.Render(snapshot, m.annotations, prompt.Options{Context: prompt.DefaultContext})
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(w, res.Text)
+			return err
+		}
+	case "html":
+		render = func(w io.Writer) error { return report.HTML(w, snapshot) }
+	case "markdown", "md":
+		render = func(w io.Writer) error { return report.Markdown(w, snapshot) }
+	default:
+		m.lastError = fmt.Sprintf("Unknown export format %q: use %s", format, strings.Join(exportFormats, ", "))
+		return nil
+	}
+
+	f, err := os.Create(path)
+	if err == nil {
+		err = render(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		m.lastError = "Export failed: " + err.Error()
+		return clearMessageAfter(2 * time.Second)
+	}
+	m.lastMessage = fmt.Sprintf("Exported %s to %s", format, path)
+	return clearMessageAfter(2 * time.Second)
+}
+
+// viewOptions are the display settings changed with :set.
+type viewOptions struct {
+	wrap      bool // wrap long lines instead of cutting them at the edge
+	syntax    bool // syntax highlighting
+	scrolloff int  // lines kept visible above and below the cursor
+}
+
+var defaultViewOptions = viewOptions{wrap: true, syntax: true, scrolloff: 2}
+
+// setCommand changes view options: :set wrap, :set nowrap,
+// :set scrolloff=5. Without arguments it shows the current settings.
+func (m *Model) setCommand(args string, _ bool) tea.Cmd {
+	if args == "" {
+		m.lastMessage = m.options.String()
+		return nil
+	}
+	for _, field := range strings.Fields(args) {
+		name, value, hasValue := strings.Cut(field, "=")
+		switch {
+		case name == "wrap" || name == "nowrap":
+			m.options.wrap = name == "wrap"
+		case name == "syntax" || name == "nosyntax":
+			m.options.syntax = name == "syntax"
+		case name == "scrolloff" && hasValue:
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				m.lastError = fmt.Sprintf("Invalid scrolloff %q: use a number of lines", value)
+				return nil
+			}
+			m.options.scrolloff = n
+		default:
+			m.lastError = fmt.Sprintf("Unknown option %q: use wrap, syntax or scrolloff=N", field)
+			return nil
+		}
+	}
+	m.viewportTop = -1
+	m.ensureCursorVisible()
+	m.lastMessage = m.options.String()
+	return nil
+}
+
+func (o viewOptions) String() string {
+	flag := func(on bool, name string) string {
+		if on {
+			return name
+		}
+		return "no" + name
+	}
+	return fmt.Sprintf("%s %s scrolloff=%d", flag(o.wrap, "wrap"), flag(o.syntax, "syntax"), o.scrolloff)
+}
@@ -0,0 +1,236 @@
+package tui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runEx types a : command line and runs it.
+func runEx(m Model, line string) (Model, tea.Cmd) {
+	m = sendKey(m, ':')
+	m = typeKeys(m, line)
+	newModel, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return newModel.(Model), cmd
+}
+
+func isQuit(cmd tea.Cmd) bool {
+	if cmd == nil {
+		return false
+	}
+	_, ok := cmd().(tea.QuitMsg)
+	return ok
+}
+
+func TestCommandLineGotoAndQuit(t *testing.T) {
+	m := New(newTestSession("a\nb\nc\nd"))
+	m.width = 80
+	m.height = 24
+
+	m = sendKey(m, ':')
+	if m.mode != modeCommand || !strings.Contains(m.View(), ":█") {
+		t.Fatalf("expected the command line, got mode %d", m.mode)
+	}
+	m = sendKeyType(m, tea.KeyEsc)
+	if m.mode != modeNormal {
+		t.Error("expected Esc to close the command line")
+	}
+
+	m, _ = runEx(m, "3")
+	if m.cursor != 2 || m.mode != modeNormal {
+		t.Errorf(":3 should move to line 3, got %d", m.cursor)
+	}
+	m, _ = runEx(m, "$")
+	if m.cursor != 3 {
+		t.Errorf(":$ should move to the last line, got %d", m.cursor)
+	}
+
+	m, _ = runEx(m, "nope")
+	if m.lastError != "Not a command: nope" {
+		t.Errorf("unexpected error %q", m.lastError)
+	}
+
+	if _, cmd := runEx(m, "q"); !isQuit(cmd) {
+		t.Error(":q should quit without changes")
+	}
+	m.dirty = true
+	if m2, cmd := runEx(m, "q"); isQuit(cmd) || m2.mode != modeQuitConfirm {
+		t.Error(":q with unsaved changes should ask first")
+	}
+	if _, cmd := runEx(m, "q!"); !isQuit(cmd) {
+		t.Error(":q! should quit")
+	}
+}
+
+func TestCommandLineAnnotate(t *testing.T) {
+	m := New(newTestSession("a\nb\nc"))
+	m.width = 80
+	m.height = 24
+
+	m = typeKeys(m, "vj")
+	m, _ = runEx(m, "annotate question why two?")
+	if len(m.annotations) != 1 || m.annotations[0] != (fem.Annotation{Type: "question", Text: "why two?", StartLine: 1, EndLine: 2}) || m.selection.active {
+		t.Fatalf("expected a question on the selection, got %+v", m.annotations)
+	}
+
+	// Without a selection, the cursor line; the type is a command too.
+	m, _ = runEx(m, "comment fine")
+	if a := m.annotations[1]; a.Type != "comment" || a.StartLine != 2 {
+		t.Errorf("expected a comment on the cursor line, got %+v", a)
+	}
+
+	// Without text, the input opens.
+	m, _ = runEx(m, "annotate delete")
+	if m.mode != modeInput || m.inputType != "delete" {
+		t.Errorf("expected the delete input, got mode %d", m.mode)
+	}
+	m = sendKeyType(m, tea.KeyEsc)
+
+	m, _ = runEx(m, "annotate bogus text")
+	if !strings.Contains(m.lastError, `Unknown annotation type "bogus"`) {
+		t.Errorf("unexpected error %q", m.lastError)
+	}
+}
+
+func TestCommandLineFilter(t *testing.T) {
+	m := NewWithAnnotations(newTestSession("a\nb\nc"), "", []fem.Annotation{
+		{Type: "comment", Text: "c1", StartLine: 1, EndLine: 1},
+		{Type: "question", Text: "q1", StartLine: 2, EndLine: 2},
+	})
+	m.width = 80
+	m.height = 24
+
+	m, _ = runEx(m, "filter type=question")
+	if len(m.sortedAnnotations()) != 1 || len(m.annotationsOnLine(1)) != 0 {
+		t.Errorf("expected only the question shown")
+	}
+	if !strings.Contains(m.View(), "[filter: type=question]") {
+		t.Error("expected the filter in the title")
+	}
+	m = sendKey(m, 'a')
+	if view := m.View(); !strings.Contains(view, "Annotations (1/2, type=question)") || strings.Contains(view, "c1") {
+		t.Errorf("expected the filtered list:\n%s", view)
+	}
+	m = sendKeyType(m, tea.KeyEsc)
+
+	m, _ = runEx(m, "filter type=nope")
+	if m.lastError == "" || len(m.typeFilter) != 1 {
+		t.Errorf("expected an invalid type to be rejected, got %q", m.lastError)
+	}
+	m, _ = runEx(m, "filter")
+	if len(m.typeFilter) != 0 || len(m.sortedAnnotations()) != 2 {
+		t.Error("expected :filter to clear the filter")
+	}
+}
+
+func TestCommandLineSubstituteSelects(t *testing.T) {
+	m := New(newTestSession("import a\nimport b\n\ncode\nimport c"))
+	m.width = 80
+	m.height = 24
+
+	m, _ = runEx(m, "s/^import/")
+	start, end := m.selection.lines()
+	if !m.selection.active || start != 0 || end != 1 || len(m.search.matches) != 3 {
+		t.Errorf("expected lines 1-2 selected of 3 matches, got %d-%d, %v", start, end, m.search.matches)
+	}
+	if m.lastMessage != "3 matching lines; selected lines 1-2" {
+		t.Errorf("unexpected message %q", m.lastMessage)
+	}
+
+	m, _ = runEx(m, "s/[/")
+	if !strings.HasPrefix(m.lastError, "Invalid pattern") {
+		t.Errorf("expected an invalid pattern error, got %q", m.lastError)
+	}
+}
+
+func TestCommandLineExport(t *testing.T) {
+	dir := t.TempDir()
+	m := NewWithAnnotations(newTestSession("a\nb"), "", []fem.Annotation{
+		{Type: "comment", Text: "unsaved", StartLine: 2, EndLine: 2},
+	})
+
+	for _, format := range exportFormats {
+		path := filepath.Join(dir, "out."+format)
+		m, _ = runEx(m, "export "+format+" "+path)
+		if m.lastError != "" {
+			t.Fatalf("%s: %s", format, m.lastError)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "unsaved") {
+			t.Errorf("%s export is missing the annotation:\n%s", format, data)
+		}
+		if format == "json" && !json.Valid(data) {
+			t.Errorf("invalid JSON export:\n%s", data)
+		}
+	}
+
+	m, _ = runEx(m, "export pdf x.pdf")
+	if !strings.Contains(m.lastError, `Unknown export format "pdf"`) {
+		t.Errorf("unexpected error %q", m.lastError)
+	}
+}
+
+func TestCommandLineSet(t *testing.T) {
+	long := strings.Repeat("x", 200)
+	m := New(newTestSession(long + "\nshort"))
+	m.width = 80
+	m.height = 24
+
+	if rows := m.lineRows(0, 60); rows != 4 {
+		t.Fatalf("expected the long line to wrap, got %d rows", rows)
+	}
+	m, _ = runEx(m, "set nowrap scrolloff=0")
+	if rows := m.lineRows(0, 60); rows != 1 || m.options.scrolloff != 0 {
+		t.Errorf("expected nowrap and scrolloff=0, got %d rows, %+v", rows, m.options)
+	}
+	if m.lastMessage != "nowrap syntax scrolloff=0" {
+		t.Errorf("unexpected message %q", m.lastMessage)
+	}
+	m, _ = runEx(m, "set bogus")
+	if !strings.Contains(m.lastError, `Unknown option "bogus"`) {
+		t.Errorf("unexpected error %q", m.lastError)
+	}
+}
+
+func TestCommandLineCompletion(t *testing.T) {
+	m := New(newTestSession("a"))
+	m.width = 80
+	m.height = 24
+
+	m = sendKey(m, ':')
+	m = typeKeys(m, "expo")
+	m = sendKeyType(m, tea.KeyTab)
+	if m.cmdline.input != "export " {
+		t.Errorf("expected the command completed, got %q", m.cmdline.input)
+	}
+	m = sendKey(m, 's')
+	m = sendKeyType(m, tea.KeyTab)
+	if m.cmdline.input != "export sarif " {
+		t.Errorf("expected the format completed, got %q", m.cmdline.input)
+	}
+
+	m = sendKeyType(m, tea.KeyEsc)
+	m = sendKey(m, ':')
+	m = typeKeys(m, "annotate e")
+	m = sendKeyType(m, tea.KeyTab)
+	if m.cmdline.input != "annotate e" || !strings.Contains(m.View(), "emphasize  expand") {
+		t.Errorf("expected the candidates listed, got %q", m.cmdline.input)
+	}
+
+	// Up recalls earlier command lines.
+	m = sendKeyType(m, tea.KeyEsc)
+	m, _ = runEx(m, "set wrap")
+	m = sendKey(m, ':')
+	m = sendKeyType(m, tea.KeyUp)
+	if m.cmdline.input != "set wrap" {
+		t.Errorf("expected history recall, got %q", m.cmdline.input)
+	}
+}
@@ -0,0 +1,201 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charly-vibes/fabbro/internal/structure"
+)
+
+// fold is a closed fold: lines start through end (0-indexed, inclusive)
+// shown as a single summary row at start.
+type fold struct {
+	start, end int
+}
+
+// foldAt returns the outermost closed fold containing line.
+func (m *Model) foldAt(line int) (fold, bool) {
+	var found fold
+	ok := false
+	for _, f := range m.folds {
+		if f.start <= line && line <= f.end && (!ok || f.start < found.start || (f.start == found.start && f.end > found.end)) {
+			found, ok = f, true
+		}
+	}
+	return found, ok
+}
+
+// displayLine returns the line whose row shows line: the start of the
+// closed fold hiding it, or line itself.
+func (m *Model) displayLine(line int) int {
+	if f, ok := m.foldAt(line); ok {
+		return f.start
+	}
+	return line
+}
+
+// nextLine returns the first line shown after line's row.
+func (m *Model) nextLine(line int) int {
+	if f, ok := m.foldAt(line); ok {
+		return f.end + 1
+	}
+	return line + 1
+}
+
+// prevLine returns the line shown in the row before line's.
+func (m *Model) prevLine(line int) int {
+	return m.displayLine(line - 1)
+}
+
+// stepLines moves n shown lines from line, down for positive n, stopping
+// at the first and last lines.
+func (m *Model) stepLines(line, n int) int {
+	for ; n > 0; n-- {
+		next := m.nextLine(line)
+		if next >= len(m.lines) {
+			break
+		}
+		line = next
+	}
+	for ; n < 0 && line > 0; n++ {
+		line = m.prevLine(line)
+	}
+	return line
+}
+
+// lineRows returns how many screen rows line takes: one for a closed fold,
+// otherwise one per wrapped segment.
+func (m *Model) lineRows(line, width int) int {
+	if f, ok := m.foldAt(line); ok && f.start == line {
+		return 1
+	}
+	return len(m.wrap(m.lines[line], width))
+}
+
+// foldRegions returns the regions that can be folded: sections and
+// functions and types from the structure parse, fenced code blocks and
+// multi-line brace blocks.
+func (m *Model) foldRegions() []fold {
+	if m.structure == nil {
+		m.structure = structure.Parse(m.sourceFile, m.lines)
+	}
+	var regions []fold
+	add := func(start, end int) {
+		if start >= end {
+			return
+		}
+		for _, r := range regions {
+			if r.start == start && r.end == end {
+				return
+			}
+		}
+		regions = append(regions, fold{start, end})
+	}
+	for _, s := range m.structure.Symbols {
+		add(s.Start, s.End)
+	}
+	for _, b := range m.structure.Braces {
+		add(b.Open, b.Close)
+	}
+	for _, b := range codeBlocks(m.lines) {
+		add(b.Open, b.Close)
+	}
+	return regions
+}
+
+// closeFold closes the smallest region around the cursor. On a closed
+// fold, it closes the region enclosing that fold instead.
+func (m *Model) closeFold() {
+	regions := m.foldRegions()
+	if s, e := FindIndentBlock(m.lines, m.cursor, true); s >= 0 && indentWidth(m.lines[s]) < indentWidth(m.lines[m.cursor]) {
+		regions = append(regions, fold{s, e})
+	}
+
+	inner := fold{m.cursor, m.cursor}
+	closed, isClosed := m.foldAt(m.cursor)
+	if isClosed {
+		inner = closed
+	}
+	var best fold
+	found := false
+	for _, r := range regions {
+		if r.start > inner.start || r.end < inner.end || r.start == r.end || (isClosed && r == closed) {
+			continue
+		}
+		if !found || r.end-r.start < best.end-best.start {
+			best, found = r, true
+		}
+	}
+	if !found {
+		m.lastError = "No fold at cursor"
+		return
+	}
+	m.folds = append(m.folds, best)
+	m.cursor = best.start
+	m.viewportTop = -1
+	m.ensureCursorVisible()
+	m.resetPreviewIndex()
+}
+
+// openFold opens the outermost closed fold at the cursor, leaving folds
+// nested in it closed.
+func (m *Model) openFold() {
+	f, ok := m.foldAt(m.cursor)
+	if !ok {
+		m.lastError = "No fold at cursor"
+		return
+	}
+	m.removeFolds(func(g fold) bool { return g == f })
+}
+
+// closeAllFolds closes every foldable region.
+func (m *Model) closeAllFolds() {
+	m.folds = m.foldRegions()
+	if len(m.folds) == 0 {
+		m.lastError = "Nothing to fold"
+		return
+	}
+	m.viewportTop = -1
+	m.ensureCursorVisible()
+	m.resetPreviewIndex()
+}
+
+// revealLine opens the folds hiding line, so that a jump to it lands on
+// the line itself.
+func (m *Model) revealLine(line int) {
+	m.removeFolds(func(f fold) bool { return f.start < line && line <= f.end })
+}
+
+func (m *Model) removeFolds(match func(fold) bool) {
+	var kept []fold
+	for _, f := range m.folds {
+		if !match(f) {
+			kept = append(kept, f)
+		}
+	}
+	m.folds = kept
+}
+
+// annotationsIn counts the annotations overlapping lines start through
+// end (0-indexed).
+func (m *Model) annotationsIn(start, end int) int {
+	n := 0
+	for _, a := range m.annotations {
+		if a.StartLine-1 <= end && a.EndLine-1 >= start && m.shown(a) {
+			n++
+		}
+	}
+	return n
+}
+
+// foldSummary describes a closed fold's hidden lines and annotations.
+func (m *Model) foldSummary(f fold) string {
+	summary := fmt.Sprintf(" ▸ %d lines", f.end-f.start+1)
+	switch n := m.annotationsIn(f.start, f.end); n {
+	case 0:
+	case 1:
+		summary += ", 1 annotation"
+	default:
+		summary += fmt.Sprintf(", %d annotations", n)
+	}
+	return summary
+}
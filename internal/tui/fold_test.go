@@ -0,0 +1,127 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const foldSource = `package main
+
+func a() {
+	if true {
+		x := 1
+		_ = x
+	}
+}
+
+func b() {}
+`
+
+func TestFoldCloseAndOpen(t *testing.T) {
+	m := NewWithAnnotations(newTestSession(foldSource), "main.go", []fem.Annotation{
+		{Type: "comment", Text: "why?", StartLine: 5, EndLine: 5},
+		{Type: "delete", Text: "drop", StartLine: 6, EndLine: 6},
+	})
+	m.width = 80
+	m.height = 24
+	m.cursor = 4
+
+	// The innermost region is the if block.
+	m = sendKey(m, 'z')
+	m = sendKey(m, 'c')
+	if len(m.folds) != 1 || m.folds[0] != (fold{3, 6}) || m.cursor != 3 {
+		t.Fatalf("expected the if block folded with the cursor on it, got %v at %d", m.folds, m.cursor)
+	}
+	view := m.View()
+	if !strings.Contains(view, "▸ 4 lines, 2 annotations") || strings.Contains(view, "x := 1") {
+		t.Errorf("expected a summary row for the if block:\n%s", view)
+	}
+
+	// j steps over the fold, k lands back on its summary row.
+	m = sendKey(m, 'j')
+	if m.cursor != 7 {
+		t.Errorf("expected j to skip the folded lines, got %d", m.cursor)
+	}
+	m = sendKey(m, 'k')
+	if m.cursor != 3 {
+		t.Errorf("expected k to land on the fold, got %d", m.cursor)
+	}
+
+	// zc on a closed fold closes the enclosing function.
+	m = sendKey(m, 'z')
+	m = sendKey(m, 'c')
+	if f, ok := m.foldAt(5); !ok || f != (fold{2, 7}) || m.cursor != 2 {
+		t.Errorf("expected the function folded around the if block, got %v, %v", f, ok)
+	}
+
+	// zo opens one level, leaving the inner fold closed.
+	m = sendKey(m, 'z')
+	m = sendKey(m, 'o')
+	if f, ok := m.foldAt(5); !ok || f != (fold{3, 6}) {
+		t.Errorf("expected the if block still folded, got %v, %v", f, ok)
+	}
+
+	m.cursor = 0
+	m = sendKey(m, 'z')
+	m = sendKey(m, 'o')
+	if m.lastError != "No fold at cursor" {
+		t.Errorf("expected an error without a fold, got %q", m.lastError)
+	}
+}
+
+func TestFoldAllAndReveal(t *testing.T) {
+	m := NewWithFile(newTestSession(foldSource), "main.go")
+	m.width = 80
+	m.height = 24
+
+	m = sendKey(m, 'z')
+	m = sendKey(m, 'M')
+	if line, ok := m.screenToLine(4); !ok || line != 8 {
+		t.Errorf("expected the row after the folded function to show line 8, got %d, %v", line, ok)
+	}
+
+	// Searching opens the folds hiding the match.
+	m = sendKey(m, '/')
+	for _, r := range "_ = x" {
+		m = sendKey(m, r)
+	}
+	m = sendKeyType(m, tea.KeyEnter)
+	if m.cursor != 5 {
+		t.Errorf("expected the search to land on line 5, got %d", m.cursor)
+	}
+	if _, ok := m.foldAt(5); ok {
+		t.Error("expected the match to be unfolded")
+	}
+
+	m = sendKey(m, 'z')
+	m = sendKey(m, 'R')
+	if len(m.folds) != 0 {
+		t.Errorf("expected zR to open every fold, got %v", m.folds)
+	}
+}
+
+func TestFoldMarkdownSectionsAndCodeBlocks(t *testing.T) {
+	src := "# Intro\ntext\n```go\ncode\n```\n# Next\nmore"
+	m := NewWithFile(newTestSession(src), "notes.md")
+	m.width = 80
+	m.height = 24
+	m.cursor = 3
+
+	m = sendKey(m, 'z')
+	m = sendKey(m, 'c')
+	if len(m.folds) != 1 || m.folds[0] != (fold{2, 4}) {
+		t.Fatalf("expected the code block folded, got %v", m.folds)
+	}
+	m = sendKey(m, 'z')
+	m = sendKey(m, 'c')
+	if f, _ := m.foldAt(2); f != (fold{0, 4}) {
+		t.Errorf("expected the section folded next, got %v", f)
+	}
+	m = sendKey(m, 'j')
+	if m.cursor != 5 {
+		t.Errorf("expected j to reach the next heading, got %d", m.cursor)
+	}
+}
@@ -1,12 +1,13 @@
 package tui
 
 import (
-	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/search"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -83,6 +84,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleHelpMode(msg)
 		case modeAnnotations:
 			return m.handleAnnotationsMode(msg)
+		case modeOutline:
+			return m.handleOutlineMode(msg)
+		case modeCommand:
+			return m.handleCommandMode(msg)
+		case modeDiff:
+			return m.handleDiffMode(msg)
+		case modeSnippets:
+			return m.handleSnippetsMode(msg)
 		default:
 			return m.handleNormalMode(msg)
 		}
@@ -109,17 +118,31 @@ func (m *Model) openInputMode(annType string) {
 
 	m.inputTA = &ta
 	m.inputType = annType
+	m.inputRepeat = repeatAnnotation{}
+	m.inputMatches = false
 	m.mode = modeInput
 }
 
 func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.operator != nil {
+		return m.handleOperatorKey(msg.String())
+	}
+
 	if m.gPending {
 		m.gPending = false
+		count := m.count
+		m.count = 0
 		if msg.String() == "g" {
-			m.cursor = 0
-			m.viewportTop = -1
+			target := 0
+			if count > 0 {
+				target = min(count, len(m.lines)) - 1
+			}
 			m.autoViewportTop = 0
-			m.resetPreviewIndex()
+			m.jumpTo(target)
+			return m, nil
+		}
+		if msg.String() == "d" {
+			m.openChangeDiff()
 			return m, nil
 		}
 	}
@@ -132,11 +155,23 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		switch msg.String() {
 		case "z":
-			m.viewportTop = m.cursor - visibleLines/2
+			m.viewportTop = m.stepLines(m.cursor, -visibleLines/2)
 		case "t":
 			m.viewportTop = m.cursor
 		case "b":
-			m.viewportTop = m.cursor - visibleLines + 1
+			m.viewportTop = m.stepLines(m.cursor, -visibleLines+1)
+		case "c":
+			m.closeFold()
+			return m, nil
+		case "o":
+			m.openFold()
+			return m, nil
+		case "M":
+			m.closeAllFolds()
+			return m, nil
+		case "R":
+			m.folds = nil
+			return m, nil
 		}
 		if m.viewportTop < 0 {
 			m.viewportTop = 0
@@ -150,29 +185,60 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if m.aPending {
 		m.aPending = false
 		if m.selection.active {
-			switch msg.String() {
-			case "p":
-				start, end := FindParagraph(m.lines, m.cursor)
-				m.selection.anchor = start
-				m.selection.cursor = end
-				m.cursor = end
-			case "b":
-				start, end := FindCodeBlock(m.lines, m.cursor)
-				if start >= 0 {
-					m.selection.anchor = start
-					m.selection.cursor = end
-					m.cursor = end
-				}
-			case "s":
-				start, end := FindSection(m.lines, m.cursor)
-				m.selection.anchor = start
-				m.selection.cursor = end
-				m.cursor = end
-			}
+			m.selectTextObject(msg.String(), false)
 		}
 		return m, nil
 	}
 
+	if m.iPending {
+		m.iPending = false
+		m.selectTextObject(msg.String(), true)
+		return m, nil
+	}
+
+	if m.markPending != "" {
+		pending := m.markPending
+		m.markPending = ""
+		switch key := msg.String(); {
+		case key == "esc":
+		case pending == "m":
+			m.setMark(key)
+		default:
+			m.gotoMark(key)
+		}
+		return m, nil
+	}
+
+	if m.bracketPending != "" {
+		dir := 1
+		if m.bracketPending == "[" {
+			dir = -1
+		}
+		m.bracketPending = ""
+		count := max(m.count, 1)
+		m.count = 0
+		switch key := msg.String(); key {
+		case "esc":
+		case "x":
+			m.jumpToUnreviewed(dir, count)
+		default:
+			m.jumpToAnnotation(dir, count, key)
+		}
+		return m, nil
+	}
+
+	// tab is ctrl+i only right after moving in the jumplist.
+	browsing := m.jumps.browsing
+	m.jumps.browsing = false
+
+	if key := msg.String(); len(key) == 1 && key[0] >= '0' && key[0] <= '9' && (key != "0" || m.count > 0) {
+		m.count = m.count*10 + int(key[0]-'0')
+		return m, nil
+	}
+	count := max(m.count, 1)
+	counted := m.count > 0
+	m.count = 0
+
 	switch msg.String() {
 	case "ctrl+c":
 		now := time.Now()
@@ -186,8 +252,8 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, clearMessageAfter(2 * time.Second)
 
 	case "j", "down":
-		if m.cursor < len(m.lines)-1 {
-			m.cursor++
+		if next := m.stepLines(m.cursor, count); next != m.cursor {
+			m.cursor = next
 			m.viewportTop = -1
 			m.ensureCursorVisible()
 			m.resetPreviewIndex()
@@ -198,7 +264,7 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "k", "up":
 		if m.cursor > 0 {
-			m.cursor--
+			m.cursor = m.stepLines(m.cursor, -count)
 			m.viewportTop = -1
 			m.ensureCursorVisible()
 			m.resetPreviewIndex()
@@ -212,10 +278,7 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if halfPage < 1 {
 			halfPage = 1
 		}
-		m.cursor += halfPage
-		if m.cursor > len(m.lines)-1 {
-			m.cursor = len(m.lines) - 1
-		}
+		m.cursor = m.stepLines(m.cursor, halfPage)
 		m.viewportTop = -1
 		m.ensureCursorVisible()
 		m.resetPreviewIndex()
@@ -225,22 +288,23 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if halfPage < 1 {
 			halfPage = 1
 		}
-		m.cursor -= halfPage
-		if m.cursor < 0 {
-			m.cursor = 0
-		}
+		m.cursor = m.stepLines(m.cursor, -halfPage)
 		m.viewportTop = -1
 		m.ensureCursorVisible()
 		m.resetPreviewIndex()
 
 	case "g":
 		m.gPending = true
+		if counted {
+			m.count = count // for 5gg
+		}
 
 	case "G":
-		m.cursor = len(m.lines) - 1
-		m.viewportTop = -1
-		m.ensureCursorVisible()
-		m.resetPreviewIndex()
+		target := len(m.lines) - 1
+		if counted {
+			target = min(count, len(m.lines)) - 1
+		}
+		m.jumpTo(target)
 
 	case "z":
 		m.zPending = true
@@ -260,16 +324,22 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "c":
 		if m.selection.active {
 			m.openInputMode("comment")
+		} else {
+			m.startOperator("c", count, counted)
 		}
 
 	case "d":
 		if m.selection.active {
 			m.openInputMode("delete")
+		} else {
+			m.startOperator("d", count, counted)
 		}
 
 	case "q":
 		if m.selection.active {
 			m.openInputMode("question")
+		} else {
+			m.startOperator("q", count, counted)
 		}
 
 	case "e":
@@ -282,11 +352,15 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "u":
 		if m.selection.active {
 			m.openInputMode("unclear")
+		} else {
+			m.startOperator("u", count, counted)
 		}
 
 	case "r":
 		if m.selection.active {
 			m.openInputMode("change")
+		} else {
+			m.startOperator("r", count, counted)
 		}
 
 	case "R":
@@ -307,6 +381,8 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "i":
 		if m.selection.active {
 			m.openEditor()
+		} else {
+			m.iPending = true
 		}
 
 	case "a":
@@ -317,6 +393,9 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.annotationsCursor = 0
 		}
 
+	case "o":
+		m.openOutline()
+
 	case "{":
 		if m.selection.active {
 			if m.selection.cursor > m.selection.anchor {
@@ -345,17 +424,10 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.mode = modePalette
 
 	case "w":
-		if err := m.save(); err != nil {
-			if errors.Is(err, ErrTutorSession) {
-				m.lastMessage = "Tutorial sessions are not saved"
-			} else {
-				m.lastError = err.Error()
-			}
-			return m, clearMessageAfter(2 * time.Second)
-		}
-		m.dirty = false
-		m.lastMessage = "Saved!"
-		return m, clearMessageAfter(2 * time.Second)
+		return m, m.write("", false)
+
+	case ":":
+		m.openCommandLine()
 
 	case "/":
 		m.mode = modeSearch
@@ -367,12 +439,37 @@ func (m Model) handleNormalMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "N", "p":
 		m.jumpToPrevMatch()
 
+	case "m", "'":
+		m.markPending = msg.String()
+
+	case "]", "[":
+		m.bracketPending = msg.String()
+		if counted {
+			m.count = count // for 3]a
+		}
+
+	case "ctrl+o":
+		m.jumpOlder(count)
+
+	case "x":
+		m.toggleReviewed(count)
+
+	case "D":
+		m.toggleDiffPreview()
+
 	case "tab":
-		m.cyclePreviewAnnotation(1)
+		if browsing {
+			m.jumpNewer(count)
+		} else {
+			m.cyclePreviewAnnotation(1)
+		}
 
 	case "shift+tab":
 		m.cyclePreviewAnnotation(-1)
 
+	case ".":
+		m.repeatLastAnnotation()
+
 	case "?":
 		m.mode = modeHelp
 	}
@@ -388,7 +485,7 @@ func (m Model) handleHelpMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m Model) handleAnnotationsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "j", "down":
-		if len(m.annotations) > 0 && m.annotationsCursor < len(m.annotations)-1 {
+		if m.annotationsCursor < len(m.sortedAnnotations())-1 {
 			m.annotationsCursor++
 		}
 	case "k", "up":
@@ -396,11 +493,8 @@ func (m Model) handleAnnotationsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.annotationsCursor--
 		}
 	case "enter":
-		if len(m.annotations) > 0 && m.annotationsCursor < len(m.annotations) {
-			ann := m.sortedAnnotations()[m.annotationsCursor]
-			m.cursor = ann.StartLine - 1 // 0-indexed
-			m.viewportTop = -1
-			m.ensureCursorVisible()
+		if sorted := m.sortedAnnotations(); m.annotationsCursor < len(sorted) {
+			m.jumpTo(sorted[m.annotationsCursor].StartLine - 1)
 		}
 		m.mode = modeNormal
 	case "esc":
@@ -411,8 +505,12 @@ func (m Model) handleAnnotationsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 // sortedAnnotations returns annotations sorted by StartLine, then EndLine.
 func (m Model) sortedAnnotations() []fem.Annotation {
-	sorted := make([]fem.Annotation, len(m.annotations))
-	copy(sorted, m.annotations)
+	var sorted []fem.Annotation
+	for _, ann := range m.annotations {
+		if m.shown(ann) {
+			sorted = append(sorted, ann)
+		}
+	}
 	for i := 1; i < len(sorted); i++ {
 		for j := i; j > 0; j-- {
 			if sorted[j].StartLine < sorted[j-1].StartLine ||
@@ -458,59 +556,17 @@ func (m Model) handlePaletteMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleAnnotationPicker(msg)
 	}
 
-	switch msg.String() {
-	case "w":
-		if err := m.save(); err != nil {
-			if errors.Is(err, ErrTutorSession) {
-				m.lastMessage = "Tutorial sessions are not saved"
-			} else {
-				m.lastError = err.Error()
-			}
-			m.mode = modeNormal
-			return m, clearMessageAfter(2 * time.Second)
-		}
-		m.dirty = false
-		m.lastMessage = "Saved!"
-		m.mode = modeNormal
-		return m, clearMessageAfter(2 * time.Second)
-	case "Q":
-		return m, tea.Quit
-	case "c":
-		if m.selection.active {
-			m.openInputMode("comment")
-		}
-	case "d":
-		if m.selection.active {
-			m.openInputMode("delete")
-		}
-	case "q":
-		if m.selection.active {
-			m.openInputMode("question")
-		}
-	case "e":
-		if m.selection.active {
-			m.openInputMode("expand")
-		}
-	case "k":
-		if m.selection.active {
-			m.openInputMode("keep")
-		}
-	case "u":
-		if m.selection.active {
-			m.openInputMode("unclear")
-		}
-	case "r":
-		if m.selection.active {
-			m.openInputMode("change")
-		}
-	case "i":
-		if m.selection.active {
-			m.openEditor()
-		}
-	default:
+	c, ok := paletteCommand(msg.String())
+	if !ok {
 		m.mode = modeNormal
+		return m, nil
 	}
-	return m, nil
+	if c.selection && !m.selection.active {
+		return m, nil
+	}
+	m.mode = modeNormal
+	// Palette commands run as if forced: Q quits at once, like :q!.
+	return m, c.run(&m, "", true)
 }
 
 func (m Model) handleAnnotationPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
@@ -560,29 +616,16 @@ func (m Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.Type {
 	case tea.KeyEnter:
 		inputValue := strings.TrimSpace(m.inputTA.Value())
-		if inputValue != "" {
+		if inputValue != "" && m.inputMatches {
+			m.annotateMatchLines(m.inputType, encodeAnnText(inputValue))
+		} else if inputValue != "" {
 			start, end := m.selection.lines()
 			text := encodeAnnText(inputValue)
+			m.addAnnotation(m.inputType, text, start, end)
 
-			if m.inputType == "change" {
-				startLine := start + 1
-				endLine := end + 1
-				var lineRef string
-				if startLine == endLine {
-					lineRef = fmt.Sprintf("[line %d] -> ", startLine)
-				} else {
-					lineRef = fmt.Sprintf("[lines %d-%d] -> ", startLine, endLine)
-				}
-				text = lineRef + text
-			}
-
-			m.annotations = append(m.annotations, fem.Annotation{
-				StartLine: start + 1,
-				EndLine:   end + 1,
-				Type:      m.inputType,
-				Text:      text,
-			})
-			m.dirty = true
+			repeat := m.inputRepeat
+			repeat.annType, repeat.text, repeat.lines = m.inputType, text, end-start+1
+			m.lastAnnotation = &repeat
 		}
 		m.mode = modeNormal
 		m.inputTA = nil
@@ -595,6 +638,10 @@ func (m Model) handleInputMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.inputTA = nil
 		m.inputType = ""
 		return m, nil
+
+	case tea.KeyCtrlS:
+		m.openSnippets(true)
+		return m, nil
 	}
 
 	var cmd tea.Cmd
@@ -757,9 +804,9 @@ func (m Model) handleQuitConfirmMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				m.mode = modeNormal
 				return m, clearMessageAfter(2 * time.Second)
 			}
-			return m, tea.Quit
+			return m, m.quit()
 		case "n", "N":
-			return m, tea.Quit
+			return m, m.quit()
 		default:
 			m.mode = modeNormal
 			m.lastMessage = "Quit cancelled"
@@ -768,7 +815,7 @@ func (m Model) handleQuitConfirmMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 	switch msg.String() {
 	case "y", "Y":
-		return m, tea.Quit
+		return m, m.quit()
 	default:
 		m.mode = modeNormal
 		m.lastMessage = "Quit cancelled"
@@ -824,10 +871,8 @@ func (m Model) handleSearchMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.search.query != "" {
 			m.performSearch()
 			if len(m.search.matches) > 0 {
-				m.cursor = m.search.matches[m.search.current]
-				m.viewportTop = -1
-				m.ensureCursorVisible()
-			} else {
+				m.focusSearchMatch()
+			} else if m.lastError == "" {
 				m.lastError = "No matches found"
 			}
 		}
@@ -845,7 +890,26 @@ func (m Model) handleSearchMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
-	case tea.KeyRunes:
+	case tea.KeyTab:
+		switch m.searchOpts.mode {
+		case search.ModeFuzzy:
+			m.searchOpts.mode = search.ModeLiteral
+		case search.ModeLiteral:
+			m.searchOpts.mode = search.ModeRegex
+		default:
+			m.searchOpts.mode = search.ModeFuzzy
+		}
+		return m, nil
+
+	case tea.KeyCtrlT:
+		m.searchOpts.matchCase = !m.searchOpts.matchCase
+		return m, nil
+
+	case tea.KeyCtrlA:
+		m.searchOpts.annotations = !m.searchOpts.annotations
+		return m, nil
+
+	case tea.KeyRunes, tea.KeySpace:
 		m.search.query += string(msg.Runes)
 		return m, nil
 	}
@@ -853,16 +917,37 @@ func (m Model) handleSearchMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// performSearch finds the lines matching the query with the current search
+// options: content lines, or the first lines of annotations whose text
+// matches.
 func (m *Model) performSearch() {
 	m.search.matches = nil
 	m.search.current = 0
+	m.search.matcher = nil
 	if m.search.query == "" {
 		return
 	}
-	query := strings.ToLower(m.search.query)
-	for i, line := range m.lines {
-		if fuzzyMatch(strings.ToLower(line), query) {
-			m.search.matches = append(m.search.matches, i)
+	matcher, err := search.Compile(m.search.query, m.searchOpts.mode, !m.searchOpts.matchCase)
+	if err != nil {
+		m.lastError = "Invalid pattern: " + err.Error()
+		return
+	}
+	m.search.matcher = matcher
+	m.search.annotations = m.searchOpts.annotations
+	if m.search.annotations {
+		found := map[int]bool{}
+		for _, ann := range m.annotations {
+			if m.shown(ann) && matcher.Match(decodeAnnText(ann.Text)) && !found[ann.StartLine-1] {
+				found[ann.StartLine-1] = true
+				m.search.matches = append(m.search.matches, ann.StartLine-1)
+			}
+		}
+		sort.Ints(m.search.matches)
+	} else {
+		for i, line := range m.lines {
+			if matcher.Match(line) {
+				m.search.matches = append(m.search.matches, i)
+			}
 		}
 	}
 	if len(m.search.matches) > 0 {
@@ -876,14 +961,19 @@ func (m *Model) performSearch() {
 	}
 }
 
-func fuzzyMatch(text, pattern string) bool {
-	pIdx := 0
-	for _, r := range text {
-		if pIdx < len(pattern) && byte(r) == pattern[pIdx] {
-			pIdx++
+// focusSearchMatch moves the cursor to the current match. When searching
+// annotations, the matching annotation on that line is previewed.
+func (m *Model) focusSearchMatch() {
+	line := m.search.matches[m.search.current]
+	m.jumpTo(line)
+	if m.search.annotations {
+		for i, idx := range m.annotationsOnLine(line + 1) {
+			if m.search.matcher.Match(decodeAnnText(m.annotations[idx].Text)) {
+				m.previewIndex, m.previewLine = i, line+1
+				break
+			}
 		}
 	}
-	return pIdx == len(pattern)
 }
 
 func (m *Model) jumpToNextMatch() {
@@ -891,9 +981,7 @@ func (m *Model) jumpToNextMatch() {
 		return
 	}
 	m.search.current = (m.search.current + 1) % len(m.search.matches)
-	m.cursor = m.search.matches[m.search.current]
-	m.viewportTop = -1
-	m.ensureCursorVisible()
+	m.focusSearchMatch()
 }
 
 func (m *Model) jumpToPrevMatch() {
@@ -904,7 +992,5 @@ func (m *Model) jumpToPrevMatch() {
 	if m.search.current < 0 {
 		m.search.current = len(m.search.matches) - 1
 	}
-	m.cursor = m.search.matches[m.search.current]
-	m.viewportTop = -1
-	m.ensureCursorVisible()
+	m.focusSearchMatch()
 }
@@ -0,0 +1,246 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/session"
+	"github.com/charly-vibes/fabbro/internal/tutor"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxJumps bounds the jumplist; the oldest jumps are dropped first.
+const maxJumps = 100
+
+// jumpList is where large movements (G, gg, :42, searches, marks, ]a and
+// outline or annotation jumps) started from, for ctrl+o and ctrl+i.
+type jumpList struct {
+	lines    []int // 0-indexed lines, oldest first
+	pos      int   // index while moving with ctrl+o/ctrl+i, len(lines) otherwise
+	browsing bool  // the last key moved in the jumplist, so tab is ctrl+i
+}
+
+// annotationKeys are the keys of the annotation types, used by the palette
+// and by ]c, [q and the like.
+var annotationKeys = map[string]string{"comment": "c", "delete": "d", "question": "q", "expand": "e", "keep": "k", "unclear": "u", "change": "r"}
+
+// pushJump records the cursor line as the start of a jump. A line already
+// in the jumplist moves to its end.
+func (m *Model) pushJump() {
+	lines := m.jumps.lines[:0:0]
+	for _, l := range m.jumps.lines {
+		if l != m.cursor {
+			lines = append(lines, l)
+		}
+	}
+	lines = append(lines, m.cursor)
+	if len(lines) > maxJumps {
+		lines = lines[len(lines)-maxJumps:]
+	}
+	m.jumps = jumpList{lines: lines, pos: len(lines)}
+}
+
+// jumpTo moves the cursor to line as a jump, recording where it came from.
+func (m *Model) jumpTo(line int) {
+	if line != m.cursor {
+		m.pushJump()
+	}
+	m.moveTo(line)
+}
+
+// moveTo moves the cursor to line, opening the folds hiding it.
+func (m *Model) moveTo(line int) {
+	m.revealLine(line)
+	m.cursor = line
+	m.viewportTop = -1
+	m.ensureCursorVisible()
+	m.resetPreviewIndex()
+}
+
+// jumpOlder goes back count entries in the jumplist (ctrl+o).
+func (m *Model) jumpOlder(count int) {
+	if m.jumps.pos == len(m.jumps.lines) && len(m.jumps.lines) > 0 {
+		// Remember the line left so that ctrl+i comes back to it.
+		m.pushJump()
+		m.jumps.pos = len(m.jumps.lines) - 1
+	}
+	m.moveInJumps(-count, "No older jump")
+}
+
+// jumpNewer goes forward count entries in the jumplist (ctrl+i).
+func (m *Model) jumpNewer(count int) {
+	m.moveInJumps(count, "No newer jump")
+}
+
+func (m *Model) moveInJumps(delta int, missing string) {
+	target := max(min(m.jumps.pos+delta, len(m.jumps.lines)-1), 0)
+	if len(m.jumps.lines) == 0 || target == m.jumps.pos {
+		m.lastError = missing
+		return
+	}
+	m.jumps.pos = target
+	m.jumps.browsing = true
+	m.moveTo(m.jumps.lines[target])
+}
+
+// setMark sets mark r (a-z) at the cursor line (m{a-z}).
+func (m *Model) setMark(r string) {
+	if !isMarkName(r) {
+		m.lastError = "Marks are a-z"
+		return
+	}
+	if m.marks == nil {
+		m.marks = map[string]int{}
+	}
+	m.marks[r] = m.cursor
+	m.lastMessage = fmt.Sprintf("Mark %s set at line %d", r, m.cursor+1)
+}
+
+// gotoMark jumps to mark r ('{a-z}).
+func (m *Model) gotoMark(r string) {
+	line, ok := m.marks[r]
+	if !ok {
+		m.lastError = fmt.Sprintf("Mark %s not set", r)
+		return
+	}
+	m.jumpTo(line)
+}
+
+func isMarkName(r string) bool {
+	return len(r) == 1 && r[0] >= 'a' && r[0] <= 'z'
+}
+
+// marksCommand handles :marks, listing the marks set.
+func (m *Model) marksCommand(_ string, _ bool) tea.Cmd {
+	if len(m.marks) == 0 {
+		m.lastMessage = "No marks set"
+		return nil
+	}
+	names := make([]string, 0, len(m.marks))
+	for r := range m.marks {
+		names = append(names, r)
+	}
+	sort.Strings(names)
+	for i, r := range names {
+		names[i] = fmt.Sprintf("%s:%d", r, m.marks[r]+1)
+	}
+	m.lastMessage = "Marks " + strings.Join(names, " ")
+	return nil
+}
+
+// jumpToAnnotation jumps count annotations forward (dir 1, ]a) or back
+// (dir -1, [a) from the cursor, counting annotations by the line they
+// start on. key is a for any annotation, or an annotation type's key to
+// move between annotations of that type. Annotations hidden by :filter
+// are skipped.
+func (m *Model) jumpToAnnotation(dir, count int, key string) {
+	annType := ""
+	if key != "a" {
+		for t, k := range annotationKeys {
+			if k == key {
+				annType = t
+			}
+		}
+		if annType == "" {
+			m.lastError = fmt.Sprintf("Unknown annotation key: %s (use a, c d q e k u r or x)", key)
+			return
+		}
+	}
+
+	var starts []int
+	for _, ann := range m.sortedAnnotations() {
+		if annType != "" && ann.Type != annType {
+			continue
+		}
+		if line := ann.StartLine - 1; len(starts) == 0 || starts[len(starts)-1] != line {
+			starts = append(starts, line)
+		}
+	}
+
+	target := -1
+	if dir > 0 {
+		for _, line := range starts {
+			if line > m.cursor {
+				target = line
+				if count--; count == 0 {
+					break
+				}
+			}
+		}
+	} else {
+		for i := len(starts) - 1; i >= 0; i-- {
+			if starts[i] < m.cursor {
+				target = starts[i]
+				if count--; count == 0 {
+					break
+				}
+			}
+		}
+	}
+
+	if target < 0 {
+		what := "annotation"
+		if annType != "" {
+			what = annType
+		}
+		if dir > 0 {
+			m.lastError = "No next " + what
+		} else {
+			m.lastError = "No previous " + what
+		}
+		return
+	}
+	m.jumpTo(target)
+}
+
+// State returns the cursor, marks and jumplist for resume to restore.
+func (m Model) State() *session.State {
+	st := &session.State{Cursor: m.cursor + 1}
+	for r, line := range m.marks {
+		if st.Marks == nil {
+			st.Marks = map[string]int{}
+		}
+		st.Marks[r] = line + 1
+	}
+	for _, line := range m.jumps.lines {
+		st.Jumps = append(st.Jumps, line+1)
+	}
+	return st
+}
+
+// WithState restores the cursor, marks and jumplist saved when the
+// session was last closed. Lines past the end of the content are dropped.
+func (m Model) WithState(st *session.State) Model {
+	valid := func(line int) bool { return line >= 1 && line <= len(m.lines) }
+	if valid(st.Cursor) {
+		m.cursor = st.Cursor - 1
+		m.ensureCursorVisible()
+	}
+	m.marks = nil
+	for r, line := range st.Marks {
+		if isMarkName(r) && valid(line) {
+			if m.marks == nil {
+				m.marks = map[string]int{}
+			}
+			m.marks[r] = line - 1
+		}
+	}
+	m.jumps = jumpList{}
+	for _, line := range st.Jumps {
+		if valid(line) {
+			m.jumps.lines = append(m.jumps.lines, line-1)
+		}
+	}
+	m.jumps.pos = len(m.jumps.lines)
+	return m
+}
+
+// quit saves the TUI state for the next resume and quits.
+func (m *Model) quit() tea.Cmd {
+	if m.session.ID != tutor.SessionID {
+		// Quitting goes ahead even if the state can't be saved.
+		_ = session.SaveState(m.session.ID, m.State())
+	}
+	return tea.Quit
+}
@@ -0,0 +1,166 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/session"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func numberedLines(n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestMarks(t *testing.T) {
+	m := New(newTestSession(numberedLines(30)))
+	m.width = 80
+	m.height = 24
+
+	m = typeKeys(m, "5jma")
+	if m.lastMessage != "Mark a set at line 6" {
+		t.Errorf("unexpected message %q", m.lastMessage)
+	}
+	m = typeKeys(m, "G'a")
+	if m.cursor != 5 {
+		t.Errorf("'a: expected line 5, got %d", m.cursor)
+	}
+	m = typeKeys(m, "'b")
+	if m.lastError != "Mark b not set" {
+		t.Errorf("unexpected error %q", m.lastError)
+	}
+	m = typeKeys(m, "m1")
+	if m.lastError != "Marks are a-z" {
+		t.Errorf("unexpected error %q", m.lastError)
+	}
+
+	m, _ = runEx(m, "marks")
+	if m.lastMessage != "Marks a:6" {
+		t.Errorf("unexpected :marks output %q", m.lastMessage)
+	}
+}
+
+func TestJumplist(t *testing.T) {
+	m := New(newTestSession(numberedLines(30)))
+	m.width = 80
+	m.height = 24
+
+	m = sendKeyType(m, tea.KeyCtrlO)
+	if m.lastError != "No older jump" {
+		t.Errorf("expected an empty jumplist, got %q", m.lastError)
+	}
+
+	// 0 -> G (29) -> :10 (9) -> gg (0), with j moves in between not recorded.
+	m = typeKeys(m, "jjG")
+	m, _ = runEx(m, "10")
+	m = typeKeys(m, "gg")
+
+	m = sendKeyType(m, tea.KeyCtrlO)
+	if m.cursor != 9 {
+		t.Errorf("ctrl+o: expected line 9, got %d", m.cursor)
+	}
+	m = sendKeyType(m, tea.KeyCtrlO)
+	if m.cursor != 29 {
+		t.Errorf("ctrl+o: expected line 29, got %d", m.cursor)
+	}
+	m = sendKeyType(m, tea.KeyTab)
+	if m.cursor != 9 {
+		t.Errorf("ctrl+i: expected line 9, got %d", m.cursor)
+	}
+	m = sendKeyType(m, tea.KeyTab)
+	if m.cursor != 0 {
+		t.Errorf("ctrl+i: expected to come back to line 0, got %d", m.cursor)
+	}
+	m = sendKeyType(m, tea.KeyTab)
+	if m.lastError != "No newer jump" {
+		t.Errorf("unexpected error %q", m.lastError)
+	}
+
+	// Any other key ends jumplist browsing, so tab cycles previews again.
+	m = sendKeyType(m, tea.KeyCtrlO)
+	m = typeKeys(m, "j")
+	m = sendKeyType(m, tea.KeyTab)
+	if m.cursor != 10 {
+		t.Errorf("tab after j should not jump, got line %d", m.cursor)
+	}
+}
+
+func TestJumpToAnnotation(t *testing.T) {
+	m := NewWithAnnotations(newTestSession(numberedLines(20)), "", []fem.Annotation{
+		{Type: "comment", Text: "c", StartLine: 3, EndLine: 4},
+		{Type: "question", Text: "q", StartLine: 8, EndLine: 8},
+		{Type: "comment", Text: "c2", StartLine: 8, EndLine: 9},
+		{Type: "comment", Text: "c3", StartLine: 15, EndLine: 15},
+	})
+	m.width = 80
+	m.height = 24
+
+	cases := []struct {
+		keys   string
+		cursor int
+	}{
+		{"]a", 2},
+		{"]a", 7},
+		{"]a", 14},
+		{"[a", 7},
+		{"2[a", 2},
+		{"]q", 7},
+		{"]c", 14},
+		{"[c", 7},
+	}
+	for _, c := range cases {
+		m = typeKeys(m, c.keys)
+		if m.cursor != c.cursor {
+			t.Errorf("%s: expected line %d, got %d (%s)", c.keys, c.cursor, m.cursor, m.lastError)
+		}
+	}
+
+	m = typeKeys(m, "]q")
+	if m.lastError != "No next question" {
+		t.Errorf("unexpected error %q", m.lastError)
+	}
+	m = typeKeys(m, "]z")
+	if !strings.HasPrefix(m.lastError, "Unknown annotation key: z") {
+		t.Errorf("unexpected error %q", m.lastError)
+	}
+
+	// ]a skips annotations hidden by :filter, and is a jump.
+	m, _ = runEx(m, "filter type=question")
+	m = typeKeys(m, "gg]a")
+	if m.cursor != 7 {
+		t.Errorf("filtered ]a: expected line 7, got %d", m.cursor)
+	}
+	m = sendKeyType(m, tea.KeyCtrlO)
+	if m.cursor != 0 {
+		t.Errorf("ctrl+o after ]a: expected line 0, got %d", m.cursor)
+	}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	m := New(newTestSession(numberedLines(10)))
+	m.width = 80
+	m.height = 24
+	m = typeKeys(m, "3jmbG")
+
+	st := m.State()
+	want := session.State{Cursor: 10, Marks: map[string]int{"b": 4}, Jumps: []int{4}}
+	if st.Cursor != want.Cursor || st.Marks["b"] != 4 || len(st.Jumps) != 1 || st.Jumps[0] != 4 {
+		t.Fatalf("got %+v, want %+v", st, want)
+	}
+
+	// Lines past the end of the content are dropped on restore.
+	st.Marks["z"] = 99
+	restored := New(newTestSession(numberedLines(10))).WithState(st)
+	if restored.cursor != 9 || restored.marks["b"] != 3 || len(restored.marks) != 1 || len(restored.jumps.lines) != 1 {
+		t.Errorf("unexpected restored state: cursor %d, marks %v, jumps %v", restored.cursor, restored.marks, restored.jumps.lines)
+	}
+	restored = sendKeyType(restored, tea.KeyCtrlO)
+	if restored.cursor != 3 {
+		t.Errorf("expected the restored jumplist to work, got line %d", restored.cursor)
+	}
+}
@@ -6,7 +6,9 @@ import (
 
 	"github.com/charly-vibes/fabbro/internal/fem"
 	"github.com/charly-vibes/fabbro/internal/highlight"
+	"github.com/charly-vibes/fabbro/internal/search"
 	"github.com/charly-vibes/fabbro/internal/session"
+	"github.com/charly-vibes/fabbro/internal/structure"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -22,6 +24,10 @@ const (
 	modeSearch
 	modeHelp
 	modeAnnotations
+	modeOutline
+	modeCommand
+	modeDiff
+	modeSnippets
 )
 
 type editorState struct {
@@ -53,9 +59,35 @@ func (s selection) lines() (start, end int) {
 }
 
 type searchState struct {
-	query   string     // current search query
-	matches []int      // line indices (0-indexed) that match
-	current int        // index into matches for current match
+	query   string          // current search query
+	matches []int           // line indices (0-indexed) that match
+	current int             // index into matches for current match
+	matcher *search.Matcher // compiled query, for highlighting match spans
+
+	annotations bool // matches are lines of annotations whose text matched
+}
+
+// searchOptions are the / search settings, kept between searches.
+type searchOptions struct {
+	mode        search.Mode // fuzzy, literal or regex
+	matchCase   bool        // case-sensitive matching
+	annotations bool        // search annotation text instead of content
+}
+
+// String describes the options for the search prompt, e.g. "regex Aa annotations".
+func (o searchOptions) String() string {
+	parts := []string{string(o.mode)}
+	if o.matchCase {
+		parts = append(parts, "Aa")
+	} else {
+		parts = append(parts, "aa")
+	}
+	if o.annotations {
+		parts = append(parts, "annotations")
+	} else {
+		parts = append(parts, "content")
+	}
+	return strings.Join(parts, " ")
 }
 
 type Model struct {
@@ -70,13 +102,19 @@ type Model struct {
 	width          int
 	height         int
 	gPending       bool   // waiting for second 'g' in gg command
-	zPending       bool   // waiting for second key in z commands (zz, zt, zb)
-	aPending       bool   // waiting for text object key (p, b, s) after 'a'
+	zPending       bool   // waiting for second key in z commands (zz, zt, zb, zc, zo, zR, zM)
+	aPending       bool   // waiting for text object key (p, b, s, f, c, {, a, i) after 'a'
+	iPending       bool   // waiting for inner text object key after 'i'
+	markPending    string // "m" or "'" waiting for a mark letter
+	bracketPending string // "]" or "[" waiting for an annotation key
+	count          int              // count typed before a command, 0 if none
+	operator       *pendingOperator // annotation key waiting for a motion or text object
 	viewportTop     int // explicit viewport start line (-1 means auto-follow cursor)
 	autoViewportTop int // used only when viewportTop == -1 (auto-follow)
 	lastError      string // last error message to display
 	lastMessage    string // last success message to display
 	highlighter    *highlight.Highlighter
+	structure      *structure.File // functions, types and blocks, for text objects
 	sourceFile     string
 	editor         *editorState // non-nil when in editor mode
 	paletteKind    string       // "commands" or "annPick"
@@ -85,12 +123,28 @@ type Model struct {
 	lastCtrlC      time.Time    // timestamp of last CTRL+C press for double-tap quit
 	dirty          bool         // true when there are unsaved changes
 	search         searchState  // search state (query, matches, current position)
+	searchOpts     searchOptions // search mode, case and scope
+	outline        outlineState // outline panel filter and cursor
+	snippets       snippetState // snippet picker filter and cursor
+	folds          []fold       // closed folds
+	marks          map[string]int // mark letter to 0-indexed line
+	jumps          jumpList       // lines jumped from, for ctrl+o/ctrl+i
+	reviewed       map[int]bool   // 0-indexed lines marked reviewed
+	diff           diffView       // change shown by the full-screen diff
+	diffSplit      bool           // diffs show old and new side by side
+	diffPreviews   map[fem.Annotation]bool // change annotations previewed as a diff
+	cmdline        cmdlineState // : command line input and history
+	typeFilter     []string     // annotation types shown, all when empty
+	options        viewOptions  // display settings changed with :set
 	previewIndex      int          // index into annotations on current line for preview cycling
 	previewLine       int          // line number (1-indexed) for which previewIndex is valid
 	version           string       // fabbro version for display in help
 	annotationsCursor int          // cursor position in annotations list view
 	rangeEditAnnIndex int          // index into annotations for range editing (-1 if not active)
 	mouseDragging     bool         // true during left-button drag for selection
+	inputRepeat       repeatAnnotation  // how the range of the annotation being entered was chosen
+	inputMatches      bool              // the annotation being entered goes on every search match
+	lastAnnotation    *repeatAnnotation // last annotation added, repeated by .
 }
 
 func New(sess *session.Session) Model {
@@ -111,6 +165,17 @@ func NewWithVersion(sess *session.Session, sourceFile string, version string) Mo
 
 func NewWithAll(sess *session.Session, sourceFile string, annotations []fem.Annotation, version string) Model {
 	lines := strings.Split(sess.Content, "\n")
+	reviewed := map[int]bool{}
+	for _, line := range sess.Reviewed {
+		if line >= 1 && line <= len(lines) {
+			reviewed[line-1] = true
+		}
+	}
+	highlightName := sourceFile
+	if sess.Conversion != nil {
+		// Converted content is Markdown, whatever the source's extension.
+		highlightName = sourceFile + ".md"
+	}
 	return Model{
 		session:         sess,
 		lines:           lines,
@@ -118,12 +183,16 @@ func NewWithAll(sess *session.Session, sourceFile string, annotations []fem.Anno
 		selection:       selection{},
 		mode:            modeNormal,
 		annotations:     annotations,
-		highlighter:     highlight.New(sourceFile, sess.Content),
+		highlighter:     highlight.New(highlightName, sess.Content),
+		structure:       structure.Parse(highlightName, lines),
 		sourceFile:      sourceFile,
 		viewportTop:       -1, // auto-follow cursor
 		autoViewportTop:   0,
 		version:           version,
 		rangeEditAnnIndex: -1,
+		options:           defaultViewOptions,
+		searchOpts:        searchOptions{mode: search.ModeFuzzy},
+		reviewed:          reviewed,
 	}
 }
 
@@ -158,11 +227,13 @@ func (m *Model) screenToLine(screenY int) (int, bool) {
 	if start < 0 {
 		start = 0
 	}
+	if start < len(m.lines) {
+		start = m.displayLine(start)
+	}
 
 	screenRow := 0
-	for i := start; i < len(m.lines); i++ {
-		wrapped := wrapLine(m.lines[i], contentWidth)
-		rows := len(wrapped)
+	for i := start; i < len(m.lines); i = m.nextLine(i) {
+		rows := m.lineRows(i, contentWidth)
 		if contentY >= screenRow && contentY < screenRow+rows {
 			return i, true
 		}
@@ -177,7 +248,7 @@ func (m *Model) screenToLine(screenY int) (int, bool) {
 func (m *Model) annotationsOnLine(lineNum int) []int {
 	var indices []int
 	for i, ann := range m.annotations {
-		if ann.StartLine <= lineNum && lineNum <= ann.EndLine {
+		if ann.StartLine <= lineNum && lineNum <= ann.EndLine && m.shown(ann) {
 			indices = append(indices, i)
 		}
 	}
@@ -204,3 +275,16 @@ func (m *Model) previewedAnnotation() *fem.Annotation {
 
 	return &m.annotations[indices[previewIdx]]
 }
+
+// shown reports whether an annotation passes the :filter.
+func (m *Model) shown(ann fem.Annotation) bool {
+	if len(m.typeFilter) == 0 {
+		return true
+	}
+	for _, t := range m.typeFilter {
+		if ann.Type == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// operatorTypes maps the annotation keys that take a motion or text object
+// without a selection to their annotation types. e keeps editing the
+// annotation at the cursor.
+var operatorTypes = map[string]string{
+	"c": "comment",
+	"d": "delete",
+	"q": "question",
+	"u": "unclear",
+	"r": "change",
+}
+
+// pendingOperator is an annotation key waiting for its motion or text
+// object, as in c}, dap or q3j.
+type pendingOperator struct {
+	key     string // the annotation key, doubled to annotate whole lines
+	annType string
+	count   int    // count typed before the key, 0 if none
+	motion  string // keys typed since
+}
+
+// repeatAnnotation is the last annotation added from the keyboard, which
+// . adds again on a new range.
+type repeatAnnotation struct {
+	annType string
+	text    string // as entered, without the line reference of a change
+	key     string // the operator key, for a doubled-key motion
+	motion  string // the motion that chose the range, "" for a selection
+	lines   int    // number of lines annotated
+}
+
+// motionRange returns the lines an operator's motion covers from the
+// cursor. key is the operator key, so that doubling it (cc, 3dd) takes
+// whole lines. done is false while the motion is incomplete; missing
+// reports a motion that can't apply.
+func (m *Model) motionRange(key, motion string) (start, end int, done bool, missing string) {
+	digits := len(motion) - len(strings.TrimLeft(motion, "0123456789"))
+	count, counted := 1, false
+	if digits > 0 {
+		count, _ = strconv.Atoi(motion[:digits])
+		counted = true
+	}
+	start, end = m.cursor, m.cursor
+	switch rest := motion[digits:]; rest {
+	case "", "g", "a", "i":
+		return 0, 0, false, ""
+	case key:
+		end = m.stepLines(m.cursor, count-1)
+	case "j":
+		end = m.stepLines(m.cursor, count)
+	case "k":
+		start = m.stepLines(m.cursor, -count)
+	case "G", "gg":
+		target := 0
+		if counted {
+			target = min(count, len(m.lines)) - 1
+		} else if rest == "G" {
+			target = len(m.lines) - 1
+		}
+		start, end = min(m.cursor, target), max(m.cursor, target)
+	case "}":
+		for n := 0; n < count && end < len(m.lines)-1; n++ {
+			line := end
+			if n > 0 {
+				for line = end + 1; line < len(m.lines)-1 && strings.TrimSpace(m.lines[line]) == ""; line++ {
+				}
+			}
+			_, end = FindParagraph(m.lines, line)
+		}
+	case "{":
+		for n := 0; n < count && start > 0; n++ {
+			line := start
+			if n > 0 {
+				for line = start - 1; line > 0 && strings.TrimSpace(m.lines[line]) == ""; line-- {
+				}
+			}
+			start, _ = FindParagraph(m.lines, line)
+		}
+	default:
+		if len(rest) != 2 || (rest[0] != 'a' && rest[0] != 'i') {
+			return 0, 0, true, "Unknown motion: " + rest
+		}
+		start, end, missing = m.textObject(rest[1:], rest[0] == 'i')
+		if missing != "" {
+			return 0, 0, true, missing
+		}
+	}
+
+	// A range ending on a closed fold takes the whole fold.
+	start = m.displayLine(start)
+	if f, ok := m.foldAt(end); ok {
+		end = f.end
+	}
+	return start, end, true, ""
+}
+
+// handleOperatorKey adds key to the pending operator's motion and opens
+// the annotation input once the motion is complete.
+func (m Model) handleOperatorKey(key string) (tea.Model, tea.Cmd) {
+	if key == "esc" {
+		m.operator = nil
+		return m, nil
+	}
+	op := *m.operator
+	op.motion += key
+	start, end, done, missing := m.motionRange(op.key, op.motion)
+	if !done {
+		m.operator = &op
+		return m, nil
+	}
+	m.operator = nil
+	if missing != "" {
+		m.lastError = missing
+		return m, nil
+	}
+
+	motion := op.motion
+	if op.count > 0 {
+		// 2d3j takes six lines, like vim.
+		digits := len(motion) - len(strings.TrimLeft(motion, "0123456789"))
+		n := 1
+		if digits > 0 {
+			n, _ = strconv.Atoi(motion[:digits])
+		}
+		motion = strconv.Itoa(op.count*n) + motion[digits:]
+		start, end, _, _ = m.motionRange(op.key, motion)
+	}
+
+	m.selection = selection{active: true, anchor: start, cursor: end}
+	m.cursor = start
+	m.viewportTop = -1
+	m.ensureCursorVisible()
+	m.resetPreviewIndex()
+	m.openInputMode(op.annType)
+	m.inputRepeat = repeatAnnotation{key: op.key, motion: motion}
+	return m, nil
+}
+
+// addAnnotation adds an annotation on lines start through end (0-indexed),
+// prefixing a change with the lines it replaces.
+func (m *Model) addAnnotation(annType, text string, start, end int) {
+	if annType == "change" {
+		startLine := start + 1
+		endLine := end + 1
+		var lineRef string
+		if startLine == endLine {
+			lineRef = fmt.Sprintf("[line %d] -> ", startLine)
+		} else {
+			lineRef = fmt.Sprintf("[lines %d-%d] -> ", startLine, endLine)
+		}
+		text = lineRef + text
+	}
+
+	m.annotations = append(m.annotations, fem.Annotation{
+		StartLine: start + 1,
+		EndLine:   end + 1,
+		Type:      annType,
+		Text:      text,
+	})
+	m.dirty = true
+}
+
+// repeatLastAnnotation adds the last annotation again: on the selection,
+// by the same motion from the cursor, or on as many lines as before.
+func (m *Model) repeatLastAnnotation() {
+	last := m.lastAnnotation
+	if last == nil {
+		m.lastError = "No annotation to repeat"
+		return
+	}
+
+	var start, end int
+	switch {
+	case m.selection.active:
+		start, end = m.selection.lines()
+	case last.motion != "":
+		var missing string
+		start, end, _, missing = m.motionRange(last.key, last.motion)
+		if missing != "" {
+			m.lastError = missing
+			return
+		}
+	default:
+		start, end = m.cursor, min(m.cursor+last.lines-1, len(m.lines)-1)
+	}
+
+	m.addAnnotation(last.annType, last.text, start, end)
+	m.selection = selection{}
+	if start == end {
+		m.lastMessage = fmt.Sprintf("Repeated %s on line %d", last.annType, start+1)
+	} else {
+		m.lastMessage = fmt.Sprintf("Repeated %s on lines %d-%d", last.annType, start+1, end+1)
+	}
+}
+
+// pendingCommand shows the count or operator typed so far.
+func (m Model) pendingCommand() string {
+	switch {
+	case m.operator != nil:
+		prefix := ""
+		if m.operator.count > 0 {
+			prefix = strconv.Itoa(m.operator.count)
+		}
+		return fmt.Sprintf("%s%s%s  %s: motion (j k G gg { } %s%s) or text object (a/i + p b s f c { a i), Esc cancels",
+			prefix, m.operator.key, m.operator.motion, m.operator.annType, m.operator.key, m.operator.key)
+	case m.markPending != "":
+		return m.markPending + "  mark a-z"
+	case m.bracketPending != "":
+		prefix := ""
+		if m.count > 0 {
+			prefix = strconv.Itoa(m.count)
+		}
+		return prefix + m.bracketPending + "  a (any annotation), c d q e k u r (one type) or x (unreviewed)"
+	case m.count > 0:
+		return strconv.Itoa(m.count)
+	}
+	return ""
+}
+
+// startOperator waits for the motion or text object of annotation key.
+func (m *Model) startOperator(key string, count int, counted bool) {
+	m.operator = &pendingOperator{key: key, annType: operatorTypes[key]}
+	if counted {
+		m.operator.count = count
+	}
+}
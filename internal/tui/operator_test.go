@@ -0,0 +1,156 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// typeKeys sends each rune of keys in normal mode.
+func typeKeys(m Model, keys string) Model {
+	for _, r := range keys {
+		m = sendKey(m, r)
+	}
+	return m
+}
+
+// enterAnnotation types text into the open annotation input and submits it.
+func enterAnnotation(t *testing.T, m Model, text string) Model {
+	t.Helper()
+	if m.mode != modeInput {
+		t.Fatalf("expected the annotation input to be open, got mode %d (%s)", m.mode, m.lastError)
+	}
+	m = typeKeys(m, text)
+	return sendKeyType(m, tea.KeyEnter)
+}
+
+func TestCounts(t *testing.T) {
+	m := New(newTestSession("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12"))
+	m.width = 80
+	m.height = 24
+
+	m = typeKeys(m, "5j")
+	if m.cursor != 5 {
+		t.Errorf("5j: expected line 5, got %d", m.cursor)
+	}
+	m = typeKeys(m, "2k")
+	if m.cursor != 3 {
+		t.Errorf("2k: expected line 3, got %d", m.cursor)
+	}
+	m = typeKeys(m, "11G")
+	if m.cursor != 10 {
+		t.Errorf("11G: expected line 10, got %d", m.cursor)
+	}
+	m = typeKeys(m, "2gg")
+	if m.cursor != 1 {
+		t.Errorf("2gg: expected line 1, got %d", m.cursor)
+	}
+	m = typeKeys(m, "99j")
+	if m.cursor != 11 || m.count != 0 {
+		t.Errorf("99j: expected the last line and no count left, got %d, %d", m.cursor, m.count)
+	}
+	m = typeKeys(m, "3")
+	if !strings.Contains(m.View(), "\n3\n") {
+		t.Error("expected the pending count in the status bar")
+	}
+}
+
+func TestOperatorMotions(t *testing.T) {
+	src := "one\ntwo\nthree\n\nfour\nfive\n\nsix"
+	cases := []struct {
+		keys       string
+		cursor     int
+		annType    string
+		start, end int
+	}{
+		{"c}", 0, "comment", 1, 3},
+		{"c}", 1, "comment", 2, 3},
+		{"c2}", 0, "comment", 1, 6},
+		{"dap", 4, "delete", 5, 6},
+		{"q3j", 0, "question", 1, 4},
+		{"u2k", 4, "unclear", 3, 5},
+		{"cc", 2, "comment", 3, 3},
+		{"3dd", 0, "delete", 1, 3},
+		{"2c2j", 0, "comment", 1, 5},
+		{"cG", 5, "comment", 6, 8},
+		{"cgg", 1, "comment", 1, 2},
+		{"c{", 5, "comment", 5, 6},
+	}
+	for _, c := range cases {
+		m := New(newTestSession(src))
+		m.width = 80
+		m.height = 24
+		m.cursor = c.cursor
+		m = typeKeys(m, c.keys)
+		m = enterAnnotation(t, m, "note")
+		if len(m.annotations) != 1 {
+			t.Errorf("%s: expected one annotation, got %v", c.keys, m.annotations)
+			continue
+		}
+		a := m.annotations[0]
+		if a.Type != c.annType || a.StartLine != c.start || a.EndLine != c.end {
+			t.Errorf("%s from line %d: got %s %d-%d, want %s %d-%d", c.keys, c.cursor+1, a.Type, a.StartLine, a.EndLine, c.annType, c.start, c.end)
+		}
+	}
+}
+
+func TestOperatorErrors(t *testing.T) {
+	m := New(newTestSession("one\ntwo"))
+	m = typeKeys(m, "cx")
+	if m.operator != nil || m.mode != modeNormal || m.lastError != "Unknown motion: x" {
+		t.Errorf("expected an unknown motion error, got %q", m.lastError)
+	}
+	m = typeKeys(m, "dab")
+	if m.lastError != "No code block at cursor" {
+		t.Errorf("expected the text object error, got %q", m.lastError)
+	}
+	m = typeKeys(m, "q")
+	if !strings.Contains(m.View(), "question: motion") {
+		t.Error("expected the pending operator in the status bar")
+	}
+	m = sendKeyType(m, tea.KeyEsc)
+	if m.operator != nil || len(m.annotations) != 0 {
+		t.Error("expected Esc to cancel the operator")
+	}
+}
+
+func TestRepeatAnnotation(t *testing.T) {
+	m := New(newTestSession("a\nb\n\nc\nd\ne\n\nf\ng"))
+	m.width = 80
+	m.height = 24
+
+	m = sendKey(m, '.')
+	if m.lastError != "No annotation to repeat" {
+		t.Errorf("expected nothing to repeat, got %q", m.lastError)
+	}
+
+	// . repeats the motion from the new cursor.
+	m = typeKeys(m, "rap")
+	m = enterAnnotation(t, m, "tighten")
+	m = typeKeys(m, "3j")
+	m = sendKey(m, '.')
+	if len(m.annotations) != 2 {
+		t.Fatalf("expected a repeated annotation, got %v", m.annotations)
+	}
+	if a := m.annotations[1]; a.Type != "change" || a.StartLine != 4 || a.EndLine != 6 || a.Text != "[lines 4-6] -> tighten" {
+		t.Errorf("unexpected repeat %+v", a)
+	}
+	if m.lastMessage != "Repeated change on lines 4-6" {
+		t.Errorf("unexpected message %q", m.lastMessage)
+	}
+
+	// With a selection, . annotates the selection.
+	m = typeKeys(m, "4jvj.")
+	if a := m.annotations[2]; a.StartLine != 8 || a.EndLine != 9 || m.selection.active {
+		t.Errorf("expected the selection annotated, got %+v", a)
+	}
+
+	// After a selection annotation, . takes as many lines from the cursor.
+	m = typeKeys(m, "ggvjc")
+	m = enterAnnotation(t, m, "hmm")
+	m = typeKeys(m, "3j.")
+	if a := m.annotations[4]; a.Type != "comment" || a.StartLine != 5 || a.EndLine != 6 {
+		t.Errorf("expected two lines from the cursor, got %+v", a)
+	}
+}
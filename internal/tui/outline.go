@@ -0,0 +1,176 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/fuzzy"
+	"github.com/charly-vibes/fabbro/internal/structure"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// outlineState is the outline panel: the headings or symbols of the
+// content, narrowed by a fuzzy filter.
+type outlineState struct {
+	filter  string
+	matches []int // indices into the outline entries that match filter
+	cursor  int   // index into matches
+}
+
+// outlineEntries returns the named symbols of the content: headings for
+// Markdown, functions and types for code.
+func (m *Model) outlineEntries() []structure.Symbol {
+	if m.structure == nil {
+		m.structure = structure.Parse(m.sourceFile, m.lines)
+	}
+	var entries []structure.Symbol
+	for _, s := range m.structure.Symbols {
+		if s.Name != "" {
+			entries = append(entries, s)
+		}
+	}
+	return entries
+}
+
+// openOutline shows the outline with the entry around the cursor selected.
+func (m *Model) openOutline() {
+	m.outline = outlineState{}
+	m.filterOutline()
+	entries := m.outlineEntries()
+	for i, idx := range m.outline.matches {
+		if s := entries[idx]; s.Start <= m.cursor && m.cursor <= s.End {
+			m.outline.cursor = i // the last, innermost, entry containing the cursor
+		}
+	}
+	m.mode = modeOutline
+}
+
+// filterOutline recomputes the entries matching the filter.
+func (m *Model) filterOutline() {
+	query := strings.ToLower(m.outline.filter)
+	m.outline.matches = m.outline.matches[:0]
+	for i, s := range m.outlineEntries() {
+		if fuzzy.Match(strings.ToLower(s.Name), query) {
+			m.outline.matches = append(m.outline.matches, i)
+		}
+	}
+	if m.outline.cursor >= len(m.outline.matches) {
+		m.outline.cursor = max(len(m.outline.matches)-1, 0)
+	}
+}
+
+func (m Model) handleOutlineMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyDown, tea.KeyCtrlN, tea.KeyCtrlJ:
+		if m.outline.cursor < len(m.outline.matches)-1 {
+			m.outline.cursor++
+		}
+	case tea.KeyUp, tea.KeyCtrlP, tea.KeyCtrlK:
+		if m.outline.cursor > 0 {
+			m.outline.cursor--
+		}
+	case tea.KeyEnter:
+		if len(m.outline.matches) > 0 {
+			m.jumpTo(m.outlineEntries()[m.outline.matches[m.outline.cursor]].Decl)
+		}
+		m.mode = modeNormal
+	case tea.KeyEsc:
+		if m.outline.filter != "" {
+			m.outline.filter = ""
+			m.filterOutline()
+		} else {
+			m.mode = modeNormal
+		}
+	case tea.KeyBackspace:
+		if r := []rune(m.outline.filter); len(r) > 0 {
+			m.outline.filter = string(r[:len(r)-1])
+			m.filterOutline()
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.outline.filter += string(msg.Runes)
+		m.outline.cursor = 0
+		m.filterOutline()
+	}
+	return m, nil
+}
+
+// renderOutlinePanel renders the outline overlay, scrolled to keep the
+// selected entry in view.
+func (m Model) renderOutlinePanel(width int) string {
+	var b strings.Builder
+
+	boxWidth := width - 4
+	if boxWidth < 50 {
+		boxWidth = 50
+	}
+	innerWidth := boxWidth - 4
+
+	entries := m.outlineEntries()
+	header := fmt.Sprintf("─ Outline (%d) ", len(entries))
+	if m.outline.filter != "" {
+		header = fmt.Sprintf("─ Outline (%d/%d) ", len(m.outline.matches), len(entries))
+	}
+	headerPad := boxWidth - len([]rune(header)) - 2
+	if headerPad < 0 {
+		headerPad = 0
+	}
+	b.WriteString(fmt.Sprintf("┌%s%s┐\n", header, strings.Repeat("─", headerPad)))
+
+	writeRow := func(row string) {
+		rowRunes := []rune(row)
+		if len(rowRunes) > innerWidth {
+			rowRunes = append(rowRunes[:innerWidth-1], '…')
+		}
+		b.WriteString(fmt.Sprintf("│ %s%s │\n", string(rowRunes), strings.Repeat(" ", innerWidth-len(rowRunes))))
+	}
+
+	writeRow("Filter: " + m.outline.filter + "█")
+	switch {
+	case len(entries) == 0:
+		writeRow("No headings or symbols found")
+	case len(m.outline.matches) == 0:
+		writeRow("No matches")
+	default:
+		maxRows := m.height / 2
+		if maxRows < 5 {
+			maxRows = 10
+		}
+		first := 0
+		if m.outline.cursor >= maxRows {
+			first = m.outline.cursor - maxRows + 1
+		}
+		last := min(first+maxRows, len(m.outline.matches))
+		for i := first; i < last; i++ {
+			s := entries[m.outline.matches[i]]
+			cursor := " "
+			if i == m.outline.cursor {
+				cursor = ">"
+			}
+			counts := ""
+			if n := m.annotationsIn(s.Start, s.End); n > 0 {
+				counts = fmt.Sprintf("  [%d]", n)
+			}
+			label := fmt.Sprintf("%s%s %s", strings.Repeat("  ", s.Depth), outlineIcon(s.Kind), s.Name)
+			writeRow(fmt.Sprintf("%s %5d  %s%s", cursor, s.Decl+1, label, counts))
+		}
+	}
+
+	footer := "─ type to filter  ↑/↓: navigate  Enter: jump  Esc: close "
+	footerPad := boxWidth - len([]rune(footer)) - 2
+	if footerPad < 0 {
+		footerPad = 0
+	}
+	b.WriteString(fmt.Sprintf("└%s%s┘\n", footer, strings.Repeat("─", footerPad)))
+
+	return b.String()
+}
+
+func outlineIcon(kind structure.Kind) string {
+	switch kind {
+	case structure.KindFunction:
+		return "ƒ"
+	case structure.KindType:
+		return "T"
+	}
+	return "#"
+}
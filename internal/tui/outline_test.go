@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestOutlineJumpsToHeading(t *testing.T) {
+	sess := newTestSession("# Plan\nintro\n## Goals\nfast\n## Risks\nslow\nvery slow")
+	m := NewWithAnnotations(sess, "plan.md", []fem.Annotation{
+		{Type: "comment", Text: "why?", StartLine: 6, EndLine: 6},
+		{Type: "delete", Text: "drop", StartLine: 2, EndLine: 2},
+	})
+	m.width = 80
+	m.height = 24
+	m.cursor = 3
+
+	m = sendKey(m, 'o')
+	if m.mode != modeOutline {
+		t.Fatalf("expected outline mode, got %d", m.mode)
+	}
+	if len(m.outline.matches) != 3 || m.outline.cursor != 1 {
+		t.Errorf("expected 3 entries with Goals selected, got %v at %d", m.outline.matches, m.outline.cursor)
+	}
+	view := m.View()
+	for _, want := range []string{"Outline (3)", "# Plan  [2]", "  # Risks  [1]"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in the outline:\n%s", want, view)
+		}
+	}
+
+	m = sendKey(m, 'r')
+	m = sendKey(m, 's')
+	if len(m.outline.matches) != 1 || !strings.Contains(m.View(), "Outline (1/3)") {
+		t.Errorf("expected the filter to keep Risks, got %v", m.outline.matches)
+	}
+	m = sendKeyType(m, tea.KeyEnter)
+	if m.mode != modeNormal || m.cursor != 4 {
+		t.Errorf("expected to jump to line 4 in normal mode, got cursor %d mode %d", m.cursor, m.mode)
+	}
+
+	m = sendKey(m, 'o')
+	m = sendKey(m, 'x')
+	m = sendKeyType(m, tea.KeyEsc)
+	if m.mode != modeOutline || m.outline.filter != "" {
+		t.Error("expected Esc to clear the filter first")
+	}
+	m = sendKeyType(m, tea.KeyEsc)
+	if m.mode != modeNormal {
+		t.Error("expected a second Esc to close the outline")
+	}
+}
+
+func TestOutlineListsCodeSymbols(t *testing.T) {
+	sess := newTestSession("package main\n\ntype T struct{}\n\nfunc (t T) Run() {\n\tgo func() {}()\n}\n")
+	m := NewWithFile(sess, "main.go")
+	m.width = 80
+	m.height = 24
+
+	m = sendKey(m, 'o')
+	view := m.View()
+	if !strings.Contains(view, "Outline (2)") || !strings.Contains(view, "T T") || !strings.Contains(view, "ƒ T.Run") {
+		t.Errorf("expected the type and method without the closure:\n%s", view)
+	}
+	m = sendKeyType(m, tea.KeyDown)
+	m = sendKeyType(m, tea.KeyEnter)
+	if m.cursor != 4 {
+		t.Errorf("expected to jump to Run, got line %d", m.cursor)
+	}
+}
@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+)
+
+// toggleReviewed marks the selection, or count lines from the cursor, as
+// reviewed, or unmarks them when they all are already. Without a
+// selection the cursor then moves past them, so that x ticks off lines
+// while reading.
+func (m *Model) toggleReviewed(count int) {
+	start, end := m.cursor, m.stepLines(m.cursor, count-1)
+	if m.selection.active {
+		start, end = m.selection.lines()
+	}
+	if f, ok := m.foldAt(end); ok {
+		end = f.end
+	}
+
+	all := true
+	for line := start; line <= end; line++ {
+		all = all && m.reviewed[line]
+	}
+	if m.reviewed == nil {
+		m.reviewed = map[int]bool{}
+	}
+	for line := start; line <= end; line++ {
+		if all {
+			delete(m.reviewed, line)
+		} else {
+			m.reviewed[line] = true
+		}
+	}
+	m.dirty = true
+
+	verb := "Marked"
+	if all {
+		verb = "Unmarked"
+	}
+	m.lastMessage = fmt.Sprintf("%s %s reviewed (%d%% reviewed)", verb, lineSpan(start, end), m.reviewedPercent())
+	if m.selection.active {
+		m.selection = selection{}
+		return
+	}
+	if next := m.nextLine(end); next < len(m.lines) && !all {
+		m.cursor = next
+		m.viewportTop = -1
+		m.ensureCursorVisible()
+		m.resetPreviewIndex()
+	}
+}
+
+// reviewedPercent is the share of lines marked reviewed, rounded down.
+func (m Model) reviewedPercent() int {
+	if len(m.lines) == 0 {
+		return 0
+	}
+	return len(m.reviewed) * 100 / len(m.lines)
+}
+
+// reviewedLines lists the reviewed lines, 1-based, for the session file.
+func (m Model) reviewedLines() []int {
+	var lines []int
+	for line := range m.reviewed {
+		lines = append(lines, line+1)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// jumpToUnreviewed jumps count runs of unreviewed lines forward (dir 1,
+// ]x) or back (dir -1, [x) to the first line of the run.
+func (m *Model) jumpToUnreviewed(dir, count int) {
+	target := -1
+	for line := m.cursor + dir; line >= 0 && line < len(m.lines); line += dir {
+		if m.reviewed[line] || (line > 0 && !m.reviewed[line-1]) {
+			continue
+		}
+		target = line
+		if count--; count == 0 {
+			break
+		}
+	}
+	if target < 0 {
+		if dir > 0 {
+			m.lastError = "No unreviewed lines below"
+		} else {
+			m.lastError = "No unreviewed lines above"
+		}
+		return
+	}
+	m.jumpTo(target)
+}
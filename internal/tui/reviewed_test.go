@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToggleReviewed(t *testing.T) {
+	sess := newTestSession("1\n2\n3\n4\n5\n6\n7\n8\n9\n10")
+	sess.Reviewed = []int{10}
+	m := New(sess)
+	m.width = 80
+	m.height = 24
+
+	if !strings.Contains(m.View(), "[10% reviewed]") {
+		t.Error("expected the progress in the title")
+	}
+
+	// x marks the cursor line and moves on; a count takes more lines.
+	m = typeKeys(m, "x3x")
+	if m.cursor != 4 || m.lastMessage != "Marked lines 2-4 reviewed (50% reviewed)" {
+		t.Errorf("unexpected cursor %d, message %q", m.cursor, m.lastMessage)
+	}
+	if !m.dirty || !reflect.DeepEqual(m.reviewedLines(), []int{1, 2, 3, 4, 10}) {
+		t.Errorf("unexpected reviewed lines %v", m.reviewedLines())
+	}
+	if !strings.Contains(m.View(), "[50% reviewed]") {
+		t.Error("expected the progress updated in the title")
+	}
+
+	// On a fully reviewed selection, x unmarks it.
+	m = typeKeys(m, "ggvjx")
+	if m.selection.active || !reflect.DeepEqual(m.reviewedLines(), []int{3, 4, 10}) {
+		t.Errorf("expected lines 1-2 unmarked, got %v", m.reviewedLines())
+	}
+	if m.lastMessage != "Unmarked lines 1-2 reviewed (30% reviewed)" {
+		t.Errorf("unexpected message %q", m.lastMessage)
+	}
+
+	if got := m.snapshot().Reviewed; !reflect.DeepEqual(got, []int{3, 4, 10}) {
+		t.Errorf("expected the reviewed lines saved with the session, got %v", got)
+	}
+}
+
+func TestJumpToUnreviewed(t *testing.T) {
+	sess := newTestSession("1\n2\n3\n4\n5\n6\n7\n8")
+	sess.Reviewed = []int{1, 2, 5, 8}
+	m := New(sess)
+	m.width = 80
+	m.height = 24
+
+	cases := []struct {
+		keys   string
+		cursor int
+	}{
+		{"]x", 2},
+		{"]x", 5},
+		{"[x", 2},
+		{"G2[x", 2},
+	}
+	for _, c := range cases {
+		m = typeKeys(m, c.keys)
+		if m.cursor != c.cursor {
+			t.Errorf("%s: expected line %d, got %d (%s)", c.keys, c.cursor, m.cursor, m.lastError)
+		}
+	}
+	m = typeKeys(m, "G]x")
+	if m.lastError != "No unreviewed lines below" {
+		t.Errorf("unexpected error %q", m.lastError)
+	}
+}
@@ -16,7 +16,7 @@ func (m *Model) ensureCursorVisible() {
 		contentWidth = 40
 	}
 
-	scrolloff := 2
+	scrolloff := m.options.scrolloff
 	if visibleRows/4 < scrolloff {
 		scrolloff = visibleRows / 4
 	}
@@ -36,19 +36,22 @@ func (m *Model) ensureCursorVisible() {
 	if m.cursor >= len(m.lines) {
 		m.cursor = len(m.lines) - 1
 	}
+	// A line hidden in a closed fold is shown by the fold's summary row.
+	m.cursor = m.displayLine(m.cursor)
+	m.autoViewportTop = m.displayLine(m.autoViewportTop)
 
 	rowsAbove := 0
-	for i := m.autoViewportTop; i < m.cursor && rowsAbove <= visibleRows+scrolloff; i++ {
-		rowsAbove += len(wrapLine(m.lines[i], contentWidth))
+	for i := m.autoViewportTop; i < m.cursor && rowsAbove <= visibleRows+scrolloff; i = m.nextLine(i) {
+		rowsAbove += m.lineRows(i, contentWidth)
 	}
-	cursorHeight := len(wrapLine(m.lines[m.cursor], contentWidth))
+	cursorHeight := m.lineRows(m.cursor, contentWidth)
 	if cursorHeight < 1 {
 		cursorHeight = 1
 	}
 
 	for rowsAbove < scrolloff && m.autoViewportTop > 0 {
-		m.autoViewportTop--
-		rowsAbove += len(wrapLine(m.lines[m.autoViewportTop], contentWidth))
+		m.autoViewportTop = m.prevLine(m.autoViewportTop)
+		rowsAbove += m.lineRows(m.autoViewportTop, contentWidth)
 	}
 
 	maxAbove := visibleRows - scrolloff - cursorHeight
@@ -56,7 +59,7 @@ func (m *Model) ensureCursorVisible() {
 		maxAbove = 0
 	}
 	for rowsAbove > maxAbove && m.autoViewportTop < m.cursor {
-		rowsAbove -= len(wrapLine(m.lines[m.autoViewportTop], contentWidth))
-		m.autoViewportTop++
+		rowsAbove -= m.lineRows(m.autoViewportTop, contentWidth)
+		m.autoViewportTop = m.nextLine(m.autoViewportTop)
 	}
 }
@@ -0,0 +1,152 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/search"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// searchFor runs a / search for query with the current search options.
+func searchFor(m Model, query string) Model {
+	m = sendKey(m, '/')
+	for _, r := range query {
+		m = sendKey(m, r)
+	}
+	return sendKeyType(m, tea.KeyEnter)
+}
+
+func TestSearchModesAndCase(t *testing.T) {
+	m := New(newTestSession("TODO one\ntodo two\ntoo done\nfoo(bar)"))
+	m.width = 80
+	m.height = 24
+
+	// Fuzzy by default: "too" matches the subsequence in every todo line.
+	m = searchFor(m, "too")
+	if len(m.search.matches) != 3 {
+		t.Errorf("fuzzy: expected 3 matches, got %v", m.search.matches)
+	}
+
+	m = sendKey(m, '/')
+	m = sendKeyType(m, tea.KeyTab)
+	if m.searchOpts.mode != search.ModeLiteral || !strings.Contains(m.View(), "literal aa content") {
+		t.Errorf("expected Tab to switch to literal, got %s", m.searchOpts)
+	}
+	m = sendKeyType(m, tea.KeyEsc)
+	m = searchFor(m, "todo")
+	if len(m.search.matches) != 2 {
+		t.Errorf("literal: expected 2 matches, got %v", m.search.matches)
+	}
+
+	m = sendKey(m, '/')
+	m = sendKeyType(m, tea.KeyCtrlT)
+	m = sendKeyType(m, tea.KeyEsc)
+	m = searchFor(m, "TODO")
+	if len(m.search.matches) != 1 || m.search.matches[0] != 0 {
+		t.Errorf("literal, match case: expected line 1 only, got %v", m.search.matches)
+	}
+
+	m = sendKey(m, '/')
+	m = sendKeyType(m, tea.KeyTab)
+	m = sendKeyType(m, tea.KeyEsc)
+	if m.searchOpts.mode != search.ModeRegex {
+		t.Fatalf("expected regex, got %s", m.searchOpts.mode)
+	}
+	m = searchFor(m, `\w+\(`)
+	if len(m.search.matches) != 1 || m.cursor != 3 {
+		t.Errorf("regex: expected line 4, got %v", m.search.matches)
+	}
+	m = searchFor(m, "(")
+	if !strings.HasPrefix(m.lastError, "Invalid pattern") {
+		t.Errorf("expected an invalid pattern error, got %q", m.lastError)
+	}
+}
+
+func TestSearchHighlightsMatchSpans(t *testing.T) {
+	m := New(newTestSession("x TODO y TODO"))
+	m.width = 80
+	m.height = 24
+	m.searchOpts.mode = search.ModeLiteral
+
+	m = searchFor(m, "todo")
+	got := m.highlightSearchMatches("", "x TODO y TODO", false)
+	if strings.Count(got, "TODO") != 2 || !strings.Contains(got, " y ") {
+		t.Errorf("expected both TODOs highlighted, got %q", got)
+	}
+	spans := m.search.matcher.Spans("x TODO y TODO")
+	if len(spans) != 2 || spans[0] != [2]int{2, 6} || spans[1] != [2]int{9, 13} {
+		t.Errorf("unexpected spans %v", spans)
+	}
+}
+
+func TestSearchAnnotationText(t *testing.T) {
+	m := NewWithAnnotations(newTestSession("a\nb\nc\nd"), "", []fem.Annotation{
+		{Type: "comment", Text: "looks fine", StartLine: 1, EndLine: 1},
+		{Type: "question", Text: "why not cache?", StartLine: 3, EndLine: 4},
+		{Type: "comment", Text: "cache this", StartLine: 3, EndLine: 3},
+	})
+	m.width = 80
+	m.height = 24
+
+	m = sendKey(m, '/')
+	m = sendKeyType(m, tea.KeyCtrlA)
+	if !strings.Contains(m.View(), "fuzzy aa annotations") {
+		t.Error("expected the annotation scope in the prompt")
+	}
+	m = sendKeyType(m, tea.KeyEsc)
+	m = searchFor(m, "cache")
+	if len(m.search.matches) != 1 || m.cursor != 2 {
+		t.Errorf("expected the line of the cache annotations, got %v", m.search.matches)
+	}
+	if m.highlightSearchMatches("c", "c", true) != "c" {
+		t.Error("annotation matches should not highlight content")
+	}
+
+	// The previewed annotation is the one that matched.
+	m.searchOpts.mode = search.ModeLiteral
+	m = searchFor(m, "cache this")
+	if m.previewLine != 3 || m.annotations[m.annotationsOnLine(3)[m.previewIndex]].Text != "cache this" {
+		t.Errorf("expected the matching annotation previewed, got index %d", m.previewIndex)
+	}
+}
+
+func TestAnnotateMatches(t *testing.T) {
+	m := New(newTestSession("// TODO a\nok\n// TODO b\n// todo c"))
+	m.width = 80
+	m.height = 24
+
+	m, _ = runEx(m, "am comment fix")
+	if !strings.HasPrefix(m.lastError, "No search matches") {
+		t.Errorf("expected an error without matches, got %q", m.lastError)
+	}
+
+	m.searchOpts = searchOptions{mode: search.ModeLiteral, matchCase: true}
+	m = searchFor(m, "TODO")
+	m, _ = runEx(m, "annotate-matches comment resolve before merge")
+	if len(m.annotations) != 2 || m.lastMessage != "Added 2 comment annotations" {
+		t.Fatalf("expected two annotations, got %v (%q)", m.annotations, m.lastMessage)
+	}
+	for i, line := range []int{1, 3} {
+		if a := m.annotations[i]; a.StartLine != line || a.EndLine != line || a.Text != "resolve before merge" {
+			t.Errorf("unexpected annotation %+v", a)
+		}
+	}
+
+	// Without text, the input opens and its text goes on every match.
+	m = searchFor(m, "// TODO")
+	m, _ = runEx(m, "am change")
+	m = enterAnnotation(t, m, "done")
+	if len(m.annotations) != 4 {
+		t.Fatalf("expected two more annotations, got %v", m.annotations)
+	}
+	if a := m.annotations[3]; a.Type != "change" || a.StartLine != 3 || a.Text != "[line 3] -> done" {
+		t.Errorf("unexpected annotation %+v", a)
+	}
+
+	m, _ = runEx(m, "am bogus x")
+	if !strings.Contains(m.lastError, `Unknown annotation type "bogus"`) {
+		t.Errorf("unexpected error %q", m.lastError)
+	}
+}
@@ -0,0 +1,214 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+	"github.com/charly-vibes/fabbro/internal/snippet"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// snippetState is the snippet picker: the configured snippets, narrowed
+// by a fuzzy filter.
+type snippetState struct {
+	all     []config.Snippet
+	filter  string
+	matches []config.Snippet
+	cursor  int  // index into matches
+	insert  bool // insert into the annotation being typed instead of adding one
+}
+
+// openSnippets shows the snippet picker. With insert the chosen snippet
+// goes into the annotation input (Ctrl+S); otherwise it annotates the
+// selection or the cursor line with its type (Space n, :snippet).
+func (m *Model) openSnippets(insert bool) {
+	snippets, ok := m.loadSnippets()
+	if !ok {
+		return
+	}
+	m.snippets = snippetState{all: snippets, matches: snippets, insert: insert}
+	m.mode = modeSnippets
+}
+
+// loadSnippets reads the user and project snippets, reporting an error
+// when there are none.
+func (m *Model) loadSnippets() ([]config.Snippet, bool) {
+	snippets, err := snippet.Load()
+	if err != nil {
+		m.lastError = err.Error()
+		return nil, false
+	}
+	if len(snippets) == 0 {
+		m.lastError = "No snippets: add them to " + config.SettingsFile + " or your user config"
+		return nil, false
+	}
+	return snippets, true
+}
+
+// snippetContext is what snippet placeholders are filled from: the
+// selection, or the cursor line.
+func (m Model) snippetContext() snippet.Context {
+	start, end := m.cursor, m.cursor
+	if m.selection.active {
+		start, end = m.selection.lines()
+	}
+	return snippet.Context{File: m.sourceFile, Start: start + 1, End: end + 1, Lines: m.lines[start : end+1]}
+}
+
+// useSnippet inserts s into the annotation input, or annotates with it.
+func (m *Model) useSnippet(s config.Snippet, insert bool) {
+	text := snippet.Expand(s.Text, m.snippetContext())
+	if insert {
+		m.mode = modeInput
+		m.inputTA.InsertString(text)
+		return
+	}
+	m.mode = modeNormal
+	m.annotateCommand(snippet.Type(s), text)
+}
+
+// snippetCommand handles :snippet [name]; without a name it opens the
+// picker.
+func (m *Model) snippetCommand(args string, _ bool) tea.Cmd {
+	if args == "" {
+		m.openSnippets(false)
+		return nil
+	}
+	snippets, ok := m.loadSnippets()
+	if !ok {
+		return nil
+	}
+	s, ok := snippet.Find(snippets, args)
+	if !ok {
+		m.lastError = fmt.Sprintf("Unknown snippet %q", args)
+		return nil
+	}
+	m.useSnippet(s, false)
+	return nil
+}
+
+// snippetNames completes :snippet.
+func snippetNames(_ *Model, _ string) []string {
+	snippets, _ := snippet.Load()
+	names := make([]string, len(snippets))
+	for i, s := range snippets {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// closeSnippets leaves the picker for the mode it was opened from.
+func (m *Model) closeSnippets() {
+	if m.snippets.insert {
+		m.mode = modeInput
+	} else {
+		m.mode = modeNormal
+	}
+	m.snippets = snippetState{}
+}
+
+func (m *Model) filterSnippets() {
+	m.snippets.matches = snippet.Filter(m.snippets.all, m.snippets.filter)
+	m.snippets.cursor = 0
+}
+
+func (m Model) handleSnippetsMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyDown, tea.KeyCtrlN, tea.KeyCtrlJ:
+		if m.snippets.cursor < len(m.snippets.matches)-1 {
+			m.snippets.cursor++
+		}
+	case tea.KeyUp, tea.KeyCtrlP, tea.KeyCtrlK:
+		if m.snippets.cursor > 0 {
+			m.snippets.cursor--
+		}
+	case tea.KeyEnter:
+		if len(m.snippets.matches) == 0 {
+			return m, nil
+		}
+		s, insert := m.snippets.matches[m.snippets.cursor], m.snippets.insert
+		m.snippets = snippetState{}
+		m.useSnippet(s, insert)
+	case tea.KeyEsc:
+		if m.snippets.filter != "" {
+			m.snippets.filter = ""
+			m.filterSnippets()
+		} else {
+			m.closeSnippets()
+		}
+	case tea.KeyBackspace:
+		if r := []rune(m.snippets.filter); len(r) > 0 {
+			m.snippets.filter = string(r[:len(r)-1])
+			m.filterSnippets()
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.snippets.filter += string(msg.Runes)
+		m.filterSnippets()
+	}
+	return m, nil
+}
+
+// renderSnippetsPanel renders the snippet picker, scrolled to keep the
+// selected snippet in view, with its text as it will be inserted.
+func (m Model) renderSnippetsPanel(width int) string {
+	var b strings.Builder
+
+	boxWidth := width - 4
+	if boxWidth < 50 {
+		boxWidth = 50
+	}
+	innerWidth := boxWidth - 4
+
+	header := fmt.Sprintf("─ Snippets (%d) ", len(m.snippets.all))
+	if m.snippets.filter != "" {
+		header = fmt.Sprintf("─ Snippets (%d/%d) ", len(m.snippets.matches), len(m.snippets.all))
+	}
+	headerPad := boxWidth - len([]rune(header)) - 2
+	if headerPad < 0 {
+		headerPad = 0
+	}
+	b.WriteString(fmt.Sprintf("┌%s%s┐\n", header, strings.Repeat("─", headerPad)))
+
+	writeRow := func(row string) {
+		rowRunes := []rune(row)
+		if len(rowRunes) > innerWidth {
+			rowRunes = append(rowRunes[:innerWidth-1], '…')
+		}
+		b.WriteString(fmt.Sprintf("│ %s%s │\n", string(rowRunes), strings.Repeat(" ", innerWidth-len(rowRunes))))
+	}
+
+	writeRow("Filter: " + m.snippets.filter + "█")
+	if len(m.snippets.matches) == 0 {
+		writeRow("No matches")
+	} else {
+		maxRows := m.height / 2
+		if maxRows < 5 {
+			maxRows = 10
+		}
+		first := 0
+		if m.snippets.cursor >= maxRows {
+			first = m.snippets.cursor - maxRows + 1
+		}
+		last := min(first+maxRows, len(m.snippets.matches))
+		ctx := m.snippetContext()
+		for i := first; i < last; i++ {
+			s := m.snippets.matches[i]
+			cursor := " "
+			if i == m.snippets.cursor {
+				cursor = ">"
+			}
+			text := encodeAnnText(snippet.Expand(s.Text, ctx))
+			writeRow(fmt.Sprintf("%s %-12s %-9s %s", cursor, s.Name, snippet.Type(s), text))
+		}
+	}
+
+	footer := "─ type to filter  ↑/↓: navigate  Enter: insert  Esc: close "
+	footerPad := boxWidth - len([]rune(footer)) - 2
+	if footerPad < 0 {
+		footerPad = 0
+	}
+	b.WriteString(fmt.Sprintf("└%s%s┘\n", footer, strings.Repeat("─", footerPad)))
+
+	return b.String()
+}
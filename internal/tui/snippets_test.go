@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/charly-vibes/fabbro/internal/config"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// useSnippets runs the test in a project whose settings define snippets.
+func useSnippets(t *testing.T, settings string) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	t.Cleanup(func() { os.Chdir(origDir) })
+
+	config.Init()
+	os.WriteFile(config.SettingsFile, []byte(settings), 0600)
+}
+
+const testSnippets = `{"snippets": [
+	{"name": "test", "text": "Add a test for {lines}"},
+	{"name": "wrap", "text": "Needs error wrapping"},
+	{"name": "why", "type": "question", "text": "Why does {file} do {text}?"}
+]}`
+
+func TestSnippetPickerInsertsIntoInput(t *testing.T) {
+	useSnippets(t, testSnippets)
+	m := NewWithFile(newTestSession("a\nb\nc"), "main.go")
+	m.width = 80
+	m.height = 24
+
+	m = typeKeys(m, "vj")
+	m = sendKey(m, 'c')
+	m = typeKeys(m, "see: ")
+	if m = sendKeyType(m, tea.KeyCtrlS); m.mode != modeSnippets {
+		t.Fatalf("expected Ctrl+S to open the snippets, got mode %d (%s)", m.mode, m.lastError)
+	}
+	view := m.View()
+	if !strings.Contains(view, "Snippets (3)") || !strings.Contains(view, "Add a test for 1-2") {
+		t.Errorf("expected the snippets with placeholders filled, got:\n%s", view)
+	}
+
+	m = typeKeys(m, "tst")
+	if len(m.snippets.matches) != 1 || m.snippets.matches[0].Name != "test" {
+		t.Fatalf("expected tst to match test, got %+v", m.snippets.matches)
+	}
+	m = sendKeyEnter(m)
+	if m.mode != modeInput || m.inputTA.Value() != "see: Add a test for 1-2" {
+		t.Fatalf("expected the snippet inserted into the input, got mode %d %q", m.mode, m.inputTA.Value())
+	}
+
+	m = sendKeyEnter(m)
+	if len(m.annotations) != 1 || m.annotations[0].Text != "see: Add a test for 1-2" || m.annotations[0].EndLine != 2 {
+		t.Errorf("unexpected annotations %+v", m.annotations)
+	}
+}
+
+func TestSnippetPickerEscReturnsToInput(t *testing.T) {
+	useSnippets(t, testSnippets)
+	m := New(newTestSession("a"))
+
+	m = typeKeys(m, "vc")
+	m = sendKeyType(m, tea.KeyCtrlS)
+	m = typeKeys(m, "zzz")
+	if len(m.snippets.matches) != 0 || !strings.Contains(m.View(), "No matches") {
+		t.Errorf("expected no matches, got %+v", m.snippets.matches)
+	}
+	if m = sendKeyEsc(m); m.mode != modeSnippets || m.snippets.filter != "" {
+		t.Errorf("expected Esc to clear the filter first, got mode %d filter %q", m.mode, m.snippets.filter)
+	}
+	if m = sendKeyEsc(m); m.mode != modeInput || m.inputTA == nil {
+		t.Errorf("expected Esc to return to the input, got mode %d", m.mode)
+	}
+}
+
+func TestSnippetFromPalette(t *testing.T) {
+	useSnippets(t, testSnippets)
+	m := NewWithFile(newTestSession("x := f()\ny"), "main.go")
+
+	m = typeKeys(m, " n")
+	if m.mode != modeSnippets {
+		t.Fatalf("expected Space n to open the snippets, got mode %d (%s)", m.mode, m.lastError)
+	}
+	m = typeKeys(m, "why")
+	m = sendKeyEnter(m)
+	if m.mode != modeNormal || len(m.annotations) != 1 {
+		t.Fatalf("expected a snippet annotation, got mode %d %+v", m.mode, m.annotations)
+	}
+	if ann := m.annotations[0]; ann.Type != "question" || ann.Text != "Why does main.go do x := f()?" || ann.StartLine != 1 {
+		t.Errorf("unexpected annotation %+v", ann)
+	}
+}
+
+func TestSnippetCommand(t *testing.T) {
+	useSnippets(t, testSnippets)
+	m := New(newTestSession("a\nb\nc"))
+
+	m = typeKeys(m, "jvj")
+	m, _ = runEx(m, "snippet test")
+	if len(m.annotations) != 1 || m.annotations[0].Type != "comment" || m.annotations[0].Text != "Add a test for 2-3" {
+		t.Errorf("unexpected annotations %+v", m.annotations)
+	}
+	if m.selection.active {
+		t.Error("expected the selection cleared")
+	}
+
+	if m, _ = runEx(m, "snippet nope"); m.lastError != `Unknown snippet "nope"` {
+		t.Errorf("expected an unknown snippet error, got %q", m.lastError)
+	}
+
+	if m, _ = runEx(m, "snippet"); m.mode != modeSnippets {
+		t.Errorf("expected :snippet alone to open the picker, got mode %d", m.mode)
+	}
+
+	m = New(newTestSession("a"))
+	m = sendKey(m, ':')
+	m = typeKeys(m, "snippet w")
+	m = sendKeyType(m, tea.KeyTab)
+	if !strings.Contains(m.cmdline.input, "snippet w") || !strings.Contains(m.View(), "why") {
+		t.Errorf("expected snippet names completed, got %q", m.cmdline.input)
+	}
+}
+
+func TestSnippetsNoneConfigured(t *testing.T) {
+	useSnippets(t, `{}`)
+	m := New(newTestSession("a"))
+
+	m, _ = runEx(m, "snippet")
+	if m.mode != modeNormal || !strings.HasPrefix(m.lastError, "No snippets") {
+		t.Errorf("expected an error without snippets, got mode %d %q", m.mode, m.lastError)
+	}
+
+	useSnippets(t, `{"snippets": [{"name": "x", "type": "praise", "text": "Nice"}]}`)
+	m = typeKeys(m, "vc")
+	if m = sendKeyType(m, tea.KeyCtrlS); m.mode != modeInput || !strings.Contains(m.lastError, "praise") {
+		t.Errorf("expected the input kept and a config error, got mode %d %q", m.mode, m.lastError)
+	}
+}
@@ -1,6 +1,10 @@
 package tui
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/charly-vibes/fabbro/internal/structure"
+)
 
 func FindParagraph(lines []string, line int) (start, end int) {
 	if line < 0 || line >= len(lines) {
@@ -29,10 +33,18 @@ func FindCodeBlock(lines []string, line int) (start, end int) {
 		return -1, -1
 	}
 
-	type block struct {
-		start, end int
+	for _, b := range codeBlocks(lines) {
+		if line >= b.Open && line <= b.Close {
+			return b.Open, b.Close
+		}
 	}
-	var blocks []block
+
+	return -1, -1
+}
+
+// codeBlocks returns the fenced code blocks, fence lines included.
+func codeBlocks(lines []string) []structure.Span {
+	var blocks []structure.Span
 
 	inBlock := false
 	blockStart := 0
@@ -43,19 +55,12 @@ func FindCodeBlock(lines []string, line int) (start, end int) {
 				inBlock = true
 				blockStart = i
 			} else {
-				blocks = append(blocks, block{start: blockStart, end: i})
+				blocks = append(blocks, structure.Span{Open: blockStart, Close: i})
 				inBlock = false
 			}
 		}
 	}
-
-	for _, b := range blocks {
-		if line >= b.start && line <= b.end {
-			return b.start, b.end
-		}
-	}
-
-	return -1, -1
+	return blocks
 }
 
 func FindSection(lines []string, line int) (start, end int) {
@@ -117,3 +122,154 @@ func FindSection(lines []string, line int) (start, end int) {
 
 	return sectionStart, end
 }
+
+// FindIndentBlock returns the lines around line indented at least as deeply
+// as it, blank lines included. With around, it also takes the
+// less-indented line that introduces the block and a closing bracket line
+// at that line's indentation.
+func FindIndentBlock(lines []string, line int, around bool) (start, end int) {
+	if line < 0 || line >= len(lines) {
+		return -1, -1
+	}
+	blank := func(i int) bool { return strings.TrimSpace(lines[i]) == "" }
+
+	// On a blank line, use the block of the next line with text.
+	ref := line
+	for ref < len(lines) && blank(ref) {
+		ref++
+	}
+	if ref == len(lines) {
+		for ref = line; ref >= 0 && blank(ref); ref-- {
+		}
+		if ref < 0 {
+			return -1, -1
+		}
+	}
+	level := indentWidth(lines[ref])
+
+	start, end = ref, ref
+	for start > 0 && (blank(start-1) || indentWidth(lines[start-1]) >= level) {
+		start--
+	}
+	for end < len(lines)-1 && (blank(end+1) || indentWidth(lines[end+1]) >= level) {
+		end++
+	}
+	for start < ref && blank(start) {
+		start++
+	}
+	for end > ref && blank(end) {
+		end--
+	}
+
+	if around && start > 0 {
+		start--
+		header := indentWidth(lines[start])
+		if end < len(lines)-1 && !blank(end+1) && indentWidth(lines[end+1]) == header {
+			switch strings.TrimSpace(lines[end+1])[0] {
+			case '}', ')', ']':
+				end++
+			}
+		}
+	}
+	return start, end
+}
+
+// indentWidth returns the width of a line's leading whitespace, counting
+// tabs to the next multiple of four.
+func indentWidth(line string) int {
+	w := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			w++
+		case '\t':
+			w += 4 - w%4
+		default:
+			return w
+		}
+	}
+	return w
+}
+
+// textObject returns the lines of the text object named by key around the
+// cursor: p paragraph, b fenced code block, s section, f function, c class
+// or type, { brace block, a argument list, i indentation block. inner
+// leaves out the delimiters: a function's signature and closing brace, a
+// block's fences, a section's heading. It returns a message instead when
+// the cursor isn't in such an object.
+func (m *Model) textObject(key string, inner bool) (start, end int, missing string) {
+	if m.structure == nil {
+		m.structure = structure.Parse(m.sourceFile, m.lines)
+	}
+	switch key {
+	case "p":
+		start, end = FindParagraph(m.lines, m.cursor)
+		return start, end, ""
+	case "b":
+		start, end = FindCodeBlock(m.lines, m.cursor)
+		if start < 0 {
+			return 0, 0, "No code block at cursor"
+		}
+		if inner {
+			start, end = start+1, end-1
+		}
+	case "s":
+		start, end = FindSection(m.lines, m.cursor)
+		if inner && start < end && strings.HasPrefix(strings.TrimLeft(m.lines[start], " \t"), "#") {
+			start++
+		}
+		return start, end, ""
+	case "f", "c":
+		kind, name := structure.KindFunction, "function"
+		if key == "c" {
+			kind, name = structure.KindType, "class or type"
+		}
+		sym, ok := m.structure.Innermost(kind, m.cursor)
+		if !ok {
+			return 0, 0, "No " + name + " at cursor"
+		}
+		start, end = sym.Start, sym.End
+		if inner {
+			start, end = sym.BodyStart, sym.BodyEnd
+		}
+	case "{", "}", "B", "a":
+		spans, name := m.structure.Braces, "brace block"
+		if key == "a" {
+			spans, name = m.structure.Parens, "multi-line argument list"
+		}
+		span, ok := structure.Innermost(spans, m.cursor)
+		if !ok {
+			return 0, 0, "No " + name + " at cursor"
+		}
+		start, end = span.Open, span.Close
+		if inner {
+			start, end = start+1, end-1
+		}
+	case "i":
+		start, end = FindIndentBlock(m.lines, m.cursor, !inner)
+		if start < 0 {
+			return 0, 0, "No indented block at cursor"
+		}
+	default:
+		return 0, 0, "Unknown text object: " + key
+	}
+	if start > end {
+		return 0, 0, "Nothing inside"
+	}
+	return start, end, ""
+}
+
+// selectTextObject selects the text object named by key, or reports why
+// it can't.
+func (m *Model) selectTextObject(key string, inner bool) {
+	start, end, missing := m.textObject(key, inner)
+	if missing != "" {
+		m.lastError = missing
+		return
+	}
+	m.selection = selection{active: true, anchor: start, cursor: end}
+	m.cursor = end
+	m.viewportTop = -1
+	m.ensureCursorVisible()
+	m.resetPreviewIndex()
+}
@@ -243,3 +243,85 @@ func TestFindSection(t *testing.T) {
 		})
 	}
 }
+
+func TestFindIndentBlock(t *testing.T) {
+	lines := []string{
+		"def f():",
+		"    a = 1",
+		"",
+		"    if a:",
+		"        b()",
+		"    return a",
+		"x = {",
+		"\tkey: 1,",
+		"}",
+	}
+	tests := []struct {
+		name      string
+		line      int
+		around    bool
+		wantStart int
+		wantEnd   int
+	}{
+		{"inner spans blank lines", 1, false, 1, 5},
+		{"around adds the header", 1, true, 0, 5},
+		{"nested block", 4, false, 4, 4},
+		{"around nested block", 4, true, 3, 4},
+		{"blank line uses the next block", 2, false, 1, 5},
+		{"around adds a closing bracket", 7, true, 6, 8},
+		{"top level", 0, false, 0, 8},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			start, end := FindIndentBlock(lines, tc.line, tc.around)
+			if start != tc.wantStart || end != tc.wantEnd {
+				t.Errorf("FindIndentBlock() = (%d, %d), want (%d, %d)", start, end, tc.wantStart, tc.wantEnd)
+			}
+		})
+	}
+}
+
+func TestCodeTextObjects(t *testing.T) {
+	src := "package main\n\n// add adds.\nfunc add(a, b int) int {\n\tif a > 0 {\n\t\treturn a + b\n\t}\n\treturn b\n}\n"
+	sess := newTestSession(src)
+	m := NewWithFile(sess, "main.go")
+	m.width = 80
+	m.height = 20
+
+	m.cursor = 5
+	m = sendKey(m, 'v')
+	m = sendKey(m, 'a')
+	m = sendKey(m, 'f')
+	if start, end := m.selection.lines(); !m.selection.active || start != 2 || end != 8 {
+		t.Errorf("af selected %d-%d, want 2-8 with the doc comment", start, end)
+	}
+
+	m = sendKey(m, 'v')
+	m.cursor = 5
+	m = sendKey(m, 'i')
+	m = sendKey(m, 'f')
+	if start, end := m.selection.lines(); !m.selection.active || start != 4 || end != 7 {
+		t.Errorf("if selected %d-%d, want the body 4-7", start, end)
+	}
+
+	m = sendKey(m, 'v')
+	m.cursor = 5
+	m = sendKey(m, 'v')
+	m = sendKey(m, 'a')
+	m = sendKey(m, '{')
+	if start, end := m.selection.lines(); start != 4 || end != 6 {
+		t.Errorf("a{ selected %d-%d, want the if block 4-6", start, end)
+	}
+
+	m = sendKey(m, 'v')
+	m.cursor = 0
+	m = sendKey(m, 'v')
+	m = sendKey(m, 'a')
+	m = sendKey(m, 'c')
+	if m.lastError != "No class or type at cursor" {
+		t.Errorf("expected a message for a missing type, got %q", m.lastError)
+	}
+	if start, end := m.selection.lines(); start != 0 || end != 0 {
+		t.Errorf("expected the selection unchanged, got %d-%d", start, end)
+	}
+}
@@ -377,11 +377,15 @@ func TestEnterCommentMode(t *testing.T) {
 	sess := newTestSession("line1\nline2")
 	m := New(sess)
 
-	// Pressing c without selection does nothing
+	// Pressing c without selection waits for a motion; Esc cancels it
 	newModel, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
 	m = newModel.(Model)
-	if m.mode != modeNormal {
-		t.Error("expected to stay in normal mode when no selection")
+	if m.mode != modeNormal || m.operator == nil {
+		t.Error("expected to stay in normal mode waiting for a motion when no selection")
+	}
+	m = sendKeyType(m, tea.KeyEsc)
+	if m.operator != nil {
+		t.Error("expected Esc to cancel the pending comment")
 	}
 
 	// Select a line first
@@ -748,6 +752,19 @@ func TestView(t *testing.T) {
 	}
 }
 
+func TestViewShowsStatusInTitle(t *testing.T) {
+	sess := newTestSession("line1")
+	sess.Status = session.StatusReady
+	m := New(sess)
+	m.width = 80
+	m.height = 20
+
+	title := strings.SplitN(m.View(), "\n", 2)[0]
+	if !strings.Contains(title, "(ready)") {
+		t.Errorf("title should show session status, got %q", title)
+	}
+}
+
 func TestViewInputMode(t *testing.T) {
 	sess := newTestSession("line1")
 	m := New(sess)
@@ -858,6 +875,37 @@ func TestWriteCommand(t *testing.T) {
 	}
 }
 
+func TestPaletteSubmitMarksReadyAndQuits(t *testing.T) {
+	tmpDir := t.TempDir()
+	origDir, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(origDir)
+
+	config.Init()
+
+	sess, _ := session.Create("line1\nline2", "")
+	session.SetStatus(sess, session.StatusClosed)
+	m := New(sess)
+	m.annotations = []fem.Annotation{{Type: "comment", Text: "done", StartLine: 1, EndLine: 1}}
+
+	m = sendKey(m, ' ')
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	if cmd == nil || cmd() != tea.Quit() {
+		t.Error("expected submit to quit")
+	}
+
+	loaded, err := session.Load(sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Status != session.StatusReady {
+		t.Errorf("expected submitted session to be ready, got %q", loaded.Status)
+	}
+	if !strings.Contains(loaded.Content, "{>> done <<}") {
+		t.Errorf("expected submit to save annotations, got %q", loaded.Content)
+	}
+}
+
 func TestWriteCommandShowsSavedMessage(t *testing.T) {
 	tmpDir := t.TempDir()
 	origDir, _ := os.Getwd()
@@ -3,15 +3,12 @@ package tui
 import (
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
-	"github.com/charly-vibes/fabbro/internal/config"
 	"github.com/charly-vibes/fabbro/internal/fem"
+	"github.com/charly-vibes/fabbro/internal/session"
 	"github.com/charly-vibes/fabbro/internal/tutor"
 )
 
@@ -23,12 +20,25 @@ func (m Model) View() string {
 		width = 50
 	}
 
+	if m.mode == modeDiff {
+		return m.renderDiffScreen(width)
+	}
+
 	title := fmt.Sprintf("─── Review: %s ", m.session.ID)
+	if m.session.Status != "" {
+		title += fmt.Sprintf("(%s) ", m.session.Status.Label())
+	}
 	if m.selection.active {
 		selStart, selEnd := m.selection.lines()
 		lineCount := selEnd - selStart + 1
 		title += fmt.Sprintf("[%d lines selected] ", lineCount)
 	}
+	if len(m.typeFilter) > 0 {
+		title += fmt.Sprintf("[filter: type=%s] ", strings.Join(m.typeFilter, ","))
+	}
+	if len(m.reviewed) > 0 {
+		title += fmt.Sprintf("[%d%% reviewed] ", m.reviewedPercent())
+	}
 	titleRunes := []rune(title)
 	if len(titleRunes) > width {
 		titleRunes = titleRunes[:width]
@@ -54,7 +64,9 @@ func (m Model) View() string {
 
 	annotatedLines := make(map[int]bool)
 	for _, ann := range m.annotations {
-		annotatedLines[ann.StartLine] = true
+		if m.shown(ann) {
+			annotatedLines[ann.StartLine] = true
+		}
 	}
 
 	// Get the currently previewed annotation for range highlighting
@@ -81,15 +93,18 @@ func (m Model) View() string {
 		}
 	}
 
+	if len(m.lines) > 0 {
+		start = m.displayLine(start)
+	}
+
 	screenRows := 0
 	end := start
 	for end < len(m.lines) && screenRows < visibleLines {
-		wrapped := wrapLine(m.lines[end], contentWidth)
-		screenRows += len(wrapped)
-		end++
+		screenRows += m.lineRows(end, contentWidth)
+		end = m.nextLine(end)
 	}
 
-	for i := start; i < end; i++ {
+	for i := start; i < end; i = m.nextLine(i) {
 		lineNum := fmt.Sprintf("%3d", i+1)
 		line := m.lines[i]
 
@@ -105,6 +120,8 @@ func (m Model) View() string {
 			} else {
 				selIndicator = "▌"
 			}
+		} else if m.reviewed[i] {
+			selIndicator = "✓"
 		}
 
 		// Annotation range highlight: show when previewing an annotation
@@ -125,19 +142,36 @@ func (m Model) View() string {
 			searchIndicator = "◎"
 		}
 
-		highlightedLine := m.highlighter.RenderLine(line)
+		if f, ok := m.foldAt(i); ok {
+			if m.annotationsIn(f.start, f.end) > 0 {
+				annIndicator = "●"
+			}
+			indicator := annIndicator
+			if searchIndicator != " " {
+				indicator = searchIndicator
+			}
+			summary := m.foldSummary(f)
+			header := []rune(line)
+			if room := contentWidth - len([]rune(summary)); len(header) > room {
+				header = append(header[:max(room-1, 0)], '…')
+			}
+			b.WriteString(fmt.Sprintf("%s%s%s %s %s │ %s%s\n", cursor, rangeIndicator, selIndicator, lineNum, indicator, m.renderLine(string(header)), summary))
+			continue
+		}
+
+		highlightedLine := m.renderLine(line)
 		isCurrentMatch := m.isCurrentSearchMatch(i)
 		if m.search.query != "" && m.isSearchMatch(i) {
 			highlightedLine = m.highlightSearchMatches(highlightedLine, line, isCurrentMatch)
 		}
 
-		wrapped := wrapLine(line, contentWidth)
+		wrapped := m.wrap(line, contentWidth)
 		for j, part := range wrapped {
 			var displayPart string
 			if j == 0 && len(wrapped) == 1 {
 				displayPart = highlightedLine
 			} else {
-				displayPart = m.highlighter.RenderLine(part)
+				displayPart = m.renderLine(part)
 				if m.search.query != "" && m.isSearchMatch(i) {
 					displayPart = m.highlightSearchMatches(displayPart, part, isCurrentMatch)
 				}
@@ -167,7 +201,7 @@ func (m Model) View() string {
 			boxTotalWidth = 64
 		}
 		innerWidth := boxTotalWidth - 4
-		header := fmt.Sprintf("─ %s (Ctrl+J newline, Enter submit) ", prompt)
+		header := fmt.Sprintf("─ %s (Ctrl+J newline, Ctrl+S snippet, Enter submit) ", prompt)
 		headerPad := boxTotalWidth - len([]rune(header)) - 2 // -2 for ┌ and ┐
 		if headerPad < 0 {
 			headerPad = 0
@@ -252,7 +286,7 @@ func (m Model) View() string {
 			b.WriteString("└────────────────────────────────────────────────────┘\n")
 		} else {
 			b.WriteString("┌─ Commands ─────────────────────────────────────────┐\n")
-			b.WriteString("│ [w]rite                                            │\n")
+			b.WriteString("│ [w]rite  [s]ubmit review  s[n]ippet                │\n")
 			if m.selection.active {
 				b.WriteString("├─ Annotations ──────────────────────────────────────┤\n")
 				b.WriteString("│ [c]omment  [d]elete  [q]uestion  [r]eplace         │\n")
@@ -272,15 +306,28 @@ func (m Model) View() string {
 		if len(m.search.matches) > 0 {
 			b.WriteString(fmt.Sprintf(" [%d/%d]", m.search.current+1, len(m.search.matches)))
 		}
+		b.WriteString("  " + m.searchOpts.String() + "  (Tab mode, Ctrl+T case, Ctrl+A scope)")
 	case modeHelp:
 		b.WriteString(m.renderHelpPanel(width))
 	case modeAnnotations:
 		b.WriteString(m.renderAnnotationsPanel(width))
+	case modeOutline:
+		b.WriteString(m.renderOutlinePanel(width))
+	case modeSnippets:
+		b.WriteString(m.renderSnippetsPanel(width))
+	case modeCommand:
+		b.WriteString(m.renderCommandLine(width))
 	default:
 		// Check if cursor is on an annotated line
 		cursorLine := m.cursor + 1 // 1-indexed
 		annotationIndices := m.annotationsOnLine(cursorLine)
-		if len(annotationIndices) > 0 && !m.selection.active {
+		if pending := m.pendingCommand(); pending != "" {
+			if len([]rune(pending)) > width {
+				pending = string([]rune(pending)[:width])
+			}
+			b.WriteString(pending)
+			b.WriteString("\n")
+		} else if len(annotationIndices) > 0 && !m.selection.active {
 			// Determine which annotation to show
 			previewIdx := 0
 			if m.previewLine == cursorLine && m.previewIndex < len(annotationIndices) {
@@ -316,49 +363,40 @@ func (m Model) View() string {
 
 var ErrTutorSession = errors.New("tutorial sessions are not saved")
 
-func (m Model) save() error {
-	if m.session.ID == tutor.SessionID {
-		return ErrTutorSession
-	}
+// snapshot returns the session with the current annotations rendered
+// into its content, as it would be saved.
+func (m Model) snapshot() *session.Session {
+	saved := *m.session
+	saved.Content = fem.Render(strings.Join(m.lines, "\n"), m.annotations)
+	saved.Reviewed = m.reviewedLines()
+	return &saved
+}
 
-	annotationsByLine := make(map[int][]fem.Annotation)
-	for _, a := range m.annotations {
-		annotationsByLine[a.StartLine] = append(annotationsByLine[a.StartLine], a)
+// renderLine highlights a line unless syntax highlighting is off.
+func (m Model) renderLine(line string) string {
+	if !m.options.syntax {
+		return line
 	}
+	return m.highlighter.RenderLine(line)
+}
 
-	var result []string
-	for i, line := range m.lines {
-		lineNum := i + 1
-		if anns, ok := annotationsByLine[lineNum]; ok {
-			for _, ann := range anns {
-				marker := fem.Markers[ann.Type]
-				line = line + " " + marker[0] + ann.Text + marker[1]
-			}
+// wrap splits a line into screen rows, or cuts it at the edge with nowrap.
+func (m Model) wrap(line string, width int) []string {
+	if !m.options.wrap {
+		if r := []rune(line); width > 0 && len(r) > width {
+			return []string{string(r[:width-1]) + "…"}
 		}
-		result = append(result, line)
+		return []string{line}
 	}
+	return wrapLine(line, width)
+}
 
-	content := strings.Join(result, "\n")
-
-	var sourceFileLine string
-	if m.session.SourceFile != "" {
-		escaped := strings.ReplaceAll(m.session.SourceFile, "'", "''")
-		sourceFileLine = fmt.Sprintf("source_file: '%s'\n", escaped)
+func (m Model) save() error {
+	if m.session.ID == tutor.SessionID {
+		return ErrTutorSession
 	}
 
-	fileContent := fmt.Sprintf(`---
-session_id: %s
-created_at: %s
-%s---
-
-%s`, m.session.ID, m.session.CreatedAt.Format(time.RFC3339), sourceFileLine, content)
-
-	sessionsDir, err := config.GetSessionsDir()
-	if err != nil {
-		return fmt.Errorf("failed to find project root: %w", err)
-	}
-	sessionPath := filepath.Join(sessionsDir, m.session.ID+".fem")
-	if err := os.WriteFile(sessionPath, []byte(fileContent), 0600); err != nil {
+	if err := session.Save(m.snapshot()); err != nil {
 		return fmt.Errorf("failed to save session: %w", err)
 	}
 	return nil
@@ -383,12 +421,12 @@ func (m Model) isCurrentSearchMatch(lineIdx int) bool {
 	return false
 }
 
+// highlightSearchMatches styles the spans of original the search matched.
+// Lines matched by annotation text have no spans to show.
 func (m Model) highlightSearchMatches(rendered, original string, isCurrent bool) string {
-	if m.search.query == "" {
+	if m.search.matcher == nil || m.search.annotations {
 		return rendered
 	}
-	query := strings.ToLower(m.search.query)
-	lowerOriginal := strings.ToLower(original)
 
 	var matchStyle lipgloss.Style
 	if isCurrent {
@@ -397,41 +435,22 @@ func (m Model) highlightSearchMatches(rendered, original string, isCurrent bool)
 		matchStyle = lipgloss.NewStyle().Background(lipgloss.Color("226")).Foreground(lipgloss.Color("0"))
 	}
 
-	matchPositions := fuzzyMatchPositions(lowerOriginal, query)
-	if len(matchPositions) == 0 {
+	spans := m.search.matcher.Spans(original)
+	if len(spans) == 0 {
 		return rendered
 	}
 
 	var result strings.Builder
 	lastEnd := 0
-	for _, pos := range matchPositions {
-		if pos > lastEnd {
-			result.WriteString(original[lastEnd:pos])
-		}
-		result.WriteString(matchStyle.Render(string(original[pos])))
-		lastEnd = pos + 1
-	}
-	if lastEnd < len(original) {
-		result.WriteString(original[lastEnd:])
+	for _, span := range spans {
+		result.WriteString(original[lastEnd:span[0]])
+		result.WriteString(matchStyle.Render(original[span[0]:span[1]]))
+		lastEnd = span[1]
 	}
+	result.WriteString(original[lastEnd:])
 	return result.String()
 }
 
-func fuzzyMatchPositions(text, pattern string) []int {
-	var positions []int
-	pIdx := 0
-	for i := 0; i < len(text) && pIdx < len(pattern); i++ {
-		if text[i] == pattern[pIdx] {
-			positions = append(positions, i)
-			pIdx++
-		}
-	}
-	if pIdx == len(pattern) {
-		return positions
-	}
-	return nil
-}
-
 // renderAnnotationPreview renders a preview box for annotations on the current line.
 // It displays the first annotation's content and shows a count if multiple exist.
 func (m Model) renderAnnotationPreview(annotationIndices []int, width int) string {
@@ -461,6 +480,10 @@ func (m Model) renderAnnotationPreviewAt(annotationIndices []int, previewIdx int
 
 	// Header: "─ type [start-end] ─────"
 	header := fmt.Sprintf("─ %s [%d-%d] ", ann.Type, ann.StartLine, ann.EndLine)
+	showDiff := ann.Type == "change" && m.diffPreviews[ann]
+	if showDiff {
+		header += "diff "
+	}
 	headerPad := boxTotalWidth - len([]rune(header)) - 2 // -2 for ┌ and ┐
 	if headerPad < 0 {
 		headerPad = 0
@@ -470,11 +493,19 @@ func (m Model) renderAnnotationPreviewAt(annotationIndices []int, previewIdx int
 	// Wrap annotation text to fit within inner width
 	textLines := wrapText(ann.Text, innerWidth)
 	maxLines := 3 // Limit preview to 3 lines
+	if showDiff {
+		// Diff rows are already padded to the inner width.
+		textLines, maxLines = m.renderChangeDiff(ann, innerWidth, m.diffSplit), 6
+	}
 	for i, line := range textLines {
 		if i >= maxLines {
 			b.WriteString(fmt.Sprintf("│ ...%s │\n", strings.Repeat(" ", innerWidth-4)))
 			break
 		}
+		if showDiff {
+			b.WriteString(fmt.Sprintf("│ %s │\n", line))
+			continue
+		}
 		lineRunes := []rune(line)
 		padding := innerWidth - len(lineRunes)
 		if padding < 0 {
@@ -541,15 +572,22 @@ func (m Model) renderAnnotationsPanel(width int) string {
 	innerWidth := boxWidth - 4
 
 	// Header
+	sorted := m.sortedAnnotations()
 	header := fmt.Sprintf("─ Annotations (%d) ", len(m.annotations))
+	if len(m.typeFilter) > 0 {
+		header = fmt.Sprintf("─ Annotations (%d/%d, type=%s) ", len(sorted), len(m.annotations), strings.Join(m.typeFilter, ","))
+	}
 	headerPad := boxWidth - len([]rune(header)) - 2
 	if headerPad < 0 {
 		headerPad = 0
 	}
 	b.WriteString(fmt.Sprintf("┌%s%s┐\n", header, strings.Repeat("─", headerPad)))
 
-	if len(m.annotations) == 0 {
+	if len(sorted) == 0 {
 		msg := "No annotations yet"
+		if len(m.annotations) > 0 {
+			msg = "No annotations match the filter"
+		}
 		padding := innerWidth - len([]rune(msg))
 		if padding < 0 {
 			padding = 0
@@ -568,7 +606,6 @@ func (m Model) renderAnnotationsPanel(width int) string {
 		}
 		b.WriteString(fmt.Sprintf("│ %s%s │\n", string(colRunes), strings.Repeat(" ", colPad)))
 
-		sorted := m.sortedAnnotations()
 		for i, ann := range sorted {
 			cursor := " "
 			if i == m.annotationsCursor {
@@ -652,16 +689,26 @@ func (m Model) renderHelpPanel(width int) string {
 
 	// Navigation section
 	writeRow("NAVIGATION", "")
-	writeRow("  j/k, ↑/↓", "move cursor")
+	writeRow("  j/k, ↑/↓", "move cursor (5j: five lines)")
 	writeRow("  Ctrl+d/u", "scroll half page")
-	writeRow("  gg / G", "jump to first/last line")
+	writeRow("  gg / G", "jump to first/last line (3G: line 3)")
 	writeRow("  zz/zt/zb", "center/top/bottom cursor")
+	writeRow("  zc/zo", "close/open fold at cursor")
+	writeRow("  zM/zR", "close/open all folds")
+	writeRow("  ma / 'a", "set mark a / jump to mark a")
+	writeRow("  Ctrl+o / Tab", "back / forward in the jumplist")
+	writeRow("  ]a / [a", "next/previous annotation (]c: comment)")
+	writeRow("  x", "mark line(s) reviewed (again: unmark)")
+	writeRow("  ]x / [x", "next/previous unreviewed lines")
 	writeRow("", "")
 
 	// Selection section
 	writeRow("SELECTION", "")
 	writeRow("  v", "toggle line selection")
 	writeRow("  ap / ab / as", "expand to paragraph/block/section")
+	writeRow("  af / ac / a{", "expand to function/class/brace block")
+	writeRow("  aa / ai", "expand to argument list/indented block")
+	writeRow("  if / i{ / ii", "select inside (no selection)")
 	writeRow("  { / }", "shrink / grow selection")
 	writeRow("  Esc", "clear selection")
 	writeRow("", "")
@@ -677,19 +724,32 @@ func (m Model) renderHelpPanel(width int) string {
 	writeRow("  i", "inline edit")
 	writeRow("", "")
 
+	writeRow("ANNOTATIONS (with a motion)", "")
+	writeRow("  c} / dap / q3j", "c d q u r + motion or text object")
+	writeRow("  cc / 3dd", "annotate the cursor line(s)")
+	writeRow("  .", "repeat last annotation on a new range")
+	writeRow("  Ctrl+S (typing)", "insert a snippet")
+	writeRow("", "")
+
 	// Editing section
 	writeRow("EDITING (no selection)", "")
 	writeRow("  e", "edit annotation text")
 	writeRow("  R", "edit annotation range")
+	writeRow("  D", "show change as a diff in the preview")
+	writeRow("  gd / :diff", "full-screen diff of a change")
 	writeRow("", "")
 
 	// General section
 	writeRow("GENERAL", "")
 	writeRow("  a", "annotations list")
+	writeRow("  o", "outline: headings/symbols")
 	writeRow("  /", "search")
 	writeRow("  n / N,p", "next/prev match")
 	writeRow("  Space", "command palette")
+	writeRow("  Space n", "annotate with a snippet (:snippet)")
+	writeRow("  :", "command line (:w :q :42 :set ...)")
 	writeRow("  w", "save session")
+	writeRow("  Space s", "submit review (save, mark ready, quit)")
 	writeRow("  ?", "this help")
 	writeRow("  Ctrl+C Ctrl+C", "quit")
 
@@ -703,3 +763,26 @@ func (m Model) renderHelpPanel(width int) string {
 
 	return b.String()
 }
+
+// submit saves the session and marks the review as ready for the agent.
+// Sessions that were applied or closed are reopened first, since the human
+// has reviewed them again.
+func (m Model) submit() error {
+	if err := m.save(); err != nil {
+		return err
+	}
+	sess, err := session.Load(m.session.ID)
+	if err != nil {
+		return fmt.Errorf("failed to submit review: %w", err)
+	}
+	if !session.CanTransition(sess.Status, session.StatusReady) && sess.Status != session.StatusReady {
+		if err := session.SetStatus(sess, session.StatusInProgress); err != nil {
+			return fmt.Errorf("failed to submit review: %w", err)
+		}
+	}
+	if err := session.SetStatus(sess, session.StatusReady); err != nil {
+		return fmt.Errorf("failed to submit review: %w", err)
+	}
+	m.session.Status = sess.Status
+	return nil
+}
@@ -305,10 +305,12 @@ async function listSessions(pi: ExtensionAPI, cwd: string): Promise<FabbroSessio
     throw new Error((result.stderr || result.stdout || "fabbro session list failed").trim());
   }
 
-  const sessions = parseJSON<Array<Omit<FabbroSessionListEntry, "resumeCommand">>>(
-    "fabbro session list --json",
-    result.stdout,
-  );
+  // fabbro before schemaVersion 1 printed a bare array.
+  const listed = parseJSON<
+    | Array<Omit<FabbroSessionListEntry, "resumeCommand">>
+    | { schemaVersion: number; sessions: Array<Omit<FabbroSessionListEntry, "resumeCommand">> }
+  >("fabbro session list --json", result.stdout);
+  const sessions = Array.isArray(listed) ? listed : listed?.sessions;
 
   return (Array.isArray(sessions) ? sessions : []).map((session) => ({
     ...session,